@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"time"
 
 	"github.com/lostz/Aegis/mysql"
 )
@@ -13,10 +14,65 @@ import (
 type Client interface {
 	Connect(addr string, user string, password string, db string) error
 	Execute(command string, args ...interface{}) (*mysql.Result, error)
+	UseDB(db string) error
+	FieldList(table string, wildcard string) ([]*mysql.Field, error)
+	Begin() error
+	Commit() error
 	Rollback() error
+	ConnectionId() uint32
+	Addr() string
+	KillQuery() error
+	SetResultLimit(limit *ResultLimit)
+	Ping() error
+	Reset() error
+	IsInTransaction() bool
+	Err() error
+	SetDeadline(t time.Time) error
 	Close() error
 }
 
+// ResultLimit bounds the resultsets read from the backend, 0 is no limit.
+// The rows past a limit are read and dropped so the connection stays in
+// step, with Truncate the rows before the limit are returned.
+type ResultLimit struct {
+	MaxRows  int64
+	MaxBytes int64
+	Truncate bool
+}
+
+func (self *ResultLimit) check(rows int64, size int64) error {
+	if self.MaxRows > 0 && rows > self.MaxRows {
+		return mysql.NewMysqlError(mysql.ER_TOO_BIG_SELECT,
+			fmt.Sprintf("The result exceeds the limit of %d rows", self.MaxRows))
+	}
+	if self.MaxBytes > 0 && size > self.MaxBytes {
+		return mysql.NewMysqlError(mysql.ER_TOO_BIG_SELECT,
+			fmt.Sprintf("The result exceeds the limit of %d bytes", self.MaxBytes))
+	}
+	return nil
+}
+
+// Apply holds r, a result put together by the proxy, to the limit. The
+// rows past it are dropped when the limit truncates, it is an error
+// otherwise. It tells whether rows were dropped.
+func (self *ResultLimit) Apply(r *mysql.Resultset) (bool, error) {
+	var size int64
+	for i, data := range r.RowDatas {
+		size += int64(len(data))
+		if err := self.check(int64(i+1), size); err != nil {
+			if !self.Truncate {
+				return false, err
+			}
+			r.RowDatas = r.RowDatas[:i]
+			if len(r.Values) > i {
+				r.Values = r.Values[:i]
+			}
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
 type Conn struct {
 	conn net.Conn
 
@@ -29,6 +85,9 @@ type Conn struct {
 
 	capability uint32
 
+	//thread id of this connection on the backend
+	connectionId uint32
+
 	status uint16
 
 	collation mysql.CollationId
@@ -38,6 +97,38 @@ type Conn struct {
 	lastPing int64
 
 	pkgErr error
+
+	compress          string
+	compressLevel     int
+	compressMinLength int
+
+	dialTimeout time.Duration
+
+	limit *ResultLimit
+}
+
+// SetCompress asks for the compressed protocol on the next (re)connect,
+// it is only used when the server supports it
+func (self *Conn) SetCompress(algorithm string, level int, minLength int) {
+	self.compress = algorithm
+	self.compressLevel = level
+	self.compressMinLength = minLength
+}
+
+// SetDialTimeout bounds the next (re)connects, the handshake included, 0
+// waits as long as the system does
+func (self *Conn) SetDialTimeout(timeout time.Duration) {
+	self.dialTimeout = timeout
+}
+
+// SetDeadline bounds the reads and writes on the connection, the zero
+// time lifts it. A command past the deadline fails with ErrBadConn and
+// the connection is of no use afterwards.
+func (self *Conn) SetDeadline(t time.Time) error {
+	if self.conn == nil {
+		return nil
+	}
+	return self.conn.SetDeadline(t)
 }
 
 func (self *Conn) Connect(addr string, user string, password string, db string) error {
@@ -58,13 +149,17 @@ func (self *Conn) ReConnect() error {
 		self.conn.Close()
 	}
 
-	conn, err := net.Dial("tcp", self.addr)
+	conn, err := net.DialTimeout("tcp", self.addr, self.dialTimeout)
 	if err != nil {
 		return err
 	}
+	if self.dialTimeout > 0 {
+		conn.SetDeadline(time.Now().Add(self.dialTimeout))
+	}
 
 	self.conn = conn
 	self.pkg = mysql.NewPackets(conn)
+	self.pkgErr = nil
 
 	if err := self.readInitialHandshake(); err != nil {
 		self.conn.Close()
@@ -83,6 +178,14 @@ func (self *Conn) ReConnect() error {
 		return err
 	}
 
+	if self.capability&mysql.CLIENT_COMPRESS > 0 {
+		if err := self.pkg.EnableCompression(self.compress, self.compressLevel, self.compressMinLength); err != nil {
+			self.conn.Close()
+
+			return err
+		}
+	}
+
 	//we must always use autocommit
 	if !self.IsAutoCommit() {
 		if _, err := self.exec("set autocommit = 1"); err != nil {
@@ -92,11 +195,15 @@ func (self *Conn) ReConnect() error {
 		}
 	}
 
+	if self.dialTimeout > 0 {
+		self.conn.SetDeadline(time.Time{})
+	}
+
 	return nil
 }
 
 func (self *Conn) readInitialHandshake() error {
-	data, err := self.pkg.ReadPacket()
+	data, err := self.readPacket()
 	if err != nil {
 		return err
 	}
@@ -109,10 +216,13 @@ func (self *Conn) readInitialHandshake() error {
 		return fmt.Errorf("invalid protocol version %d, must >= 10", data[0])
 	}
 
-	//skip mysql version and connection id
+	//skip mysql version
 	//mysql version end with 0x00
+	pos := 1 + bytes.IndexByte(data[1:], 0x00) + 1
+
 	//connection id length is 4
-	pos := 1 + bytes.IndexByte(data[1:], 0x00) + 1 + 4
+	self.connectionId = binary.LittleEndian.Uint32(data[pos : pos+4])
+	pos += 4
 
 	self.salt = append(self.salt, data[pos:pos+8]...)
 
@@ -153,7 +263,12 @@ func (self *Conn) readInitialHandshake() error {
 func (self *Conn) writeAuthHandshake() error {
 	// Adjust client capability flags based on server support
 	capability := mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_SECURE_CONNECTION |
-		mysql.CLIENT_LONG_PASSWORD | mysql.CLIENT_TRANSACTIONS | mysql.CLIENT_LONG_FLAG
+		mysql.CLIENT_LONG_PASSWORD | mysql.CLIENT_TRANSACTIONS | mysql.CLIENT_LONG_FLAG |
+		mysql.CLIENT_MULTI_RESULTS | mysql.CLIENT_PS_MULTI_RESULTS | mysql.CLIENT_DEPRECATE_EOF
+
+	if self.compress == mysql.CompressZlib {
+		capability |= mysql.CLIENT_COMPRESS
+	}
 
 	capability &= self.capability
 
@@ -217,7 +332,7 @@ func (self *Conn) writeAuthHandshake() error {
 		//data[pos] = 0x00
 	}
 
-	return self.pkg.WritePacket(data)
+	return self.writePacket(data)
 }
 
 func (self *Conn) Execute(command string, args ...interface{}) (*mysql.Result, error) {
@@ -235,10 +350,121 @@ func (self *Conn) Execute(command string, args ...interface{}) (*mysql.Result, e
 	}
 }
 
+// Close quits the session on the backend and closes the socket
 func (self *Conn) Close() error {
+	if self.conn == nil {
+		return nil
+	}
+
+	//the server does not answer COM_QUIT
+	if self.pkgErr == nil {
+		self.writeCommand(mysql.COM_QUIT)
+		self.pkg.Flush()
+	}
+	err := self.conn.Close()
+	self.conn = nil
+	return err
+}
+
+func (self *Conn) Ping() error {
+	if err := self.writeCommand(mysql.COM_PING); err != nil {
+		return err
+	}
+
+	if _, err := self.readOK(); err != nil {
+		return err
+	}
+
+	self.lastPing = time.Now().Unix()
+	return nil
+}
+
+// Reset drops the session state on the backend with COM_RESET_CONNECTION,
+// servers older than 5.7.3 do not know it and get a rollback instead
+func (self *Conn) Reset() error {
+	if err := self.writeCommand(mysql.COM_RESET_CONNECTION); err != nil {
+		return err
+	}
+
+	if _, err := self.readOK(); err != nil {
+		if e, ok := err.(*mysql.MysqlError); ok && e.Code == mysql.ER_UNKNOWN_COM_ERROR {
+			return self.Rollback()
+		}
+		return err
+	}
+
+	//the reset brings back the default autocommit of the server
+	if !self.IsAutoCommit() {
+		if _, err := self.exec("set autocommit = 1"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (self *Conn) IsInTransaction() bool {
+	return self.status&mysql.SERVER_STATUS_IN_TRANS > 0
+}
+
+// Err is the protocol error the connection saw, it can not be used
+// any more after one
+func (self *Conn) Err() error {
+	return self.pkgErr
+}
+
+func (self *Conn) UseDB(db string) error {
+	if len(db) == 0 || self.db == db {
+		return nil
+	}
+
+	if err := self.writeCommandStr(mysql.COM_INIT_DB, db); err != nil {
+		return err
+	}
+
+	if _, err := self.readOK(); err != nil {
+		return err
+	}
 
+	self.db = db
 	return nil
+}
+
+func (self *Conn) FieldList(table string, wildcard string) ([]*mysql.Field, error) {
+	if err := self.writeCommandStrStr(mysql.COM_FIELD_LIST, table, wildcard); err != nil {
+		return nil, err
+	}
+
+	var fields []*mysql.Field
+	for {
+		data, err := self.readPacket()
+		if err != nil {
+			return nil, err
+		}
+
+		if data[0] == mysql.ERR_HEADER {
+			return nil, self.handleErrorPacket(data)
+		}
+
+		if self.isEOFPacket(data) || (self.deprecateEOF() && self.isResultsetEnd(data)) {
+			return fields, nil
+		}
+
+		f, err := mysql.FieldData(data).Parse()
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+}
 
+func (self *Conn) Begin() error {
+	_, err := self.exec("begin")
+	return err
+}
+
+func (self *Conn) Commit() error {
+	_, err := self.exec("commit")
+	return err
 }
 
 func (self *Conn) Rollback() error {
@@ -246,12 +472,68 @@ func (self *Conn) Rollback() error {
 	return err
 }
 
+func (self *Conn) ConnectionId() uint32 {
+	return self.connectionId
+}
+
+func (self *Conn) Addr() string {
+	return self.addr
+}
+
+// SetResultLimit bounds the resultsets of the next statements, nil
+// removes the limit
+func (self *Conn) SetResultLimit(limit *ResultLimit) {
+	self.limit = limit
+}
+
+// KillQuery stops the statement running on this connection, the kill is
+// sent over a side connection because this one is busy with the statement
+func (self *Conn) KillQuery() error {
+	c := &Conn{}
+	c.SetCompress(self.compress, self.compressLevel, self.compressMinLength)
+	if err := c.Connect(self.addr, self.user, self.password, ""); err != nil {
+		return err
+	}
+	defer c.Close()
+
+	_, err := c.exec(fmt.Sprintf("KILL QUERY %d", self.connectionId))
+	return err
+}
+
 func (self *Conn) exec(query string) (*mysql.Result, error) {
 	if err := self.writeCommandStr(mysql.COM_QUERY, query); err != nil {
 		return nil, err
 	}
 
-	return self.readResult(false)
+	return self.readResults(false)
+}
+
+func (self *Conn) readPacket() ([]byte, error) {
+	data, err := self.pkg.ReadPacket()
+	if err != nil {
+		self.pkgErr = err
+	}
+	return data, err
+}
+
+func (self *Conn) writePacket(data []byte) error {
+	err := self.pkg.WritePacket(data)
+	if err != nil {
+		self.pkgErr = err
+	}
+	return err
+}
+
+func (self *Conn) writeCommand(command byte) error {
+	self.pkg.Sequence = 0
+
+	return self.writePacket([]byte{
+		0x01, //1 byte long
+		0x00,
+		0x00,
+		0x00, //sequence
+		command,
+	})
 }
 
 func (self *Conn) writeCommandStr(command byte, arg string) error {
@@ -265,13 +547,26 @@ func (self *Conn) writeCommandStr(command byte, arg string) error {
 
 	copy(data[5:], arg)
 
-	return self.pkg.WritePacket(data)
+	return self.writePacket(data)
+}
+
+func (self *Conn) writeCommandStrStr(command byte, arg1 string, arg2 string) error {
+	self.pkg.Sequence = 0
+
+	data := make([]byte, 4, 4+1+len(arg1)+1+len(arg2))
+
+	data = append(data, command)
+	data = append(data, arg1...)
+	data = append(data, 0)
+	data = append(data, arg2...)
+
+	return self.writePacket(data)
 }
 
 func (self *Conn) writeCommandUint32(command byte, arg uint32) error {
 	self.pkg.Sequence = 0
 
-	return self.pkg.WritePacket([]byte{
+	return self.writePacket([]byte{
 		0x05, //5 bytes long
 		0x00,
 		0x00,
@@ -287,7 +582,7 @@ func (self *Conn) writeCommandUint32(command byte, arg uint32) error {
 }
 
 func (self *Conn) readOK() (*mysql.Result, error) {
-	data, err := self.pkg.ReadPacket()
+	data, err := self.readPacket()
 	if err != nil {
 		return nil, err
 	}
@@ -301,8 +596,27 @@ func (self *Conn) readOK() (*mysql.Result, error) {
 	}
 }
 
+// readResults reads every result the server sends back for one command,
+// the results after the first one are chained through Result.Next
+func (self *Conn) readResults(binary bool) (*mysql.Result, error) {
+	r, err := self.readResult(binary)
+	if err != nil {
+		return nil, err
+	}
+
+	last := r
+	for last.Status&mysql.SERVER_MORE_RESULTS_EXISTS > 0 {
+		if last.Next, err = self.readResult(binary); err != nil {
+			return nil, err
+		}
+		last = last.Next
+	}
+
+	return r, nil
+}
+
 func (self *Conn) readResult(binary bool) (*mysql.Result, error) {
-	data, err := self.pkg.ReadPacket()
+	data, err := self.readPacket()
 	if err != nil {
 		return nil, err
 	}
@@ -322,6 +636,17 @@ func (self *Conn) isEOFPacket(data []byte) bool {
 	return data[0] == mysql.EOF_HEADER && len(data) <= 5
 }
 
+// isResultsetEnd reports the OK packet closing a result set when
+// CLIENT_DEPRECATE_EOF is used, a row starting with 0xfe would be
+// at least 16M long
+func (self *Conn) isResultsetEnd(data []byte) bool {
+	return data[0] == mysql.EOF_HEADER && len(data) < mysql.MaxPayloadLen
+}
+
+func (self *Conn) deprecateEOF() bool {
+	return self.capability&mysql.CLIENT_DEPRECATE_EOF > 0
+}
+
 func (self *Conn) handleOKPacket(data []byte) (*mysql.Result, error) {
 	var n int
 	var pos int = 1
@@ -406,11 +731,21 @@ func (self *Conn) readResultColumns(result *mysql.Result) (err error) {
 	var data []byte
 
 	for {
-		data, err = self.pkg.ReadPacket()
+		//without EOF packets the column count tells where they end
+		if self.deprecateEOF() && i == len(result.Fields) {
+			return
+		}
+
+		data, err = self.readPacket()
 		if err != nil {
 			return
 		}
 
+		//the server gave up, nothing follows the error
+		if data[0] == mysql.ERR_HEADER {
+			return self.handleErrorPacket(data)
+		}
+
 		// EOF Packet
 		if self.isEOFPacket(data) {
 			if self.capability&mysql.CLIENT_PROTOCOL_41 > 0 {
@@ -440,14 +775,32 @@ func (self *Conn) readResultColumns(result *mysql.Result) (err error) {
 
 func (self *Conn) readResultRows(result *mysql.Result, isBinary bool) (err error) {
 	var data []byte
+	var rows, size int64
+	var exceeded error
 
 	for {
-		data, err = self.pkg.ReadPacket()
+		data, err = self.readPacket()
 
 		if err != nil {
 			return
 		}
 
+		//like ER_QUERY_INTERRUPTED after a KILL QUERY, nothing follows
+		//the error
+		if data[0] == mysql.ERR_HEADER {
+			return self.handleErrorPacket(data)
+		}
+
+		// OK Packet with EOF header
+		if self.deprecateEOF() && self.isResultsetEnd(data) {
+			var r *mysql.Result
+			if r, err = self.handleOKPacket(data); err != nil {
+				return
+			}
+			result.Status = r.Status
+			break
+		}
+
 		// EOF Packet
 		if self.isEOFPacket(data) {
 			if self.capability&mysql.CLIENT_PROTOCOL_41 > 0 {
@@ -460,7 +813,28 @@ func (self *Conn) readResultRows(result *mysql.Result, isBinary bool) (err error
 			break
 		}
 
-		result.RowDatas = append(result.RowDatas, data)
+		if exceeded != nil || result.Truncated {
+			continue
+		}
+		if self.limit != nil {
+			rows++
+			size += int64(len(data))
+			if e := self.limit.check(rows, size); e != nil {
+				if self.limit.Truncate {
+					result.Truncated = true
+				} else {
+					exceeded = e
+				}
+				continue
+			}
+		}
+
+		//the packet buffer is reused by the next read
+		result.RowDatas = append(result.RowDatas, append([]byte(nil), data...))
+	}
+
+	if exceeded != nil {
+		return exceeded
 	}
 
 	result.Values = make([][]interface{}, len(result.RowDatas))
@@ -476,11 +850,26 @@ func (self *Conn) readResultRows(result *mysql.Result, isBinary bool) (err error
 	return nil
 }
 
+// readDefinitions skips count parameter or column definitions, they are
+// closed by an EOF packet unless CLIENT_DEPRECATE_EOF was negotiated
+func (self *Conn) readDefinitions(count int) error {
+	for i := 0; i < count; i++ {
+		if _, err := self.readPacket(); err != nil {
+			return err
+		}
+	}
+
+	if self.deprecateEOF() {
+		return nil
+	}
+	return self.readUntilEOF()
+}
+
 func (self *Conn) readUntilEOF() (err error) {
 	var data []byte
 
 	for {
-		data, err = self.pkg.ReadPacket()
+		data, err = self.readPacket()
 
 		if err != nil {
 			return
@@ -491,7 +880,6 @@ func (self *Conn) readUntilEOF() (err error) {
 			return
 		}
 	}
-	return
 }
 
 func (self *Conn) IsAutoCommit() bool {
@@ -0,0 +1,144 @@
+package backend
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/lostz/Aegis/mysql"
+)
+
+// testConn is a connection with the capabilities given, a fake server on
+// the other end answers its next command with the packets
+func testConn(t *testing.T, capability uint32, packets ...[]byte) *Conn {
+	client, server := net.Pipe()
+	//a reader waiting for packets that never come fails the test
+	client.SetDeadline(time.Now().Add(time.Second))
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+
+	go func() {
+		p := mysql.NewPackets(server)
+		if _, err := p.ReadPacket(); err != nil {
+			return
+		}
+		for _, data := range packets {
+			if err := p.WritePacket(append(make([]byte, 4), data...)); err != nil {
+				return
+			}
+		}
+	}()
+
+	return &Conn{conn: client, pkg: mysql.NewPackets(client), capability: capability}
+}
+
+func columnDef(name string) []byte {
+	return (&mysql.Field{Name: []byte(name), Type: mysql.MYSQL_TYPE_VAR_STRING}).Dump()
+}
+
+func textRow(values ...string) []byte {
+	var data []byte
+	for _, v := range values {
+		data = append(data, byte(len(v)))
+		data = append(data, v...)
+	}
+	return data
+}
+
+// eofPacket is an EOF packet with the autocommit status
+func eofPacket() []byte {
+	return []byte{mysql.EOF_HEADER, 0, 0, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0}
+}
+
+// okEOFPacket is the OK packet ending the rows with CLIENT_DEPRECATE_EOF
+func okEOFPacket() []byte {
+	return []byte{mysql.EOF_HEADER, 0, 0, byte(mysql.SERVER_STATUS_AUTOCOMMIT), 0, 0, 0}
+}
+
+func errPacket(code uint16, state string, message string) []byte {
+	data := []byte{mysql.ERR_HEADER, byte(code), byte(code >> 8), '#'}
+	data = append(data, state...)
+	return append(data, message...)
+}
+
+// resultsetPackets is a result set of one column a and the rows, with or
+// without the EOF packets as CLIENT_DEPRECATE_EOF says
+func resultsetPackets(deprecateEOF bool, rows ...[]byte) [][]byte {
+	packets := [][]byte{{1}, columnDef("a")}
+	if !deprecateEOF {
+		packets = append(packets, eofPacket())
+	}
+	packets = append(packets, rows...)
+	if deprecateEOF {
+		return append(packets, okEOFPacket())
+	}
+	return append(packets, eofPacket())
+}
+
+func TestResultset(t *testing.T) {
+	for _, deprecateEOF := range []bool{false, true} {
+		capability := uint32(mysql.CLIENT_PROTOCOL_41)
+		if deprecateEOF {
+			capability |= mysql.CLIENT_DEPRECATE_EOF
+		}
+		c := testConn(t, capability, resultsetPackets(deprecateEOF, textRow("1"), textRow("2"))...)
+		r, err := c.Execute("select a from t")
+		if err != nil {
+			t.Fatalf("deprecate eof %v: %v", deprecateEOF, err)
+		}
+		var got []string
+		for i := range r.Values {
+			s, _ := r.GetString(i, 0)
+			got = append(got, s)
+		}
+		if !reflect.DeepEqual(got, []string{"1", "2"}) || r.Status != mysql.SERVER_STATUS_AUTOCOMMIT {
+			t.Fatalf("deprecate eof %v: rows %v status %d", deprecateEOF, got, r.Status)
+		}
+		//the connection is ready for the next command
+		if !c.IsAutoCommit() || c.pkgErr != nil {
+			t.Fatalf("deprecate eof %v: status %d, %v", deprecateEOF, c.status, c.pkgErr)
+		}
+	}
+}
+
+func TestErrorBetweenRows(t *testing.T) {
+	interrupted := errPacket(mysql.ER_QUERY_INTERRUPTED, "70100", "Query execution was interrupted")
+	for _, deprecateEOF := range []bool{false, true} {
+		capability := uint32(mysql.CLIENT_PROTOCOL_41)
+		if deprecateEOF {
+			capability |= mysql.CLIENT_DEPRECATE_EOF
+		}
+
+		//in the rows
+		packets := resultsetPackets(deprecateEOF, textRow("1"))
+		packets = append(packets[:len(packets)-1], interrupted)
+		c := testConn(t, capability, packets...)
+		_, err := c.Execute("select a from t")
+		if e, ok := err.(*mysql.MysqlError); !ok || e.Code != mysql.ER_QUERY_INTERRUPTED || e.State != "70100" {
+			t.Fatalf("deprecate eof %v: rows ended by %v", deprecateEOF, err)
+		}
+
+		//in the columns
+		c = testConn(t, capability, []byte{2}, columnDef("a"), interrupted)
+		_, err = c.Execute("select a, b from t")
+		if e, ok := err.(*mysql.MysqlError); !ok || e.Code != mysql.ER_QUERY_INTERRUPTED {
+			t.Fatalf("deprecate eof %v: columns ended by %v", deprecateEOF, err)
+		}
+	}
+}
+
+func TestDeadline(t *testing.T) {
+	//the server reads the command and never answers
+	c := testConn(t, mysql.CLIENT_PROTOCOL_41)
+	c.SetDeadline(time.Now().Add(50 * time.Millisecond))
+	start := time.Now()
+	if _, err := c.Execute("select sleep(10)"); err != mysql.ErrBadConn {
+		t.Fatalf("%v", err)
+	}
+	if d := time.Since(start); d > 500*time.Millisecond {
+		t.Fatalf("gave up after %v", d)
+	}
+}
@@ -23,7 +23,7 @@ func (self *Stmt) Execute(args ...interface{}) (*mysql.Result, error) {
 		return nil, err
 	}
 
-	return self.conn.readResult(true)
+	return self.conn.readResults(true)
 }
 
 func (self *Stmt) Close() error {
@@ -153,15 +153,15 @@ func (self *Stmt) write(args ...interface{}) error {
 
 	self.conn.pkg.Sequence = 0
 
-	return self.conn.pkg.WritePacket(data)
+	return self.conn.writePacket(data)
 }
 
-func (self Conn) Prepare(query string) (*Stmt, error) {
+func (self *Conn) Prepare(query string) (*Stmt, error) {
 	if err := self.writeCommandStr(mysql.COM_STMT_PREPARE, query); err != nil {
 		return nil, err
 	}
 
-	data, err := self.pkg.ReadPacket()
+	data, err := self.readPacket()
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +173,7 @@ func (self Conn) Prepare(query string) (*Stmt, error) {
 	}
 
 	s := new(Stmt)
-	s.conn = &self
+	s.conn = self
 
 	pos := 1
 
@@ -193,13 +193,13 @@ func (self Conn) Prepare(query string) (*Stmt, error) {
 	//warnings = binary.LittleEndian.Uint16(data[pos:])
 
 	if s.params > 0 {
-		if err := s.conn.readUntilEOF(); err != nil {
+		if err := s.conn.readDefinitions(s.params); err != nil {
 			return nil, err
 		}
 	}
 
 	if s.columns > 0 {
-		if err := s.conn.readUntilEOF(); err != nil {
+		if err := s.conn.readDefinitions(s.columns); err != nil {
 			return nil, err
 		}
 	}
@@ -0,0 +1,242 @@
+package balance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/logging"
+)
+
+var logger = logging.GetLogger("balance")
+
+var nowFunc = time.Now
+
+// BreakerConfig is when a server is ejected: within Window at least
+// MinRequests statements ran and ErrorRate percent of them failed, or
+// their p99 took Latency or more. The server gets nothing for Ejection,
+// doubled for each ejection in a row up to MaxEjection, then one
+// statement probes it. MinRequests 0 turns the breaker off, so does 0
+// for both ErrorRate and Latency.
+type BreakerConfig struct {
+	Window      time.Duration
+	MinRequests int
+	ErrorRate   int
+	Latency     time.Duration
+	Ejection    time.Duration
+	MaxEjection time.Duration
+}
+
+const (
+	BreakerClosed   = "closed"
+	BreakerOpen     = "open"
+	BreakerHalfOpen = "half-open"
+)
+
+// windowBuckets is how many slices the window is kept in, the oldest is
+// dropped as time moves on
+const windowBuckets = 10
+
+// maxDoubling bounds the growth of the ejection before MaxEjection
+// applies, so the shift can not overflow
+const maxDoubling = 16
+
+type bucket struct {
+	start    time.Time
+	requests int
+	failures int
+	//statements that took Latency or more
+	slow int
+}
+
+// BreakerStats is a snapshot of a breaker for monitoring
+type BreakerStats struct {
+	State string
+	//ejections so far, the end of the current or last one and why
+	Ejections int
+	Until     time.Time
+	Reason    string
+}
+
+// Breaker watches the statements of one server. It is fed from the
+// goroutines of the sessions and read by the Rebalancer.
+type Breaker struct {
+	mu      sync.Mutex
+	name    string
+	cfg     BreakerConfig
+	buckets [windowBuckets]bucket
+
+	state  string
+	until  time.Time
+	closed time.Time
+	//ejections in a row, they make the next one longer
+	inRow int
+	probe time.Time
+
+	ejections int
+	reason    string
+}
+
+// NewBreaker is the breaker of the server called name in the logs
+func NewBreaker(name string, cfg BreakerConfig) *Breaker {
+	if cfg.Window <= 0 {
+		cfg.Window = 10 * time.Second
+	}
+	if cfg.Ejection <= 0 {
+		cfg.Ejection = 30 * time.Second
+	}
+	if cfg.MaxEjection < cfg.Ejection {
+		cfg.MaxEjection = cfg.Ejection
+	}
+	return &Breaker{name: name, cfg: cfg, state: BreakerClosed}
+}
+
+func (self *Breaker) enabled() bool {
+	return self.cfg.MinRequests > 0 && (self.cfg.ErrorRate > 0 || self.cfg.Latency > 0)
+}
+
+// Observe records a statement, failed is for errors of the server
+// itself rather than of the statement
+func (self *Breaker) Observe(d time.Duration, failed bool) {
+	if !self.enabled() {
+		return
+	}
+	now := nowFunc()
+	self.mu.Lock()
+	defer self.mu.Unlock()
+
+	switch self.state {
+	case BreakerOpen:
+		//statements of connections borrowed before the ejection
+		return
+	case BreakerHalfOpen:
+		if failed || (self.cfg.Latency > 0 && d >= self.cfg.Latency) {
+			self.eject(now, "probe failed")
+			return
+		}
+		self.state = BreakerClosed
+		self.closed = now
+		self.probe = time.Time{}
+		return
+	}
+
+	b := self.bucket(now)
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if self.cfg.Latency > 0 && d >= self.cfg.Latency {
+		b.slow++
+	}
+
+	if reason := self.trip(now); reason != "" {
+		self.eject(now, reason)
+	}
+}
+
+// bucket is the bucket of now, an old one is emptied for it
+func (self *Breaker) bucket(now time.Time) *bucket {
+	width := self.cfg.Window / windowBuckets
+	if width <= 0 {
+		width = time.Millisecond
+	}
+	start := now.Truncate(width)
+	b := &self.buckets[int(start.UnixNano()/int64(width))%windowBuckets]
+	if !b.start.Equal(start) {
+		*b = bucket{start: start}
+	}
+	return b
+}
+
+// trip says why the window calls for an ejection, empty when it does not
+func (self *Breaker) trip(now time.Time) string {
+	var requests, failures, slow int
+	for i := range self.buckets {
+		b := &self.buckets[i]
+		if b.start.IsZero() || now.Sub(b.start) >= self.cfg.Window {
+			continue
+		}
+		requests += b.requests
+		failures += b.failures
+		slow += b.slow
+	}
+	if requests < self.cfg.MinRequests {
+		return ""
+	}
+
+	if self.cfg.ErrorRate > 0 && failures*100 >= requests*self.cfg.ErrorRate {
+		return fmt.Sprintf("%d of %d statements failed", failures, requests)
+	}
+	//the p99 is at least Latency once more than 1% took that long
+	if self.cfg.Latency > 0 && slow*100 > requests {
+		return fmt.Sprintf("p99 over %s, %d of %d statements", self.cfg.Latency.String(), slow, requests)
+	}
+	return ""
+}
+
+// eject opens the breaker, the ejection doubles each time unless the
+// server stayed in for MaxEjection since the last one
+func (self *Breaker) eject(now time.Time, reason string) {
+	if self.inRow > 0 && self.state == BreakerClosed && now.Sub(self.closed) >= self.cfg.MaxEjection {
+		self.inRow = 0
+	}
+	d := self.cfg.MaxEjection
+	if self.inRow < maxDoubling {
+		if e := self.cfg.Ejection << uint(self.inRow); e < d {
+			d = e
+		}
+	}
+	self.inRow++
+	self.ejections++
+	self.state = BreakerOpen
+	self.until = now.Add(d)
+	self.reason = reason
+	self.probe = time.Time{}
+	for i := range self.buckets {
+		self.buckets[i] = bucket{}
+	}
+	logger.Warningf("backend %s ejected for %s: %s", self.name, d.String(), reason)
+}
+
+// available is whether the server may be picked, a half-open breaker
+// takes one probe at a time
+func (self *Breaker) available(now time.Time) bool {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	switch self.state {
+	case BreakerOpen:
+		return !now.Before(self.until)
+	case BreakerHalfOpen:
+		//a probe that never reported back does not block for good
+		return self.probe.IsZero() || now.Sub(self.probe) >= self.cfg.Window
+	}
+	return true
+}
+
+// pick is called for the server the Rebalancer chose, it starts the
+// probe of an ejected server that is due
+func (self *Breaker) pick(now time.Time) {
+	self.mu.Lock()
+	if self.state == BreakerOpen && !now.Before(self.until) {
+		self.state = BreakerHalfOpen
+	}
+	if self.state == BreakerHalfOpen {
+		self.probe = now
+	}
+	self.mu.Unlock()
+}
+
+func (self *Breaker) Stats() BreakerStats {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	state := self.state
+	if state == BreakerOpen && !nowFunc().Before(self.until) {
+		state = BreakerHalfOpen
+	}
+	return BreakerStats{
+		State:     state,
+		Ejections: self.ejections,
+		Until:     self.until,
+		Reason:    self.reason,
+	}
+}
@@ -0,0 +1,186 @@
+package balance
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/pool"
+)
+
+// fakeClock moves nowFunc by hand for the duration of a test
+type fakeClock struct {
+	now time.Time
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{now: time.Unix(1000000, 0)}
+	nowFunc = func() time.Time { return c.now }
+	return c
+}
+
+func (self *fakeClock) add(d time.Duration) {
+	self.now = self.now.Add(d)
+}
+
+var testBreaker = BreakerConfig{
+	Window:      10 * time.Second,
+	MinRequests: 10,
+	ErrorRate:   50,
+	Latency:     100 * time.Millisecond,
+	Ejection:    time.Second,
+	MaxEjection: 4 * time.Second,
+}
+
+func TestBreakerErrorRate(t *testing.T) {
+	clock := useFakeClock(t)
+	defer func() { nowFunc = time.Now }()
+
+	b := NewBreaker("a", testBreaker)
+	for i := 0; i < 9; i++ {
+		b.Observe(time.Millisecond, true)
+	}
+	if s := b.Stats(); s.State != BreakerClosed {
+		t.Fatalf("ejected below MinRequests: %+v", s)
+	}
+	b.Observe(time.Millisecond, true)
+	if s := b.Stats(); s.State != BreakerOpen || s.Ejections != 1 || !s.Until.Equal(clock.now.Add(time.Second)) {
+		t.Fatalf("not ejected after 10 failures: %+v", s)
+	}
+	if b.available(clock.now) {
+		t.Fatal("ejected server available")
+	}
+}
+
+func TestBreakerWindowSlides(t *testing.T) {
+	clock := useFakeClock(t)
+	defer func() { nowFunc = time.Now }()
+
+	b := NewBreaker("a", testBreaker)
+	for i := 0; i < 9; i++ {
+		b.Observe(time.Millisecond, true)
+	}
+	//the failures leave the window, the successes do not make up for them
+	clock.add(11 * time.Second)
+	for i := 0; i < 9; i++ {
+		b.Observe(time.Millisecond, false)
+	}
+	b.Observe(time.Millisecond, true)
+	if s := b.Stats(); s.State != BreakerClosed {
+		t.Fatalf("ejected for failures out of the window: %+v", s)
+	}
+}
+
+func TestBreakerLatency(t *testing.T) {
+	useFakeClock(t)
+	defer func() { nowFunc = time.Now }()
+
+	b := NewBreaker("a", testBreaker)
+	for i := 0; i < 99; i++ {
+		b.Observe(time.Millisecond, false)
+	}
+	b.Observe(200*time.Millisecond, false)
+	if s := b.Stats(); s.State != BreakerClosed {
+		t.Fatalf("ejected with 1%% slow: %+v", s)
+	}
+	b.Observe(200*time.Millisecond, false)
+	if s := b.Stats(); s.State != BreakerOpen {
+		t.Fatalf("not ejected with 2%% slow: %+v", s)
+	}
+}
+
+func TestBreakerHalfOpen(t *testing.T) {
+	clock := useFakeClock(t)
+	defer func() { nowFunc = time.Now }()
+
+	b := NewBreaker("a", testBreaker)
+	trip := func() {
+		for i := 0; i < 10; i++ {
+			b.Observe(time.Millisecond, true)
+		}
+	}
+	trip()
+
+	//failed probes double the ejection up to MaxEjection
+	for _, d := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 4 * time.Second} {
+		s := b.Stats()
+		if got := s.Until.Sub(clock.now); got != d {
+			t.Fatalf("ejected for %s, want %s", got, d)
+		}
+		clock.add(d)
+		if !b.available(clock.now) {
+			t.Fatal("not available after the ejection")
+		}
+		b.pick(clock.now)
+		if b.available(clock.now) {
+			t.Fatal("second probe while one runs")
+		}
+		b.Observe(time.Millisecond, true)
+	}
+
+	clock.add(4 * time.Second)
+	b.pick(clock.now)
+	b.Observe(time.Millisecond, false)
+	if s := b.Stats(); s.State != BreakerClosed || s.Ejections != 5 {
+		t.Fatalf("probe passed: %+v", s)
+	}
+
+	//a long time in, the next ejection starts short again
+	clock.add(5 * time.Second)
+	trip()
+	if s := b.Stats(); s.Until.Sub(clock.now) != time.Second {
+		t.Fatalf("ejected for %s after a clean run", s.Until.Sub(clock.now))
+	}
+}
+
+func TestBreakerDisabled(t *testing.T) {
+	b := NewBreaker("a", BreakerConfig{})
+	for i := 0; i < 100; i++ {
+		b.Observe(time.Second, true)
+	}
+	if s := b.Stats(); s.State != BreakerClosed {
+		t.Fatalf("disabled breaker ejected: %+v", s)
+	}
+}
+
+func TestRebalancerEjects(t *testing.T) {
+	clock := useFakeClock(t)
+	defer func() { nowFunc = time.Now }()
+
+	servers := newServers(1, 1)
+	failing := pool.NewPool(func() (backend.Client, error) {
+		return nil, errors.New("down")
+	}, 10)
+	failing.Addr = "c"
+	servers = append(servers, NewRbServer(1, failing))
+	r := NewRebalancer(servers, NewWeightedRoundRobin())
+	r.SetBreaker(testBreaker)
+
+	//the dial failures of c eject it
+	for i := 0; i < 30; i++ {
+		if c, err := r.Get(); err == nil {
+			c.Close()
+		}
+	}
+	if s := servers[2].Breaker(); s.State != BreakerOpen {
+		t.Fatalf("failing server not ejected: %+v", s)
+	}
+	if c := picks(t, r, 10); c["c"] != 0 {
+		t.Fatalf("ejected server picked %v", c)
+	}
+
+	//with every server ejected they are all used again
+	r.SetGood("a", false)
+	r.SetGood("b", false)
+	if c := picks(t, r, 3); c["c"] != 3 {
+		t.Fatalf("only server left not picked %v", c)
+	}
+
+	r.SetGood("a", true)
+	r.SetGood("b", true)
+	clock.add(time.Second)
+	if c := picks(t, r, 3); c["c"] != 1 {
+		t.Fatalf("one probe wanted, picked %v", c)
+	}
+}
@@ -0,0 +1,93 @@
+package balance
+
+import (
+	"math/rand"
+	"time"
+)
+
+// LeastOutstanding picks the server with the fewest borrowed connections
+// for its weight, ties go round robin
+type LeastOutstanding struct {
+	withLatency bool
+	next        int
+}
+
+func NewLeastOutstanding() *LeastOutstanding {
+	return &LeastOutstanding{}
+}
+
+// NewEWMA is LeastOutstanding with each borrowed connection counting for
+// the average statement time of its server, so a slow server gets less
+func NewEWMA() *LeastOutstanding {
+	return &LeastOutstanding{withLatency: true}
+}
+
+func (self *LeastOutstanding) Next(servers []*RbServer) *RbServer {
+	var best *RbServer
+	var bestLoad float64
+	n := len(servers)
+	for i := 0; i < n; i++ {
+		s := servers[(self.next+i)%n]
+		if s.effectiveWeight() <= 0 {
+			continue
+		}
+		if l := load(s, self.withLatency); best == nil || l < bestLoad {
+			best, bestLoad = s, l
+		}
+	}
+	self.next++
+	return best
+}
+
+func (self *LeastOutstanding) Reset(servers []*RbServer) {
+	self.next = 0
+}
+
+// PowerOfTwo draws two servers at random by weight and takes the less
+// loaded one, cheaper than looking at all of them and without the herd
+// LeastOutstanding sends to a server that just became idle
+type PowerOfTwo struct {
+	rand *rand.Rand
+}
+
+func NewPowerOfTwo() *PowerOfTwo {
+	return &PowerOfTwo{rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+func (self *PowerOfTwo) Next(servers []*RbServer) *RbServer {
+	a := self.draw(servers)
+	if a == nil {
+		return nil
+	}
+	b := self.draw(servers)
+	if load(b, false) < load(a, false) {
+		return b
+	}
+	return a
+}
+
+func (self *PowerOfTwo) draw(servers []*RbServer) *RbServer {
+	total := 0
+	for _, s := range servers {
+		if w := s.effectiveWeight(); w > 0 {
+			total += w
+		}
+	}
+	if total == 0 {
+		return nil
+	}
+	n := self.rand.Intn(total)
+	for _, s := range servers {
+		w := s.effectiveWeight()
+		if w <= 0 {
+			continue
+		}
+		if n < w {
+			return s
+		}
+		n -= w
+	}
+	return nil
+}
+
+func (self *PowerOfTwo) Reset(servers []*RbServer) {}
@@ -1,24 +1,37 @@
 package balance
 
 import (
+	"errors"
 	"fmt"
 	"io"
+	"sync"
 
 	"github.com/lostz/Aegis/pool"
 )
 
+var ErrServerNotFound = errors.New("no such server")
+var ErrServerExists = errors.New("server already exists")
+
+// Rebalancer spreads the load over servers with a Strategy. The servers
+// and their weights can be changed while it is in use, a server that is
+// not good or has weight 0 gets nothing.
 type Rebalancer struct {
-	index         int
-	gcb           int
-	cw            int
-	servers       []*RbServer
-	currentWeight int
+	mu       sync.Mutex
+	strategy Strategy
+	servers  []*RbServer
+	breaker  *BreakerConfig
 }
 
 type RbServer struct {
 	weight int
 	good   bool
 	pool   *pool.Pool
+
+	//ejected by its breaker or excluded by the caller for the
+	//current pick
+	breaker  *Breaker
+	ejected  bool
+	excluded bool
 }
 
 func NewRbServer(w int, p *pool.Pool) *RbServer {
@@ -26,55 +39,194 @@ func NewRbServer(w int, p *pool.Pool) *RbServer {
 
 }
 
-func NewRebalancer(s []*RbServer) Rebalancer {
-	r := Rebalancer{index: -1, servers: s}
-	r.getgcd()
+func NewRebalancer(s []*RbServer, strategy Strategy) *Rebalancer {
+	r := &Rebalancer{strategy: strategy, servers: s}
+	r.strategy.Reset(s)
 	return r
 }
 
 func (self *Rebalancer) Get() (io.Closer, error) {
-	for {
-		self.index = (self.index + 1) % len(self.servers)
-		if self.index == 0 {
-			self.cw = self.cw - self.gcb
-			if self.cw <= 0 {
-				self.cw = self.getMaxCw()
-				if self.cw == 0 {
-					return nil, fmt.Errorf("no avaliable server")
-				}
+	return self.GetExcept()
+}
+
+// GetExcept is Get from a server other than the ones dialing exclude
+func (self *Rebalancer) GetExcept(exclude ...string) (io.Closer, error) {
+	p, err := self.next(exclude...)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get()
+}
+
+func (self *Rebalancer) next(exclude ...string) (*pool.Pool, error) {
+	self.mu.Lock()
+	now := nowFunc()
+	usable := 0
+	for _, s := range self.servers {
+		s.ejected = s.breaker != nil && !s.breaker.available(now)
+		s.excluded = false
+		for _, addr := range exclude {
+			if s.pool.Addr == addr {
+				s.excluded = true
 			}
 		}
-		if wt := self.servers[self.index].weight; wt >= self.cw {
-			con, err := self.servers[self.index].pool.Get()
-			return con, err
+		if s.good && s.weight > 0 && !s.ejected && !s.excluded {
+			usable++
 		}
+	}
+	if usable == 0 {
+		//all that is left is ejected, better a sick server than none
+		for _, s := range self.servers {
+			s.ejected = false
+		}
+	}
+	s := self.strategy.Next(self.servers)
+	if s != nil && s.breaker != nil {
+		s.breaker.pick(now)
+	}
+	self.mu.Unlock()
+	if s == nil {
+		return nil, fmt.Errorf("no avaliable server")
+	}
+	return s.pool, nil
+}
 
+// SetBreaker gives each server a Breaker fed by its pool, the servers
+// added later get one as well
+func (self *Rebalancer) SetBreaker(cfg BreakerConfig) {
+	self.mu.Lock()
+	self.breaker = &cfg
+	for _, s := range self.servers {
+		self.addBreaker(s)
 	}
+	self.mu.Unlock()
+}
 
+func (self *Rebalancer) addBreaker(s *RbServer) {
+	s.breaker = NewBreaker(s.pool.Addr, *self.breaker)
+	s.pool.SetObserver(s.breaker.Observe)
 }
 
-func (self *Rebalancer) getMaxCw() int {
-	max := -1
+// Pools are the connection pools of the servers
+func (self *Rebalancer) Pools() []*pool.Pool {
+	self.mu.Lock()
+	pools := make([]*pool.Pool, 0, len(self.servers))
 	for _, s := range self.servers {
-		if s.weight > max {
-			max = s.weight
+		pools = append(pools, s.pool)
+	}
+	self.mu.Unlock()
+	return pools
+}
+
+// Servers returns a copy of the servers, later changes do not show
+func (self *Rebalancer) Servers() []*RbServer {
+	self.mu.Lock()
+	servers := make([]*RbServer, 0, len(self.servers))
+	for _, s := range self.servers {
+		c := *s
+		servers = append(servers, &c)
+	}
+	self.mu.Unlock()
+	return servers
+}
+
+// SetWeight changes the weight of the server dialing addr
+func (self *Rebalancer) SetWeight(addr string, w int) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	s := self.find(addr)
+	if s == nil {
+		return ErrServerNotFound
+	}
+	s.weight = w
+	self.restart()
+	return nil
+}
+
+// SetGood takes the server dialing addr in or out of the rotation
+func (self *Rebalancer) SetGood(addr string, good bool) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	s := self.find(addr)
+	if s == nil {
+		return ErrServerNotFound
+	}
+	s.good = good
+	self.restart()
+	return nil
+}
+
+func (self *Rebalancer) Add(s *RbServer) error {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.find(s.pool.Addr) != nil {
+		return ErrServerExists
+	}
+	if self.breaker != nil {
+		self.addBreaker(s)
+	}
+	self.servers = append(self.servers, s)
+	self.restart()
+	return nil
+}
+
+// Remove takes the server dialing addr out, its pool is left to the
+// caller
+func (self *Rebalancer) Remove(addr string) (*RbServer, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	for i, s := range self.servers {
+		if s.pool.Addr != addr {
+			continue
 		}
+		//a new slice, the old one may still be held by a snapshot
+		servers := make([]*RbServer, 0, len(self.servers)-1)
+		servers = append(servers, self.servers[:i]...)
+		self.servers = append(servers, self.servers[i+1:]...)
+		self.restart()
+		return s, nil
 	}
-	return max
+	return nil, ErrServerNotFound
 }
 
-func (self *Rebalancer) getgcd() {
-	m := 1
+func (self *Rebalancer) find(addr string) *RbServer {
 	for _, s := range self.servers {
-		m = gcd(m, s.weight)
+		if s.pool.Addr == addr {
+			return s
+		}
 	}
-	self.gcb = m
+	return nil
+}
+
+// restart lets the strategy start over after the servers changed, it
+// is called with the lock held
+func (self *Rebalancer) restart() {
+	self.strategy.Reset(self.servers)
+}
+
+func (self *RbServer) Weight() int {
+	return self.weight
+}
 
+func (self *RbServer) Good() bool {
+	return self.good
+}
+
+func (self *RbServer) Pool() *pool.Pool {
+	return self.pool
+}
+
+// Breaker is the state of the breaker, closed when there is none
+func (self *RbServer) Breaker() BreakerStats {
+	if self.breaker == nil {
+		return BreakerStats{State: BreakerClosed}
+	}
+	return self.breaker.Stats()
 }
 
-func gcd(a, b int) int {
-	for b != 0 {
-		a, b = b, a%b
+func (self *RbServer) effectiveWeight() int {
+	if !self.good || self.ejected || self.excluded {
+		return 0
 	}
-	return a
+	return self.weight
 }
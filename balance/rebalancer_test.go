@@ -2,13 +2,22 @@ package balance
 
 import (
 	"fmt"
-	"io"
 	"testing"
 
+	"github.com/lostz/Aegis/backend"
 	"github.com/lostz/Aegis/pool"
 )
 
 type Mock struct {
+	backend.Client
+}
+
+func (self Mock) Addr() string {
+	return "mock"
+}
+
+func (self Mock) ConnectionId() uint32 {
+	return 0
 }
 
 func (self Mock) Close() error {
@@ -16,22 +25,115 @@ func (self Mock) Close() error {
 }
 
 func TestBalance(t *testing.T) {
-	w := pool.NewPool(func() (io.Closer, error) {
+	w := pool.NewPool(func() (backend.Client, error) {
 		return Mock{}, nil
 	}, 10)
-	r1 := pool.NewPool(func() (io.Closer, error) {
+	r1 := pool.NewPool(func() (backend.Client, error) {
 		return Mock{}, nil
 	}, 10)
-	r2 := pool.NewPool(func() (io.Closer, error) {
+	r2 := pool.NewPool(func() (backend.Client, error) {
 		return Mock{}, nil
 	}, 10)
-	w1s := &RbServer{weight: 2, pool: w}
-	r1s := &RbServer{weight: 4, pool: r1}
-	r2s := &RbServer{weight: 8, pool: r2}
-	b := Rebalancer{index: -1, servers: []*RbServer{w1s, r1s, r2s}}
-	b.getgcd()
+	w1s := NewRbServer(2, w)
+	r1s := NewRbServer(4, r1)
+	r2s := NewRbServer(8, r2)
+	b := NewRebalancer([]*RbServer{w1s, r1s, r2s}, NewWeightedRoundRobin())
 	for i := 0; i < 10; i++ {
 		fmt.Println(b.Get())
 	}
 
 }
+
+func newMockPool(addr string) *pool.Pool {
+	p := pool.NewPool(func() (backend.Client, error) {
+		return Mock{}, nil
+	}, 10)
+	p.Addr = addr
+	return p
+}
+
+func picks(t *testing.T, b *Rebalancer, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		p, err := b.next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts[p.Addr]++
+	}
+	return counts
+}
+
+func TestRebalancerChange(t *testing.T) {
+	b := NewRebalancer([]*RbServer{
+		NewRbServer(1, newMockPool("a")),
+		NewRbServer(3, newMockPool("b")),
+	}, NewWeightedRoundRobin())
+	if c := picks(t, b, 40); c["a"] != 10 || c["b"] != 30 {
+		t.Fatalf("weights 1:3 picked %v", c)
+	}
+
+	if err := b.SetWeight("a", 0); err != nil {
+		t.Fatal(err)
+	}
+	if c := picks(t, b, 10); c["b"] != 10 {
+		t.Fatalf("weight 0 picked %v", c)
+	}
+
+	if err := b.Add(NewRbServer(3, newMockPool("c"))); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Add(NewRbServer(3, newMockPool("c"))); err != ErrServerExists {
+		t.Fatalf("add twice: %v", err)
+	}
+	if c := picks(t, b, 20); c["b"] != 10 || c["c"] != 10 {
+		t.Fatalf("added server picked %v", c)
+	}
+
+	if err := b.SetGood("b", false); err != nil {
+		t.Fatal(err)
+	}
+	if c := picks(t, b, 10); c["c"] != 10 {
+		t.Fatalf("offline server picked %v", c)
+	}
+
+	if _, err := b.Remove("c"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.next(); err == nil {
+		t.Fatal("picked a server with all of them out")
+	}
+	if err := b.SetWeight("c", 1); err != ErrServerNotFound {
+		t.Fatalf("removed server: %v", err)
+	}
+
+	if err := b.SetGood("b", true); err != nil {
+		t.Fatal(err)
+	}
+	if c := picks(t, b, 10); c["b"] != 10 {
+		t.Fatalf("online server picked %v", c)
+	}
+}
+
+func TestRebalancerExcept(t *testing.T) {
+	b := NewRebalancer([]*RbServer{
+		NewRbServer(1, newMockPool("a")),
+		NewRbServer(1, newMockPool("b")),
+	}, NewWeightedRoundRobin())
+
+	for i := 0; i < 10; i++ {
+		p, err := b.next("a")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Addr != "b" {
+			t.Fatalf("excluded server %s picked", p.Addr)
+		}
+	}
+	if _, err := b.next("a", "b"); err == nil {
+		t.Fatal("picked with every server excluded")
+	}
+	if c := picks(t, b, 10); c["a"] != 5 || c["b"] != 5 {
+		t.Fatalf("exclusion stuck, picked %v", c)
+	}
+}
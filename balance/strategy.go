@@ -0,0 +1,50 @@
+package balance
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	StrategyWeightedRoundRobin = "wrr"
+	StrategyLeastOutstanding   = "least_outstanding"
+	StrategyPowerOfTwo         = "p2c"
+	StrategyEWMA               = "ewma"
+)
+
+// Strategy chooses the server for the next connection. The Rebalancer
+// calls it with its lock held, so it needs no locking of its own.
+type Strategy interface {
+	//Next returns nil when no server can take load
+	Next(servers []*RbServer) *RbServer
+	//Reset is called when the servers or their weights changed
+	Reset(servers []*RbServer)
+}
+
+// NewStrategy returns the strategy with the given name, the weighted
+// round robin when name is empty
+func NewStrategy(name string) (Strategy, error) {
+	switch name {
+	case "", StrategyWeightedRoundRobin:
+		return NewWeightedRoundRobin(), nil
+	case StrategyLeastOutstanding:
+		return NewLeastOutstanding(), nil
+	case StrategyPowerOfTwo:
+		return NewPowerOfTwo(), nil
+	case StrategyEWMA:
+		return NewEWMA(), nil
+	}
+	return nil, fmt.Errorf("unknown balance strategy %s", name)
+}
+
+// load is the work waiting on a server for each unit of weight, with
+// latency the borrowed connections count for as long as statements take
+func load(s *RbServer, withLatency bool) float64 {
+	busy, latency := s.pool.Load()
+	l := float64(busy + 1)
+	if withLatency {
+		//servers without samples yet look fast so they get some
+		l *= float64(latency + time.Microsecond)
+	}
+	return l / float64(s.effectiveWeight())
+}
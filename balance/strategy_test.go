@@ -0,0 +1,213 @@
+package balance
+
+import (
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lostz/Aegis/pool"
+)
+
+func newServers(weights ...int) []*RbServer {
+	servers := make([]*RbServer, len(weights))
+	for i, w := range weights {
+		servers[i] = NewRbServer(w, newMockPool(string(rune('a'+i))))
+	}
+	return servers
+}
+
+// borrow takes n connections from the pool of s and keeps them
+func borrow(t *testing.T, s *RbServer, n int) []io.Closer {
+	var conns []io.Closer
+	for i := 0; i < n; i++ {
+		c, err := s.pool.Get()
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, c)
+	}
+	return conns
+}
+
+func observe(t *testing.T, s *RbServer, d time.Duration) {
+	c, err := s.pool.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.(pool.Tracked).Observe(d, false)
+	c.Close()
+}
+
+func TestNewStrategy(t *testing.T) {
+	for _, name := range []string{"", StrategyWeightedRoundRobin, StrategyLeastOutstanding,
+		StrategyPowerOfTwo, StrategyEWMA} {
+		if _, err := NewStrategy(name); err != nil {
+			t.Fatalf("strategy %q: %s", name, err.Error())
+		}
+	}
+	if _, err := NewStrategy("random"); err == nil {
+		t.Fatal("unknown strategy accepted")
+	}
+}
+
+func TestWeightedRoundRobinSmooth(t *testing.T) {
+	servers := newServers(5, 1, 1)
+	s := NewWeightedRoundRobin()
+	s.Reset(servers)
+
+	var seq []string
+	for i := 0; i < 7; i++ {
+		seq = append(seq, s.Next(servers).pool.Addr)
+	}
+	if got := strings.Join(seq, ""); got != "aabacaa" {
+		t.Fatalf("sequence %s, want aabacaa", got)
+	}
+}
+
+func TestStrategiesSkipUnusable(t *testing.T) {
+	for _, name := range []string{StrategyWeightedRoundRobin, StrategyLeastOutstanding,
+		StrategyPowerOfTwo, StrategyEWMA} {
+		strategy, _ := NewStrategy(name)
+		servers := newServers(0, 3, 3)
+		servers[2].good = false
+		strategy.Reset(servers)
+		for i := 0; i < 20; i++ {
+			if s := strategy.Next(servers); s != servers[1] {
+				t.Fatalf("%s picked %v", name, s)
+			}
+		}
+
+		servers[1].good = false
+		strategy.Reset(servers)
+		if s := strategy.Next(servers); s != nil {
+			t.Fatalf("%s picked %v without usable servers", name, s)
+		}
+	}
+}
+
+func TestLeastOutstanding(t *testing.T) {
+	servers := newServers(1, 1, 2)
+	s := NewLeastOutstanding()
+	s.Reset(servers)
+
+	borrow(t, servers[0], 2)
+	borrow(t, servers[2], 3)
+	//loads for their weight: a 3, b 1, c 2
+	if got := s.Next(servers); got != servers[1] {
+		t.Fatalf("picked %s, want b", got.pool.Addr)
+	}
+
+	borrow(t, servers[1], 2)
+	//a 3, b 3, c 2
+	if got := s.Next(servers); got != servers[2] {
+		t.Fatalf("picked %s, want c", got.pool.Addr)
+	}
+}
+
+func TestLeastOutstandingTies(t *testing.T) {
+	servers := newServers(1, 1, 1)
+	s := NewLeastOutstanding()
+	s.Reset(servers)
+
+	counts := make(map[*RbServer]int)
+	for i := 0; i < 30; i++ {
+		counts[s.Next(servers)]++
+	}
+	for _, srv := range servers {
+		if counts[srv] != 10 {
+			t.Fatalf("idle servers picked %d times, want 10", counts[srv])
+		}
+	}
+}
+
+func TestPowerOfTwo(t *testing.T) {
+	servers := newServers(1, 1)
+	s := NewPowerOfTwo()
+	s.Reset(servers)
+
+	borrow(t, servers[0], 5)
+	counts := make(map[*RbServer]int)
+	for i := 0; i < 1000; i++ {
+		counts[s.Next(servers)]++
+	}
+	//a only wins when it is drawn twice
+	if counts[servers[0]] > 350 || counts[servers[1]] < 650 {
+		t.Fatalf("loaded server picked %d of 1000", counts[servers[0]])
+	}
+}
+
+func TestEWMA(t *testing.T) {
+	servers := newServers(1, 1)
+	s := NewEWMA()
+	s.Reset(servers)
+
+	observe(t, servers[0], 40*time.Millisecond)
+	observe(t, servers[1], 10*time.Millisecond)
+	if got := s.Next(servers); got != servers[1] {
+		t.Fatalf("picked %s, want the faster b", got.pool.Addr)
+	}
+
+	//b is 4 times faster, it takes 4 times the connections first
+	borrow(t, servers[1], 4)
+	if got := s.Next(servers); got != servers[0] {
+		t.Fatalf("picked %s, want a once b is busy", got.pool.Addr)
+	}
+}
+
+func TestRebalancerConcurrent(t *testing.T) {
+	for _, name := range []string{StrategyWeightedRoundRobin, StrategyLeastOutstanding,
+		StrategyPowerOfTwo, StrategyEWMA} {
+		strategy, _ := NewStrategy(name)
+		b := NewRebalancer(newServers(1, 2, 3), strategy)
+		done := make(chan bool)
+		for i := 0; i < 4; i++ {
+			go func() {
+				for j := 0; j < 1000; j++ {
+					c, err := b.Get()
+					if err != nil {
+						t.Error(err)
+						break
+					}
+					c.Close()
+				}
+				done <- true
+			}()
+		}
+		for i := 0; i < 100; i++ {
+			b.SetWeight("a", i%3)
+		}
+		for i := 0; i < 4; i++ {
+			<-done
+		}
+	}
+}
+
+func benchmarkStrategy(b *testing.B, name string) {
+	strategy, _ := NewStrategy(name)
+	r := NewRebalancer(newServers(1, 2, 3, 4, 5, 6, 7, 8), strategy)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := r.next(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkWeightedRoundRobin(b *testing.B) {
+	benchmarkStrategy(b, StrategyWeightedRoundRobin)
+}
+
+func BenchmarkLeastOutstanding(b *testing.B) {
+	benchmarkStrategy(b, StrategyLeastOutstanding)
+}
+
+func BenchmarkPowerOfTwo(b *testing.B) {
+	benchmarkStrategy(b, StrategyPowerOfTwo)
+}
+
+func BenchmarkEWMA(b *testing.B) {
+	benchmarkStrategy(b, StrategyEWMA)
+}
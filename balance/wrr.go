@@ -0,0 +1,35 @@
+package balance
+
+// WeightedRoundRobin is the smooth weighted round robin of nginx, weights
+// 5, 1, 1 give a a b a c a a instead of a a a a a b c
+type WeightedRoundRobin struct {
+	current map[*RbServer]int
+}
+
+func NewWeightedRoundRobin() *WeightedRoundRobin {
+	return &WeightedRoundRobin{current: make(map[*RbServer]int)}
+}
+
+func (self *WeightedRoundRobin) Next(servers []*RbServer) *RbServer {
+	var best *RbServer
+	total := 0
+	for _, s := range servers {
+		w := s.effectiveWeight()
+		if w <= 0 {
+			continue
+		}
+		total += w
+		self.current[s] += w
+		if best == nil || self.current[s] > self.current[best] {
+			best = s
+		}
+	}
+	if best != nil {
+		self.current[best] -= total
+	}
+	return best
+}
+
+func (self *WeightedRoundRobin) Reset(servers []*RbServer) {
+	self.current = make(map[*RbServer]int, len(servers))
+}
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"bytes"
+	"io/ioutil"
+	"os"
+
 	"github.com/BurntSushi/toml"
 	"github.com/lostz/Aegis/logging"
 )
@@ -11,11 +15,229 @@ type Config struct {
 	Password string
 	Addr     string
 	User     string
+	Users    []UserConfig
 	Pidfile  string
 	DBAddr   string
 	DB       string
 	DBUser   string
 	DBPasswd string
+
+	//compressed protocol towards clients and backends: "zlib" or "none"
+	Compress          string
+	BackendCompress   string
+	CompressLevel     int
+	CompressMinLength int
+
+	//time limit of a SELECT in milliseconds, 0 means none
+	MaxExecutionTime int
+
+	//rows and bytes a statement may return, 0 means no limit. With
+	//ResultLimitMode "truncate" the rows before the limit are returned
+	//with a warning, otherwise the statement fails
+	MaxResultRows   int64
+	MaxResultBytes  int64
+	ResultLimitMode string
+
+	//SELECTs without WHERE and LIMIT on a table with more rows than
+	//this are rejected, 0 turns the check off
+	FullScanMaxRows int64
+
+	//backend connections older than BackendMaxLifetime seconds are
+	//closed, the ones idle for BackendPingInterval seconds are pinged
+	//before use, 0 turns either off. BackendReset is how a connection
+	//is cleaned up for the next session: "rollback" rolls back an open
+	//transaction, "reset" sends COM_RESET_CONNECTION every time
+	BackendMaxLifetime  int
+	BackendPingInterval int
+	BackendReset        string
+
+	//at most BackendMaxConns connections per backend, 0 is no limit.
+	//A session waits up to BackendWaitTimeout milliseconds for one
+	//and gets ER_CON_COUNT_ERROR then, 0 waits as long as it takes
+	BackendMaxConns    int
+	BackendWaitTimeout int
+
+	//idle connections kept ready per backend, refilled with at most one
+	//dial every BackendRefillInterval milliseconds
+	BackendMinIdle        int
+	BackendRefillInterval int
+
+	//a warning is logged for backend connections a session holds
+	//longer than BackendHoldWarn seconds, 0 turns it off. Connections
+	//still borrowed by a closed session are always logged as leaked
+	BackendHoldWarn int
+
+	//reads are balanced over the master and the replicas by weight, a
+	//replica with Offline or weight 0 gets none. Without replicas a
+	//MasterWeight of 0 still sends the reads to the master
+	MasterWeight int
+	Replicas     []ReplicaConfig
+
+	//how reads are spread: "wrr" (smooth weighted round robin, the
+	//default), "least_outstanding", "p2c" or "ewma"
+	Balance string
+
+	//a backend is taken out of the reads for BreakerEjection seconds,
+	//doubling up to BreakerMaxEjection seconds, when in the last
+	//BreakerWindow seconds at least BreakerMinRequests statements ran
+	//and BreakerErrorRate percent failed or more than 1% took
+	//BreakerLatency milliseconds. BreakerMinRequests 0 turns it off
+	BreakerWindow      int
+	BreakerMinRequests int
+	BreakerErrorRate   int
+	BreakerLatency     int
+	BreakerEjection    int
+	BreakerMaxEjection int
+
+	//an autocommit SELECT that lost its backend is run again on up to
+	//ReadRetries other ones, 0 turns it off. Writes, locking reads and
+	//anything in a transaction are never retried
+	ReadRetries int
+
+	//the servers that may become master, DBAddr is the first one. The
+	//writable one is looked for every MasterCheckInterval milliseconds
+	//and writes follow it
+	MasterCandidates    []string
+	MasterCheckInterval int
+
+	//AdminUser may run the AEGIS admin statements, with AdminPersist
+	//their changes are written back to the config file
+	AdminUser    string
+	AdminPersist bool
+
+	//statements go to the cluster of the first route whose Schema, a
+	//name or a pattern like "log_*", matches the schemas they use. The
+	//rest goes to the default cluster of DBAddr, MasterWeight,
+	//Replicas, Balance and MasterCandidates
+	Clusters []ClusterConfig
+	Routes   []RouteConfig
+
+	//tables whose rows are spread over clusters
+	Shards []ShardConfig
+
+	//a SELECT on a sharded table that hits several shards runs on all of
+	//them at once and the results are merged, when there are at most
+	//ScatterMaxShards, 0 turns it off. The shards get ScatterTimeout
+	//milliseconds, 0 leaves only MaxExecutionTime
+	ScatterMaxShards int
+	ScatterTimeout   int
+
+	//columns whose ids are made by the proxy, unique over the shards.
+	//The segment sequences keep their rows in SequenceTable, a
+	//schema.table of the cluster they name
+	Sequences     []SequenceConfig
+	SequenceTable string
+
+	//the file the config was loaded from and the default cluster,
+	//written back to the fields above by Save
+	path string
+	main ClusterConfig
+}
+
+const (
+	ResultLimitError    = "error"
+	ResultLimitTruncate = "truncate"
+)
+
+const (
+	BackendResetRollback   = "rollback"
+	BackendResetConnection = "reset"
+)
+
+// ReplicaConfig is a backend that only takes reads
+type ReplicaConfig struct {
+	Addr    string
+	Weight  int
+	Offline bool
+}
+
+// DefaultCluster is the name of the cluster of the top level backends
+const DefaultCluster = "default"
+
+// ClusterConfig is a master and its replicas, the fields are those of
+// the default cluster
+type ClusterConfig struct {
+	Name             string
+	DBAddr           string
+	MasterWeight     int
+	Replicas         []ReplicaConfig
+	Balance          string
+	MasterCandidates []string
+}
+
+// RouteConfig sends the schemas matching Schema to Cluster
+type RouteConfig struct {
+	Schema  string
+	Cluster string
+}
+
+// GetClusters are the clusters, the default one first. Changes to them
+// are kept by Save.
+func (self *Config) GetClusters() []*ClusterConfig {
+	clusters := []*ClusterConfig{&self.main}
+	for i := range self.Clusters {
+		clusters = append(clusters, &self.Clusters[i])
+	}
+	return clusters
+}
+
+// SequenceConfig fills Column of Table with an id when an INSERT leaves
+// it out or sets it to NULL or 0, like AUTO_INCREMENT. Generator
+// "snowflake" makes the id of the time, of WorkerId, between 0 and 1023
+// and unique among the proxies, and of a counter. Generator "segment"
+// hands out the ids of ranges of Step ids reserved in SequenceTable on
+// Cluster, created by
+//
+//	CREATE TABLE aegis_sequence (name VARCHAR(128) PRIMARY KEY, max_id BIGINT NOT NULL)
+//
+// An empty Schema is any.
+type SequenceConfig struct {
+	Schema    string
+	Table     string
+	Column    string
+	Generator string
+	WorkerId  int64
+	Cluster   string
+	Step      int64
+}
+
+// ShardConfig spreads the rows of Table over the Shards clusters by its
+// Key column. Rule "hash" takes an integer key modulo the number of
+// shards and any other by its crc32. With "range" shard i takes the keys
+// below Ranges[i], with "date" the dates before Dates[i] written as
+// 2006-01-02, the last shard takes the rest. An empty Schema is any.
+type ShardConfig struct {
+	Schema string
+	Table  string
+	Key    string
+	Rule   string
+	Shards []string
+	Ranges []int64
+	Dates  []string
+}
+
+// UserConfig is a proxy user, Users adds to the single User/Password pair
+type UserConfig struct {
+	User     string
+	Password string
+
+	//override the global limits when not 0
+	MaxExecutionTime int
+	MaxResultRows    int64
+	MaxResultBytes   int64
+}
+
+// GetUser returns the proxy user with the given name, or nil
+func (self *Config) GetUser(name string) *UserConfig {
+	if name == self.User {
+		return &UserConfig{User: self.User, Password: self.Password}
+	}
+	for i := range self.Users {
+		if self.Users[i].User == name {
+			return &self.Users[i]
+		}
+	}
+	return nil
 }
 
 func LoadConfig(conffile string) (*Config, error) {
@@ -26,9 +248,38 @@ func LoadConfig(conffile string) (*Config, error) {
 
 func LoadConfigFile(file string) (*Config, error) {
 	var config Config
+	config.path = file
 	if _, err := toml.DecodeFile(file, &config); err != nil {
 		return &config, err
 	}
+	config.main = ClusterConfig{
+		Name:             DefaultCluster,
+		DBAddr:           config.DBAddr,
+		MasterWeight:     config.MasterWeight,
+		Replicas:         config.Replicas,
+		Balance:          config.Balance,
+		MasterCandidates: config.MasterCandidates,
+	}
 	return &config, nil
 
 }
+
+// Save writes the config back to the file it was loaded from, the
+// comments in the file are lost
+func (self *Config) Save() error {
+	self.DBAddr = self.main.DBAddr
+	self.MasterWeight = self.main.MasterWeight
+	self.Replicas = self.main.Replicas
+	self.Balance = self.main.Balance
+	self.MasterCandidates = self.main.MasterCandidates
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(self); err != nil {
+		return err
+	}
+	tmp := self.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, self.path)
+}
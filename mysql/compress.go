@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"net"
+)
+
+const (
+	CompressNone = "none"
+	CompressZlib = "zlib"
+	CompressZstd = "zstd"
+)
+
+const (
+	//compressed packet header: 3 bytes compressed length,
+	//1 byte sequence, 3 bytes length before compression
+	compressedHeaderLen int = 7
+
+	//mysql does not compress payloads shorter than this
+	DefaultMinCompressLength int = 50
+)
+
+var ErrZstdNotSupported = errors.New("zstd compression is not supported")
+
+// compressor implements the compressed protocol framing, it sits
+// between the packet layer and the raw connection. The packets written
+// are queued and sent together, in frames of up to MaxPayloadLen, when
+// the packet layer flushes: like mysql, a whole response goes out in as
+// few frames as it can.
+type compressor struct {
+	conn      net.Conn
+	buf       buffer
+	level     int
+	threshold int
+	sequence  uint8
+
+	//inflated data not consumed by the packet layer yet
+	data []byte
+
+	//packets not sent yet, and the deflater reused for every frame
+	pending []byte
+	zbuf    bytes.Buffer
+	zw      *zlib.Writer
+}
+
+func newCompressor(conn net.Conn, level int, threshold int) *compressor {
+	if threshold <= 0 {
+		threshold = DefaultMinCompressLength
+	}
+	if level < zlib.HuffmanOnly || level > zlib.BestCompression {
+		level = zlib.DefaultCompression
+	}
+	c := &compressor{conn: conn, level: level, threshold: threshold}
+	c.buf = newBuffer(conn)
+	return c
+}
+
+func (c *compressor) Read(b []byte) (int, error) {
+	for len(c.data) == 0 {
+		if err := c.readFrame(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(b, c.data)
+	c.data = c.data[n:]
+	return n, nil
+}
+
+func (c *compressor) readFrame() error {
+	header, err := c.buf.readNext(compressedHeaderLen)
+	if err != nil {
+		return err
+	}
+	compLen := int(uint32(header[0]) | uint32(header[1])<<8 | uint32(header[2])<<16)
+	//the server may answer an error before it got all our frames,
+	//so just follow the sequence of the peer
+	c.sequence = header[3] + 1
+	rawLen := int(uint32(header[4]) | uint32(header[5])<<8 | uint32(header[6])<<16)
+
+	payload, err := c.buf.readNext(compLen)
+	if err != nil {
+		return err
+	}
+
+	//length 0 means the payload was sent uncompressed
+	if rawLen == 0 {
+		c.data = append(c.data[:0], payload...)
+		return nil
+	}
+
+	r, err := zlib.NewReader(bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	data := make([]byte, rawLen)
+	if _, err = io.ReadFull(r, data); err != nil {
+		return err
+	}
+	c.data = data
+	return nil
+}
+
+// Write queues the raw packets in data, a frame is only sent once
+// MaxPayloadLen are queued
+func (c *compressor) Write(data []byte) (int, error) {
+	c.pending = append(c.pending, data...)
+	if len(c.pending) < MaxPayloadLen {
+		return len(data), nil
+	}
+
+	sent := 0
+	for len(c.pending)-sent >= MaxPayloadLen {
+		if err := c.writeFrame(c.pending[sent : sent+MaxPayloadLen]); err != nil {
+			c.pending = c.pending[:0]
+			return 0, err
+		}
+		sent += MaxPayloadLen
+	}
+	c.pending = c.pending[:copy(c.pending, c.pending[sent:])]
+	return len(data), nil
+}
+
+// flush sends the queued packets
+func (c *compressor) flush() error {
+	if len(c.pending) == 0 {
+		return nil
+	}
+	err := c.writeFrame(c.pending)
+	c.pending = c.pending[:0]
+	return err
+}
+
+func (c *compressor) writeFrame(raw []byte) error {
+	var payload []byte
+	rawLen := len(raw)
+
+	if rawLen < c.threshold {
+		payload = raw
+		rawLen = 0
+	} else {
+		c.zbuf.Reset()
+		if c.zw == nil {
+			w, err := zlib.NewWriterLevel(&c.zbuf, c.level)
+			if err != nil {
+				return err
+			}
+			c.zw = w
+		} else {
+			c.zw.Reset(&c.zbuf)
+		}
+		if _, err := c.zw.Write(raw); err != nil {
+			return err
+		}
+		if err := c.zw.Close(); err != nil {
+			return err
+		}
+
+		//not worth it, send the payload as it is
+		if c.zbuf.Len() >= len(raw) || c.zbuf.Len() > MaxPayloadLen {
+			payload = raw
+			rawLen = 0
+		} else {
+			payload = c.zbuf.Bytes()
+		}
+	}
+
+	frame := make([]byte, compressedHeaderLen, compressedHeaderLen+len(payload))
+	frame[0] = byte(len(payload))
+	frame[1] = byte(len(payload) >> 8)
+	frame[2] = byte(len(payload) >> 16)
+	frame[3] = c.sequence
+	frame[4] = byte(rawLen)
+	frame[5] = byte(rawLen >> 8)
+	frame[6] = byte(rawLen >> 16)
+	frame = append(frame, payload...)
+
+	n, err := c.conn.Write(frame)
+	if err != nil {
+		return err
+	}
+	if n != len(frame) {
+		return io.ErrShortWrite
+	}
+	c.sequence++
+	return nil
+}
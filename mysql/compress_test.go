@@ -0,0 +1,157 @@
+package mysql
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+)
+
+// bufConn is a connection reading back what was written to it, it counts
+// the writes
+type bufConn struct {
+	net.Conn
+	buf    bytes.Buffer
+	writes int
+}
+
+func (c *bufConn) Read(b []byte) (int, error) {
+	return c.buf.Read(b)
+}
+
+func (c *bufConn) Write(b []byte) (int, error) {
+	c.writes++
+	return c.buf.Write(b)
+}
+
+func compressedPackets(t *testing.T, conn net.Conn) *Packets {
+	p := NewPackets(conn)
+	if err := p.EnableCompression(CompressZlib, 6, 0); err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func writePackets(t *testing.T, p *Packets, payloads ...[]byte) {
+	for _, payload := range payloads {
+		if err := p.WritePacket(append(make([]byte, 4), payload...)); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func readPackets(t *testing.T, p *Packets, payloads ...[]byte) {
+	for i, want := range payloads {
+		data, err := p.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if !bytes.Equal(data, want) {
+			t.Fatalf("packet %d: %d bytes, want %d", i, len(data), len(want))
+		}
+	}
+}
+
+// frameHeader is the sequence and the length before compression of the
+// next frame in conn, it is left unread
+func frameHeader(conn *bufConn) (uint8, int) {
+	h := conn.buf.Bytes()
+	return h[3], int(h[4]) | int(h[5])<<8 | int(h[6])<<16
+}
+
+func TestCompressResponse(t *testing.T) {
+	conn := &bufConn{}
+	client := compressedPackets(t, conn)
+	server := compressedPackets(t, conn)
+
+	//a command of one small packet goes out as it is
+	query := []byte("\x03select 1")
+	writePackets(t, client, query)
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if seq, rawLen := frameHeader(conn); conn.writes != 1 || seq != 0 || rawLen != 0 {
+		t.Fatalf("%d writes, frame %d of %d bytes", conn.writes, seq, rawLen)
+	}
+	readPackets(t, server, query)
+
+	//the packets of a response share one compressed frame
+	rows := [][]byte{{1}, bytes.Repeat([]byte("column"), 20), []byte("\x05hello"), []byte("\x05world"), {EOF_HEADER, 0, 0, 2, 0}}
+	conn.writes = 0
+	writePackets(t, server, rows...)
+	if conn.writes != 0 {
+		t.Fatalf("%d writes before the flush", conn.writes)
+	}
+	if err := server.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	seq, rawLen := frameHeader(conn)
+	if conn.writes != 1 || seq != 1 || rawLen == 0 {
+		t.Fatalf("%d writes, frame %d of %d bytes", conn.writes, seq, rawLen)
+	}
+	//the packets are numbered on from the command, the packet sequence
+	//goes on from the compressed one after the flush
+	if server.Sequence != 2 {
+		t.Fatalf("sequence %d after the flush", server.Sequence)
+	}
+	readPackets(t, client, rows...)
+	if client.Sequence != uint8(len(rows)+1) {
+		t.Fatalf("sequence %d after the response", client.Sequence)
+	}
+
+	//the next command starts both sequences over
+	client.Sequence = 0
+	writePackets(t, client, query)
+	client.Flush()
+	if seq, _ := frameHeader(conn); seq != 0 {
+		t.Fatalf("command frame %d", seq)
+	}
+	readPackets(t, server, query)
+}
+
+func TestCompressLargePacket(t *testing.T) {
+	conn := &bufConn{}
+	client := compressedPackets(t, conn)
+	server := compressedPackets(t, conn)
+
+	//split in a packet of MaxPayloadLen and the rest, sent in two frames
+	payload := bytes.Repeat([]byte("0123456789"), MaxPayloadLen/10+10)
+	writePackets(t, client, payload)
+	if conn.writes != 1 {
+		t.Fatalf("%d writes before the flush", conn.writes)
+	}
+	if err := client.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if conn.writes != 2 {
+		t.Fatalf("%d writes", conn.writes)
+	}
+	readPackets(t, server, payload)
+	if server.Sequence != 2 {
+		t.Fatalf("sequence %d", server.Sequence)
+	}
+}
+
+// a read sends what is queued first, the peer could not answer otherwise
+func TestCompressFlushOnRead(t *testing.T) {
+	c1, c2 := net.Pipe()
+	defer c1.Close()
+	defer c2.Close()
+	c1.SetDeadline(time.Now().Add(time.Second))
+	c2.SetDeadline(time.Now().Add(time.Second))
+
+	go func() {
+		server := compressedPackets(t, c2)
+		data, err := server.ReadPacket()
+		if err != nil {
+			return
+		}
+		server.WritePacket(append(make([]byte, 4), data...))
+		server.Flush()
+	}()
+
+	client := compressedPackets(t, c1)
+	query := []byte("\x03select 1")
+	writePackets(t, client, query)
+	readPackets(t, client, query)
+}
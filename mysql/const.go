@@ -52,6 +52,11 @@ const (
 	CLIENT_PLUGIN_AUTH
 	CLIENT_CONNECT_ATTRS
 	CLIENT_PLUGIN_AUTH_LENENC_CLIENT_DATA
+	CLIENT_CAN_HANDLE_EXPIRED_PASSWORDS
+	CLIENT_SESSION_TRACK
+	CLIENT_DEPRECATE_EOF
+	CLIENT_OPTIONAL_RESULTSET_METADATA
+	CLIENT_ZSTD_COMPRESSION_ALGORITHM
 )
 
 const (
@@ -89,6 +94,43 @@ const (
 	COM_RESET_CONNECTION
 )
 
+// CommandName is the name of a command in the Command column of
+// SHOW PROCESSLIST
+var CommandName = map[byte]string{
+	COM_SLEEP:               "Sleep",
+	COM_QUIT:                "Quit",
+	COM_INIT_DB:             "Init DB",
+	COM_QUERY:               "Query",
+	COM_FIELD_LIST:          "Field List",
+	COM_CREATE_DB:           "Create DB",
+	COM_DROP_DB:             "Drop DB",
+	COM_REFRESH:             "Refresh",
+	COM_SHUTDOWN:            "Shutdown",
+	COM_STATISTICS:          "Statistics",
+	COM_PROCESS_INFO:        "Processlist",
+	COM_CONNECT:             "Connect",
+	COM_PROCESS_KILL:        "Kill",
+	COM_DEBUG:               "Debug",
+	COM_PING:                "Ping",
+	COM_TIME:                "Time",
+	COM_DELAYED_INSERT:      "Delayed insert",
+	COM_CHANGE_USER:         "Change user",
+	COM_BINLOG_DUMP:         "Binlog Dump",
+	COM_TABLE_DUMP:          "Table Dump",
+	COM_CONNECT_OUT:         "Connect Out",
+	COM_REGISTER_SLAVE:      "Register Slave",
+	COM_STMT_PREPARE:        "Prepare",
+	COM_STMT_EXECUTE:        "Execute",
+	COM_STMT_SEND_LONG_DATA: "Long Data",
+	COM_STMT_CLOSE:          "Close stmt",
+	COM_STMT_RESET:          "Reset stmt",
+	COM_SET_OPTION:          "Set option",
+	COM_STMT_FETCH:          "Fetch",
+	COM_DAEMON:              "Daemon",
+	COM_BINLOG_DUMP_GTID:    "Binlog Dump GTID",
+	COM_RESET_CONNECTION:    "Reset Connection",
+}
+
 const (
 	NOT_NULL_FLAG       = 1
 	PRI_KEY_FLAG        = 2
@@ -868,3 +868,8 @@ const (
 	ER_ROW_IN_WRONG_PARTITION                                                  = 1863
 	ER_ERROR_LAST                                                              = 1863
 )
+
+// errors added in mysql 5.7
+const (
+	ER_QUERY_TIMEOUT = 3024
+)
@@ -2,6 +2,7 @@ package mysql
 
 import (
 	"fmt"
+	"io"
 	"net"
 )
 
@@ -9,16 +10,44 @@ type Packets struct {
 	Sequence uint8
 	conn     net.Conn
 	buf      buffer
+	w        io.Writer
+	compress *compressor
 }
 
 func NewPackets(conn net.Conn) *Packets {
-	p := &Packets{conn: conn}
+	p := &Packets{conn: conn, w: conn}
 	p.buf = newBuffer(p.conn)
 	return p
 
 }
 
+// EnableCompression switches the connection to the compressed protocol,
+// it must be called right after the handshake OK packet, when nothing
+// is buffered yet
+func (p *Packets) EnableCompression(algorithm string, level int, threshold int) error {
+	switch algorithm {
+	case CompressZlib:
+	case CompressZstd:
+		return ErrZstdNotSupported
+	default:
+		return fmt.Errorf("unknown compression algorithm %s", algorithm)
+	}
+	p.compress = newCompressor(p.conn, level, threshold)
+	p.buf = newBuffer(p.compress)
+	p.w = p.compress
+	return nil
+}
+
+func (p *Packets) IsCompressed() bool {
+	return p.compress != nil
+}
+
 func (p *Packets) ReadPacket() ([]byte, error) {
+	//the peer answers what we sent, it must have all of it
+	if err := p.Flush(); err != nil {
+		return nil, err
+	}
+
 	var payload []byte
 	for {
 		data, err := p.buf.readNext(4)
@@ -29,7 +58,10 @@ func (p *Packets) ReadPacket() ([]byte, error) {
 		if pktLen < 1 {
 			return nil, fmt.Errorf("invalid payload length %d", pktLen)
 		}
-		if data[3] != p.Sequence {
+		if p.compress != nil {
+			//the peer syncs the packet sequence with the compressed one
+			p.Sequence = data[3]
+		} else if data[3] != p.Sequence {
 			return nil, fmt.Errorf("invalid sequence %d != %d", data[3], p.Sequence)
 		}
 		p.Sequence++
@@ -53,6 +85,14 @@ func (p *Packets) ReadPacket() ([]byte, error) {
 }
 
 func (p *Packets) WritePacket(data []byte) error {
+	//a new command starts a new compressed sequence as well
+	if p.compress != nil && p.Sequence == 0 {
+		if err := p.Flush(); err != nil {
+			return err
+		}
+		p.compress.sequence = 0
+	}
+
 	pktLen := len(data) - 4
 	for {
 		var size int
@@ -68,19 +108,33 @@ func (p *Packets) WritePacket(data []byte) error {
 			size = pktLen
 		}
 		data[3] = p.Sequence
-		n, err := p.conn.Write(data[:4+size])
-		if err == nil && n == 4+size {
-			p.Sequence++
-			if size != MaxPayloadLen {
-				return nil
-			}
-			pktLen -= size
-			data = data[size:]
-			continue
+		n, err := p.w.Write(data[:4+size])
+		if err != nil || n != 4+size {
+			return ErrBadConn
+		}
+		p.Sequence++
+		if size != MaxPayloadLen {
+			break
 		}
+		pktLen -= size
+		data = data[size:]
+	}
+	return nil
+}
 
+// Flush sends the packets the compressed protocol still queues, it is
+// done before each read. Without compression the packets are already
+// sent.
+func (p *Packets) Flush() error {
+	if p.compress == nil {
+		return nil
+	}
+	if err := p.compress.flush(); err != nil {
+		return ErrBadConn
 	}
-	return ErrBadConn
+	//like mysql, the packet sequence goes on from the compressed one
+	p.Sequence = p.compress.sequence
+	return nil
 }
 
 func (p *Packets) TakeSmallBuffer(length int) []byte {
@@ -239,6 +239,13 @@ type Result struct {
 	AffectedRows uint64
 
 	*Resultset
+
+	// Truncated is set when rows past a result limit were dropped
+	Truncated bool
+
+	// Next is the following result of a command that produced several,
+	// like a CALL of a procedure returning more than one result set
+	Next *Result
 }
 
 type Resultset struct {
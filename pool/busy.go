@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"sort"
+	"time"
+)
+
+// Tracked is implemented by the connections Get hands out, the borrower
+// tells the pool what it does with them so leaks can be traced back,
+// and how long statements take for the balancers
+type Tracked interface {
+	SetOwner(id uint32)
+	SetStatement(stmt string)
+	Observe(d time.Duration, failed bool)
+}
+
+// latencyDecay is the weight of a new sample in the latency average
+const latencyDecay = 0.2
+
+// BusyConn describes a connection that is borrowed from the pool
+type BusyConn struct {
+	Addr         string
+	ConnectionId uint32
+	Owner        uint32
+	Borrowed     time.Time
+	Statement    string
+}
+
+func (self *Pool) putBusy(pc *pooledConnection) {
+	self.mu.Lock()
+	if self.busy == nil {
+		self.busy = make(map[*pooledConnection]struct{})
+	}
+	pc.borrowed = nowFunc()
+	self.busy[pc] = struct{}{}
+	self.mu.Unlock()
+}
+
+func (self *Pool) removeBusy(pc *pooledConnection) {
+	self.mu.Lock()
+	delete(self.busy, pc)
+	self.mu.Unlock()
+}
+
+// Busy lists the borrowed connections, the longest held first
+func (self *Pool) Busy() []BusyConn {
+	return self.HeldLonger(0)
+}
+
+// HeldLonger lists the connections borrowed for at least d, the longest
+// held first
+func (self *Pool) HeldLonger(d time.Duration) []BusyConn {
+	now := nowFunc()
+	self.mu.Lock()
+	conns := make([]BusyConn, 0, len(self.busy))
+	for pc := range self.busy {
+		if now.Sub(pc.borrowed) < d {
+			continue
+		}
+		conns = append(conns, BusyConn{
+			Addr:         pc.c.Addr(),
+			ConnectionId: pc.c.ConnectionId(),
+			Owner:        pc.owner,
+			Borrowed:     pc.borrowed,
+			Statement:    pc.statement,
+		})
+	}
+	self.mu.Unlock()
+
+	sort.Slice(conns, func(i, j int) bool {
+		return conns[i].Borrowed.Before(conns[j].Borrowed)
+	})
+	return conns
+}
+
+// Load is how busy the backend is: the connections borrowed from the
+// pool and a moving average of the statement times, 0 before the first
+func (self *Pool) Load() (int, time.Duration) {
+	self.mu.Lock()
+	busy, latency := len(self.busy), time.Duration(self.latency)
+	self.mu.Unlock()
+	return busy, latency
+}
+
+// SetObserver has fn told about each statement and failed dial
+func (self *Pool) SetObserver(fn func(d time.Duration, failed bool)) {
+	self.mu.Lock()
+	self.observer = fn
+	self.mu.Unlock()
+}
+
+func (self *Pool) observe(d time.Duration, failed bool) {
+	self.mu.Lock()
+	if !failed {
+		if self.latency == 0 {
+			self.latency = float64(d)
+		} else {
+			self.latency += (float64(d) - self.latency) * latencyDecay
+		}
+	}
+	fn := self.observer
+	self.mu.Unlock()
+
+	if fn != nil {
+		fn(d, failed)
+	}
+}
+
+func (self *pooledConnection) SetOwner(id uint32) {
+	self.p.mu.Lock()
+	self.owner = id
+	self.p.mu.Unlock()
+}
+
+func (self *pooledConnection) SetStatement(stmt string) {
+	self.p.mu.Lock()
+	self.statement = stmt
+	self.p.mu.Unlock()
+}
+
+// Observe reports how long a statement took on the connection, failed
+// is for errors of the backend rather than of the statement
+func (self *pooledConnection) Observe(d time.Duration, failed bool) {
+	self.p.observe(d, failed)
+}
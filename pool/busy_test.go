@@ -0,0 +1,108 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestBusy(t *testing.T) {
+	clock := useFakeClock(t)
+	p := newTestPool(&fakeDialer{})
+	start := clock.now
+
+	c1 := get(t, p)
+	c1.(Tracked).SetOwner(7)
+	c1.(Tracked).SetStatement("select 1")
+	clock.add(time.Second)
+	c2 := get(t, p)
+	c2.(Tracked).SetOwner(8)
+	clock.add(time.Second)
+
+	busy := p.Busy()
+	if len(busy) != 2 || p.Stats().Busy != 2 {
+		t.Fatalf("%+v", busy)
+	}
+	want := BusyConn{Addr: "fake", ConnectionId: 1, Owner: 7, Borrowed: start, Statement: "select 1"}
+	if busy[0] != want || busy[1].Owner != 8 {
+		t.Fatalf("%+v", busy)
+	}
+
+	//only the first is held long enough to be reported
+	if held := p.HeldLonger(1500 * time.Millisecond); len(held) != 1 || held[0].Owner != 7 {
+		t.Fatalf("%+v", held)
+	}
+
+	//a returned connection is no longer busy, and comes back without
+	//what its last borrower did with it
+	c1.Close()
+	c1.Close()
+	if busy := p.Busy(); len(busy) != 1 || busy[0].Owner != 8 {
+		t.Fatalf("%+v", busy)
+	}
+	c1 = get(t, p)
+	if busy := p.HeldLonger(0); len(busy) != 2 || busy[1] != (BusyConn{Addr: "fake", ConnectionId: 1, Borrowed: clock.now}) {
+		t.Fatalf("%+v", busy)
+	}
+
+	c1.Close()
+	c2.Close()
+	if s := p.Stats(); s.Busy != 0 || s.Idle != 2 {
+		t.Fatalf("%+v", s)
+	}
+}
+
+func TestBusyClosedConnection(t *testing.T) {
+	clock := useFakeClock(t)
+	p := newTestPool(&fakeDialer{})
+	p.MaxLifetime = time.Minute
+
+	//closed for real on return, it is not busy either
+	c := get(t, p)
+	clock.add(time.Minute)
+	c.Close()
+	if s := p.Stats(); s.Busy != 0 || s.Active != 0 {
+		t.Fatalf("%+v", s)
+	}
+
+	//a failed Get leaves nothing behind
+	p.MaxActive = 1
+	held := get(t, p)
+	if _, err := p.Get(); err != ErrPoolExhausted {
+		t.Fatalf("%v", err)
+	}
+	if s := p.Stats(); s.Busy != 1 {
+		t.Fatalf("%+v", s)
+	}
+	held.Close()
+}
+
+func TestBusyConcurrent(t *testing.T) {
+	p := newTestPool(&fakeDialer{})
+	p.MaxActive = 4
+	p.Wait = true
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(id uint32) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c, err := p.Get()
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				c.(Tracked).SetOwner(id)
+				c.(Tracked).SetStatement("select 1")
+				p.Busy()
+				c.Close()
+			}
+		}(uint32(i))
+	}
+	wg.Wait()
+
+	if s := p.Stats(); s.Busy != 0 || s.Active != s.Idle || s.Active > 4 {
+		t.Fatalf("%+v", s)
+	}
+}
@@ -2,57 +2,107 @@ package pool
 
 import (
 	"container/list"
+	"context"
 	"errors"
 	"sync"
 	"time"
 
+	"github.com/lostz/Aegis/backend"
 	"github.com/lostz/Aegis/mysql"
 )
 
-type Client interface {
-	Connect(addr string, user string, password string, db string) error
-	Execute(command string, args ...interface{}) (*mysql.Result, error)
-	Rollback() error
-	CollationId() uint32
-	Close() error
-}
-
 var nowFunc = time.Now
 var ErrPoolClosed = errors.New("connection pool closed")
 var ErrPoolExhausted = errors.New("connection pool exhausted")
 var ErrConnClosed = errors.New("connection closed")
+var ErrPoolTimeout = errors.New("connection pool wait timeout")
 
+// Pool keeps idle connections for reuse. TestOnReturn prepares a
+// connection for the idle list, it is closed instead when that fails.
+// Connections older than MaxLifetime are closed, 0 keeps them.
+// With Wait, Get waits up to MaxWait for a connection when MaxActive
+// are in use, 0 waits as long as the context allows. Waiters are
+// served in order. MinIdle connections are kept ready, dialed at most
+// one per RefillInterval, see Warm. Addr names the backend the pool
+// dials for monitoring.
 type Pool struct {
-	Dial         func() (Client, error)
-	TestOnBorrow func(c Client, t time.Time) error
-	MaxIdle      int
-	MaxActive    int
-	IdleTimeout  time.Duration
-	Wait         bool
-	mu           sync.Mutex
-	cond         *sync.Cond
-	closed       bool
-	active       int
-	idle         list.List
-	busy         map[uint32]Client
+	Addr           string
+	Dial           func() (backend.Client, error)
+	TestOnBorrow   func(c backend.Client, t time.Time) error
+	TestOnReturn   func(c backend.Client) error
+	MaxIdle        int
+	MaxActive      int
+	IdleTimeout    time.Duration
+	MaxLifetime    time.Duration
+	Wait           bool
+	MaxWait        time.Duration
+	MinIdle        int
+	RefillInterval time.Duration
+	mu             sync.Mutex
+	closed         bool
+	active         int
+	idle           list.List
+	waiters        list.List
+	busy           map[*pooledConnection]struct{}
+	filling        bool
+	dialFailed     bool
+
+	waitCount    int64
+	waitDuration time.Duration
+	timeoutCount int64
+
+	//nanoseconds, see Load
+	latency  float64
+	observer func(d time.Duration, failed bool)
+}
+
+// Stats is a snapshot of the pool for monitoring
+type Stats struct {
+	Active  int
+	Idle    int
+	Busy    int
+	Waiting int
+
+	//the last dial failed
+	Down bool
+
+	//Gets that had to wait, how long they waited in total and how
+	//many gave up at MaxWait
+	WaitCount    int64
+	WaitDuration time.Duration
+	TimeoutCount int64
 }
 
 type idleConn struct {
-	c Client
-	t time.Time
+	c       backend.Client
+	t       time.Time
+	created time.Time
 }
 
-func NewPool(newFn func() (Client, error), maxIdle int) *Pool {
+func NewPool(newFn func() (backend.Client, error), maxIdle int) *Pool {
 	return &Pool{Dial: newFn, MaxIdle: maxIdle}
 }
 
-func (self *Pool) Get() (Client, error) {
-	c, err := self.get()
+func (self *Pool) Get() (backend.Client, error) {
+	return self.GetContext(context.Background())
+}
+
+// GetContext is Get giving up when ctx is done, MaxWait still applies
+func (self *Pool) GetContext(ctx context.Context) (backend.Client, error) {
+	if self.MaxWait > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, self.MaxWait)
+		defer cancel()
+	}
+
+	ic, err := self.get(ctx)
 	if err != nil {
 		return nil, err
 	}
-	go self.putBusy(c)
-	return &pooledConnection{p: self, c: c}, nil
+	self.Warm()
+	pc := &pooledConnection{p: self, c: ic.c, created: ic.created}
+	self.putBusy(pc)
+	return pc, nil
 }
 
 func (self *Pool) ActiveCount() int {
@@ -62,15 +112,32 @@ func (self *Pool) ActiveCount() int {
 	return active
 }
 
+func (self *Pool) Stats() Stats {
+	self.mu.Lock()
+	s := Stats{
+		Active:       self.active,
+		Idle:         self.idle.Len(),
+		Busy:         len(self.busy),
+		Waiting:      self.waiters.Len(),
+		Down:         self.dialFailed,
+		WaitCount:    self.waitCount,
+		WaitDuration: self.waitDuration,
+		TimeoutCount: self.timeoutCount,
+	}
+	self.mu.Unlock()
+	return s
+}
+
 func (self *Pool) Close() error {
 	self.mu.Lock()
 	idle := self.idle
 	self.idle.Init()
 	self.closed = true
 	self.active -= idle.Len()
-	if self.cond != nil {
-		self.cond.Broadcast()
+	for e := self.waiters.Front(); e != nil; e = e.Next() {
+		close(e.Value.(chan idleConn))
 	}
+	self.waiters.Init()
 	self.mu.Unlock()
 	for e := idle.Front(); e != nil; e = e.Next() {
 		e.Value.(idleConn).c.Close()
@@ -78,14 +145,22 @@ func (self *Pool) Close() error {
 	return nil
 }
 
+// release frees the slot of a closed connection, the first waiter
+// takes it over to dial a new one
 func (self *Pool) release() {
-	self.active -= 1
-	if self.cond != nil {
-		self.cond.Signal()
+	if e := self.waiters.Front(); e != nil {
+		self.waiters.Remove(e)
+		e.Value.(chan idleConn) <- idleConn{}
+		return
 	}
+	self.active -= 1
+}
+
+func (self *Pool) expired(ic idleConn) bool {
+	return self.MaxLifetime > 0 && nowFunc().Sub(ic.created) >= self.MaxLifetime
 }
 
-func (self *Pool) get() (Client, error) {
+func (self *Pool) get(ctx context.Context) (idleConn, error) {
 	self.mu.Lock()
 
 	if timeout := self.IdleTimeout; timeout > 0 {
@@ -95,7 +170,7 @@ func (self *Pool) get() (Client, error) {
 				break
 			}
 			ic := e.Value.(idleConn)
-			if ic.t.Add(timeout).After(nowFunc()) {
+			if ic.t.Add(timeout).After(nowFunc()) || self.idle.Len() <= self.MinIdle {
 				break
 			}
 			self.idle.Remove(e)
@@ -106,8 +181,9 @@ func (self *Pool) get() (Client, error) {
 		}
 	}
 
-	for {
-
+	//queued waiters go first, a freed connection or slot is handed
+	//to them directly
+	if self.waiters.Len() == 0 {
 		for i, n := 0, self.idle.Len(); i < n; i++ {
 			e := self.idle.Front()
 			if e == nil {
@@ -117,58 +193,112 @@ func (self *Pool) get() (Client, error) {
 			self.idle.Remove(e)
 			test := self.TestOnBorrow
 			self.mu.Unlock()
-			if test == nil || test(ic.c, ic.t) == nil {
-				return ic.c, nil
+			if !self.expired(ic) && (test == nil || test(ic.c, ic.t) == nil) {
+				return ic, nil
 			}
 			ic.c.Close()
 			self.mu.Lock()
 			self.release()
 		}
+	}
 
-		if self.closed {
-			self.mu.Unlock()
-			return nil, ErrPoolClosed
-		}
+	if self.closed {
+		self.mu.Unlock()
+		return idleConn{}, ErrPoolClosed
+	}
 
-		if self.MaxActive == 0 || self.active < self.MaxActive {
-			dial := self.Dial
-			self.active += 1
-			self.mu.Unlock()
-			c, err := dial()
-			if err != nil {
-				self.mu.Lock()
-				self.release()
-				self.mu.Unlock()
-				c = nil
-			}
-			return c, err
-		}
+	if self.waiters.Len() == 0 && (self.MaxActive == 0 || self.active < self.MaxActive) {
+		self.active += 1
+		self.mu.Unlock()
+		return self.dial()
+	}
 
-		if !self.Wait {
-			self.mu.Unlock()
-			return nil, ErrPoolExhausted
-		}
+	if !self.Wait {
+		self.mu.Unlock()
+		return idleConn{}, ErrPoolExhausted
+	}
+
+	return self.wait(ctx)
+}
+
+// wait queues the caller until a connection or a free slot is handed
+// over, it is called with the lock held and returns without it
+func (self *Pool) wait(ctx context.Context) (idleConn, error) {
+	ch := make(chan idleConn, 1)
+	e := self.waiters.PushBack(ch)
+	self.waitCount++
+	start := nowFunc()
+	self.mu.Unlock()
 
-		if self.cond == nil {
-			self.cond = sync.NewCond(&self.mu)
+	var ic idleConn
+	var ok bool
+	select {
+	case ic, ok = <-ch:
+		self.mu.Lock()
+	case <-ctx.Done():
+		self.mu.Lock()
+		select {
+		case ic, ok = <-ch:
+			//served right at the deadline
+		default:
+			self.waiters.Remove(e)
+			self.waitDuration += nowFunc().Sub(start)
+			err := ctx.Err()
+			if err == context.DeadlineExceeded {
+				self.timeoutCount++
+				err = ErrPoolTimeout
+			}
+			self.mu.Unlock()
+			return idleConn{}, err
 		}
-		self.cond.Wait()
 	}
-}
+	self.waitDuration += nowFunc().Sub(start)
+	self.mu.Unlock()
 
-func (self *Pool) putBusy(c Client) {
-	self.busy[c.CollationId()] = c
+	if !ok {
+		return idleConn{}, ErrPoolClosed
+	}
+	if ic.c != nil {
+		return ic, nil
+	}
+	return self.dial()
 }
 
-func (self *Pool) removeBusy(c Client) {
-	delete(self.busy, c.CollationId())
+// dial opens a connection in a slot already counted as active
+func (self *Pool) dial() (idleConn, error) {
+	c, err := self.Dial()
+	if err != nil {
+		self.observe(0, true)
+	}
+	self.mu.Lock()
+	if err != nil {
+		self.dialFailed = true
+		self.release()
+		self.mu.Unlock()
+		return idleConn{}, err
+	}
+	//the backend is back, fill the pool up again
+	recovered := self.dialFailed
+	self.dialFailed = false
+	self.mu.Unlock()
 
+	if recovered {
+		self.Warm()
+	}
+	now := nowFunc()
+	return idleConn{c: c, t: now, created: now}, nil
 }
 
-func (self *Pool) put(c Client, forceClose bool) error {
+func (self *Pool) put(c backend.Client, created time.Time, forceClose bool) error {
 	self.mu.Lock()
 	if !self.closed && !forceClose {
-		self.idle.PushFront(idleConn{t: nowFunc(), c: c})
+		if e := self.waiters.Front(); e != nil {
+			self.waiters.Remove(e)
+			e.Value.(chan idleConn) <- idleConn{t: nowFunc(), c: c, created: created}
+			self.mu.Unlock()
+			return nil
+		}
+		self.idle.PushFront(idleConn{t: nowFunc(), c: c, created: created})
 		if self.idle.Len() > self.MaxIdle {
 			c = self.idle.Remove(self.idle.Back()).(idleConn).c
 		} else {
@@ -177,23 +307,25 @@ func (self *Pool) put(c Client, forceClose bool) error {
 	}
 
 	if c == nil {
-		if self.cond != nil {
-			self.cond.Signal()
-		}
 		self.mu.Unlock()
-		go self.removeBusy(c)
 		return nil
 	}
 
 	self.release()
 	self.mu.Unlock()
-	go self.removeBusy(c)
 	return c.Close()
 }
 
 type pooledConnection struct {
-	p *Pool
-	c Client
+	p       *Pool
+	c       backend.Client
+	created time.Time
+
+	//what the connection is used for while it is borrowed, guarded
+	//by the pool lock
+	owner     uint32
+	borrowed  time.Time
+	statement string
 }
 
 func (self *pooledConnection) Connect(addr string, user string, password string, db string) error {
@@ -202,19 +334,72 @@ func (self *pooledConnection) Connect(addr string, user string, password string,
 func (self *pooledConnection) Execute(command string, args ...interface{}) (*mysql.Result, error) {
 	return self.c.Execute(command, args...)
 }
+func (self *pooledConnection) UseDB(db string) error {
+	return self.c.UseDB(db)
+}
+func (self *pooledConnection) FieldList(table string, wildcard string) ([]*mysql.Field, error) {
+	return self.c.FieldList(table, wildcard)
+}
+func (self *pooledConnection) Begin() error {
+	return self.c.Begin()
+}
+func (self *pooledConnection) Commit() error {
+	return self.c.Commit()
+}
 func (self *pooledConnection) Rollback() error {
 	return self.c.Rollback()
 }
 
-func (self *pooledConnection) CollationId() uint32 {
-	return self.c.CollationId()
+func (self *pooledConnection) ConnectionId() uint32 {
+	return self.c.ConnectionId()
+}
+
+func (self *pooledConnection) Addr() string {
+	return self.c.Addr()
+}
+
+func (self *pooledConnection) KillQuery() error {
+	return self.c.KillQuery()
+}
+
+func (self *pooledConnection) SetResultLimit(limit *backend.ResultLimit) {
+	self.c.SetResultLimit(limit)
+}
+
+func (self *pooledConnection) Ping() error {
+	return self.c.Ping()
 }
 
+func (self *pooledConnection) Reset() error {
+	return self.c.Reset()
+}
+
+func (self *pooledConnection) IsInTransaction() bool {
+	return self.c.IsInTransaction()
+}
+
+func (self *pooledConnection) Err() error {
+	return self.c.Err()
+}
+
+func (self *pooledConnection) SetDeadline(t time.Time) error {
+	return self.c.SetDeadline(t)
+}
+
+// Close hands the connection back to the pool, it is closed for real
+// when it is too old or can not be prepared for the next user
 func (self *pooledConnection) Close() error {
 	c := self.c
 	if self.c == nil {
 		return nil
 	}
-	self.p.put(c, false)
+	self.p.removeBusy(self)
+	self.c = nil
+
+	forceClose := self.p.expired(idleConn{c: c, created: self.created})
+	if test := self.p.TestOnReturn; !forceClose && test != nil {
+		forceClose = test(c) != nil
+	}
+	self.p.put(c, self.created, forceClose)
 	return nil
 }
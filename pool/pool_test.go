@@ -0,0 +1,305 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/mysql"
+)
+
+// fakeClock moves nowFunc by hand for the duration of a test, only for
+// pools without a refill running
+type fakeClock struct {
+	now time.Time
+}
+
+func useFakeClock(t *testing.T) *fakeClock {
+	c := &fakeClock{now: time.Unix(1000000, 0)}
+	nowFunc = func() time.Time { return c.now }
+	t.Cleanup(func() { nowFunc = time.Now })
+	return c
+}
+
+func (self *fakeClock) add(d time.Duration) {
+	self.now = self.now.Add(d)
+}
+
+// fakeConn is a backend connection that remembers what was done to it
+type fakeConn struct {
+	backend.Client
+	id       uint32
+	pings    int
+	pingErr  error
+	resets   int
+	resetErr error
+	closed   int32
+}
+
+func (self *fakeConn) Addr() string {
+	return "fake"
+}
+
+func (self *fakeConn) ConnectionId() uint32 {
+	return self.id
+}
+
+func (self *fakeConn) Ping() error {
+	self.pings++
+	return self.pingErr
+}
+
+func (self *fakeConn) Reset() error {
+	self.resets++
+	return self.resetErr
+}
+
+func (self *fakeConn) Close() error {
+	atomic.StoreInt32(&self.closed, 1)
+	return nil
+}
+
+func (self *fakeConn) isClosed() bool {
+	return atomic.LoadInt32(&self.closed) == 1
+}
+
+// fakeDialer hands out numbered fakeConns and fails while err is set
+type fakeDialer struct {
+	mu    sync.Mutex
+	conns []*fakeConn
+	times []time.Time
+	err   error
+}
+
+func (self *fakeDialer) dial() (backend.Client, error) {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	if self.err != nil {
+		return nil, self.err
+	}
+	c := &fakeConn{id: uint32(len(self.conns) + 1)}
+	self.conns = append(self.conns, c)
+	self.times = append(self.times, time.Now())
+	return c, nil
+}
+
+func (self *fakeDialer) dialed() int {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return len(self.conns)
+}
+
+func (self *fakeDialer) conn(i int) *fakeConn {
+	self.mu.Lock()
+	defer self.mu.Unlock()
+	return self.conns[i]
+}
+
+func (self *fakeDialer) setErr(err error) {
+	self.mu.Lock()
+	self.err = err
+	self.mu.Unlock()
+}
+
+func newTestPool(d *fakeDialer) *Pool {
+	return &Pool{Addr: "fake", Dial: d.dial, MaxIdle: 10}
+}
+
+func get(t *testing.T, p *Pool) backend.Client {
+	c, err := p.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// waitFor fails the test when cond does not hold within a second
+func waitFor(t *testing.T, what string, cond func() bool) {
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+	}
+}
+
+// inner is the fakeConn behind a connection of the pool
+func inner(c backend.Client) *fakeConn {
+	return c.(*pooledConnection).c.(*fakeConn)
+}
+
+func TestMaxLifetime(t *testing.T) {
+	clock := useFakeClock(t)
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.MaxLifetime = time.Minute
+
+	c := get(t, p)
+	first := inner(c)
+	c.Close()
+	if s := p.Stats(); s.Idle != 1 || first.isClosed() {
+		t.Fatalf("young connection not kept: %+v", s)
+	}
+
+	//an idle connection past its lifetime is closed on borrow
+	clock.add(time.Minute)
+	c = get(t, p)
+	if !first.isClosed() || inner(c).id != 2 {
+		t.Fatalf("old connection %d borrowed", inner(c).id)
+	}
+
+	//and a borrowed one on return
+	second := inner(c)
+	clock.add(time.Minute)
+	c.Close()
+	if s := p.Stats(); !second.isClosed() || s.Idle != 0 || s.Active != 0 {
+		t.Fatalf("old connection returned: %+v", s)
+	}
+}
+
+func TestPingOnBorrow(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.TestOnBorrow = func(c backend.Client, t time.Time) error {
+		return c.Ping()
+	}
+
+	c := get(t, p)
+	first := inner(c)
+	if first.pings != 0 {
+		t.Fatal("new connection pinged")
+	}
+	first.pingErr = mysql.ErrBadConn
+	c.Close()
+
+	//the dead idle connection is dropped for a new one
+	c = get(t, p)
+	if first.pings != 1 || !first.isClosed() || inner(c).id != 2 {
+		t.Fatalf("dead connection borrowed, %d pings", first.pings)
+	}
+	if s := p.Stats(); s.Active != 1 {
+		t.Fatalf("%d active", s.Active)
+	}
+
+	//a live one is reused
+	second := inner(c)
+	c.Close()
+	c = get(t, p)
+	if inner(c) != second || second.pings != 1 {
+		t.Fatalf("connection %d borrowed", inner(c).id)
+	}
+	c.Close()
+}
+
+func TestResetOnReturn(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.TestOnReturn = func(c backend.Client) error {
+		return c.Reset()
+	}
+
+	c := get(t, p)
+	fc := inner(c)
+	c.Close()
+	if s := p.Stats(); fc.resets != 1 || s.Idle != 1 {
+		t.Fatalf("%d resets: %+v", fc.resets, s)
+	}
+
+	//a connection that can not be reset is not handed out again
+	c = get(t, p)
+	fc.resetErr = mysql.ErrBadConn
+	c.Close()
+	if s := p.Stats(); !fc.isClosed() || s.Idle != 0 || s.Active != 0 {
+		t.Fatalf("connection kept after a failed reset: %+v", s)
+	}
+	if c.Close() != nil || fc.resets != 2 {
+		t.Fatal("second close reached the connection")
+	}
+}
+
+func TestWaitersInOrder(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.MaxActive = 1
+	p.Wait = true
+
+	held := get(t, p)
+	served := make(chan int, 3)
+	for i := 0; i < 3; i++ {
+		go func(i int) {
+			c, err := p.Get()
+			if err != nil {
+				served <- -1
+				return
+			}
+			served <- i
+			c.Close()
+		}(i)
+		//queue them one after the other
+		waitFor(t, "waiter", func() bool { return p.Stats().Waiting == i+1 })
+	}
+
+	held.Close()
+	for i := 0; i < 3; i++ {
+		if got := <-served; got != i {
+			t.Fatalf("waiter %d served %dth", got, i)
+		}
+	}
+	//the connection went from hand to hand
+	if s := p.Stats(); d.dialed() != 1 || s.Waiting != 0 || s.WaitCount != 3 || s.Idle != 1 {
+		t.Fatalf("%d dials: %+v", d.dialed(), s)
+	}
+}
+
+func TestMaxWait(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.MaxActive = 1
+	p.Wait = true
+	p.MaxWait = 20 * time.Millisecond
+
+	held := get(t, p)
+	start := time.Now()
+	if _, err := p.Get(); err != ErrPoolTimeout {
+		t.Fatalf("%v", err)
+	}
+	if waited := time.Since(start); waited < p.MaxWait {
+		t.Fatalf("gave up after %v", waited)
+	}
+	s := p.Stats()
+	if s.Waiting != 0 || s.WaitCount != 1 || s.TimeoutCount != 1 || s.WaitDuration < p.MaxWait {
+		t.Fatalf("%+v", s)
+	}
+
+	//a canceled caller is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := p.GetContext(ctx); err != context.Canceled {
+		t.Fatalf("%v", err)
+	}
+	if s := p.Stats(); s.TimeoutCount != 1 || s.Waiting != 0 {
+		t.Fatalf("%+v", s)
+	}
+
+	//the connection goes idle rather than to a waiter that left
+	held.Close()
+	if s := p.Stats(); s.Idle != 1 || s.Active != 1 {
+		t.Fatalf("%+v", s)
+	}
+}
+
+func TestExhausted(t *testing.T) {
+	p := newTestPool(&fakeDialer{})
+	p.MaxActive = 1
+
+	held := get(t, p)
+	if _, err := p.Get(); err != ErrPoolExhausted {
+		t.Fatalf("%v", err)
+	}
+	held.Close()
+	if s := p.Stats(); s.WaitCount != 0 {
+		t.Fatalf("%+v", s)
+	}
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"time"
+)
+
+// Warm dials connections in the background until MinIdle of them are
+// idle, one every RefillInterval at most so a freshly started backend
+// is not flooded with handshakes. It returns at once and does nothing
+// while a refill is running.
+func (self *Pool) Warm() {
+	self.mu.Lock()
+	if self.filling || !self.needRefill() {
+		self.mu.Unlock()
+		return
+	}
+	self.filling = true
+	self.mu.Unlock()
+
+	go self.refill()
+}
+
+// needRefill is called with the lock held
+func (self *Pool) needRefill() bool {
+	min := self.MinIdle
+	if min > self.MaxIdle {
+		min = self.MaxIdle
+	}
+	return !self.closed && self.idle.Len() < min && self.waiters.Len() == 0 &&
+		(self.MaxActive == 0 || self.active < self.MaxActive)
+}
+
+func (self *Pool) refill() {
+	for {
+		self.mu.Lock()
+		if !self.needRefill() {
+			self.filling = false
+			self.mu.Unlock()
+			return
+		}
+		self.active += 1
+		self.mu.Unlock()
+
+		c, err := self.Dial()
+		if err != nil {
+			//the backend is down, the next successful dial starts over
+			logger.Errorf("refill pool: %s", err.Error())
+			self.observe(0, true)
+			self.mu.Lock()
+			self.dialFailed = true
+			self.filling = false
+			self.release()
+			self.mu.Unlock()
+			return
+		}
+		self.put(c, nowFunc(), false)
+
+		if self.RefillInterval > 0 {
+			time.Sleep(self.RefillInterval)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func refilling(p *Pool) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.filling
+}
+
+func TestWarm(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.MinIdle = 3
+	p.RefillInterval = 20 * time.Millisecond
+	defer p.Close()
+
+	p.Warm()
+	waitFor(t, "warm up", func() bool { return p.Stats().Idle == 3 && !refilling(p) })
+	if n := d.dialed(); n != 3 {
+		t.Fatalf("%d dials", n)
+	}
+	//one dial per interval
+	d.mu.Lock()
+	times := append([]time.Time(nil), d.times...)
+	d.mu.Unlock()
+	for i := 1; i < len(times); i++ {
+		if gap := times[i].Sub(times[i-1]); gap < p.RefillInterval {
+			t.Fatalf("dial %d after %v", i, gap)
+		}
+	}
+
+	//a borrowed connection is replaced
+	c := get(t, p)
+	waitFor(t, "refill", func() bool { return p.Stats().Idle == 3 && !refilling(p) })
+	if s := p.Stats(); d.dialed() != 4 || s.Active != 4 {
+		t.Fatalf("%d dials: %+v", d.dialed(), s)
+	}
+	c.Close()
+	if s := p.Stats(); s.Idle != 4 {
+		t.Fatalf("%+v", s)
+	}
+}
+
+func TestWarmUpToMaxIdle(t *testing.T) {
+	d := &fakeDialer{}
+	p := newTestPool(d)
+	p.MinIdle = 3
+	p.MaxIdle = 2
+	defer p.Close()
+
+	p.Warm()
+	waitFor(t, "warm up", func() bool { return !refilling(p) })
+	if s := p.Stats(); d.dialed() != 2 || s.Idle != 2 {
+		t.Fatalf("%d dials: %+v", d.dialed(), s)
+	}
+}
+
+func TestRefillBackendDown(t *testing.T) {
+	d := &fakeDialer{}
+	d.setErr(errors.New("connection refused"))
+	p := newTestPool(d)
+	p.MinIdle = 2
+	defer p.Close()
+
+	//the refill gives up at the first failed dial
+	p.Warm()
+	waitFor(t, "failed refill", func() bool { return !refilling(p) })
+	if s := p.Stats(); !s.Down || s.Active != 0 || s.Idle != 0 {
+		t.Fatalf("%+v", s)
+	}
+	if _, err := p.Get(); err == nil {
+		t.Fatal("borrowed from a down backend")
+	}
+
+	//and starts over once a dial works again
+	d.setErr(nil)
+	c := get(t, p)
+	waitFor(t, "refill", func() bool { return p.Stats().Idle == 2 && !refilling(p) })
+	if s := p.Stats(); s.Down || s.Active != 3 {
+		t.Fatalf("%+v", s)
+	}
+	c.Close()
+}
@@ -0,0 +1,114 @@
+package sequence
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/config"
+)
+
+const (
+	GeneratorSnowflake = "snowflake"
+	GeneratorSegment   = "segment"
+)
+
+// Generator hands out ids, each one once
+type Generator interface {
+	Next() (int64, error)
+}
+
+// FetchFunc reserves the next step ids of the segment sequence name on
+// cluster and returns the last of them
+type FetchFunc func(cluster, name string, step int64) (int64, error)
+
+func newGenerator(cfg *config.SequenceConfig, name string, fetch FetchFunc) (Generator, error) {
+	switch cfg.Generator {
+	case GeneratorSnowflake:
+		return NewSnowflake(cfg.WorkerId)
+	case GeneratorSegment:
+		if cfg.Step <= 0 {
+			return nil, fmt.Errorf("segment step %d is not positive", cfg.Step)
+		}
+		return NewSegment(cfg.Step, func(step int64) (int64, error) {
+			return fetch(cfg.Cluster, name, step)
+		}), nil
+	}
+	return nil, fmt.Errorf("unknown generator %q", cfg.Generator)
+}
+
+const (
+	workerBits  = 10
+	counterBits = 12
+	maxWorker   = 1<<workerBits - 1
+	maxCounter  = 1<<counterBits - 1
+)
+
+// snowflakeEpoch is the time the milliseconds of the ids count from
+var snowflakeEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Snowflake makes ids of 41 bits of milliseconds, 10 of worker and 12 of
+// counter, they grow with time. When the clock goes back or the counter
+// runs out, the ids go on from the last millisecond used.
+type Snowflake struct {
+	sync.Mutex
+	worker  int64
+	last    int64
+	counter int64
+	now     func() time.Time
+}
+
+func NewSnowflake(worker int64) (*Snowflake, error) {
+	if worker < 0 || worker > maxWorker {
+		return nil, fmt.Errorf("snowflake worker %d is not between 0 and %d", worker, maxWorker)
+	}
+	return &Snowflake{worker: worker, last: -1, now: time.Now}, nil
+}
+
+func (self *Snowflake) Next() (int64, error) {
+	self.Lock()
+	defer self.Unlock()
+
+	ms := self.now().Sub(snowflakeEpoch).Nanoseconds() / int64(time.Millisecond)
+	if ms > self.last {
+		self.last = ms
+		self.counter = 0
+	} else if self.counter < maxCounter {
+		self.counter++
+	} else {
+		self.last++
+		self.counter = 0
+	}
+	return self.last<<(workerBits+counterBits) | self.worker<<counterBits | self.counter, nil
+}
+
+// Segment hands out the ids of a range reserved by fetch, and reserves
+// the next one when it is used up
+type Segment struct {
+	sync.Mutex
+	step  int64
+	next  int64
+	max   int64
+	fetch func(step int64) (int64, error)
+}
+
+func NewSegment(step int64, fetch func(step int64) (int64, error)) *Segment {
+	return &Segment{step: step, next: 1, fetch: fetch}
+}
+
+func (self *Segment) Next() (int64, error) {
+	self.Lock()
+	defer self.Unlock()
+
+	if self.next > self.max {
+		max, err := self.fetch(self.step)
+		if err != nil {
+			return 0, err
+		}
+		self.max = max
+		self.next = max - self.step + 1
+	}
+	id := self.next
+	self.next++
+	return id, nil
+}
@@ -0,0 +1,162 @@
+package sequence
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/sql"
+)
+
+// Sequence fills a column of a table with the ids of its generator
+type Sequence struct {
+	schema    string
+	table     string
+	column    string
+	generator Generator
+}
+
+// Name is the sequence as the errors and SequenceTable show it
+func (self *Sequence) Name() string {
+	name := self.table + "." + self.column
+	if self.schema != "" {
+		name = self.schema + "." + name
+	}
+	return name
+}
+
+// Registry holds the sequences of the config, one a table
+type Registry struct {
+	sequences []*Sequence
+}
+
+func NewRegistry(cfgs []config.SequenceConfig, fetch FetchFunc) (*Registry, error) {
+	r := &Registry{}
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		if cfg.Table == "" || cfg.Column == "" {
+			return nil, fmt.Errorf("sequence of table %q needs a column", cfg.Table)
+		}
+		s := &Sequence{schema: cfg.Schema, table: cfg.Table, column: cfg.Column}
+		for _, other := range r.sequences {
+			if other.schema == s.schema && other.table == s.table {
+				return nil, fmt.Errorf("table %s has sequences %s and %s", s.table, other.Name(), s.Name())
+			}
+		}
+		g, err := newGenerator(cfg, s.Name(), fetch)
+		if err != nil {
+			return nil, fmt.Errorf("sequence %s: %s", s.Name(), err.Error())
+		}
+		s.generator = g
+		r.sequences = append(r.sequences, s)
+	}
+	return r, nil
+}
+
+func (self *Registry) find(schema, table string) *Sequence {
+	for _, s := range self.sequences {
+		if s.table == table && (s.schema == "" || s.schema == schema) {
+			return s
+		}
+	}
+	return nil
+}
+
+// Fill gives ids to the rows of stmt, an INSERT or REPLACE, that leave
+// the column of a sequence out or set it to NULL, 0 or DEFAULT. The ids
+// are written into the values of stmt, it returns the first of them, 0
+// when there is none to give. The tables without a schema are in db.
+func (self *Registry) Fill(stmt sql.IStatement, db string) (uint64, error) {
+	if len(self.sequences) == 0 {
+		return 0, nil
+	}
+	var table sql.ITable
+	var fields interface{}
+	switch s := stmt.(type) {
+	case *sql.Insert:
+		table, fields = s.Table, s.InsertFields
+	case *sql.Replace:
+		table, fields = s.Table, s.ReplaceFields
+	default:
+		return 0, nil
+	}
+	t, ok := table.(*sql.SimpleTable)
+	if !ok {
+		return 0, nil
+	}
+	schema := db
+	if len(t.Qualifier) > 0 {
+		schema = string(t.Qualifier)
+	}
+	seq := self.find(schema, string(t.Name))
+	if seq == nil {
+		return 0, nil
+	}
+	values, ok := fields.(*sql.InsertValues)
+	if !ok {
+		//INSERT ... SELECT brings its own ids
+		return 0, nil
+	}
+	if len(values.Columns) == 0 {
+		for _, row := range values.Rows {
+			if len(row) > 0 {
+				//the rows give every column, in the order of the table
+				return 0, nil
+			}
+		}
+	}
+
+	col := -1
+	for i, c := range values.Columns {
+		if isColumn(seq, c) {
+			col = i
+		}
+	}
+
+	var first uint64
+	for i, row := range values.Rows {
+		if col >= 0 && (col >= len(row) || !empty(row[col])) {
+			continue
+		}
+		id, err := seq.generator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("sequence %s: %s", seq.Name(), err.Error())
+		}
+		if first == 0 {
+			first = uint64(id)
+		}
+		v := sql.NumVal(strconv.FormatInt(id, 10))
+		if col >= 0 {
+			row[col] = v
+		} else {
+			values.Rows[i] = append(row, v)
+		}
+	}
+	if first != 0 && col < 0 {
+		values.Columns = append(values.Columns, &sql.SchemaObject{Column: []byte(seq.column)})
+	}
+	return first, nil
+}
+
+func isColumn(seq *Sequence, e sql.IValExpr) bool {
+	c, ok := e.(*sql.SchemaObject)
+	return ok && c != nil && strings.EqualFold(string(c.Column), seq.column)
+}
+
+// empty is whether v asks for an id, like for an AUTO_INCREMENT column
+func empty(v sql.IExpr) bool {
+	switch e := v.(type) {
+	case *sql.Predicate:
+		return empty(e.Expr)
+	case *sql.NullVal:
+		return true
+	case sql.NumVal:
+		n, err := strconv.ParseFloat(string(e), 64)
+		return err == nil && n == 0
+	case sql.StrVal:
+		//DEFAULT is kept without quotes
+		return strings.EqualFold(string(e), "DEFAULT")
+	}
+	return false
+}
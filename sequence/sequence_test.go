@@ -0,0 +1,140 @@
+package sequence
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/sql"
+)
+
+func TestSnowflake(t *testing.T) {
+	s, err := NewSnowflake(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := snowflakeEpoch.Add(time.Second)
+	s.now = func() time.Time { return now }
+
+	var last int64
+	for i := 0; i < maxCounter+3; i++ {
+		id, _ := s.Next()
+		if id <= last {
+			t.Fatalf("id %d after %d", id, last)
+		}
+		last = id
+	}
+	if ms := last >> (workerBits + counterBits); ms != 1001 {
+		t.Fatalf("counter overflow: ms %d, want 1001", ms)
+	}
+	if worker := last >> counterBits & maxWorker; worker != 5 {
+		t.Fatalf("worker %d", worker)
+	}
+
+	//a clock going back keeps the ids growing
+	now = now.Add(-time.Minute)
+	if id, _ := s.Next(); id <= last {
+		t.Fatalf("id %d after %d", id, last)
+	}
+
+	if _, err := NewSnowflake(1024); err == nil {
+		t.Fatal("worker 1024 taken")
+	}
+}
+
+func TestSegment(t *testing.T) {
+	var max int64
+	fetches := 0
+	s := NewSegment(3, func(step int64) (int64, error) {
+		fetches++
+		max += step
+		return max, nil
+	})
+	for want := int64(1); want <= 7; want++ {
+		id, err := s.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if id != want {
+			t.Fatalf("id %d, want %d", id, want)
+		}
+	}
+	if fetches != 3 {
+		t.Fatalf("%d fetches", fetches)
+	}
+
+	s = NewSegment(3, func(int64) (int64, error) { return 0, fmt.Errorf("down") })
+	if _, err := s.Next(); err == nil {
+		t.Fatal("no error")
+	}
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	var max int64 = 100
+	r, err := NewRegistry([]config.SequenceConfig{
+		{Table: "orders", Column: "id", Generator: GeneratorSegment, Cluster: "c", Step: 10},
+		{Schema: "s", Table: "users", Column: "uid", Generator: GeneratorSegment, Cluster: "c", Step: 10},
+	}, func(cluster, name string, step int64) (int64, error) {
+		if cluster != "c" {
+			t.Fatalf("cluster %s", cluster)
+		}
+		max += step
+		return max, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestFill(t *testing.T) {
+	r := newTestRegistry(t)
+	for _, c := range []struct {
+		query string
+		sql   string
+		id    uint64
+	}{
+		{"INSERT INTO orders (a, b) VALUES (1, 'x'), (2, 'y')",
+			"insert into orders (a, b, id) values (1, 'x', 101), (2, 'y', 102)", 101},
+		{"INSERT INTO orders (id, a) VALUES (NULL, 1), (7, 2), (0, 3), (DEFAULT, 4)",
+			"insert into orders (id, a) values (103, 1), (7, 2), (104, 3), (105, 4)", 103},
+		{"insert orders set a = 1 on duplicate key update a = 2",
+			"insert into orders (a, id) values (1, 106) on duplicate key update a = 2", 106},
+		{"REPLACE INTO orders SET id = NULL, a = 1", "replace into orders (id, a) values (107, 1)", 107},
+		{"INSERT INTO orders () VALUES ()", "insert into orders (id) values (108)", 108},
+		{"INSERT INTO orders (id) VALUES (5)", "insert into orders (id) values (5)", 0},
+		{"INSERT INTO orders VALUES (NULL, 1)", "insert into orders values (null, 1)", 0},
+		{"INSERT INTO orders (id) SELECT id FROM o", "insert into orders (id) select id from o", 0},
+		{"INSERT INTO t.users (a) VALUES (1)", "insert into t.users (a) values (1)", 0},
+		{"INSERT INTO s.users (a) VALUES (1)", "insert into s.users (a, uid) values (1, 111)", 111},
+		{"INSERT INTO other (a) VALUES (1)", "insert into other (a) values (1)", 0},
+	} {
+		stmt, err := sql.Parse(c.query)
+		if err != nil {
+			t.Fatalf("parse %s: %s", c.query, err.Error())
+		}
+		id, err := r.Fill(stmt, "db")
+		if err != nil {
+			t.Fatalf("%s: %s", c.query, err.Error())
+		}
+		if query := sql.String(stmt); query != c.sql || id != c.id {
+			t.Fatalf("%s: %q %d, want %q %d", c.query, query, id, c.sql, c.id)
+		}
+	}
+}
+
+func TestRegistryErrors(t *testing.T) {
+	fetch := func(string, string, int64) (int64, error) { return 0, nil }
+	for _, cfgs := range [][]config.SequenceConfig{
+		{{Table: "t", Generator: GeneratorSnowflake}},
+		{{Table: "t", Column: "id", Generator: "uuid"}},
+		{{Table: "t", Column: "id", Generator: GeneratorSegment}},
+		{{Table: "t", Column: "id", Generator: GeneratorSnowflake, WorkerId: -1}},
+		{{Table: "t", Column: "id", Generator: GeneratorSnowflake}, {Table: "t", Column: "x", Generator: GeneratorSnowflake}},
+	} {
+		if _, err := NewRegistry(cfgs, fetch); err == nil {
+			t.Fatalf("%+v: no error", cfgs)
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/lostz/Aegis/balance"
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sql"
+)
+
+// handleAegisAdmin runs an AEGIS admin statement, only the AdminUser
+// may change the backends
+func (self *Session) handleAegisAdmin(stmt *sql.AegisAdmin) error {
+	if !self.isAdmin() {
+		return mysql.NewDefaultError(mysql.ER_SPECIFIC_ACCESS_DENIED_ERROR, "AEGIS ADMIN")
+	}
+	if err := self.s.adminBackend(stmt); err != nil {
+		return err
+	}
+	return self.writeOK(nil)
+}
+
+// isAdmin is whether the session runs as the AdminUser, who may also see
+// and kill the sessions of the other users
+func (self *Session) isAdmin() bool {
+	admin := self.s.cfg.AdminUser
+	return admin != "" && self.user == admin
+}
+
+// adminBackend applies stmt to the balancer and the pools of the cluster
+// of the backend, then to the config which is saved with AdminPersist.
+// Replicas are added to the default cluster. The master always takes the
+// writes, its weight and state only matter for reads.
+func (self *Server) adminBackend(stmt *sql.AegisAdmin) error {
+	self.adminLock.Lock()
+	defer self.adminLock.Unlock()
+
+	weight := defaultWeight
+	if stmt.Weight != nil {
+		w, err := strconv.Atoi(string(stmt.Weight))
+		if err != nil || w < 0 {
+			return mysql.NewDefaultError(mysql.ER_WRONG_ARGUMENTS, "WEIGHT")
+		}
+		weight = w
+	}
+
+	c := self.findBackend(stmt.Addr)
+	var err error
+	switch {
+	case stmt.Action == sql.AegisAction_AddReplica:
+		if c == nil {
+			c = self.defaultCluster()
+		}
+		err = self.addReplica(c, stmt.Addr, weight)
+	case c == nil:
+		err = balance.ErrServerNotFound
+	case stmt.Action == sql.AegisAction_SetWeight:
+		err = c.readerPool.SetWeight(stmt.Addr, weight)
+	case stmt.Action == sql.AegisAction_Offline:
+		err = c.readerPool.SetGood(stmt.Addr, false)
+	case stmt.Action == sql.AegisAction_Online:
+		err = c.readerPool.SetGood(stmt.Addr, true)
+	case stmt.Action == sql.AegisAction_Drain:
+		err = self.drainBackend(c, stmt.Addr)
+	}
+	switch err {
+	case nil:
+	case balance.ErrServerNotFound:
+		return mysql.NewMysqlError(mysql.ER_WRONG_ARGUMENTS, fmt.Sprintf("Unknown backend %s", stmt.Addr))
+	case balance.ErrServerExists:
+		return mysql.NewMysqlError(mysql.ER_WRONG_ARGUMENTS, fmt.Sprintf("Backend %s already exists", stmt.Addr))
+	default:
+		return err
+	}
+	logger.Infof("admin %s on backend %s of cluster %s, weight %d", adminActionName[stmt.Action], stmt.Addr, c.name, weight)
+
+	self.updateConfig(c, stmt, weight)
+	if self.cfg.AdminPersist {
+		if err := self.cfg.Save(); err != nil {
+			logger.Errorf("save config: %s", err.Error())
+			return mysql.NewMysqlError(mysql.ER_UNKNOWN_ERROR,
+				fmt.Sprintf("The change is applied but not saved: %s", err.Error()))
+		}
+	}
+	return nil
+}
+
+var adminActionName = map[sql.AegisAction]string{
+	sql.AegisAction_SetWeight:  "set weight",
+	sql.AegisAction_Offline:    "offline",
+	sql.AegisAction_Online:     "online",
+	sql.AegisAction_Drain:      "drain",
+	sql.AegisAction_AddReplica: "add replica",
+}
+
+// drainBackend takes a replica out of the balancer for good, its idle
+// connections are closed now and the borrowed ones once they come back
+func (self *Server) drainBackend(c *cluster, addr string) error {
+	if addr == c.writerPool().Addr {
+		return mysql.NewMysqlError(mysql.ER_WRONG_ARGUMENTS, "The master can not be drained")
+	}
+	rs, err := c.readerPool.Remove(addr)
+	if err != nil {
+		return err
+	}
+	return rs.Pool().Close()
+}
+
+func (self *Server) addReplica(c *cluster, addr string, weight int) error {
+	p := self.newPool(addr)
+	if err := c.readerPool.Add(balance.NewRbServer(weight, p)); err != nil {
+		return err
+	}
+	p.Warm()
+	return nil
+}
+
+// updateConfig records an applied admin statement in the config, the
+// state of the master is not kept
+func (self *Server) updateConfig(c *cluster, stmt *sql.AegisAdmin, weight int) {
+	cfg := c.cfg
+	if stmt.Action == sql.AegisAction_AddReplica {
+		cfg.Replicas = append(cfg.Replicas, config.ReplicaConfig{Addr: stmt.Addr, Weight: weight})
+		return
+	}
+	if stmt.Addr == c.writerPool().Addr {
+		if stmt.Action == sql.AegisAction_SetWeight {
+			cfg.MasterWeight = weight
+		}
+		return
+	}
+
+	for i := range cfg.Replicas {
+		r := &cfg.Replicas[i]
+		if r.Addr != stmt.Addr {
+			continue
+		}
+		switch stmt.Action {
+		case sql.AegisAction_SetWeight:
+			r.Weight = weight
+		case sql.AegisAction_Offline:
+			r.Offline = true
+		case sql.AegisAction_Online:
+			r.Offline = false
+		case sql.AegisAction_Drain:
+			cfg.Replicas = append(cfg.Replicas[:i], cfg.Replicas[i+1:]...)
+		}
+		return
+	}
+}
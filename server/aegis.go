@@ -0,0 +1,226 @@
+package server
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/balance"
+	"github.com/lostz/Aegis/pool"
+)
+
+const (
+	roleMaster = "master"
+	roleSlave  = "slave"
+)
+
+// lagTimeout bounds the SHOW SLAVE STATUS run for SHOW AEGIS BACKENDS
+const lagTimeout = time.Second
+
+// backendInfo is a backend as the balancers see it, the master also
+// takes reads when it is one of the reader servers
+type backendInfo struct {
+	cluster string
+	addr    string
+	role    string
+	weight  int
+	good    bool
+	pool    *pool.Pool
+	breaker balance.BreakerStats
+}
+
+func (self *Server) backends() []backendInfo {
+	var backends []backendInfo
+	for _, c := range self.clusters {
+		backends = append(backends, c.backends()...)
+	}
+	return backends
+}
+
+func (self *cluster) backends() []backendInfo {
+	writer := self.writerPool()
+	master := backendInfo{
+		cluster: self.name,
+		addr:    writer.Addr,
+		role:    roleMaster,
+		good:    true,
+		pool:    writer,
+
+		breaker: balance.BreakerStats{State: balance.BreakerClosed},
+	}
+	backends := []backendInfo{master}
+	for _, rs := range self.readerPool.Servers() {
+		if rs.Pool() == writer {
+			backends[0].weight = rs.Weight()
+			backends[0].good = rs.Good()
+			backends[0].breaker = rs.Breaker()
+			continue
+		}
+		backends = append(backends, backendInfo{
+			cluster: self.name,
+			addr:    rs.Pool().Addr,
+			role:    roleSlave,
+			weight:  rs.Weight(),
+			good:    rs.Good(),
+			pool:    rs.Pool(),
+
+			breaker: rs.Breaker(),
+		})
+	}
+	return backends
+}
+
+// replicationLag is Seconds_Behind_Master of a slave, empty when it is
+// unknown or the slave does not answer within lagTimeout
+func replicationLag(p *pool.Pool) string {
+	deadline := time.Now().Add(lagTimeout)
+	ctx, cancel := context.WithDeadline(context.Background(), deadline)
+	defer cancel()
+	conn, err := p.GetContext(ctx)
+	if err != nil {
+		return ""
+	}
+	defer conn.Close()
+
+	//a timed out conn saw an error, the pool closes it
+	conn.SetDeadline(deadline)
+	res, err := conn.Execute("SHOW SLAVE STATUS")
+	conn.SetDeadline(time.Time{})
+	if err != nil || res.Resultset == nil || res.RowNumber() == 0 {
+		return ""
+	}
+	lag, err := res.GetStringByName(0, "Seconds_Behind_Master")
+	if err != nil {
+		return ""
+	}
+	return lag
+}
+
+// handleShowAegisBackends answers SHOW AEGIS BACKENDS, a backend is down
+// when the balancer marked it bad or its last dial failed. The breaker
+// columns tell whether it is ejected from the reads, until when and why.
+func (self *Session) handleShowAegisBackends() error {
+	names := []string{"Addr", "Role", "Weight", "State", "Lag", "Active", "Busy",
+		"Breaker", "Ejections", "Ejected_until", "Ejection_reason", "Cluster"}
+
+	//the slaves are asked at once, a stuck one costs lagTimeout at most
+	backends := self.s.backends()
+	lags := make([]string, len(backends))
+	var wg sync.WaitGroup
+	for i, b := range backends {
+		if b.role != roleSlave {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, p *pool.Pool) {
+			defer wg.Done()
+			lags[i] = replicationLag(p)
+		}(i, b.pool)
+	}
+	wg.Wait()
+
+	var values [][]interface{}
+	for i, b := range backends {
+		stats := b.pool.Stats()
+		state := "up"
+		if !b.good || stats.Down {
+			state = "down"
+		}
+		lag := lags[i]
+		var until string
+		if b.breaker.Ejections > 0 {
+			until = b.breaker.Until.Format("2006-01-02 15:04:05")
+		}
+		values = append(values, []interface{}{
+			b.addr,
+			b.role,
+			b.weight,
+			state,
+			lag,
+			stats.Active,
+			stats.Busy,
+			b.breaker.State,
+			b.breaker.Ejections,
+			until,
+			b.breaker.Reason,
+			b.cluster,
+		})
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
+
+// handleShowAegisPools answers SHOW AEGIS POOLS, Wait_time is the total
+// time sessions waited for a connection in milliseconds
+func (self *Session) handleShowAegisPools() error {
+	names := []string{"Addr", "Active", "Idle", "Busy", "Waiting", "Max_active", "Min_idle", "Max_idle",
+		"Wait_count", "Wait_time", "Wait_timeouts"}
+
+	var values [][]interface{}
+	for _, p := range self.s.pools() {
+		stats := p.Stats()
+		values = append(values, []interface{}{
+			p.Addr,
+			stats.Active,
+			stats.Idle,
+			stats.Busy,
+			stats.Waiting,
+			p.MaxActive,
+			p.MinIdle,
+			p.MaxIdle,
+			stats.WaitCount,
+			int64(stats.WaitDuration / time.Millisecond),
+			stats.TimeoutCount,
+		})
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
+
+// handleShowAegisSessions answers SHOW AEGIS SESSIONS, one summary row
+// for each session the user may see. Connected is in seconds, Questions
+// counts the statements run so far.
+func (self *Session) handleShowAegisSessions() error {
+	names := []string{"Id", "User", "Host", "db", "Command", "Time", "Connected", "Questions",
+		"Pinned", "Backend", "Backend_thread_id"}
+
+	var values [][]interface{}
+	for _, p := range self.processList() {
+		pinned := "No"
+		if p.pinned {
+			pinned = "Yes"
+		}
+		var threadId string
+		if p.backend != "" {
+			threadId = strconv.FormatUint(uint64(p.threadId), 10)
+		}
+		values = append(values, []interface{}{
+			p.id,
+			p.user,
+			p.host,
+			p.db,
+			p.command,
+			p.time,
+			p.connected,
+			p.questions,
+			pinned,
+			p.backend,
+			threadId,
+		})
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
@@ -0,0 +1,44 @@
+package server
+
+import (
+	"time"
+
+	"github.com/lostz/Aegis/pool"
+)
+
+// pools are the backend connection pools, one per backend
+func (self *Server) pools() []*pool.Pool {
+	backends := self.backends()
+	pools := make([]*pool.Pool, 0, len(backends))
+	for _, b := range backends {
+		pools = append(pools, b.pool)
+	}
+	return pools
+}
+
+// watchBusy looks for backend connections that are held too long, or
+// still borrowed by a session that is gone and so will never come back
+func (self *Server) watchBusy() {
+	hold := time.Duration(self.cfg.BackendHoldWarn) * time.Second
+	interval := 10 * time.Second
+	if hold > 0 && hold < interval {
+		interval = hold
+	}
+
+	for range time.Tick(interval) {
+		for _, p := range self.pools() {
+			for _, b := range p.Busy() {
+				held := time.Since(b.Borrowed)
+				if b.Owner != 0 && self.sessions.Get(b.Owner) == nil {
+					logger.Errorf("backend connection %s thread %d leaked by closed session %d, held %s, last statement: %s",
+						b.Addr, b.ConnectionId, b.Owner, held.String(), b.Statement)
+					continue
+				}
+				if hold > 0 && held >= hold {
+					logger.Warningf("backend connection %s thread %d held by session %d for %s, last statement: %s",
+						b.Addr, b.ConnectionId, b.Owner, held.String(), b.Statement)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package server
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/balance"
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/pool"
+	"github.com/lostz/Aegis/shard"
+	"github.com/lostz/Aegis/sql"
+)
+
+// cluster is a master and its replicas, the writes go to the master and
+// the reads are balanced over all of them
+type cluster struct {
+	name       string
+	cfg        *config.ClusterConfig
+	writer     *pool.Pool
+	writerLock sync.RWMutex
+	readerPool *balance.Rebalancer
+}
+
+// route sends the schemas matching the pattern to a cluster
+type route struct {
+	schema  string
+	cluster *cluster
+}
+
+// writerPool is the pool of the current master
+func (self *cluster) writerPool() *pool.Pool {
+	self.writerLock.RLock()
+	p := self.writer
+	self.writerLock.RUnlock()
+	return p
+}
+
+func (self *Server) newCluster(cfg *config.ClusterConfig) (*cluster, error) {
+	strategy, err := balance.NewStrategy(cfg.Balance)
+	if err != nil {
+		return nil, err
+	}
+	c := &cluster{name: cfg.Name, cfg: cfg, writer: self.newPool(cfg.DBAddr)}
+
+	masterWeight := cfg.MasterWeight
+	if masterWeight == 0 && len(cfg.Replicas) == 0 {
+		masterWeight = defaultWeight
+	}
+	servers := []*balance.RbServer{balance.NewRbServer(masterWeight, c.writer)}
+	for _, r := range cfg.Replicas {
+		servers = append(servers, balance.NewRbServer(r.Weight, self.newPool(r.Addr)))
+	}
+	c.readerPool = balance.NewRebalancer(servers, strategy)
+	if self.cfg.BreakerMinRequests > 0 {
+		c.readerPool.SetBreaker(balance.BreakerConfig{
+			Window:      time.Duration(self.cfg.BreakerWindow) * time.Second,
+			MinRequests: self.cfg.BreakerMinRequests,
+			ErrorRate:   self.cfg.BreakerErrorRate,
+			Latency:     time.Duration(self.cfg.BreakerLatency) * time.Millisecond,
+			Ejection:    time.Duration(self.cfg.BreakerEjection) * time.Second,
+			MaxEjection: time.Duration(self.cfg.BreakerMaxEjection) * time.Second,
+		})
+	}
+	for _, r := range cfg.Replicas {
+		if r.Offline {
+			c.readerPool.SetGood(r.Addr, false)
+		}
+	}
+	return c, nil
+}
+
+// initClusters builds the clusters and the routing table from the config
+func (self *Server) initClusters() error {
+	for _, cfg := range self.cfg.GetClusters() {
+		if cfg.Name == "" || self.getCluster(cfg.Name) != nil {
+			return fmt.Errorf("cluster name %q is empty or used twice", cfg.Name)
+		}
+		c, err := self.newCluster(cfg)
+		if err != nil {
+			return fmt.Errorf("cluster %s: %s", cfg.Name, err.Error())
+		}
+		self.clusters = append(self.clusters, c)
+	}
+
+	shards, err := shard.NewRouter(self.cfg.Shards)
+	if err != nil {
+		return err
+	}
+	for _, name := range shards.Clusters() {
+		if self.getCluster(name) == nil {
+			return fmt.Errorf("shard on unknown cluster %s", name)
+		}
+	}
+	self.shards = shards
+	if err := self.initSequences(); err != nil {
+		return err
+	}
+
+	for _, r := range self.cfg.Routes {
+		c := self.getCluster(r.Cluster)
+		if c == nil {
+			return fmt.Errorf("route %s: unknown cluster %s", r.Schema, r.Cluster)
+		}
+		if _, err := path.Match(r.Schema, ""); err != nil {
+			return fmt.Errorf("route %s: %s", r.Schema, err.Error())
+		}
+		self.routes = append(self.routes, route{schema: r.Schema, cluster: c})
+	}
+	return nil
+}
+
+// defaultCluster serves the schemas no route matches
+func (self *Server) defaultCluster() *cluster {
+	return self.clusters[0]
+}
+
+func (self *Server) getCluster(name string) *cluster {
+	for _, c := range self.clusters {
+		if c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// findBackend is the cluster with a backend dialing addr
+func (self *Server) findBackend(addr string) *cluster {
+	for _, c := range self.clusters {
+		if c.writerPool().Addr == addr {
+			return c
+		}
+		for _, rs := range c.readerPool.Servers() {
+			if rs.Pool().Addr == addr {
+				return c
+			}
+		}
+	}
+	return nil
+}
+
+// schemaCluster is the cluster the schema is routed to
+func (self *Server) schemaCluster(schema string) *cluster {
+	for _, r := range self.routes {
+		if ok, _ := path.Match(r.schema, schema); ok {
+			return r.cluster
+		}
+	}
+	return self.defaultCluster()
+}
+
+// schemaStatement is a statement that knows the schemas it names
+type schemaStatement interface {
+	GetSchemas() []string
+}
+
+// route picks the cluster of stmt: the one of the schemas it names, or
+// of the current db for its unqualified tables. A statement without
+// tables stays on the cluster of the transaction. Statements across
+// clusters are rejected, so are transactions.
+func (self *Session) route(stmt sql.IStatement) error {
+	self.scatter = nil
+	plan, err := self.s.shards.Route(stmt, self.db)
+	if err != nil {
+		return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET, err.Error())
+	}
+	if plan != nil {
+		return self.routeShard(stmt, plan)
+	}
+
+	var schemas []string
+	tables := false
+	if s, ok := stmt.(schemaStatement); ok {
+		schemas = s.GetSchemas()
+		tables = true
+		if sel, ok := stmt.(sql.ISelect); ok {
+			//a SELECT knows its tables, those without a schema are in
+			//the current db
+			t := sel.GetTables()
+			tables = len(t) > 0 || len(schemas) > 0
+			if len(t) > len(schemas) {
+				schemas = append(schemas, self.db)
+			}
+		} else if len(schemas) == 0 {
+			schemas = []string{self.db}
+		}
+	}
+
+	if !tables {
+		if self.pinConn != nil {
+			self.cluster = self.pinCluster
+			return nil
+		}
+		schemas = []string{self.db}
+	}
+	return self.routeSchemas(schemas)
+}
+
+// routeSchemas picks the cluster all of schemas are routed to
+func (self *Session) routeSchemas(schemas []string) error {
+	c := self.s.schemaCluster(schemas[0])
+	for _, schema := range schemas[1:] {
+		if other := self.s.schemaCluster(schema); other != c {
+			return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET,
+				fmt.Sprintf("Statement uses schemas on clusters %s and %s", c.name, other.name))
+		}
+	}
+	return self.useCluster(c)
+}
+
+// routeShard sends a statement on a sharded table to the cluster of its
+// shards. Only an autocommit SELECT may run on several, up to
+// ScatterMaxShards of them.
+func (self *Session) routeShard(stmt sql.IStatement, plan *shard.Plan) error {
+	clusters := plan.Clusters()
+	if len(clusters) > 1 {
+		_, ok := stmt.(*sql.Select)
+		max := self.s.cfg.ScatterMaxShards
+		if !ok || max <= 0 {
+			return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET,
+				fmt.Sprintf("Statement on sharded table %s runs on %d shards, scatter-gather is not allowed",
+					plan.Table.Name(), len(clusters)))
+		}
+		if len(clusters) > max {
+			return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET,
+				fmt.Sprintf("Statement on sharded table %s runs on %d shards, more than %d",
+					plan.Table.Name(), len(clusters), max))
+		}
+		if self.pinConn != nil || self.needBeginTx() {
+			return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET,
+				fmt.Sprintf("Statement on sharded table %s runs on %d shards in a transaction",
+					plan.Table.Name(), len(clusters)))
+		}
+		self.scatter = plan
+	}
+	return self.useCluster(self.s.getCluster(clusters[0]))
+}
+
+func (self *Session) useCluster(c *cluster) error {
+	if self.pinConn != nil && self.pinCluster != c {
+		return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET,
+			fmt.Sprintf("Statement on cluster %s in a transaction on cluster %s", c.name, self.pinCluster.name))
+	}
+	self.cluster = c
+	return nil
+}
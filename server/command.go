@@ -0,0 +1,203 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/lostz/Aegis/mysql"
+)
+
+const (
+	MYSQL_OPTION_MULTI_STATEMENTS_ON  uint16 = 0
+	MYSQL_OPTION_MULTI_STATEMENTS_OFF uint16 = 1
+)
+
+// handleFieldList answers COM_FIELD_LIST with the column definitions
+// of the table in the session's db, read from a backend
+func (self *Session) handleFieldList(data []byte) error {
+	index := bytes.IndexByte(data, 0x00)
+	if index < 0 {
+		return mysql.ErrMalformPacket
+	}
+	table := string(data[0:index])
+	wildcard := string(data[index+1:])
+
+	if err := self.routeSchemas([]string{self.db}); err != nil {
+		return err
+	}
+	conn, err := self.getConn(true)
+	if err != nil {
+		return err
+	}
+
+	fields, err := conn.FieldList(table, wildcard)
+	self.closeDBConn(conn, false)
+	if err != nil {
+		return err
+	}
+
+	return self.writeFieldList(self.status, fields)
+}
+
+func (self *Session) writeFieldList(status uint16, fields []*mysql.Field) error {
+	data := make([]byte, 4, 1024)
+
+	for _, v := range fields {
+		data = data[0:4]
+		data = append(data, v.Dump()...)
+		if err := self.pkg.WritePacket(data); err != nil {
+			return err
+		}
+	}
+
+	return self.writeEOF(status)
+}
+
+// handleStatistics answers COM_STATISTICS with a human readable string,
+// like mysqladmin status shows it
+func (self *Session) handleStatistics() error {
+	uptime := time.Since(self.s.startTime).Seconds()
+	questions := atomic.LoadUint64(&self.s.questions)
+
+	var qps float64
+	if uptime > 0 {
+		qps = float64(questions) / uptime
+	}
+
+	stat := fmt.Sprintf("Uptime: %d  Threads: %d  Questions: %d  Slow queries: 0  Opens: 0  Flush tables: 0  Open tables: 0  Queries per second avg: %.3f",
+		int64(uptime),
+		self.s.sessions.Count(),
+		questions,
+		qps,
+	)
+
+	data := make([]byte, 4, 4+len(stat))
+	data = append(data, stat...)
+	return self.pkg.WritePacket(data)
+}
+
+// handleDebug answers COM_DEBUG, the proxy state goes to the log
+func (self *Session) handleDebug() error {
+	logger.Infof("debug: version %s commit %s uptime %s sessions %d questions %d",
+		self.s.Version,
+		self.s.CommitId,
+		time.Since(self.s.startTime).String(),
+		self.s.sessions.Count(),
+		atomic.LoadUint64(&self.s.questions),
+	)
+	return self.writeEOF(self.status)
+}
+
+// handleSetOption answers COM_SET_OPTION, which turns multi-statements
+// on or off for this session
+func (self *Session) handleSetOption(data []byte) error {
+	if len(data) < 2 {
+		return mysql.ErrMalformPacket
+	}
+
+	switch binary.LittleEndian.Uint16(data) {
+	case MYSQL_OPTION_MULTI_STATEMENTS_ON:
+		self.capability |= mysql.CLIENT_MULTI_STATEMENTS
+	case MYSQL_OPTION_MULTI_STATEMENTS_OFF:
+		self.capability &^= mysql.CLIENT_MULTI_STATEMENTS
+	default:
+		return mysql.NewDefaultError(mysql.ER_UNKNOWN_COM_ERROR)
+	}
+
+	return self.writeEOF(self.status)
+}
+
+// handleResetConnection answers COM_RESET_CONNECTION, the session state
+// is reset without a new authentication
+func (self *Session) handleResetConnection() error {
+	self.resetSession()
+	return self.writeOK(nil)
+}
+
+// resetSession drops the session state the way mysql does on a reset,
+// the running transaction is rolled back and the pinned connection freed
+func (self *Session) resetSession() {
+	self.releasePinConn(true)
+	self.pinBatch = false
+	self.status = mysql.SERVER_STATUS_AUTOCOMMIT
+	self.affectedRows = 0
+	self.insertId = 0
+	self.lastInsertId = 0
+	self.warnings = nil
+	self.collation = mysql.DEFAULT_COLLATION_ID
+	self.charset = mysql.DEFAULT_CHARSET
+}
+
+// handleChangeUser answers COM_CHANGE_USER, the client authenticates
+// again with the salt of the initial handshake and the session starts
+// over as the new user, like after a reset
+func (self *Session) handleChangeUser(data []byte) error {
+	pos := bytes.IndexByte(data, 0x00)
+	if pos < 0 {
+		return mysql.ErrMalformPacket
+	}
+	user := string(data[:pos])
+	pos++
+
+	var auth []byte
+	if self.capability&mysql.CLIENT_SECURE_CONNECTION > 0 {
+		if pos >= len(data) {
+			return mysql.ErrMalformPacket
+		}
+		authLen := int(data[pos])
+		pos++
+		if pos+authLen > len(data) {
+			return mysql.ErrMalformPacket
+		}
+		auth = data[pos : pos+authLen]
+		pos += authLen
+	} else {
+		index := bytes.IndexByte(data[pos:], 0x00)
+		if index < 0 {
+			return mysql.ErrMalformPacket
+		}
+		auth = data[pos : pos+index]
+		pos += index + 1
+	}
+
+	var db string
+	if pos < len(data) {
+		index := bytes.IndexByte(data[pos:], 0x00)
+		if index < 0 {
+			index = len(data) - pos
+		}
+		db = string(data[pos : pos+index])
+		pos += index + 1
+	}
+
+	collation := mysql.DEFAULT_COLLATION_ID
+	if pos+2 <= len(data) {
+		collation = mysql.CollationId(data[pos])
+	}
+
+	if err := self.checkAuth(user, auth); err != nil {
+		//mysql drops the connection on a failed change user
+		self.writeError(err)
+		self.Close()
+		return nil
+	}
+
+	self.resetSession()
+	self.Lock()
+	self.user = user
+	self.Unlock()
+	self.collation = collation
+
+	//if db name is "", use default db
+	if db == "" {
+		db = self.s.cfg.DB
+	}
+	if err := self.useDB(db); err != nil {
+		return err
+	}
+
+	return self.writeOK(nil)
+}
@@ -0,0 +1,36 @@
+package server
+
+import (
+	"fmt"
+
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sql"
+)
+
+func (self *Session) handleExec(stmt sql.IStatement, sqlstmt string) error {
+	if err := self.checkDB(); err != nil {
+		return err
+	}
+
+	conn, err := self.getConn(false)
+	if err != nil {
+		return err
+	} else if conn == nil {
+		return fmt.Errorf("no available connection")
+	}
+
+	var res *mysql.Result
+	res, err = self.execute(conn, sqlstmt, 0)
+
+	self.closeDBConn(conn, false)
+	if err == nil {
+		if self.insertId != 0 {
+			res.InsertId = self.insertId
+		}
+		if res.InsertId != 0 {
+			self.lastInsertId = res.InsertId
+		}
+		err = self.writeResult(res)
+	}
+	return err
+}
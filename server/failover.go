@@ -0,0 +1,249 @@
+package server
+
+import (
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/pool"
+)
+
+// masterState is what a candidate master said about itself
+type masterState struct {
+	writable    bool
+	replicating bool
+}
+
+// masterMonitor follows the master of a cluster among its candidates.
+// Writes move to
+// a candidate only when it is the one primary, writable and not
+// replicating, and the current master is no longer writable, so there
+// is never a time where both take writes through the proxy.
+type masterMonitor struct {
+	s *Server
+	c *cluster
+
+	//the candidates are probed at once, each with its own conn
+	lock  sync.Mutex
+	conns map[string]*backend.Conn
+}
+
+func newMasterMonitor(s *Server, c *cluster) *masterMonitor {
+	return &masterMonitor{s: s, c: c, conns: make(map[string]*backend.Conn)}
+}
+
+func (self *masterMonitor) candidates() []string {
+	cfg := self.c.cfg
+	candidates := []string{cfg.DBAddr}
+	for _, addr := range cfg.MasterCandidates {
+		if addr != cfg.DBAddr {
+			candidates = append(candidates, addr)
+		}
+	}
+	return candidates
+}
+
+// interval is the time between two checks, it also bounds a probe
+func (self *masterMonitor) interval() time.Duration {
+	interval := time.Duration(self.s.cfg.MasterCheckInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return interval
+}
+
+func (self *masterMonitor) run() {
+	for range time.Tick(self.interval()) {
+		self.check()
+	}
+}
+
+func (self *masterMonitor) check() {
+	current := self.c.writerPool().Addr
+	candidates := self.candidates()
+
+	//a hung candidate must not hold up the others
+	states := make([]masterState, len(candidates))
+	var wg sync.WaitGroup
+	for i, addr := range candidates {
+		wg.Add(1)
+		go func(i int, addr string) {
+			defer wg.Done()
+			states[i] = self.probe(addr)
+		}(i, addr)
+	}
+	wg.Wait()
+
+	var primaries []string
+	var currentState masterState
+	for i, addr := range candidates {
+		state := states[i]
+		if addr == current {
+			currentState = state
+		}
+		if state.writable && !state.replicating {
+			primaries = append(primaries, addr)
+		}
+	}
+
+	switch {
+	case len(primaries) == 0:
+		return
+	case len(primaries) > 1:
+		logger.Errorf("more than one writable master in cluster %s: %v, writes stay on %s", self.c.name, primaries, current)
+		return
+	case primaries[0] == current:
+		return
+	case currentState.writable:
+		//not demoted yet, wait until it is
+		return
+	}
+	self.s.switchMaster(self.c, primaries[0])
+}
+
+// probe asks addr for read_only, super_read_only and whether it runs a
+// replication SQL thread. It gives up after an interval, a candidate that
+// does not answer in time is not writable.
+func (self *masterMonitor) probe(addr string) masterState {
+	conn, err := self.conn(addr)
+	if err != nil {
+		return masterState{}
+	}
+	conn.SetDeadline(time.Now().Add(self.interval()))
+
+	state := masterState{writable: true}
+	res, err := conn.Execute("SHOW GLOBAL VARIABLES WHERE Variable_name IN ('read_only', 'super_read_only')")
+	if err != nil {
+		self.drop(addr, err)
+		return masterState{}
+	}
+	for i := 0; i < res.RowNumber(); i++ {
+		if v, _ := res.GetString(i, 1); v == "ON" || v == "1" {
+			state.writable = false
+		}
+	}
+
+	res, err = conn.Execute("SHOW SLAVE STATUS")
+	if err != nil {
+		self.drop(addr, err)
+		return masterState{}
+	}
+	if res.Resultset != nil && res.RowNumber() > 0 {
+		running, _ := res.GetStringByName(0, "Slave_SQL_Running")
+		state.replicating = running == "Yes"
+	}
+	return state
+}
+
+func (self *masterMonitor) conn(addr string) (*backend.Conn, error) {
+	self.lock.Lock()
+	conn, ok := self.conns[addr]
+	self.lock.Unlock()
+	if ok {
+		return conn, nil
+	}
+
+	conn = &backend.Conn{}
+	conn.SetDialTimeout(self.interval())
+	if err := conn.Connect(addr, self.s.cfg.DBUser, self.s.cfg.DBPasswd, ""); err != nil {
+		return nil, err
+	}
+	self.lock.Lock()
+	self.conns[addr] = conn
+	self.lock.Unlock()
+	return conn, nil
+}
+
+// drop closes the conn of addr after an error, a timed out one included,
+// the next probe connects again
+func (self *masterMonitor) drop(addr string, err error) {
+	logger.Errorf("check master candidate %s: %s", addr, err.Error())
+	self.lock.Lock()
+	conn := self.conns[addr]
+	delete(self.conns, addr)
+	self.lock.Unlock()
+	conn.Close()
+}
+
+// switchMaster sends the writes of c to addr from now on. Transactions
+// on the old master are aborted, their sessions get an error on the next
+// statement and a running statement is killed.
+func (self *Server) switchMaster(c *cluster, addr string) {
+	self.adminLock.Lock()
+	defer self.adminLock.Unlock()
+
+	//a replica keeps its pool and its share of the reads, so does
+	//the old master
+	old := c.writerPool()
+	var next *pool.Pool
+	var oldReads bool
+	for _, rs := range c.readerPool.Servers() {
+		if rs.Pool().Addr == addr {
+			next = rs.Pool()
+		}
+		if rs.Pool() == old {
+			oldReads = true
+		}
+	}
+	if next == nil {
+		next = self.newPool(addr)
+	}
+
+	c.writerLock.Lock()
+	c.writer = next
+	c.writerLock.Unlock()
+	logger.Warningf("master of cluster %s changed from %s to %s", c.name, old.Addr, addr)
+
+	for _, s := range self.sessions.Sessions() {
+		s.abortTransaction(old)
+	}
+	if !oldReads {
+		old.Close()
+	}
+	next.Warm()
+
+	c.cfg.DBAddr = addr
+	if self.cfg.AdminPersist {
+		if err := self.cfg.Save(); err != nil {
+			logger.Errorf("save config: %s", err.Error())
+		}
+	}
+}
+
+// abortTransaction aborts the transaction of the session when it runs on
+// a connection of p, it is called from the monitor goroutine
+func (self *Session) abortTransaction(p *pool.Pool) {
+	self.Lock()
+	conn := self.pinConn
+	if conn == nil || conn.Addr() != p.Addr {
+		self.Unlock()
+		return
+	}
+	self.aborted = true
+	running := self.running
+	self.Unlock()
+
+	if running != nil {
+		if err := running.KillQuery(); err != nil {
+			logger.Errorf("kill query of session %d on old master: %s", self.connectionId, err.Error())
+		}
+	}
+}
+
+// checkAborted fails the statement after a master change aborted the
+// transaction, the session goes on without it afterwards
+func (self *Session) checkAborted() error {
+	self.Lock()
+	aborted := self.aborted
+	self.aborted = false
+	self.Unlock()
+	if !aborted {
+		return nil
+	}
+
+	self.status &^= mysql.SERVER_STATUS_IN_TRANS
+	self.releasePinConn(true)
+	return mysql.NewMysqlError(mysql.ER_QUERY_INTERRUPTED,
+		"The transaction was aborted because the master changed")
+}
@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/pool"
+	"github.com/lostz/Aegis/sql"
+)
+
+// handleKill answers KILL [CONNECTION | QUERY] id, the id is a proxy
+// connection id, like the ones SHOW PROCESSLIST shows
+func (self *Session) handleKill(stmt *sql.Kill) error {
+	//the id comes out of the expression rules wrapped in a predicate
+	var v sql.NumVal
+	if p, ok := stmt.Id.(*sql.Predicate); ok {
+		v, _ = p.Expr.(sql.NumVal)
+	}
+	if v == nil {
+		return mysql.NewDefaultError(mysql.ER_SYNTAX_ERROR)
+	}
+	id, err := strconv.ParseUint(string(v), 10, 32)
+	if err != nil {
+		return mysql.NewMysqlError(mysql.ER_NO_SUCH_THREAD, fmt.Sprintf("Unknown thread id: %s", v))
+	}
+
+	target := self.s.sessions.Get(uint32(id))
+	if target == nil {
+		return mysql.NewMysqlError(mysql.ER_NO_SUCH_THREAD, fmt.Sprintf("Unknown thread id: %d", id))
+	}
+	if !self.isAdmin() && target.userName() != self.user {
+		return mysql.NewMysqlError(mysql.ER_KILL_DENIED_ERROR, fmt.Sprintf("You are not owner of thread %d", id))
+	}
+
+	if target == self {
+		if stmt.Type == sql.KillType_Query {
+			//nothing is running besides the kill itself
+			return self.writeOK(nil)
+		}
+		//like mysql, the connection goes away without an answer
+		return self.Close()
+	}
+
+	if err := target.Kill(stmt.Type == sql.KillType_Query); err != nil {
+		return err
+	}
+	return self.writeOK(nil)
+}
+
+// Kill is called from another session's goroutine, the statement running
+// on the backend is killed and for a connection kill the client socket
+// is woken up, the session then closes itself in its own goroutine
+func (self *Session) Kill(query bool) error {
+	self.Lock()
+	conns := append([]backend.Client(nil), self.scattered...)
+	if self.running != nil {
+		conns = append([]backend.Client{self.running}, conns...)
+	}
+	self.Unlock()
+
+	var err error
+	for _, conn := range conns {
+		if kerr := conn.KillQuery(); kerr != nil {
+			logger.Errorf("kill query on backend thread %d of session %d: %s",
+				conn.ConnectionId(), self.connectionId, kerr.Error())
+			err = kerr
+		}
+	}
+
+	if !query {
+		self.c.SetDeadline(time.Now())
+		return nil
+	}
+	return err
+}
+
+// execute runs sqlstmt on conn, the connection is recorded as running
+// meanwhile so KILL QUERY from another session can reach it. With a
+// timeout the statement is killed on the backend once it runs too long.
+func (self *Session) execute(conn backend.Client, sqlstmt string, timeout time.Duration) (*mysql.Result, error) {
+	self.Lock()
+	self.running = conn
+	self.Unlock()
+	defer func() {
+		self.Lock()
+		self.running = nil
+		self.Unlock()
+	}()
+	return self.checkTruncated(self.executeTimed(conn, sqlstmt, timeout))
+}
+
+// executeTimed runs sqlstmt on conn, killing it after timeout. It only
+// touches the session to read its limits.
+func (self *Session) executeTimed(conn backend.Client, sqlstmt string, timeout time.Duration) (*mysql.Result, error) {
+	if t, ok := conn.(pool.Tracked); ok {
+		t.SetStatement(sqlstmt)
+	}
+
+	if limit := self.resultLimit(); limit != nil {
+		conn.SetResultLimit(limit)
+		defer conn.SetResultLimit(nil)
+	}
+
+	if timeout <= 0 {
+		return self.run(conn, sqlstmt)
+	}
+
+	killed := make(chan error, 1)
+	timer := time.AfterFunc(timeout, func() {
+		killed <- conn.KillQuery()
+	})
+
+	res, err := self.run(conn, sqlstmt)
+	if timer.Stop() {
+		return res, err
+	}
+
+	//the kill was sent, wait for it so it can not hit a later
+	//statement on this connection, which is fine to reuse then
+	if kerr := <-killed; kerr != nil {
+		logger.Errorf("kill query on backend thread %d of session %d after %s: %s",
+			conn.ConnectionId(), self.connectionId, timeout.String(), kerr.Error())
+	}
+	if err == nil {
+		//finished before the kill got through
+		return res, nil
+	}
+	return nil, mysql.NewDefaultError(mysql.ER_QUERY_TIMEOUT)
+}
+
+// run executes sqlstmt on conn and tells its pool how long it took
+func (self *Session) run(conn backend.Client, sqlstmt string) (*mysql.Result, error) {
+	start := time.Now()
+	res, err := conn.Execute(sqlstmt)
+	if t, ok := conn.(pool.Tracked); ok {
+		t.Observe(time.Since(start), backendFailed(err))
+	}
+	return res, err
+}
+
+// maxExecutionTime is the time limit of a SELECT, a MAX_EXECUTION_TIME
+// hint wins over the limit of the user, which wins over the global one
+func (self *Session) maxExecutionTime(sqlstmt string) time.Duration {
+	ms, ok := sql.MaxExecutionTime(sqlstmt)
+	if !ok {
+		ms = self.s.cfg.MaxExecutionTime
+		if u := self.s.cfg.GetUser(self.user); u != nil && u.MaxExecutionTime > 0 {
+			ms = u.MaxExecutionTime
+		}
+	}
+	return time.Duration(ms) * time.Millisecond
+}
@@ -0,0 +1,165 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sql"
+)
+
+// how long the row count of a table is trusted by the full scan check
+const tableRowsTTL = 60 * time.Second
+
+// resultLimit is the result limit of the session user, the limits of
+// the user win over the global ones
+func (self *Session) resultLimit() *backend.ResultLimit {
+	cfg := self.s.cfg
+	limit := &backend.ResultLimit{
+		MaxRows:  cfg.MaxResultRows,
+		MaxBytes: cfg.MaxResultBytes,
+		Truncate: cfg.ResultLimitMode == config.ResultLimitTruncate,
+	}
+	if u := cfg.GetUser(self.user); u != nil {
+		if u.MaxResultRows > 0 {
+			limit.MaxRows = u.MaxResultRows
+		}
+		if u.MaxResultBytes > 0 {
+			limit.MaxBytes = u.MaxResultBytes
+		}
+	}
+
+	if limit.MaxRows <= 0 && limit.MaxBytes <= 0 {
+		return nil
+	}
+	return limit
+}
+
+// checkTruncated raises a warning for every result cut by the limit
+func (self *Session) checkTruncated(res *mysql.Result, err error) (*mysql.Result, error) {
+	if err != nil {
+		return res, err
+	}
+	for r := res; r != nil; r = r.Next {
+		if !r.Truncated {
+			continue
+		}
+		limit := self.resultLimit()
+		self.warnings = append(self.warnings, mysql.NewMysqlError(mysql.ER_TOO_BIG_SELECT,
+			fmt.Sprintf("The result was truncated to the limit of %d rows and %d bytes", limit.MaxRows, limit.MaxBytes)))
+	}
+	return res, nil
+}
+
+func (self *Session) warningCount() uint16 {
+	if len(self.warnings) > 0xffff {
+		return 0xffff
+	}
+	return uint16(len(self.warnings))
+}
+
+// handleShowWarnings answers SHOW WARNINGS with the warnings of the proxy
+func (self *Session) handleShowWarnings(count bool) error {
+	var names []string
+	var values [][]interface{}
+
+	if count {
+		names = []string{"@@session.warning_count"}
+		values = append(values, []interface{}{int64(len(self.warnings))})
+	} else {
+		names = []string{"Level", "Code", "Message"}
+		for _, w := range self.warnings {
+			values = append(values, []interface{}{"Warning", w.Code, w.Message})
+		}
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
+
+// checkFullScan rejects a SELECT without WHERE and LIMIT when one of its
+// tables has more rows than FullScanMaxRows
+func (self *Session) checkFullScan(stmt sql.ISelect) error {
+	max := self.s.cfg.FullScanMaxRows
+	if max <= 0 {
+		return nil
+	}
+	s, ok := stmt.(*sql.Select)
+	if !ok || s.From == nil || s.Where != nil || s.Limit != nil {
+		return nil
+	}
+
+	for _, t := range sql.FromTables(s) {
+		if len(t.Name) == 0 {
+			continue
+		}
+		schema, table := self.db, string(t.Name)
+		if len(t.Qualifier) > 0 {
+			schema = string(t.Qualifier)
+		}
+
+		rows, err := self.s.tableRows.Get(self, schema, table)
+		if err != nil {
+			logger.Errorf("table rows of %s.%s: %s", schema, table, err.Error())
+			continue
+		}
+		if rows > max {
+			return mysql.NewMysqlError(mysql.ER_TOO_BIG_SELECT,
+				fmt.Sprintf("SELECT without WHERE and LIMIT on table %s.%s with about %d rows is not allowed", schema, table, rows))
+		}
+	}
+	return nil
+}
+
+// tableRowsCache keeps the estimated row counts of the tables, as
+// information_schema reports them
+type tableRowsCache struct {
+	lock sync.Mutex
+	m    map[string]tableRows
+}
+
+type tableRows struct {
+	rows int64
+	t    time.Time
+}
+
+func (self *tableRowsCache) Get(s *Session, schema string, table string) (int64, error) {
+	key := strings.ToLower(schema + "." + table)
+
+	self.lock.Lock()
+	e, ok := self.m[key]
+	self.lock.Unlock()
+	if ok && time.Since(e.t) < tableRowsTTL {
+		return e.rows, nil
+	}
+
+	conn, err := s.getReader()
+	if err != nil {
+		return 0, err
+	}
+	res, err := conn.Execute("SELECT TABLE_ROWS FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ?", schema, table)
+	conn.Close()
+	if err != nil {
+		return 0, err
+	}
+
+	var rows int64
+	if res.Resultset != nil && len(res.Values) > 0 {
+		rows, _ = res.GetInt(0, 0)
+	}
+
+	self.lock.Lock()
+	if self.m == nil {
+		self.m = make(map[string]tableRows)
+	}
+	self.m[key] = tableRows{rows: rows, t: time.Now()}
+	self.lock.Unlock()
+	return rows, nil
+}
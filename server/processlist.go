@@ -0,0 +1,172 @@
+package server
+
+import (
+	"sort"
+	"time"
+
+	"github.com/lostz/Aegis/mysql"
+)
+
+// mysql cuts the statement to this length without SHOW FULL PROCESSLIST
+const processInfoLen = 100
+
+// processInfo is a snapshot of what a session is doing
+type processInfo struct {
+	id       uint32
+	user     string
+	host     string
+	db       string
+	command  string
+	time     int64
+	state    string
+	info     string
+	backend  string
+	threadId uint32
+	pinned   bool
+
+	connected int64
+	questions uint64
+}
+
+// setCommand records the command the session runs, the statement text
+// is copied since the packet buffer is reused by the next read
+func (self *Session) setCommand(cmd byte, arg []byte) {
+	var info string
+	if cmd == mysql.COM_QUERY {
+		info = string(arg)
+	}
+
+	self.Lock()
+	self.command = cmd
+	self.commandTime = time.Now()
+	self.info = info
+	if cmd == mysql.COM_QUERY || cmd == mysql.COM_STMT_EXECUTE {
+		self.questions++
+	}
+	self.Unlock()
+}
+
+// userName is the user of the session, it is safe to call from another
+// session's goroutine
+func (self *Session) userName() string {
+	self.Lock()
+	defer self.Unlock()
+	return self.user
+}
+
+func (self *Session) processInfo() processInfo {
+	self.Lock()
+	defer self.Unlock()
+
+	p := processInfo{
+		id:      self.connectionId,
+		user:    self.user,
+		host:    self.host,
+		db:      self.db,
+		command: mysql.CommandName[self.command],
+		time:    int64(time.Since(self.commandTime).Seconds()),
+		info:    self.info,
+
+		connected: int64(time.Since(self.connected).Seconds()),
+		questions: self.questions,
+	}
+
+	conn := self.running
+	if conn != nil {
+		p.state = "executing"
+	}
+	if self.pinConn != nil {
+		conn = self.pinConn
+		p.pinned = true
+	}
+	if conn != nil {
+		p.backend = conn.Addr()
+		p.threadId = conn.ConnectionId()
+	}
+	return p
+}
+
+// processList returns the sessions the user of this session may see,
+// all of them for the AdminUser, ordered by connection id
+func (self *Session) processList() []processInfo {
+	admin := self.isAdmin()
+	sessions := self.s.sessions.Sessions()
+	list := make([]processInfo, 0, len(sessions))
+	for _, s := range sessions {
+		p := s.processInfo()
+		if !admin && p.user != self.user {
+			continue
+		}
+		list = append(list, p)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].id < list[j].id })
+	return list
+}
+
+// handleShowProcessList answers SHOW [FULL] PROCESSLIST from the proxy
+// sessions, the Backend column is the backend a session is pinned to
+func (self *Session) handleShowProcessList(full bool) error {
+	names := []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Backend"}
+
+	var values [][]interface{}
+	for _, p := range self.processList() {
+		info := p.info
+		if !full && len(info) > processInfoLen {
+			info = info[:processInfoLen]
+		}
+		var backend string
+		if p.pinned {
+			backend = p.backend
+		}
+		values = append(values, []interface{}{
+			p.id,
+			p.user,
+			p.host,
+			p.db,
+			p.command,
+			p.time,
+			p.state,
+			info,
+			backend,
+		})
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
+
+// handleShowBackendProcessList answers SHOW AEGIS BACKEND PROCESSLIST,
+// it maps the proxy connection ids to the backend thread ids of the
+// sessions holding a backend connection
+func (self *Session) handleShowBackendProcessList() error {
+	names := []string{"Id", "User", "Backend", "Backend_thread_id", "Pinned", "State", "Info"}
+
+	var values [][]interface{}
+	for _, p := range self.processList() {
+		if p.backend == "" {
+			continue
+		}
+		pinned := "No"
+		if p.pinned {
+			pinned = "Yes"
+		}
+		values = append(values, []interface{}{
+			p.id,
+			p.user,
+			p.backend,
+			p.threadId,
+			pinned,
+			p.state,
+			p.info,
+		})
+	}
+
+	result, err := self.buildResultset(names, values)
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
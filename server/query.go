@@ -3,44 +3,144 @@ package server
 import (
 	"fmt"
 
+	"github.com/lostz/Aegis/mysql"
 	"github.com/lostz/Aegis/sql"
 	"github.com/lostz/Aegis/utils"
 )
 
 func (self *Session) handleQuery(sqlstmt string) (err error) {
-	var stmt sql.IStatement
-	stmt, err = sql.Parse(sqlstmt)
+	var sqls []string
+	if self.capability&mysql.CLIENT_MULTI_STATEMENTS > 0 {
+		sqls = sql.Split(sqlstmt)
+	}
+	if len(sqls) <= 1 {
+		stmt, err := parse(sqlstmt)
+		if err != nil {
+			return err
+		}
+		return self.handleStatement(stmt, sqlstmt)
+	}
+
+	//like mysql, the statements before a bad one are still executed
+	stmts := make([]sql.IStatement, 0, len(sqls))
+	var parseErr error
+	for _, s := range sqls {
+		stmt, err := parse(s)
+		if err != nil {
+			parseErr = err
+			break
+		}
+		stmts = append(stmts, stmt)
+	}
+
+	//statements of a batch with writes share one writer connection,
+	//so the reads after a write see its changes
+	self.pinBatch = hasWrite(stmts)
+	defer func() {
+		self.status &^= mysql.SERVER_MORE_RESULTS_EXISTS
+		self.pinBatch = false
+		if !self.isInTransaction() {
+			self.releasePinConn(err != nil)
+		}
+	}()
+
+	for i, stmt := range stmts {
+		if i < len(sqls)-1 {
+			self.status |= mysql.SERVER_MORE_RESULTS_EXISTS
+		} else {
+			self.status &^= mysql.SERVER_MORE_RESULTS_EXISTS
+		}
+
+		//stop at the first failing statement
+		if err = self.handleStatement(stmt, sqls[i]); err != nil {
+			return err
+		}
+	}
+
+	return parseErr
+}
+
+func parse(sqlstmt string) (sql.IStatement, error) {
+	stmt, err := sql.Parse(sqlstmt)
+	if err != nil {
+		return nil, fmt.Errorf(`parse sql "%s" error "%s"`, sqlstmt, err.Error())
+	}
+	return stmt, nil
+}
+
+func hasWrite(stmts []sql.IStatement) bool {
+	for _, stmt := range stmts {
+		if v, ok := stmt.(sql.ISelect); ok && !v.IsLocked() {
+			continue
+		}
+		if _, ok := stmt.(sql.IShow); ok {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func (self *Session) handleStatement(stmt sql.IStatement, sqlstmt string) error {
+	//the warnings are kept for SHOW WARNINGS only
+	if _, ok := stmt.(*sql.ShowWarnings); !ok {
+		self.warnings = nil
+	}
+	self.insertId = 0
+	sqlstmt, err := self.fillSequences(stmt, sqlstmt)
 	if err != nil {
-		return fmt.Errorf(`parse sql "%s" error "%s"`, sqlstmt, err.Error())
+		return err
+	}
+	//the backend connections are shared, LAST_INSERT_ID() is the id of
+	//the session only on the proxy
+	if name, ok := sql.LastInsertIdSelect(stmt); ok {
+		return self.handleLastInsertId(name)
+	}
+	sqlstmt = self.fillLastInsertId(stmt, sqlstmt)
+	if err := self.route(stmt); err != nil {
+		return err
 	}
 
 	switch v := stmt.(type) {
 	case sql.ISelect:
 		return self.handleSelect(v, sqlstmt)
-		//	case *sql.Insert:
-		//		return self.handleExec(stmt, sqlstmt, false)
-		//	case *sql.Update:
-		//		return self.handleExec(stmt, sqlstmt, false)
-		//	case *sql.Delete:
-		//		return self.handleExec(stmt, sqlstmt, false)
-		//	case *sql.Replace:
-		//		return self.handleExec(stmt, sqlstmt, false)
+	case *sql.Insert:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Update:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Delete:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Replace:
+		return self.handleExec(stmt, sqlstmt)
 		//	case *sql.Set:
 		//		return self.handleSet(v, sqlstmt)
-		//	case *sql.Begin:
-		//		return self.handleBegin()
-		//	case *sql.Commit:
-		//		return self.handleCommit()
-		//	case *sql.Rollback:
-		//		return self.handleRollback()
+	case *sql.Begin:
+		return self.handleBegin()
+	case *sql.StartTrans:
+		return self.handleBegin()
+	case *sql.Commit:
+		return self.handleCommit()
+	case *sql.Rollback:
+		if v.Point != nil {
+			return self.handleExec(stmt, sqlstmt)
+		}
+		return self.handleRollback()
+	case *sql.SavePoint:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Release:
+		return self.handleExec(stmt, sqlstmt)
 	case sql.IShow:
 		return self.handleShow(sqlstmt, v)
-	//	case sql.IDDLStatement:
-	//		return self.handleExec(stmt, sqlstmt, false)
-	//	case *sql.Do:
-	//		return self.handleExec(stmt, sqlstmt, false)
-	//	case *sql.Call:
-	//		return selfc.handleExec(stmt, sqlstmt, false)
+	case sql.IDDLStatement:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Do:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Call:
+		return self.handleExec(stmt, sqlstmt)
+	case *sql.Kill:
+		return self.handleKill(v)
+	case *sql.AegisAdmin:
+		return self.handleAegisAdmin(v)
 	case *sql.Use:
 		if err := self.useDB(utils.String(stmt.(*sql.Use).DB)); err != nil {
 			return err
@@ -51,6 +151,4 @@ func (self *Session) handleQuery(sqlstmt string) (err error) {
 	default:
 		return fmt.Errorf("statement %T[%s] not support now", stmt, sqlstmt)
 	}
-
-	return nil
 }
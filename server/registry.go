@@ -0,0 +1,52 @@
+package server
+
+import (
+	"sync"
+)
+
+// sessionRegistry keeps the live sessions of the server by their
+// connection id, so one session can reach another one
+type sessionRegistry struct {
+	lock sync.RWMutex
+	m    map[uint32]*Session
+}
+
+func (self *sessionRegistry) Register(s *Session) {
+	self.lock.Lock()
+	if self.m == nil {
+		self.m = make(map[uint32]*Session)
+	}
+	self.m[s.connectionId] = s
+	self.lock.Unlock()
+}
+
+func (self *sessionRegistry) Unregister(s *Session) {
+	self.lock.Lock()
+	delete(self.m, s.connectionId)
+	self.lock.Unlock()
+}
+
+func (self *sessionRegistry) Get(id uint32) *Session {
+	self.lock.RLock()
+	s := self.m[id]
+	self.lock.RUnlock()
+	return s
+}
+
+func (self *sessionRegistry) Count() int {
+	self.lock.RLock()
+	n := len(self.m)
+	self.lock.RUnlock()
+	return n
+}
+
+// Sessions returns a snapshot of the live sessions
+func (self *sessionRegistry) Sessions() []*Session {
+	self.lock.RLock()
+	sessions := make([]*Session, 0, len(self.m))
+	for _, s := range self.m {
+		sessions = append(sessions, s)
+	}
+	self.lock.RUnlock()
+	return sessions
+}
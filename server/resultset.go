@@ -98,6 +98,15 @@ func (self *Session) buildResultset(names []string, values [][]interface{}) (*my
 		r.RowDatas = append(r.RowDatas, row)
 	}
 
+	//without rows the column types are unknown, send them as strings
+	if len(values) == 0 {
+		for j := range names {
+			field := &mysql.Field{Name: utils.Slice(names[j])}
+			formatField(field, "")
+			r.Fields[j] = field
+		}
+	}
+
 	return r, nil
 }
 
@@ -121,8 +130,11 @@ func (self *Session) writeResultset(status uint16, r *mysql.Resultset) error {
 		}
 	}
 
-	if err := self.writeEOF(status); err != nil {
-		return err
+	//no EOF between columns and rows with CLIENT_DEPRECATE_EOF
+	if !self.deprecateEOF() {
+		if err := self.writeEOF(status); err != nil {
+			return err
+		}
 	}
 
 	for _, v := range r.RowDatas {
@@ -139,3 +151,29 @@ func (self *Session) writeResultset(status uint16, r *mysql.Resultset) error {
 
 	return nil
 }
+
+// writeResult sends every result of rs to the client, all but the last
+// one are flagged with SERVER_MORE_RESULTS_EXISTS
+func (self *Session) writeResult(rs *mysql.Result) error {
+	for r := rs; r != nil; r = r.Next {
+		status := self.status | r.Status
+		if r.Next != nil {
+			status |= mysql.SERVER_MORE_RESULTS_EXISTS
+		}
+
+		var err error
+		if r.Resultset != nil {
+			err = self.writeResultset(status, r.Resultset)
+		} else {
+			err = self.writeOK(&mysql.Result{
+				Status:       status,
+				AffectedRows: r.AffectedRows,
+				InsertId:     r.InsertId,
+			})
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"bytes"
+	"net"
+	"testing"
+
+	"github.com/lostz/Aegis/mysql"
+)
+
+// bufConn is a connection reading back what was written to it
+type bufConn struct {
+	net.Conn
+	buf bytes.Buffer
+}
+
+func (c *bufConn) Read(b []byte) (int, error) {
+	return c.buf.Read(b)
+}
+
+func (c *bufConn) Write(b []byte) (int, error) {
+	return c.buf.Write(b)
+}
+
+// testSession is a session with the capabilities given and one warning,
+// what it writes is read back from the returned packets
+func testSession(capability uint32) (*Session, *mysql.Packets) {
+	conn := &bufConn{}
+	s := &Session{
+		pkg:        mysql.NewPackets(conn),
+		c:          conn,
+		capability: capability,
+		warnings:   []*mysql.MysqlError{mysql.NewMysqlError(mysql.ER_UNKNOWN_ERROR, "warning")},
+	}
+	return s, mysql.NewPackets(conn)
+}
+
+func readAll(t *testing.T, p *mysql.Packets, want ...[]byte) {
+	for i := range want {
+		data, err := p.ReadPacket()
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if !bytes.Equal(data, want[i]) {
+			t.Fatalf("packet %d: %v, want %v", i, data, want[i])
+		}
+	}
+	if _, err := p.ReadPacket(); err == nil {
+		t.Fatal("packets after the last one")
+	}
+}
+
+const status = mysql.SERVER_STATUS_AUTOCOMMIT
+
+// the EOF packet has the warnings first, the OK packet replacing it with
+// CLIENT_DEPRECATE_EOF the status
+var (
+	eofPacket   = []byte{mysql.EOF_HEADER, 1, 0, byte(status), 0}
+	okEOFPacket = []byte{mysql.EOF_HEADER, 0, 0, byte(status), 0, 1, 0}
+)
+
+func TestWriteEOF(t *testing.T) {
+	s, p := testSession(mysql.CLIENT_PROTOCOL_41)
+	if err := s.writeEOF(status); err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, p, eofPacket)
+
+	s, p = testSession(mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_DEPRECATE_EOF)
+	if err := s.writeEOF(status); err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, p, okEOFPacket)
+}
+
+func TestWriteResultset(t *testing.T) {
+	field := &mysql.Field{Name: []byte("a"), Type: mysql.MYSQL_TYPE_VAR_STRING}
+	r := &mysql.Resultset{
+		Fields:   []*mysql.Field{field},
+		RowDatas: []mysql.RowData{[]byte("\x011"), []byte("\x012")},
+	}
+
+	s, p := testSession(mysql.CLIENT_PROTOCOL_41)
+	if err := s.writeResultset(status, r); err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, p, []byte{1}, field.Dump(), eofPacket, r.RowDatas[0], r.RowDatas[1], eofPacket)
+
+	//no EOF after the columns, an OK packet after the rows
+	s, p = testSession(mysql.CLIENT_PROTOCOL_41 | mysql.CLIENT_DEPRECATE_EOF)
+	if err := s.writeResultset(status, r); err != nil {
+		t.Fatal(err)
+	}
+	readAll(t, p, []byte{1}, field.Dump(), r.RowDatas[0], r.RowDatas[1], okEOFPacket)
+}
@@ -0,0 +1,77 @@
+package server
+
+import (
+	"time"
+
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sql"
+)
+
+// backendFailed is whether err is the fault of the backend rather than
+// of the statement: the connection broke or mysql refused to serve it
+func backendFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+	e, ok := err.(*mysql.MysqlError)
+	if !ok {
+		return true
+	}
+	switch e.Code {
+	case mysql.ER_CON_COUNT_ERROR,
+		mysql.ER_TOO_MANY_USER_CONNECTIONS,
+		mysql.ER_HANDSHAKE_ERROR,
+		mysql.ER_SERVER_SHUTDOWN,
+		mysql.ER_NORMAL_SHUTDOWN,
+		mysql.ER_SHUTDOWN_COMPLETE,
+		mysql.ER_NET_PACKETS_OUT_OF_ORDER,
+		mysql.ER_NET_READ_ERROR,
+		mysql.ER_NET_READ_INTERRUPTED,
+		mysql.ER_NET_ERROR_ON_WRITE,
+		mysql.ER_NET_WRITE_INTERRUPTED:
+		return true
+	}
+	return false
+}
+
+// retryable is whether stmt may run again on another backend after it
+// failed with err. Only a plain read outside of a transaction is, the
+// result is buffered so the client has not seen any of it yet.
+func (self *Session) retryable(stmt sql.IStatement, err error) bool {
+	if self.s.cfg.ReadRetries <= 0 || !backendFailed(err) {
+		return false
+	}
+	s, ok := stmt.(sql.ISelect)
+	if !ok || s.IsLocked() {
+		return false
+	}
+	return self.pinConn == nil && !self.pinBatch && !self.needBeginTx()
+}
+
+// retryRead runs sqlstmt on up to ReadRetries backends that did not fail
+// it yet, err is returned when none is left
+func (self *Session) retryRead(sqlstmt string, timeout time.Duration, addr string, err error) (*mysql.Result, error) {
+	failed := []string{addr}
+	for i := 0; i < self.s.cfg.ReadRetries; i++ {
+		conn, cerr := self.getReader(failed...)
+		if cerr != nil {
+			return nil, err
+		}
+		if cerr = conn.UseDB(self.db); cerr != nil {
+			failed = append(failed, conn.Addr())
+			self.closeDBConn(conn, false)
+			continue
+		}
+
+		logger.Warningf("session %d retries on %s after %s failed: %s",
+			self.connectionId, conn.Addr(), failed[len(failed)-1], err.Error())
+		var res *mysql.Result
+		res, err = self.execute(conn, sqlstmt, timeout)
+		failed = append(failed, conn.Addr())
+		self.closeDBConn(conn, false)
+		if !backendFailed(err) {
+			return res, err
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lostz/Aegis/backend"
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/shard"
+	"github.com/lostz/Aegis/sql"
+)
+
+// handleScatter runs a SELECT on all the shards of the plan at once and
+// sends the merged result. The shards share the time limit of the
+// statement and ScatterTimeout, the first error fails it and kills the
+// statement on the others.
+func (self *Session) handleScatter(stmt *sql.Select, sqlstmt string) error {
+	if err := self.checkFullScan(stmt); err != nil {
+		return err
+	}
+	scat, err := shard.NewScatter(stmt)
+	if err != nil {
+		return mysql.NewMysqlError(mysql.ER_NOT_SUPPORTED_YET, err.Error())
+	}
+
+	timeout := self.maxExecutionTime(sqlstmt)
+	if t := time.Duration(self.s.cfg.ScatterTimeout) * time.Millisecond; t > 0 && (timeout <= 0 || t < timeout) {
+		timeout = t
+	}
+
+	clusters := self.scatter.Clusters()
+	results := make([]*mysql.Result, len(clusters))
+	//the first error fails the statement, the other shards are stopped
+	var (
+		once     sync.Once
+		failed   int32
+		first    error
+		failedOn string
+	)
+	var wg sync.WaitGroup
+	for i, name := range clusters {
+		wg.Add(1)
+		go func(i int, c *cluster) {
+			defer wg.Done()
+			res, e := self.scatterOn(c, scat.SQL, timeout, stmt.IsLocked(), &failed)
+			if e == nil && res.Resultset == nil {
+				e = mysql.NewMysqlError(mysql.ER_UNKNOWN_ERROR, "shard "+clusters[i]+" returned no rows")
+			}
+			if e != nil {
+				once.Do(func() {
+					first, failedOn = e, clusters[i]
+					atomic.StoreInt32(&failed, 1)
+					self.killScattered()
+				})
+				return
+			}
+			results[i] = res
+		}(i, self.s.getCluster(name))
+	}
+	wg.Wait()
+	if first != nil {
+		logger.Errorf("session %d on shard %s: %s", self.connectionId, failedOn, first.Error())
+		return first
+	}
+
+	//one warning for the statement however many shards were cut
+	truncated := false
+	sets := make([]*mysql.Resultset, len(results))
+	for i, res := range results {
+		sets[i] = res.Resultset
+		truncated = truncated || res.Truncated
+	}
+	r, err := scat.Merge(sets)
+	if err != nil {
+		return mysql.NewMysqlError(mysql.ER_UNKNOWN_ERROR, err.Error())
+	}
+	if limit := self.resultLimit(); limit != nil {
+		cut, err := limit.Apply(r)
+		if err != nil {
+			return err
+		}
+		truncated = truncated || cut
+	}
+	self.checkTruncated(&mysql.Result{Resultset: r, Truncated: truncated}, nil)
+	return self.writeResultset(self.status, r)
+}
+
+// killScattered kills the statements still running on the shards, once
+// one of them failed their rows are of no use
+func (self *Session) killScattered() {
+	self.Lock()
+	conns := append([]backend.Client(nil), self.scattered...)
+	self.Unlock()
+
+	for _, conn := range conns {
+		if err := conn.KillQuery(); err != nil {
+			logger.Errorf("kill query on backend thread %d of session %d: %s",
+				conn.ConnectionId(), self.connectionId, err.Error())
+		}
+	}
+}
+
+// scatterOn runs the statement of a shard on a backend of cluster c, the
+// connection is recorded so KILL QUERY reaches it
+func (self *Session) scatterOn(c *cluster, sqlstmt string, timeout time.Duration, locked bool, failed *int32) (*mysql.Result, error) {
+	var conn backend.Client
+	if locked {
+		p, err := c.writerPool().Get()
+		if err != nil {
+			return nil, poolError(err)
+		}
+		conn = p
+	} else {
+		p, err := c.readerPool.Get()
+		if err != nil {
+			return nil, poolError(err)
+		}
+		conn = p.(backend.Client)
+	}
+	conn = self.track(conn)
+	defer conn.Close()
+
+	if err := conn.UseDB(self.db); err != nil {
+		return nil, err
+	}
+	self.Lock()
+	self.scattered = append(self.scattered, conn)
+	self.Unlock()
+	//out of reach of KILL before it goes back to its pool
+	defer func() {
+		self.Lock()
+		for i, sc := range self.scattered {
+			if sc == conn {
+				self.scattered = append(self.scattered[:i], self.scattered[i+1:]...)
+				break
+			}
+		}
+		self.Unlock()
+	}()
+	//a shard failed before the connection could be killed
+	if atomic.LoadInt32(failed) != 0 {
+		return nil, mysql.NewDefaultError(mysql.ER_QUERY_INTERRUPTED)
+	}
+	return self.executeTimed(conn, sqlstmt, timeout)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/lostz/Aegis/backend"
 	"github.com/lostz/Aegis/mysql"
@@ -12,9 +13,18 @@ func (self *Session) handleSelect(stmt sql.IStatement, sqlstmt string) error {
 	if err := self.checkDB(); err != nil {
 		return err
 	}
+	if self.scatter != nil {
+		return self.handleScatter(stmt.(*sql.Select), sqlstmt)
+	}
 
+	//like mysql, only SELECT has a time limit
+	var timeout time.Duration
 	isread := false
 	if s, ok := stmt.(sql.ISelect); ok {
+		if err := self.checkFullScan(s); err != nil {
+			return err
+		}
+		timeout = self.maxExecutionTime(sqlstmt)
 		isread = !s.IsLocked()
 	} else if _, sok := stmt.(sql.IShow); sok {
 		isread = true
@@ -28,9 +38,14 @@ func (self *Session) handleSelect(stmt sql.IStatement, sqlstmt string) error {
 	}
 
 	var res *mysql.Result
-	res, err = conn.Execute(sqlstmt)
-
+	res, err = self.execute(conn, sqlstmt, timeout)
+	//the address is gone once the connection is back in its pool
+	addr := conn.Addr()
 	self.closeDBConn(conn, false)
+
+	if err != nil && self.retryable(stmt, err) {
+		res, err = self.retryRead(sqlstmt, timeout, addr, err)
+	}
 	if err == nil {
 		err = self.mergeSelectResult(res)
 	}
@@ -38,7 +53,9 @@ func (self *Session) handleSelect(stmt sql.IStatement, sqlstmt string) error {
 }
 
 func (self *Session) closeDBConn(c backend.Client, rollback bool) {
-	if self.isInTransaction() || !self.isAutoCommit() {
+	//the pinned connection is released by commit, rollback or
+	//at the end of the batch
+	if c == self.pinConn {
 		return
 	}
 	if rollback {
@@ -48,6 +65,9 @@ func (self *Session) closeDBConn(c backend.Client, rollback bool) {
 }
 
 func (self *Session) mergeSelectResult(rs *mysql.Result) error {
+	if rs.Resultset == nil || rs.Next != nil {
+		return self.writeResult(rs)
+	}
 	r := rs.Resultset
 	status := self.status | rs.Status
 	return self.writeResultset(status, r)
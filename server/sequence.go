@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sequence"
+	"github.com/lostz/Aegis/sql"
+)
+
+// initSequences builds the sequences of the config, the segments reserve
+// their ranges on the writer of their cluster
+func (self *Server) initSequences() error {
+	for _, cfg := range self.cfg.Sequences {
+		if cfg.Generator != sequence.GeneratorSegment {
+			continue
+		}
+		if self.getCluster(cfg.Cluster) == nil {
+			return fmt.Errorf("sequence of table %s on unknown cluster %q", cfg.Table, cfg.Cluster)
+		}
+		if self.cfg.SequenceTable == "" {
+			return fmt.Errorf("sequence of table %s needs SequenceTable", cfg.Table)
+		}
+	}
+	sequences, err := sequence.NewRegistry(self.cfg.Sequences, self.fetchSequence)
+	if err != nil {
+		return err
+	}
+	self.sequences = sequences
+	return nil
+}
+
+// fetchSequence reserves the next step ids of the segment name in
+// SequenceTable. LAST_INSERT_ID(expr) makes the new max_id the insert id
+// of the statement, the row is locked until it is read.
+func (self *Server) fetchSequence(cluster, name string, step int64) (int64, error) {
+	conn, err := self.getCluster(cluster).writerPool().Get()
+	if err != nil {
+		return 0, poolError(err)
+	}
+	defer conn.Close()
+
+	query := fmt.Sprintf("INSERT INTO %s (name, max_id) VALUES ('%s', LAST_INSERT_ID(%d)) "+
+		"ON DUPLICATE KEY UPDATE max_id = LAST_INSERT_ID(max_id + %d)",
+		self.cfg.SequenceTable, strings.Replace(name, "'", "''", -1), step, step)
+	res, err := conn.Execute(query)
+	if err != nil {
+		return 0, err
+	}
+	if res.InsertId != 0 {
+		return int64(res.InsertId), nil
+	}
+
+	res, err = conn.Execute("SELECT LAST_INSERT_ID()")
+	if err != nil {
+		return 0, err
+	}
+	if res.Resultset == nil || len(res.Values) != 1 {
+		return 0, fmt.Errorf("no max_id for sequence %s", name)
+	}
+	return res.GetInt(0, 0)
+}
+
+// fillSequences writes the ids of the sequences into an INSERT, the first
+// of them becomes the insert id of the statement
+func (self *Session) fillSequences(stmt sql.IStatement, sqlstmt string) (string, error) {
+	id, err := self.s.sequences.Fill(stmt, self.db)
+	if err != nil {
+		return "", mysql.NewMysqlError(mysql.ER_UNKNOWN_ERROR, err.Error())
+	}
+	if id == 0 {
+		return sqlstmt, nil
+	}
+	self.insertId = id
+	return sql.String(stmt), nil
+}
+
+// fillLastInsertId writes the last insert id of the session in place of
+// the LAST_INSERT_ID() calls of the statement
+func (self *Session) fillLastInsertId(stmt sql.IStatement, sqlstmt string) string {
+	if !sql.ReplaceLastInsertId(stmt, self.lastInsertId) {
+		return sqlstmt
+	}
+	text := sql.String(stmt)
+
+	//the time limit hint is a comment, the tree does not keep it
+	if ms, ok := sql.MaxExecutionTime(sqlstmt); ok {
+		i := len(text) - len(strings.TrimLeft(text, "("))
+		if strings.HasPrefix(text[i:], "select ") {
+			text = fmt.Sprintf("%sselect /*+ MAX_EXECUTION_TIME(%d) */ %s", text[:i], ms, text[i+len("select "):])
+		}
+	}
+	return text
+}
+
+// handleLastInsertId answers SELECT LAST_INSERT_ID() with the last insert
+// id the session got, from a backend or a sequence
+func (self *Session) handleLastInsertId(name string) error {
+	result, err := self.buildResultset([]string{name}, [][]interface{}{{self.lastInsertId}})
+	if err != nil {
+		return err
+	}
+	return self.writeResultset(self.status, result)
+}
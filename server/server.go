@@ -3,21 +3,26 @@ package server
 import (
 	"io"
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/lostz/Aegis/backend"
-	"github.com/lostz/Aegis/balance"
 	"github.com/lostz/Aegis/config"
 	"github.com/lostz/Aegis/logging"
 	"github.com/lostz/Aegis/mysql"
 	"github.com/lostz/Aegis/pool"
+	"github.com/lostz/Aegis/sequence"
+	"github.com/lostz/Aegis/shard"
 )
 
 var logger = logging.GetLogger("server")
 
 const DefaultConcurrency = 1 * 1024
 
+// weight of a backend for reads when none is given
+const defaultWeight = 20
+
 type Server struct {
 	Version    string
 	BuildStamp string
@@ -26,35 +31,101 @@ type Server struct {
 	perIPConnCounter perIPConnCounter
 	running          bool
 	listener         net.Listener
-	writerPool       pool.Pool
-	readerPool       balance.Rebalancer
 	Concurrency      int
 	MaxConnsPerIP    int
 	cfg              *config.Config
+	capability       uint32
+
+	startTime time.Time
+	sessions  sessionRegistry
+	questions uint64
+	tableRows tableRowsCache
+
+	//the default cluster first, then the configured ones
+	clusters  []*cluster
+	routes    []route
+	shards    *shard.Router
+	sequences *sequence.Registry
+
+	//serializes the AEGIS admin statements
+	adminLock sync.Mutex
 }
 
 func NewServer(conf *config.Config) *Server {
 	s := &Server{cfg: conf}
 	s.Concurrency = 1
 	s.MaxConnsPerIP = 1
+	s.capability = DEFAULT_CAPABILITY
+	if conf.Compress == mysql.CompressZlib {
+		s.capability |= mysql.CLIENT_COMPRESS
+	}
 	return s
 }
 
 func (self *Server) Start() error {
-	wPool := pool.Pool{
-		MaxIdle:     10,
-		IdleTimeout: 240 * time.Second,
+	self.startTime = time.Now()
+	if err := self.initClusters(); err != nil {
+		return err
+	}
+
+	for _, p := range self.pools() {
+		p.Warm()
+	}
+	go self.watchBusy()
+	for _, c := range self.clusters {
+		if len(c.cfg.MasterCandidates) > 0 {
+			go newMasterMonitor(self, c).run()
+		}
+	}
+
+	return self.ListenAndServe()
+}
+
+// newPool is the connection pool of the backend at addr
+func (self *Server) newPool(addr string) *pool.Pool {
+	return &pool.Pool{
+		Addr:           addr,
+		MaxIdle:        10,
+		IdleTimeout:    240 * time.Second,
+		MaxLifetime:    time.Duration(self.cfg.BackendMaxLifetime) * time.Second,
+		MaxActive:      self.cfg.BackendMaxConns,
+		Wait:           self.cfg.BackendMaxConns > 0,
+		MaxWait:        time.Duration(self.cfg.BackendWaitTimeout) * time.Millisecond,
+		MinIdle:        self.cfg.BackendMinIdle,
+		RefillInterval: time.Duration(self.cfg.BackendRefillInterval) * time.Millisecond,
+		TestOnBorrow:   self.testOnBorrow,
+		TestOnReturn:   self.testOnReturn,
 		Dial: func() (backend.Client, error) {
 			conn := &backend.Conn{}
-			err := conn.Connect(self.cfg.DBAddr, self.cfg.DBUser, self.cfg.DBPasswd, self.cfg.DB)
+			conn.SetCompress(self.cfg.BackendCompress, self.cfg.CompressLevel, self.cfg.CompressMinLength)
+			err := conn.Connect(addr, self.cfg.DBUser, self.cfg.DBPasswd, self.cfg.DB)
 			return conn, err
 		},
 	}
-	self.writerPool = wPool
-	rs := balance.NewRbServer(20, &wPool)
-	self.readerPool = balance.NewRebalancer([]*balance.RbServer{rs})
+}
 
-	return self.ListenAndServe()
+// testOnBorrow pings a backend connection that was idle for a while
+func (self *Server) testOnBorrow(c backend.Client, t time.Time) error {
+	interval := time.Duration(self.cfg.BackendPingInterval) * time.Second
+	if interval <= 0 || time.Since(t) < interval {
+		return nil
+	}
+	return c.Ping()
+}
+
+// testOnReturn cleans a backend connection up for the next session,
+// one that saw a protocol error is closed instead
+func (self *Server) testOnReturn(c backend.Client) error {
+	if err := c.Err(); err != nil {
+		return err
+	}
+	if self.cfg.BackendReset == config.BackendResetConnection {
+		return c.Reset()
+	}
+	if c.IsInTransaction() {
+		return c.Rollback()
+	}
+	return nil
 }
 
 func (self *Server) WriteTooManyConnection(c net.Conn) {
@@ -98,8 +169,6 @@ func (self *Server) Serve(ln net.Listener) error {
 		}
 
 	}
-	return nil
-
 }
 
 func (self *Server) getConcurrency() int {
@@ -112,6 +181,9 @@ func (self *Server) getConcurrency() int {
 
 func (self *Server) serveConn(c net.Conn) error {
 	s := self.newSessionConn(c)
+	self.sessions.Register(s)
+	defer self.sessions.Unregister(s)
+
 	if err := s.Handshake(); err != nil {
 		return err
 	}
@@ -127,6 +199,10 @@ func (self *Server) newSessionConn(c net.Conn) *Session {
 	s.s = self
 	s.pkg.Sequence = 0
 	s.connectionId = atomic.AddUint32(&baseConnId, 1)
+	s.host = c.RemoteAddr().String()
+	s.command = mysql.COM_CONNECT
+	s.commandTime = time.Now()
+	s.connected = s.commandTime
 	s.status = mysql.SERVER_STATUS_AUTOCOMMIT
 	s.salt, _ = mysql.RandomBuf(20)
 	s.collation = mysql.DEFAULT_COLLATION_ID
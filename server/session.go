@@ -6,15 +6,21 @@ import (
 	"fmt"
 	"net"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/lostz/Aegis/backend"
 	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/pool"
+	"github.com/lostz/Aegis/shard"
 	"github.com/lostz/Aegis/utils"
 )
 
 var DEFAULT_CAPABILITY uint32 = mysql.CLIENT_LONG_PASSWORD | mysql.CLIENT_LONG_FLAG |
 	mysql.CLIENT_CONNECT_WITH_DB | mysql.CLIENT_PROTOCOL_41 |
-	mysql.CLIENT_TRANSACTIONS | mysql.CLIENT_SECURE_CONNECTION
+	mysql.CLIENT_TRANSACTIONS | mysql.CLIENT_SECURE_CONNECTION |
+	mysql.CLIENT_MULTI_STATEMENTS | mysql.CLIENT_MULTI_RESULTS |
+	mysql.CLIENT_DEPRECATE_EOF
 var baseConnId uint32 = 10000
 
 type Session struct {
@@ -27,23 +33,65 @@ type Session struct {
 	collation    mysql.CollationId
 	charset      string
 	user         string
+	host         string
 	db           string
 	salt         []byte
 	affectedRows int64
 	s            *Server
 	closed       bool
+
+	//backend connection pinned by a transaction or a batch with writes
+	pinConn  backend.Client
+	pinBatch bool
+
+	//cluster of the current statement and of the pinned connection
+	cluster    *cluster
+	pinCluster *cluster
+
+	//shards of the current statement when it runs on several
+	scatter *shard.Plan
+
+	//insert id of the current statement given by a sequence, and the
+	//last insert id SELECT LAST_INSERT_ID() returns
+	insertId     uint64
+	lastInsertId uint64
+
+	//backend connection executing the current statement, guarded
+	//by the session lock since KILL reads it from other sessions
+	running backend.Client
+
+	//backend connections of a SELECT running on several shards,
+	//guarded by the session lock as well
+	scattered []backend.Client
+
+	//what the session is doing for SHOW PROCESSLIST, guarded
+	//by the session lock as well
+	command     byte
+	commandTime time.Time
+	info        string
+	connected   time.Time
+	questions   uint64
+
+	//warnings raised by the proxy itself for the last statement
+	warnings []*mysql.MysqlError
+
+	//the master changed under the transaction, guarded by the lock
+	aborted bool
 }
 
 func (self *Session) Run() {
+	defer self.Close()
 	for {
 		data, err := self.pkg.ReadPacket()
 		if err != nil {
 			return
 		}
+		self.setCommand(data[0], data[1:])
 		if err := self.dispatch(data); err != nil {
 			logger.Errorf("dispath %s %d", err.Error(), self.connectionId)
 			self.writeError(err)
 		}
+		self.setCommand(mysql.COM_SLEEP, nil)
 
 		if self.closed {
 			return
@@ -56,6 +104,13 @@ func (self *Session) Run() {
 }
 
 func (self *Session) Close() error {
+	if self.closed {
+		return nil
+	}
+	self.closed = true
+	self.releasePinConn(true)
+	//an error written before closing is still queued when compressed
+	self.pkg.Flush()
 	self.c.Close()
 	return nil
 
@@ -69,6 +124,7 @@ func (self *Session) dispatch(data []byte) error {
 		self.Close()
 		return nil
 	case mysql.COM_QUERY:
+		atomic.AddUint64(&self.s.questions, 1)
 		return self.handleQuery(utils.String(data))
 	case mysql.COM_INIT_DB:
 		if err := self.useDB(utils.String(data)); err != nil {
@@ -76,13 +132,28 @@ func (self *Session) dispatch(data []byte) error {
 		} else {
 			return self.writeOK(nil)
 		}
+	case mysql.COM_PING:
+		return self.writeOK(nil)
+	case mysql.COM_FIELD_LIST:
+		return self.handleFieldList(data)
+	case mysql.COM_STATISTICS:
+		return self.handleStatistics()
+	case mysql.COM_PROCESS_INFO:
+		return self.handleQuery("show processlist")
+	case mysql.COM_DEBUG:
+		return self.handleDebug()
+	case mysql.COM_SET_OPTION:
+		return self.handleSetOption(data)
+	case mysql.COM_RESET_CONNECTION:
+		return self.handleResetConnection()
+	case mysql.COM_CHANGE_USER:
+		return self.handleChangeUser(data)
 	default:
 		msg := fmt.Sprintf("command %d not supported now", cmd)
 		logger.Errorf("ClientConn", "dispatch", msg, 0)
 		return mysql.NewDefaultError(mysql.ER_UNKNOWN_ERROR, msg)
 
 	}
-	return nil
 }
 
 func (self *Session) Handshake() error {
@@ -109,6 +180,13 @@ func (self *Session) Handshake() error {
 		return err
 	}
 
+	if self.capability&mysql.CLIENT_COMPRESS > 0 {
+		cfg := self.s.cfg
+		if err := self.pkg.EnableCompression(cfg.Compress, cfg.CompressLevel, cfg.CompressMinLength); err != nil {
+			return err
+		}
+	}
+
 	self.pkg.Sequence = 0
 	return nil
 }
@@ -132,8 +210,8 @@ func (self *Session) writeInitialHandshake() error {
 	//filter [00]
 	data = append(data, 0)
 
-	//capability flag lower 2 bytes, using default capability here
-	data = append(data, byte(DEFAULT_CAPABILITY), byte(DEFAULT_CAPABILITY>>8))
+	//capability flag lower 2 bytes
+	data = append(data, byte(self.s.capability), byte(self.s.capability>>8))
 
 	//charset, utf-8 default
 	data = append(data, uint8(mysql.DEFAULT_COLLATION_ID))
@@ -142,8 +220,8 @@ func (self *Session) writeInitialHandshake() error {
 	data = append(data, byte(self.status), byte(self.status>>8))
 
 	//below 13 byte may not be used
-	//capability flag upper 2 bytes, using default capability here
-	data = append(data, byte(DEFAULT_CAPABILITY>>16), byte(DEFAULT_CAPABILITY>>24))
+	//capability flag upper 2 bytes
+	data = append(data, byte(self.s.capability>>16), byte(self.s.capability>>24))
 
 	//filter [0x15], for wireshark dump, value is 0x15
 	data = append(data, 0x15)
@@ -169,8 +247,8 @@ func (self *Session) readHandshakeResponse() error {
 
 	pos := 0
 
-	//capability
-	self.capability = binary.LittleEndian.Uint32(data[:4])
+	//capability, only keep what both sides support
+	self.capability = binary.LittleEndian.Uint32(data[:4]) & self.s.capability
 	pos += 4
 
 	//skip max packet size
@@ -184,21 +262,19 @@ func (self *Session) readHandshakeResponse() error {
 	pos += 23
 
 	//user name
-	self.user = string(data[pos : pos+bytes.IndexByte(data[pos:], 0)])
-	pos += len(self.user) + 1
+	user := string(data[pos : pos+bytes.IndexByte(data[pos:], 0)])
+	self.Lock()
+	self.user = user
+	self.Unlock()
+	pos += len(user) + 1
 
 	//auth length and auth
 	authLen := int(data[pos])
 	pos++
 	auth := data[pos : pos+authLen]
 
-	checkAuth := mysql.CalcPassword(self.salt, []byte(self.s.cfg.Password))
-	if !bytes.Equal(auth, checkAuth) {
-		logger.Errorf("ClientConn", "readHandshakeResponse", "error", 0,
-			"auth", auth,
-			"checkAuth", checkAuth,
-			"passworld", self.s.cfg.Password)
-		return mysql.NewDefaultError(mysql.ER_ACCESS_DENIED_ERROR, self.c.RemoteAddr().String(), self.user, "Yes")
+	if err := self.checkAuth(self.user, auth); err != nil {
+		return err
 	}
 
 	pos += authLen
@@ -224,25 +300,52 @@ func (self *Session) readHandshakeResponse() error {
 	return nil
 }
 
+// checkAuth verifies the scrambled password of a configured proxy user
+func (self *Session) checkAuth(user string, auth []byte) error {
+	if u := self.s.cfg.GetUser(user); u != nil {
+		if bytes.Equal(auth, mysql.CalcPassword(self.salt, []byte(u.Password))) {
+			return nil
+		}
+	}
+
+	logger.Errorf("access denied for user %s from %s", user, self.c.RemoteAddr().String())
+
+	host, _, _ := net.SplitHostPort(self.c.RemoteAddr().String())
+	usePassword := "NO"
+	if len(auth) > 0 {
+		usePassword = "YES"
+	}
+	return mysql.NewDefaultError(mysql.ER_ACCESS_DENIED_ERROR, user, host, usePassword)
+}
+
 func (self *Session) useDB(db string) error {
+	self.Lock()
 	self.db = db
+	self.Unlock()
 	return nil
 }
 
 func (self *Session) writeOK(r *mysql.Result) error {
+	return self.writeOKPacket(mysql.OK_HEADER, r)
+}
+
+// writeOKPacket writes an OK packet, with CLIENT_DEPRECATE_EOF it also
+// closes result sets, using the EOF header
+func (self *Session) writeOKPacket(header byte, r *mysql.Result) error {
 	if r == nil {
 		r = &mysql.Result{Status: self.status}
 	}
 	data := make([]byte, 4, 32)
 
-	data = append(data, mysql.OK_HEADER)
+	data = append(data, header)
 
 	data = append(data, mysql.PutLengthEncodedInt(r.AffectedRows)...)
 	data = append(data, mysql.PutLengthEncodedInt(r.InsertId)...)
 
 	if self.capability&mysql.CLIENT_PROTOCOL_41 > 0 {
+		warnings := self.warningCount()
 		data = append(data, byte(r.Status), byte(r.Status>>8))
-		data = append(data, 0, 0)
+		data = append(data, byte(warnings), byte(warnings>>8))
 	}
 
 	return self.pkg.WritePacket(data)
@@ -271,41 +374,115 @@ func (self *Session) writeError(e error) error {
 }
 
 func (self *Session) writeEOF(status uint16) error {
+	if self.deprecateEOF() {
+		return self.writeOKPacket(mysql.EOF_HEADER, &mysql.Result{Status: status})
+	}
+
 	data := make([]byte, 4, 9)
 
 	data = append(data, mysql.EOF_HEADER)
 	if self.capability&mysql.CLIENT_PROTOCOL_41 > 0 {
-		data = append(data, 0, 0)
+		warnings := self.warningCount()
+		data = append(data, byte(warnings), byte(warnings>>8))
 		data = append(data, byte(status), byte(status>>8))
 	}
 
 	return self.pkg.WritePacket(data)
 }
 
+func (self *Session) deprecateEOF() bool {
+	return self.capability&mysql.CLIENT_DEPRECATE_EOF > 0
+}
+
 func (self *Session) checkDB() error {
 	return self.useDB(self.db)
 }
 
-func (self *Session) getReader() (backend.Client, error) {
-	conn, err := self.s.readerPool.Get()
+// getReader borrows a connection to read from a backend other than the
+// ones dialing exclude
+func (self *Session) getReader(exclude ...string) (backend.Client, error) {
+	conn, err := self.currentCluster().readerPool.GetExcept(exclude...)
 	if err != nil {
 		logger.Errorf("get reader conn", err.Error())
-		return nil, err
+		return nil, poolError(err)
 	}
-	return conn.(backend.Client), nil
+	return self.track(conn.(backend.Client)), nil
 
 }
 
 func (self *Session) getWriter() (backend.Client, error) {
-	conn, err := self.s.writerPool.Get()
+	conn, err := self.currentCluster().writerPool().Get()
 	if err != nil {
 		logger.Errorf("get writer conn", err.Error())
-		return nil, err
+		return nil, poolError(err)
+	}
+	return self.track(conn), nil
+}
+
+// currentCluster is the cluster the statement was routed to
+func (self *Session) currentCluster() *cluster {
+	if self.cluster == nil {
+		return self.s.defaultCluster()
+	}
+	return self.cluster
+}
+
+// track records the session as the borrower of conn in its pool
+func (self *Session) track(conn backend.Client) backend.Client {
+	if t, ok := conn.(pool.Tracked); ok {
+		t.SetOwner(self.connectionId)
+	}
+	return conn
+}
+
+// poolError tells the client the backend has no connection to spare
+// like mysql would when it is out of connections
+func poolError(err error) error {
+	if err == pool.ErrPoolTimeout || err == pool.ErrPoolExhausted {
+		return mysql.NewDefaultError(mysql.ER_CON_COUNT_ERROR)
 	}
-	return conn.(backend.Client), nil
+	return err
 }
 
 func (self *Session) getConn(isSelect bool) (backend.Client, error) {
+	conn, err := self.getBackendConn(isSelect)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := conn.UseDB(self.db); err != nil {
+		self.closeDBConn(conn, false)
+		return nil, err
+	}
+	return conn, nil
+}
+
+func (self *Session) getBackendConn(isSelect bool) (backend.Client, error) {
+	if err := self.checkAborted(); err != nil {
+		return nil, err
+	}
+	if self.pinConn != nil {
+		return self.pinConn, nil
+	}
+
+	if self.needBeginTx() || self.pinBatch {
+		conn, err := self.getWriter()
+		if err != nil {
+			return nil, err
+		}
+		if self.needBeginTx() {
+			if err := conn.Begin(); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		}
+		self.Lock()
+		self.pinConn = conn
+		self.pinCluster = self.currentCluster()
+		self.Unlock()
+		return conn, nil
+	}
+
 	if isSelect {
 		return self.getReader()
 	} else {
@@ -313,3 +490,17 @@ func (self *Session) getConn(isSelect bool) (backend.Client, error) {
 	}
 
 }
+
+func (self *Session) releasePinConn(rollback bool) {
+	if self.pinConn == nil {
+		return
+	}
+	conn := self.pinConn
+	self.Lock()
+	self.pinConn = nil
+	self.Unlock()
+	if rollback || self.isInTransaction() {
+		conn.Rollback()
+	}
+	conn.Close()
+}
@@ -1,13 +1,33 @@
 package server
 
-import "github.com/lostz/Aegis/sql"
+import (
+	"strings"
+
+	"github.com/lostz/Aegis/sql"
+)
 
 func (self *Session) handleShow(strsql string, stmt sql.IShow) error {
 	var err error
 
-	switch stmt.(type) {
+	switch v := stmt.(type) {
 	case *sql.ShowAegisStatus:
 		err = self.handleProxyStatus()
+	case *sql.ShowProcessList:
+		err = self.handleShowProcessList(v.Full)
+	case *sql.ShowAegisBackendProcessList:
+		err = self.handleShowBackendProcessList()
+	case *sql.ShowAegisPools:
+		err = self.handleShowAegisPools()
+	case *sql.ShowAegisBackends:
+		err = self.handleShowAegisBackends()
+	case *sql.ShowAegisSessions:
+		err = self.handleShowAegisSessions()
+	case *sql.ShowWarnings:
+		if len(self.warnings) == 0 {
+			err = self.handleSelect(stmt, strsql)
+		} else {
+			err = self.handleShowWarnings(v.Count)
+		}
 	default:
 		err = self.handleSelect(stmt, strsql)
 	}
@@ -20,6 +40,13 @@ func (self *Session) handleProxyStatus() error {
 	var Column = 4
 	var rows [][]string
 
+	//the backends reads of the default cluster are balanced over
+	c := self.s.defaultCluster()
+	var slaves []string
+	for _, rs := range c.readerPool.Servers() {
+		slaves = append(slaves, rs.Pool().Addr)
+	}
+
 	var names []string = []string{
 		"Version",
 		"CommitID",
@@ -31,8 +58,8 @@ func (self *Session) handleProxyStatus() error {
 		[]string{
 			self.s.Version,
 			self.s.CommitId,
-			self.s.cfg.DBAddr,
-			self.s.cfg.DBAddr,
+			c.writerPool().Addr,
+			strings.Join(slaves, ","),
 		},
 	)
 	var values [][]interface{} = make([][]interface{}, len(rows))
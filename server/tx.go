@@ -13,3 +13,65 @@ func (self *Session) isAutoCommit() bool {
 func (self *Session) needBeginTx() bool {
 	return self.isInTransaction() || !self.isAutoCommit()
 }
+
+func (self *Session) handleBegin() error {
+	if err := self.checkAborted(); err != nil {
+		return err
+	}
+	if self.pinConn != nil {
+		//like mysql, begin commits the running transaction
+		if self.isInTransaction() {
+			if err := self.pinConn.Commit(); err != nil {
+				return err
+			}
+		}
+		if err := self.pinConn.Begin(); err != nil {
+			return err
+		}
+	}
+	self.status |= mysql.SERVER_STATUS_IN_TRANS
+	return self.writeOK(nil)
+}
+
+func (self *Session) handleCommit() error {
+	if err := self.commit(); err != nil {
+		return err
+	}
+	return self.writeOK(nil)
+}
+
+func (self *Session) handleRollback() error {
+	if err := self.rollback(); err != nil {
+		return err
+	}
+	return self.writeOK(nil)
+}
+
+func (self *Session) commit() (err error) {
+	if err := self.checkAborted(); err != nil {
+		return err
+	}
+	self.status &^= mysql.SERVER_STATUS_IN_TRANS
+	if self.pinConn == nil {
+		return nil
+	}
+	err = self.pinConn.Commit()
+	if !self.pinBatch {
+		self.releasePinConn(false)
+	}
+	return err
+}
+
+func (self *Session) rollback() (err error) {
+	//the transaction is gone already
+	self.checkAborted()
+	self.status &^= mysql.SERVER_STATUS_IN_TRANS
+	if self.pinConn == nil {
+		return nil
+	}
+	err = self.pinConn.Rollback()
+	if !self.pinBatch {
+		self.releasePinConn(false)
+	}
+	return err
+}
@@ -0,0 +1,471 @@
+package shard
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/utils"
+)
+
+// row is a row of a shard, its values and the cells they were sent in
+type row struct {
+	values []interface{}
+	cells  [][]byte
+}
+
+// mergeState is the merge of an aggregate over the rows of a group
+type mergeState struct {
+	value interface{}
+	count int64
+}
+
+// Merge makes the result of the statement out of the results of the
+// shards. The rows are taken in text protocol, like COM_QUERY returns.
+func (self *Scatter) Merge(results []*mysql.Resultset) (*mysql.Resultset, error) {
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no results to merge")
+	}
+	all := results[0].Fields
+	visible := len(all) - self.hidden
+	if visible <= 0 {
+		return nil, fmt.Errorf("%d columns for %d hidden ones", len(all), self.hidden)
+	}
+	index := func(c column) (int, error) {
+		i := c.n
+		if c.fromEnd {
+			i += visible
+		}
+		if i < 0 || i >= len(all) {
+			return 0, fmt.Errorf("unknown column %d in %d columns", c.n+1, visible)
+		}
+		return i, nil
+	}
+
+	var rows []*row
+	for _, r := range results {
+		if len(r.Fields) != len(all) {
+			return nil, fmt.Errorf("shards return %d and %d columns", len(all), len(r.Fields))
+		}
+		for i, data := range r.RowDatas {
+			cells, err := splitCells(data, len(all))
+			if err != nil {
+				return nil, err
+			}
+			rows = append(rows, &row{values: r.Values[i], cells: cells})
+		}
+	}
+
+	fields := append([]*mysql.Field(nil), all[:visible]...)
+	if self.grouped {
+		var err error
+		if rows, err = self.group(rows, all, fields, index); err != nil {
+			return nil, err
+		}
+	}
+	if self.distinct {
+		for _, f := range fields {
+			if err := checkCollation(f, "DISTINCT"); err != nil {
+				return nil, err
+			}
+		}
+		rows = distinct(rows, visible)
+	}
+
+	if len(self.orders) > 0 {
+		var keys []int
+		for _, o := range self.orders {
+			i, err := index(o.col)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, i)
+		}
+		for _, i := range keys {
+			if err := checkCollation(all[i], "ORDER BY"); err != nil {
+				return nil, err
+			}
+		}
+		sort.SliceStable(rows, func(a, b int) bool {
+			for k, i := range keys {
+				c := compareValues(all[i], rows[a].values[i], rows[b].values[i])
+				if c != 0 {
+					return (c < 0) != self.orders[k].desc
+				}
+			}
+			return false
+		})
+	}
+
+	if self.count >= 0 {
+		if self.offset >= int64(len(rows)) {
+			rows = nil
+		} else {
+			rows = rows[self.offset:]
+		}
+		if self.count < int64(len(rows)) {
+			rows = rows[:self.count]
+		}
+	}
+
+	r := &mysql.Resultset{Fields: fields, FieldNames: make(map[string]int, visible)}
+	for i, f := range fields {
+		r.FieldNames[string(f.Name)] = i
+	}
+	for _, row := range rows {
+		r.Values = append(r.Values, row.values[:visible])
+		r.RowDatas = append(r.RowDatas, mysql.RowData(bytes.Join(row.cells[:visible], nil)))
+	}
+	return r, nil
+}
+
+// group merges the rows of every group, the first row of a group gives
+// the values of its columns that are neither keys nor aggregates. The
+// fields of the averages among the visible ones are replaced.
+func (self *Scatter) group(rows []*row, all, visible []*mysql.Field, index func(column) (int, error)) ([]*row, error) {
+	var keys, aggs, counts []int
+	for _, g := range self.groups {
+		i, err := index(g)
+		if err != nil {
+			return nil, err
+		}
+		if err := checkCollation(all[i], "GROUP BY"); err != nil {
+			return nil, err
+		}
+		keys = append(keys, i)
+	}
+	for _, a := range self.aggs {
+		i, err := index(a.col)
+		if err != nil {
+			return nil, err
+		}
+		if a.fn == "MIN" || a.fn == "MAX" {
+			if err := checkCollation(all[i], a.fn); err != nil {
+				return nil, err
+			}
+		}
+		aggs = append(aggs, i)
+		if a.fn == "AVG" {
+			if i, err = index(a.count); err != nil {
+				return nil, err
+			}
+		}
+		counts = append(counts, i)
+	}
+
+	var groups []*row
+	var states [][]mergeState
+	seen := make(map[string]int)
+	for _, r := range rows {
+		key := rowKey(r.values, keys)
+		g, ok := seen[key]
+		if !ok {
+			g = len(groups)
+			seen[key] = g
+			groups = append(groups, &row{
+				values: append([]interface{}(nil), r.values...),
+				cells:  append([][]byte(nil), r.cells...),
+			})
+			states = append(states, make([]mergeState, len(aggs)))
+		}
+		for k, a := range self.aggs {
+			if err := states[g][k].add(a.fn, all[aggs[k]], r.values[aggs[k]], r.values[counts[k]]); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for k, a := range self.aggs {
+		i := aggs[k]
+		field := all[i]
+		if a.fn == "AVG" {
+			field = avgField(field)
+			if i < len(visible) {
+				visible[i] = field
+			}
+		}
+		for g, r := range groups {
+			v, err := states[g][k].result(a.fn, field)
+			if err != nil {
+				return nil, err
+			}
+			r.values[i] = v
+			r.cells[i] = encodeCell(v)
+		}
+	}
+	return groups, nil
+}
+
+func (self *mergeState) add(fn string, field *mysql.Field, v, count interface{}) error {
+	if v == nil {
+		return nil
+	}
+	switch fn {
+	case "COUNT":
+		n, err := toInt(v)
+		if err != nil {
+			return err
+		}
+		self.count += n
+		self.value = self.count
+	case "SUM", "AVG":
+		if self.value == nil {
+			self.value = v
+		} else {
+			sum, err := addValues(self.value, v)
+			if err != nil {
+				return err
+			}
+			self.value = sum
+		}
+		if fn == "AVG" {
+			n, err := toInt(count)
+			if err != nil {
+				return err
+			}
+			self.count += n
+		}
+	case "MIN", "MAX":
+		c := compareValues(field, v, self.value)
+		if self.value == nil || (fn == "MIN" && c < 0) || (fn == "MAX" && c > 0) {
+			self.value = v
+		}
+	}
+	return nil
+}
+
+func (self *mergeState) result(fn string, field *mysql.Field) (interface{}, error) {
+	switch fn {
+	case "COUNT":
+		return self.count, nil
+	case "AVG":
+		if self.value == nil || self.count == 0 {
+			return nil, nil
+		}
+		if f, ok := self.value.(float64); ok {
+			return f / float64(self.count), nil
+		}
+		sum, ok := toRat(self.value)
+		if !ok {
+			return nil, fmt.Errorf("can not average %v", self.value)
+		}
+		sum.Quo(sum, new(big.Rat).SetInt64(self.count))
+		return []byte(sum.FloatString(int(field.Decimal))), nil
+	}
+	if r, ok := self.value.(*big.Rat); ok {
+		return []byte(r.FloatString(int(field.Decimal))), nil
+	}
+	return self.value, nil
+}
+
+// avgField is the field of an AVG out of the one of the SUM the shards
+// return, mysql gives it 4 more decimals
+func avgField(f *mysql.Field) *mysql.Field {
+	avg := *f
+	avg.Data = nil
+	if f.Type == mysql.MYSQL_TYPE_NEWDECIMAL || f.Type == mysql.MYSQL_TYPE_DECIMAL {
+		avg.Decimal += 4
+		if avg.Decimal > 30 {
+			avg.Decimal = 30
+		}
+		avg.ColumnLength += uint32(avg.Decimal - f.Decimal)
+	}
+	return &avg
+}
+
+func addValues(a, b interface{}) (interface{}, error) {
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return x + y, nil
+		}
+	case uint64:
+		if y, ok := b.(uint64); ok {
+			return x + y, nil
+		}
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x + y, nil
+		}
+	}
+	x, ok := toRat(a)
+	y, yok := toRat(b)
+	if !ok || !yok {
+		return nil, fmt.Errorf("can not add %v and %v", a, b)
+	}
+	return x.Add(x, y), nil
+}
+
+func toInt(v interface{}) (int64, error) {
+	switch n := v.(type) {
+	case int64:
+		return n, nil
+	case uint64:
+		return int64(n), nil
+	case []byte:
+		return strconv.ParseInt(string(n), 10, 64)
+	}
+	return 0, fmt.Errorf("%v is not a count", v)
+}
+
+// toRat is a number exactly, DECIMAL comes as its text
+func toRat(v interface{}) (*big.Rat, bool) {
+	switch n := v.(type) {
+	case *big.Rat:
+		return new(big.Rat).Set(n), true
+	case int64:
+		return new(big.Rat).SetInt64(n), true
+	case uint64:
+		return new(big.Rat).SetInt(new(big.Int).SetUint64(n)), true
+	case float64:
+		return new(big.Rat).SetFloat64(n), true
+	case []byte:
+		return new(big.Rat).SetString(string(n))
+	}
+	return nil, false
+}
+
+// binaryCollation is the collation of the binary strings
+const binaryCollation = 63
+
+// isString is whether field holds strings
+func isString(field *mysql.Field) bool {
+	switch field.Type {
+	case mysql.MYSQL_TYPE_VARCHAR, mysql.MYSQL_TYPE_VAR_STRING, mysql.MYSQL_TYPE_STRING,
+		mysql.MYSQL_TYPE_ENUM, mysql.MYSQL_TYPE_SET, mysql.MYSQL_TYPE_TINY_BLOB,
+		mysql.MYSQL_TYPE_MEDIUM_BLOB, mysql.MYSQL_TYPE_LONG_BLOB, mysql.MYSQL_TYPE_BLOB:
+		return true
+	}
+	return false
+}
+
+// checkCollation fails on a field of strings in a collation that is not
+// a binary one, mysql compares them by rules of the collation the proxy
+// does not have
+func checkCollation(field *mysql.Field, clause string) error {
+	if !isString(field) || field.Charset == binaryCollation || field.Flag&mysql.BINARY_FLAG != 0 {
+		return nil
+	}
+	return fmt.Errorf("%s on column %s of collation %d can not run on several shards, "+
+		"only binary collations compare the same on the proxy", clause, field.Name, field.Charset)
+}
+
+// compareValues orders two values of field like mysql, NULL first. The
+// strings are in a binary collation, see checkCollation, those of a
+// character set ignore trailing spaces.
+func compareValues(field *mysql.Field, a, b interface{}) int {
+	if field.Type == mysql.MYSQL_TYPE_NEWDECIMAL || field.Type == mysql.MYSQL_TYPE_DECIMAL {
+		return compareNumbers(a, b)
+	}
+	switch x := a.(type) {
+	case []byte:
+		if y, ok := b.([]byte); ok {
+			if field.Charset != binaryCollation {
+				x, y = bytes.TrimRight(x, " "), bytes.TrimRight(y, " ")
+			}
+			return bytes.Compare(x, y)
+		}
+	case nil:
+		if b == nil {
+			return 0
+		}
+		return -1
+	}
+	if b == nil {
+		return 1
+	}
+	return compareNumbers(a, b)
+}
+
+func compareNumbers(a, b interface{}) int {
+	if a == nil || b == nil {
+		switch {
+		case a == b:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	x, ok := toRat(a)
+	y, yok := toRat(b)
+	if !ok || !yok {
+		return strings.Compare(fmt.Sprint(a), fmt.Sprint(b))
+	}
+	return x.Cmp(y)
+}
+
+// rowKey tells apart the rows by the values of the columns
+func rowKey(values []interface{}, columns []int) string {
+	var b bytes.Buffer
+	for _, i := range columns {
+		switch v := values[i].(type) {
+		case nil:
+			b.WriteByte(0)
+		case []byte:
+			b.WriteByte(1)
+			b.Write(v)
+		default:
+			b.WriteByte(1)
+			fmt.Fprint(&b, v)
+		}
+		b.WriteByte(0xff)
+	}
+	return b.String()
+}
+
+// distinct drops the rows with the same visible values as one before
+func distinct(rows []*row, visible int) []*row {
+	columns := make([]int, visible)
+	for i := range columns {
+		columns[i] = i
+	}
+	seen := make(map[string]bool)
+	kept := rows[:0]
+	for _, r := range rows {
+		key := rowKey(r.values, columns)
+		if !seen[key] {
+			seen[key] = true
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
+
+// splitCells cuts a text protocol row into its n cells
+func splitCells(data mysql.RowData, n int) ([][]byte, error) {
+	cells := make([][]byte, n)
+	pos := 0
+	for i := range cells {
+		l, err := utils.SkipLengthEnodedString(data[pos:])
+		if err != nil {
+			return nil, err
+		}
+		cells[i] = data[pos : pos+l]
+		pos += l
+	}
+	return cells, nil
+}
+
+func encodeCell(v interface{}) []byte {
+	var b []byte
+	switch n := v.(type) {
+	case nil:
+		return []byte{0xfb}
+	case []byte:
+		b = n
+	case int64:
+		b = strconv.AppendInt(nil, n, 10)
+	case uint64:
+		b = strconv.AppendUint(nil, n, 10)
+	case float64:
+		b = strconv.AppendFloat(nil, n, 'g', -1, 64)
+	default:
+		b = []byte(fmt.Sprint(v))
+	}
+	return utils.PutLengthEncodedString(b)
+}
@@ -0,0 +1,468 @@
+package shard
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/sql"
+)
+
+// Table is a sharded table
+type Table struct {
+	schema string
+	name   string
+	key    string
+	shards []string
+	rule   rule
+}
+
+// Name is the table as the errors show it
+func (self *Table) Name() string {
+	if self.schema == "" {
+		return self.name
+	}
+	return self.schema + "." + self.name
+}
+
+// Plan is where a statement on a sharded table runs
+type Plan struct {
+	Table  *Table
+	Shards []int
+}
+
+// Clusters are the clusters of the shards the statement runs on, shards
+// on the same cluster share the table
+func (self *Plan) Clusters() []string {
+	clusters := make([]string, 0, len(self.Shards))
+	seen := make(map[string]bool, len(self.Shards))
+	for _, i := range self.Shards {
+		if c := self.Table.shards[i]; !seen[c] {
+			seen[c] = true
+			clusters = append(clusters, c)
+		}
+	}
+	return clusters
+}
+
+// Router finds the shards of the statements on sharded tables
+type Router struct {
+	tables []*Table
+}
+
+func NewRouter(cfgs []config.ShardConfig) (*Router, error) {
+	r := &Router{}
+	for i := range cfgs {
+		cfg := &cfgs[i]
+		if cfg.Table == "" || cfg.Key == "" || len(cfg.Shards) == 0 {
+			return nil, fmt.Errorf("sharded table %q needs a key and shards", cfg.Table)
+		}
+		rule, err := newRule(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("sharded table %s: %s", cfg.Table, err.Error())
+		}
+		r.tables = append(r.tables, &Table{
+			schema: cfg.Schema,
+			name:   cfg.Table,
+			key:    cfg.Key,
+			shards: cfg.Shards,
+			rule:   rule,
+		})
+	}
+	return r, nil
+}
+
+// Clusters are the clusters the shards are on
+func (self *Router) Clusters() []string {
+	var clusters []string
+	for _, t := range self.tables {
+		clusters = append(clusters, t.shards...)
+	}
+	return clusters
+}
+
+func (self *Router) find(schema, name string) *Table {
+	for _, t := range self.tables {
+		if t.name == name && (t.schema == "" || t.schema == schema) {
+			return t
+		}
+	}
+	return nil
+}
+
+// Route is the plan of stmt, nil when it uses no sharded table. The
+// tables without a schema are in db. A sharded table must be alone in
+// the statement, its shards are told by the key in the values of an
+// insert or the equalities and IN lists on the key in the where clause.
+// It runs on all shards otherwise.
+func (self *Router) Route(stmt sql.IStatement, db string) (*Plan, error) {
+	if len(self.tables) == 0 {
+		return nil, nil
+	}
+	switch s := stmt.(type) {
+	case sql.ISelect:
+		if err := self.alone(collect(nil, s, db)); err != nil {
+			return nil, err
+		}
+		return self.routeSelect(s, db)
+	case *sql.Insert:
+		return self.routeInsert(s, s.InsertFields, s.OnDup, db)
+	case *sql.Replace:
+		return self.routeInsert(s, s.ReplaceFields, nil, db)
+	case *sql.Update:
+		return self.routeWhere(s, s.Where, s.Set, db)
+	case *sql.Delete:
+		return self.routeWhere(s, s.Where, nil, db)
+	}
+	return nil, nil
+}
+
+// alone fails when a sharded table of refs is used with another table or
+// in a subquery, the statement would see the rows of one shard only
+func (self *Router) alone(refs []tableRef) error {
+	var t *Table
+	for _, ref := range refs {
+		if t = self.find(ref.schema, ref.name); t != nil {
+			break
+		}
+	}
+	if t == nil {
+		return nil
+	}
+	for _, ref := range refs {
+		if ref.nested {
+			return fmt.Errorf("sharded table %s can not be used with a subquery", t.Name())
+		}
+		if self.find(ref.schema, ref.name) != t {
+			return fmt.Errorf("sharded table %s can not be used with other tables", t.Name())
+		}
+	}
+	return nil
+}
+
+func (self *Router) routeSelect(stmt sql.ISelect, db string) (*Plan, error) {
+	switch s := stmt.(type) {
+	case *sql.Select:
+		return self.routeWhere(s, s.Where, nil, db)
+	case *sql.ParenSelect:
+		return self.routeSelect(s.Select, db)
+	case *sql.SubQuery:
+		return self.routeSelect(s.SelectStatement, db)
+	case *sql.Union:
+		l, err := self.routeSelect(s.Left, db)
+		if err != nil {
+			return nil, err
+		}
+		r, err := self.routeSelect(s.Right, db)
+		if err != nil || (l == nil && r == nil) {
+			return nil, err
+		}
+		//a union is fine on one shard only
+		if l != nil && r != nil && len(l.Shards) == 1 && len(r.Shards) == 1 &&
+			l.Clusters()[0] == r.Clusters()[0] {
+			return l, nil
+		}
+		return nil, fmt.Errorf("UNION on sharded table %s must stay on one shard", sharded(l, r).Name())
+	}
+	return nil, nil
+}
+
+func sharded(plans ...*Plan) *Table {
+	for _, p := range plans {
+		if p != nil {
+			return p.Table
+		}
+	}
+	return nil
+}
+
+// tableRef is a table of a statement, alias is the name its columns are
+// qualified with. A nested one is in a subquery of an expression.
+type tableRef struct {
+	schema string
+	name   string
+	alias  string
+	nested bool
+}
+
+// collect appends the tables of node, those of derived tables and of the
+// subqueries in its expressions included
+func collect(refs []tableRef, node sql.Node, db string) []tableRef {
+	c := &collector{refs: refs, db: db}
+	sql.Walk(c.visit, node)
+	return c.refs
+}
+
+type collector struct {
+	refs   []tableRef
+	db     string
+	nested bool
+}
+
+func (self *collector) visit(node sql.Node) (bool, error) {
+	switch v := node.(type) {
+	case *sql.AliasedTable:
+		switch tv := v.TableOrSubQuery.(type) {
+		case *sql.SimpleTable:
+			ref := self.ref(tv)
+			if len(v.As) > 0 {
+				ref.alias = string(v.As)
+			}
+			self.refs = append(self.refs, ref)
+			return false, nil
+		case *sql.SubQuery:
+			//a derived table is a table of the statement
+			return false, sql.Walk(self.visit, tv.SelectStatement)
+		}
+	case *sql.SimpleTable:
+		self.refs = append(self.refs, self.ref(v))
+	case *sql.SubQuery:
+		nested := self.nested
+		self.nested = true
+		err := sql.Walk(self.visit, v.SelectStatement)
+		self.nested = nested
+		return false, err
+	}
+	return true, nil
+}
+
+func (self *collector) ref(t *sql.SimpleTable) tableRef {
+	ref := newTableRef(t, self.db)
+	ref.nested = self.nested
+	return ref
+}
+
+func newTableRef(t *sql.SimpleTable, db string) tableRef {
+	ref := tableRef{schema: db, name: string(t.Name), alias: string(t.Name)}
+	if len(t.Qualifier) > 0 {
+		ref.schema = string(t.Qualifier)
+	}
+	return ref
+}
+
+// shardedRef is the one sharded table of refs, an error when it is
+// joined with other tables, itself included, or used with a subquery
+func (self *Router) shardedRef(refs []tableRef) (*Table, *tableRef, error) {
+	if err := self.alone(refs); err != nil {
+		return nil, nil, err
+	}
+	for i := range refs {
+		t := self.find(refs[i].schema, refs[i].name)
+		if t == nil {
+			continue
+		}
+		if len(refs) > 1 {
+			return nil, nil, fmt.Errorf("sharded table %s can not be used with other tables", t.Name())
+		}
+		return t, &refs[i], nil
+	}
+	return nil, nil, nil
+}
+
+func (self *Router) routeWhere(stmt sql.Node, where sql.IExpr, set []*sql.Assignment, db string) (*Plan, error) {
+	t, ref, err := self.shardedRef(collect(nil, stmt, db))
+	if t == nil || err != nil {
+		return nil, err
+	}
+	for _, a := range set {
+		if ref.isKey(t, a.Column) {
+			return nil, fmt.Errorf("the key %s of sharded table %s can not be changed", t.key, t.Name())
+		}
+	}
+
+	m := &matcher{t: t, ref: ref}
+	shards, ok, err := m.where(where)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		shards = allShards(len(t.shards))
+	}
+	return &Plan{Table: t, Shards: shards.list()}, nil
+}
+
+func (self *Router) routeInsert(stmt sql.Node, fields interface{}, onDup []*sql.Assignment, db string) (*Plan, error) {
+	t, ref, err := self.shardedRef(collect(nil, stmt, db))
+	if t == nil || err != nil {
+		return nil, err
+	}
+	for _, a := range onDup {
+		if ref.isKey(t, a.Column) {
+			return nil, fmt.Errorf("the key %s of sharded table %s can not be changed", t.key, t.Name())
+		}
+	}
+	values, ok := fields.(*sql.InsertValues)
+	if !ok {
+		return nil, fmt.Errorf("INSERT into sharded table %s must list its values", t.Name())
+	}
+
+	key := -1
+	for i, c := range values.Columns {
+		if ref.isKey(t, c) {
+			key = i
+		}
+	}
+	if key < 0 {
+		return nil, fmt.Errorf("INSERT into sharded table %s must set its key %s", t.Name(), t.key)
+	}
+
+	shards := make(shardSet, len(t.shards))
+	for _, row := range values.Rows {
+		if key >= len(row) {
+			return nil, fmt.Errorf("INSERT into sharded table %s has a row without its key %s", t.Name(), t.key)
+		}
+		v, ok := literal(row[key])
+		if !ok {
+			return nil, fmt.Errorf("INSERT into sharded table %s must set its key %s to a literal", t.Name(), t.key)
+		}
+		i, err := t.rule.shard(v)
+		if err != nil {
+			return nil, fmt.Errorf("sharded table %s: %s", t.Name(), err.Error())
+		}
+		shards[i] = true
+	}
+	return &Plan{Table: t, Shards: shards.list()}, nil
+}
+
+// isKey is whether e is the key column of t
+func (self *tableRef) isKey(t *Table, e sql.IExpr) bool {
+	if p, ok := e.(*sql.Predicate); ok {
+		e = p.Expr
+	}
+	c, ok := e.(*sql.SchemaObject)
+	if !ok || c == nil || !strings.EqualFold(string(c.Column), t.key) {
+		return false
+	}
+	if len(c.Schema) > 0 && string(c.Schema) != self.schema {
+		return false
+	}
+	return len(c.Table) == 0 || string(c.Table) == self.alias
+}
+
+// shardSet has a true for each shard a statement may run on
+type shardSet []bool
+
+func allShards(n int) shardSet {
+	s := make(shardSet, n)
+	for i := range s {
+		s[i] = true
+	}
+	return s
+}
+
+func (self shardSet) list() []int {
+	var l []int
+	for i, in := range self {
+		if in {
+			l = append(l, i)
+		}
+	}
+	return l
+}
+
+// matcher finds the shards of a where clause on the key of t
+type matcher struct {
+	t   *Table
+	ref *tableRef
+}
+
+// where is the shards e restricts the key to, ok is false when it does
+// not
+func (self *matcher) where(e sql.IExpr) (shardSet, bool, error) {
+	switch v := e.(type) {
+	case *sql.AndExpr:
+		l, lok, err := self.where(v.Left)
+		if err != nil {
+			return nil, false, err
+		}
+		r, rok, err := self.where(v.Right)
+		if err != nil || !rok {
+			return l, lok, err
+		}
+		if !lok {
+			return r, true, nil
+		}
+		for i := range l {
+			l[i] = l[i] && r[i]
+		}
+		return l, true, nil
+	case *sql.OrExpr:
+		l, lok, err := self.where(v.Left)
+		if err != nil || !lok {
+			return nil, false, err
+		}
+		r, rok, err := self.where(v.Right)
+		if err != nil || !rok {
+			return nil, false, err
+		}
+		for i := range l {
+			l[i] = l[i] || r[i]
+		}
+		return l, true, nil
+	case *sql.Predicate:
+		return self.where(v.Expr)
+	case sql.IExprs:
+		if len(v) == 1 {
+			return self.where(v[0])
+		}
+	case *sql.CompareExpr:
+		if v.Operator != sql.OP_EQ && v.Operator != sql.OP_NSE {
+			break
+		}
+		if self.ref.isKey(self.t, v.Left) {
+			return self.values(v.Right)
+		}
+		if self.ref.isKey(self.t, v.Right) {
+			return self.values(v.Left)
+		}
+	case *sql.InCond:
+		if v.Operator == sql.OP_IN && self.ref.isKey(self.t, v.Left) {
+			return self.values(v.Right...)
+		}
+	}
+	return nil, false, nil
+}
+
+// values is the shards of the keys in es, ok is false when one is not a
+// literal
+func (self *matcher) values(es ...sql.IExpr) (shardSet, bool, error) {
+	shards := make(shardSet, len(self.t.shards))
+	for _, e := range es {
+		v, ok := literal(e)
+		if !ok {
+			return nil, false, nil
+		}
+		i, err := self.t.rule.shard(v)
+		if err != nil {
+			return nil, false, fmt.Errorf("sharded table %s: %s", self.t.Name(), err.Error())
+		}
+		shards[i] = true
+	}
+	return shards, true, nil
+}
+
+// literal is the text of a number or string literal, a temporal literal
+// like DATE '2006-01-02' included
+func literal(e sql.IExpr) (string, bool) {
+	switch v := e.(type) {
+	case *sql.Predicate:
+		return literal(v.Expr)
+	case sql.NumVal:
+		return string(v), true
+	case sql.StrVal:
+		//DEFAULT is kept without quotes
+		i := strings.IndexAny(string(v), `'"`)
+		if i < 0 {
+			return "", false
+		}
+		return sql.StrVal(v[i:]).Trim(), true
+	case *sql.UnaryExpr:
+		if v.Operator != sql.OP_UMINUS {
+			return "", false
+		}
+		if n, ok := v.Expr.(sql.NumVal); ok {
+			return "-" + string(n), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,166 @@
+package shard
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/lostz/Aegis/config"
+	"github.com/lostz/Aegis/sql"
+)
+
+func newTestRouter(t *testing.T) *Router {
+	r, err := NewRouter([]config.ShardConfig{
+		{Table: "orders", Key: "user_id", Rule: RuleHash, Shards: []string{"s0", "s1", "s2", "s3"}},
+		{Schema: "app", Table: "users", Key: "id", Rule: RuleRange, Shards: []string{"low", "mid", "high"}, Ranges: []int64{100, 1000}},
+		{Table: "logs", Key: "day", Rule: RuleDate, Shards: []string{"old", "y2023", "new"}, Dates: []string{"2023-01-01", "2024-01-01"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func route(t *testing.T, r *Router, query string) (*Plan, error) {
+	stmt, err := sql.Parse(query)
+	if err != nil {
+		t.Fatalf("parse %s: %s", query, err.Error())
+	}
+	return r.Route(stmt, "app")
+}
+
+func TestRouteShards(t *testing.T) {
+	r := newTestRouter(t)
+	for _, c := range []struct {
+		query    string
+		clusters []string
+	}{
+		{"SELECT * FROM orders WHERE user_id = 5", []string{"s1"}},
+		{"SELECT * FROM orders WHERE user_id = '6' AND status = 1", []string{"s2"}},
+		{"SELECT * FROM orders o WHERE o.user_id IN (1, 2, 5)", []string{"s1", "s2"}},
+		{"SELECT * FROM orders WHERE user_id = 1 OR user_id = 3", []string{"s1", "s3"}},
+		{"SELECT * FROM orders WHERE user_id IN (1, 2) AND user_id = 2", []string{"s2"}},
+		{"SELECT * FROM orders WHERE 7 = user_id", []string{"s3"}},
+		{"SELECT * FROM orders WHERE status = 1", []string{"s0", "s1", "s2", "s3"}},
+		{"SELECT * FROM orders WHERE user_id > 5", []string{"s0", "s1", "s2", "s3"}},
+		{"SELECT * FROM orders WHERE user_id = ?", []string{"s0", "s1", "s2", "s3"}},
+		{"SELECT * FROM orders WHERE user_id = 1 OR status = 2", []string{"s0", "s1", "s2", "s3"}},
+		{"SELECT * FROM app.users WHERE id = 99", []string{"low"}},
+		{"SELECT * FROM users WHERE id IN (100, 5000)", []string{"mid", "high"}},
+		{"UPDATE users SET name = 'x' WHERE id = 500", []string{"mid"}},
+		{"DELETE FROM users WHERE id = 1000", []string{"high"}},
+		{"SELECT * FROM logs WHERE day = '2023-06-30 12:00:00'", []string{"y2023"}},
+		{"SELECT * FROM logs WHERE day = DATE '2022-12-31'", []string{"old"}},
+		{"INSERT INTO orders (id, user_id) VALUES (1, 4), (2, 8)", []string{"s0"}},
+		{"INSERT INTO orders (id, user_id) VALUES (1, 4), (2, 9)", []string{"s0", "s1"}},
+		{"INSERT INTO orders SET user_id = 3, id = 1", []string{"s3"}},
+		{"REPLACE INTO logs (day, msg) VALUES ('2024-02-01', 'x')", []string{"new"}},
+	} {
+		p, err := route(t, r, c.query)
+		if err != nil {
+			t.Fatalf("%s: %s", c.query, err.Error())
+		}
+		if p == nil {
+			t.Fatalf("%s: not sharded", c.query)
+		}
+		if clusters := p.Clusters(); !reflect.DeepEqual(clusters, c.clusters) {
+			t.Fatalf("%s: clusters %v, want %v", c.query, clusters, c.clusters)
+		}
+	}
+}
+
+func TestRouteNotSharded(t *testing.T) {
+	r := newTestRouter(t)
+	for _, query := range []string{
+		"SELECT * FROM t WHERE user_id = 5",
+		"SELECT * FROM other.users WHERE id = 5",
+		"INSERT INTO t (a) VALUES (1)",
+		"SELECT 1",
+	} {
+		p, err := route(t, r, query)
+		if err != nil || p != nil {
+			t.Fatalf("%s: plan %v, error %v", query, p, err)
+		}
+	}
+}
+
+func TestRouteErrors(t *testing.T) {
+	r := newTestRouter(t)
+	for _, query := range []string{
+		"SELECT * FROM orders JOIN t ON orders.id = t.id WHERE user_id = 1",
+		"INSERT INTO orders VALUES (1, 2)",
+		"INSERT INTO orders (id) VALUES (1)",
+		"INSERT INTO orders (id, user_id) VALUES (1, now())",
+		"INSERT INTO orders (id, user_id) SELECT id, user_id FROM t",
+		"INSERT INTO t (id, user_id) SELECT id, user_id FROM orders",
+		"UPDATE orders SET user_id = 2 WHERE user_id = 1",
+		"INSERT INTO orders (id, user_id) VALUES (1, 2) ON DUPLICATE KEY UPDATE user_id = 3",
+		"SELECT * FROM users WHERE id = 'abc'",
+		"SELECT * FROM orders WHERE user_id = 1 UNION SELECT * FROM orders WHERE user_id = 2",
+		"SELECT * FROM t WHERE id IN (SELECT user_id FROM orders)",
+		"SELECT (SELECT count(*) FROM orders)",
+		"DELETE FROM t WHERE id IN (SELECT id FROM orders WHERE user_id = 1)",
+		"SELECT * FROM orders WHERE user_id = 1 AND id IN (SELECT id FROM t)",
+		"SELECT * FROM orders WHERE user_id = 1 UNION SELECT * FROM t",
+	} {
+		if _, err := route(t, r, query); err == nil {
+			t.Fatalf("%s: no error", query)
+		}
+	}
+
+	p, err := route(t, r, "SELECT * FROM orders WHERE user_id = 1 UNION SELECT * FROM orders WHERE user_id = 5")
+	if err != nil || len(p.Shards) != 1 {
+		t.Fatalf("union on one shard: plan %v, error %v", p, err)
+	}
+}
+
+func TestPlanClusters(t *testing.T) {
+	r, err := NewRouter([]config.ShardConfig{
+		{Table: "t", Key: "id", Rule: RuleHash, Shards: []string{"a", "a", "b", "b"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := route(t, r, "SELECT * FROM t WHERE id IN (0, 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clusters := p.Clusters(); !reflect.DeepEqual(clusters, []string{"a"}) {
+		t.Fatalf("clusters %v", clusters)
+	}
+}
+
+func TestNewRouterErrors(t *testing.T) {
+	for _, cfg := range []config.ShardConfig{
+		{Table: "t", Key: "id", Rule: "modulo", Shards: []string{"a"}},
+		{Table: "t", Key: "id", Rule: RuleHash},
+		{Table: "t", Rule: RuleHash, Shards: []string{"a"}},
+		{Table: "t", Key: "id", Rule: RuleRange, Shards: []string{"a", "b"}},
+		{Table: "t", Key: "id", Rule: RuleRange, Shards: []string{"a", "b", "c"}, Ranges: []int64{10, 5}},
+		{Table: "t", Key: "id", Rule: RuleRange, Shards: []string{"a", "b", "c"}, Ranges: []int64{10, 10}},
+		{Table: "t", Key: "id", Rule: RuleDate, Shards: []string{"a", "b"}, Dates: []string{"2023"}},
+		{Table: "t", Key: "id", Rule: RuleDate, Shards: []string{"a", "b", "c"}, Dates: []string{"2023-01-01", "2022-01-01"}},
+	} {
+		if _, err := NewRouter([]config.ShardConfig{cfg}); err == nil {
+			t.Fatalf("%+v: no error", cfg)
+		}
+	}
+}
+
+func TestRules(t *testing.T) {
+	h := hashRule(4)
+	for key, want := range map[string]int{"0": 0, "5": 1, "-1": 3, "abc": int(891568578 % 4)} {
+		if i, _ := h.shard(key); i != want {
+			t.Fatalf("hash %s: shard %d, want %d", key, i, want)
+		}
+	}
+
+	rg := rangeRule{10, 20}
+	for key, want := range map[string]int{"-5": 0, "9": 0, "10": 1, "19": 1, "20": 2, "1000": 2} {
+		if i, _ := rg.shard(key); i != want {
+			t.Fatalf("range %s: shard %d, want %d", key, i, want)
+		}
+	}
+	if _, err := rg.shard("1.5"); err == nil {
+		t.Fatal("range took 1.5")
+	}
+}
@@ -0,0 +1,100 @@
+package shard
+
+import (
+	"fmt"
+	"hash/crc32"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/lostz/Aegis/config"
+)
+
+const (
+	RuleHash  = "hash"
+	RuleRange = "range"
+	RuleDate  = "date"
+)
+
+const dateLayout = "2006-01-02"
+
+// rule tells the shard of a key, given as the text of its literal
+type rule interface {
+	shard(key string) (int, error)
+}
+
+func newRule(cfg *config.ShardConfig) (rule, error) {
+	n := len(cfg.Shards)
+	switch cfg.Rule {
+	case RuleHash:
+		return hashRule(n), nil
+	case RuleRange:
+		if len(cfg.Ranges) != n-1 {
+			return nil, fmt.Errorf("%d ranges for %d shards, want %d", len(cfg.Ranges), n, n-1)
+		}
+		for i := 1; i < len(cfg.Ranges); i++ {
+			//equal bounds would leave a shard no key goes to
+			if cfg.Ranges[i] <= cfg.Ranges[i-1] {
+				return nil, fmt.Errorf("ranges %v are not ascending", cfg.Ranges)
+			}
+		}
+		return rangeRule(cfg.Ranges), nil
+	case RuleDate:
+		if len(cfg.Dates) != n-1 {
+			return nil, fmt.Errorf("%d dates for %d shards, want %d", len(cfg.Dates), n, n-1)
+		}
+		dates := make(dateRule, 0, len(cfg.Dates))
+		for _, d := range cfg.Dates {
+			t, err := time.Parse(dateLayout, d)
+			if err != nil {
+				return nil, err
+			}
+			if len(dates) > 0 && !t.After(dates[len(dates)-1]) {
+				return nil, fmt.Errorf("dates %v are not ascending", cfg.Dates)
+			}
+			dates = append(dates, t)
+		}
+		return dates, nil
+	}
+	return nil, fmt.Errorf("unknown rule %q", cfg.Rule)
+}
+
+// hashRule takes an integer key modulo the number of shards, any other
+// key by its crc32. A string holding an integer is the integer, like
+// mysql compares them.
+type hashRule int
+
+func (self hashRule) shard(key string) (int, error) {
+	n := int64(self)
+	if i, err := strconv.ParseInt(key, 10, 64); err == nil {
+		return int((i%n + n) % n), nil
+	}
+	return int(int64(crc32.ChecksumIEEE([]byte(key))) % n), nil
+}
+
+// rangeRule gives shard i the keys below rangeRule[i], the last shard
+// the rest
+type rangeRule []int64
+
+func (self rangeRule) shard(key string) (int, error) {
+	i, err := strconv.ParseInt(key, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("key %s is not an integer", key)
+	}
+	return sort.Search(len(self), func(j int) bool { return i < self[j] }), nil
+}
+
+// dateRule gives shard i the dates before dateRule[i], the last shard
+// the rest. Only the date of a datetime key counts.
+type dateRule []time.Time
+
+func (self dateRule) shard(key string) (int, error) {
+	if len(key) > len(dateLayout) {
+		key = key[:len(dateLayout)]
+	}
+	t, err := time.Parse(dateLayout, key)
+	if err != nil {
+		return 0, fmt.Errorf("key %s is not a date", key)
+	}
+	return sort.Search(len(self), func(j int) bool { return t.Before(self[j]) }), nil
+}
@@ -0,0 +1,347 @@
+package shard
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lostz/Aegis/sql"
+)
+
+// Scatter is a SELECT on a sharded table rewritten to run on every shard,
+// with what it takes to merge the results as if the table was whole.
+// The shards return the columns of the statement, then hidden ones for
+// the keys and for the counts of the averages.
+type Scatter struct {
+	// SQL is the statement each shard runs
+	SQL string
+
+	hidden   int
+	grouped  bool
+	distinct bool
+	aggs     []aggColumn
+	groups   []column
+	orders   []orderKey
+	offset   int64
+	count    int64
+}
+
+// column is a column of the results. The hidden ones and the items after
+// a * are counted from the end of the columns of the statement, the
+// width of a * is known only from the results.
+type column struct {
+	n       int
+	fromEnd bool
+}
+
+type aggColumn struct {
+	fn  string
+	col column
+
+	// count is the hidden COUNT of an AVG, which col holds the SUM of
+	count column
+}
+
+type orderKey struct {
+	col  column
+	desc bool
+}
+
+type scatterPlanner struct {
+	stmt   *sql.Select
+	scat   *Scatter
+	items  sql.SelectFields
+	hidden sql.SelectFields
+	// stars is the number of * items up to every item
+	stars []int
+}
+
+// aggregates are the aggregate functions, by lower case name
+var aggregates = map[string]bool{
+	"avg":         true,
+	"bit_and":     true,
+	"bit_or":      true,
+	"bit_xor":     true,
+	"count":       true,
+	"max":         true,
+	"min":         true,
+	"std":         true,
+	"stddev":      true,
+	"stddev_pop":  true,
+	"stddev_samp": true,
+	"sum":         true,
+	"variance":    true,
+	"var_pop":     true,
+	"var_samp":    true,
+}
+
+// NewScatter rewrites stmt to run on every shard, stmt is left as it is.
+// Aggregates other than COUNT, SUM, MIN, MAX and AVG, DISTINCT inside
+// them, HAVING on groups and WITH ROLLUP can not be merged.
+func NewScatter(stmt *sql.Select) (*Scatter, error) {
+	if stmt.Into != "" || stmt.Procedure != "" {
+		return nil, fmt.Errorf("INTO and PROCEDURE can not run on several shards")
+	}
+	p := &scatterPlanner{stmt: stmt, scat: &Scatter{count: -1}}
+	for _, o := range stmt.Options {
+		switch o {
+		case sql.SELECT_SQL_CALC_FOUND_ROWS:
+			return nil, fmt.Errorf("SQL_CALC_FOUND_ROWS can not run on several shards")
+		case sql.SELECT_DISTINCT:
+			p.scat.distinct = true
+		}
+	}
+	if stmt.Rollup {
+		return nil, fmt.Errorf("WITH ROLLUP can not run on several shards")
+	}
+
+	stars := 0
+	for _, f := range stmt.Fields {
+		if _, ok := f.(*sql.StarExpr); ok {
+			stars++
+		}
+		p.stars = append(p.stars, stars)
+	}
+	for i, f := range stmt.Fields {
+		item, err := p.item(i, f)
+		if err != nil {
+			return nil, err
+		}
+		p.items = append(p.items, item)
+	}
+	p.scat.grouped = len(p.scat.aggs) > 0 || len(stmt.GroupBy) > 0
+	if p.scat.grouped && stmt.Having != nil {
+		return nil, fmt.Errorf("HAVING on groups can not run on several shards")
+	}
+
+	for _, g := range stmt.GroupBy {
+		col, err := p.key(g.Expr)
+		if err != nil {
+			return nil, err
+		}
+		p.scat.groups = append(p.scat.groups, col)
+	}
+
+	orders := stmt.OrderBy
+	if len(orders) == 1 {
+		if _, ok := unwrap(orders[0].Expr).(*sql.NullVal); ok {
+			orders = nil
+		}
+	} else if orders == nil {
+		//like mysql 5.7, groups come sorted by their keys
+		orders = sql.OrderBy(stmt.GroupBy)
+	}
+	for _, o := range orders {
+		col, err := p.key(o.Expr)
+		if err != nil {
+			return nil, err
+		}
+		p.scat.orders = append(p.scat.orders, orderKey{col: col, desc: o.Direction == sql.OP_DESC})
+	}
+
+	if err := p.limit(stmt.Limit); err != nil {
+		return nil, err
+	}
+	p.scat.hidden = len(p.hidden)
+	p.scat.SQL = p.build()
+	return p.scat, nil
+}
+
+// unwrap is e without the Predicate around it
+func unwrap(e sql.IExpr) sql.IExpr {
+	if p, ok := e.(*sql.Predicate); ok {
+		return p.Expr
+	}
+	return e
+}
+
+// isAggregate is whether node is a call of an aggregate
+func isAggregate(node sql.Node) bool {
+	switch n := node.(type) {
+	case *sql.FuncExpr:
+		return len(n.Qualifier) == 0 && aggregates[strings.ToLower(string(n.Name))]
+	case *sql.GroupConcatExpr:
+		return true
+	}
+	return false
+}
+
+// hasAggregate is whether an aggregate is called in e, out of its
+// subqueries
+func hasAggregate(e sql.IExpr) bool {
+	found := false
+	sql.Walk(func(node sql.Node) (bool, error) {
+		if _, ok := node.(*sql.SubQuery); ok {
+			return false, nil
+		}
+		if isAggregate(node) {
+			found = true
+		}
+		return !found, nil
+	}, e)
+	return found
+}
+
+// item is the i-th item for the shards
+func (self *scatterPlanner) item(i int, f sql.ISelectField) (sql.ISelectField, error) {
+	af, ok := f.(*sql.AliasedField)
+	if !ok || !hasAggregate(af.Expr) {
+		return f, nil
+	}
+
+	col, ok := self.itemColumn(i)
+	if !ok {
+		return nil, fmt.Errorf("aggregate %s between * items", sql.String(af.Expr))
+	}
+	agg, err := self.aggregate(af.Expr, col)
+	if err != nil {
+		return nil, err
+	}
+	if agg.fn == "AVG" {
+		//the shards sum, the proxy divides by the hidden count. The
+		//column keeps the name mysql gives it.
+		name := af.As
+		if len(name) == 0 {
+			name = []byte(sql.String(af.Expr))
+		}
+		f = &sql.AliasedField{Expr: call("SUM", af.Expr), As: name}
+	}
+	self.scat.aggs = append(self.scat.aggs, agg)
+	return f, nil
+}
+
+// call is the aggregate fn on the arguments of e, an aggregate call
+func call(fn string, e sql.IExpr) *sql.FuncExpr {
+	return &sql.FuncExpr{Name: []byte(fn), Exprs: unwrap(e).(*sql.FuncExpr).Exprs}
+}
+
+// aggregate is the merge of expr, held in col
+func (self *scatterPlanner) aggregate(expr sql.IExpr, col column) (aggColumn, error) {
+	f, ok := unwrap(expr).(*sql.FuncExpr)
+	if !ok || !isAggregate(f) {
+		if _, ok := unwrap(expr).(*sql.GroupConcatExpr); ok {
+			return aggColumn{}, fmt.Errorf("%s can not run on several shards", sql.String(expr))
+		}
+		return aggColumn{}, fmt.Errorf("aggregate inside %s can not run on several shards", sql.String(expr))
+	}
+	fn := strings.ToUpper(string(f.Name))
+	switch fn {
+	case "COUNT", "SUM", "AVG":
+		if f.Distinct {
+			return aggColumn{}, fmt.Errorf("%s can not run on several shards", sql.String(expr))
+		}
+	case "MIN", "MAX":
+	default:
+		return aggColumn{}, fmt.Errorf("%s can not run on several shards", sql.String(expr))
+	}
+
+	agg := aggColumn{fn: fn, col: col}
+	if agg.fn == "AVG" {
+		agg.count = self.hide(call("COUNT", f))
+	}
+	return agg, nil
+}
+
+// key is the column holding a GROUP BY or ORDER BY expression: the item
+// at a position, the item of an alias, or a hidden column
+func (self *scatterPlanner) key(expr sql.IExpr) (column, error) {
+	switch e := unwrap(expr).(type) {
+	case sql.NumVal:
+		n, err := strconv.Atoi(string(e))
+		if err != nil || n < 1 {
+			return column{}, fmt.Errorf("unknown column %s", e)
+		}
+		return column{n: n - 1}, nil
+	case *sql.SchemaObject:
+		if len(e.Schema) > 0 || len(e.Table) > 0 {
+			break
+		}
+		for i, f := range self.stmt.Fields {
+			af, ok := f.(*sql.AliasedField)
+			if ok && len(af.As) > 0 && strings.EqualFold(string(e.Column), string(af.As)) {
+				if col, ok := self.itemColumn(i); ok {
+					return col, nil
+				}
+			}
+		}
+	}
+
+	if !hasAggregate(expr) {
+		return self.hide(expr), nil
+	}
+	if !self.scat.grouped {
+		return column{}, fmt.Errorf("aggregate %s without groups", sql.String(expr))
+	}
+	hidden := expr
+	if f, ok := unwrap(expr).(*sql.FuncExpr); ok && strings.EqualFold(string(f.Name), "AVG") {
+		hidden = call("SUM", f)
+	}
+	col := self.hide(hidden)
+	agg, err := self.aggregate(expr, col)
+	if err != nil {
+		return column{}, err
+	}
+	self.scat.aggs = append(self.scat.aggs, agg)
+	return col, nil
+}
+
+// itemColumn is the column of the i-th item, known unless a * is before
+// it and another at or after it
+func (self *scatterPlanner) itemColumn(i int) (column, bool) {
+	if self.stars[i] == 0 {
+		return column{n: i}, true
+	}
+	last := len(self.stars) - 1
+	if _, star := self.stmt.Fields[i].(*sql.StarExpr); star || self.stars[last] != self.stars[i] {
+		return column{}, false
+	}
+	return column{n: i - len(self.stars), fromEnd: true}, true
+}
+
+// hide adds a hidden column
+func (self *scatterPlanner) hide(e sql.IExpr) column {
+	self.hidden = append(self.hidden, &sql.AliasedField{Expr: e})
+	return column{n: len(self.hidden) - 1, fromEnd: true}
+}
+
+// limit takes the LIMIT of the statement, each shard returns the rows up
+// to its end. The groups are complete only after the merge, so for them
+// the shards return everything.
+func (self *scatterPlanner) limit(limit *sql.Limit) error {
+	if limit == nil {
+		return nil
+	}
+	count, err := limitValue(limit.Rowcount)
+	if err != nil {
+		return err
+	}
+	var offset int64
+	if limit.Offset != nil {
+		if offset, err = limitValue(limit.Offset); err != nil {
+			return err
+		}
+	}
+	self.scat.offset = offset
+	self.scat.count = count
+	return nil
+}
+
+func limitValue(v sql.IValExpr) (int64, error) {
+	n, ok := v.(sql.NumVal)
+	if !ok {
+		return 0, fmt.Errorf("LIMIT of several shards must be a number")
+	}
+	return strconv.ParseInt(string(n), 10, 64)
+}
+
+// build formats a copy of the statement with the items, the hidden
+// columns and the LIMIT of the shards
+func (self *scatterPlanner) build() string {
+	st := *self.stmt
+	st.Fields = append(append(sql.SelectFields{}, self.items...), self.hidden...)
+	st.Limit = nil
+	if self.scat.count >= 0 && !self.scat.grouped {
+		st.Limit = &sql.Limit{Rowcount: sql.NumVal(strconv.FormatInt(self.scat.offset+self.scat.count, 10))}
+	}
+	return sql.String(&st)
+}
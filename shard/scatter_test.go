@@ -0,0 +1,261 @@
+package shard
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/lostz/Aegis/mysql"
+	"github.com/lostz/Aegis/sql"
+)
+
+func newTestScatter(t *testing.T, query string) (*Scatter, error) {
+	stmt, err := sql.Parse(query)
+	if err != nil {
+		t.Fatalf("parse %s: %s", query, err.Error())
+	}
+	return NewScatter(stmt.(*sql.Select))
+}
+
+// field is a column in the binary collation, which mysql gives numbers
+func field(name string, typ uint8, decimal uint8) *mysql.Field {
+	return &mysql.Field{Name: []byte(name), Type: typ, Decimal: decimal, Charset: binaryCollation}
+}
+
+// resultset is a shard result of rows of int, string or nil values
+func resultset(t *testing.T, fields []*mysql.Field, rows ...[]interface{}) *mysql.Resultset {
+	r := &mysql.Resultset{Fields: fields}
+	for _, values := range rows {
+		var data []byte
+		for _, v := range values {
+			if s, ok := v.(string); ok {
+				v = []byte(s)
+			} else if n, ok := v.(int); ok {
+				v = int64(n)
+			}
+			data = append(data, encodeCell(v)...)
+		}
+		parsed, err := mysql.RowData(data).ParseText(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.RowDatas = append(r.RowDatas, data)
+		r.Values = append(r.Values, parsed)
+	}
+	return r
+}
+
+// rows are the values of r as text, NULL for nil
+func rows(t *testing.T, r *mysql.Resultset) [][]string {
+	var list [][]string
+	for i, data := range r.RowDatas {
+		values, err := data.ParseText(r.Fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, r.Values[i]) {
+			t.Fatalf("row %d: data %v, values %v", i, values, r.Values[i])
+		}
+		var row []string
+		for _, v := range values {
+			switch s := v.(type) {
+			case nil:
+				row = append(row, "NULL")
+			case []byte:
+				row = append(row, string(s))
+			default:
+				row = append(row, fmt.Sprint(s))
+			}
+		}
+		list = append(list, row)
+	}
+	return list
+}
+
+func TestScatterSQL(t *testing.T) {
+	for _, c := range []struct {
+		query string
+		sql   string
+	}{
+		{"SELECT * FROM orders", "select * from orders"},
+		{"SELECT id, name n FROM orders WHERE status = 1 ORDER BY n DESC, id LIMIT 10, 5",
+			"select id, `name` as n, id from orders where `status` = 1 order by n desc, id limit 15"},
+		{"SELECT user_id, COUNT(*), AVG(amount) a FROM orders GROUP BY user_id ORDER BY a DESC LIMIT 3",
+			"select user_id, COUNT(*), SUM(amount) as a, COUNT(amount), user_id from orders group by user_id order by a desc"},
+		{"SELECT avg(x) FROM orders", "select SUM(x) as `avg(x)`, COUNT(x) from orders"},
+		{"SELECT DISTINCT status FROM orders ORDER BY 1 LIMIT 2 FOR UPDATE",
+			"select distinct `status` from orders order by 1 limit 2 for update"},
+		{"SELECT status, COUNT(*) FROM orders GROUP BY status ORDER BY COUNT(*) DESC",
+			"select `status`, COUNT(*), `status`, COUNT(*) from orders group by `status` order by COUNT(*) desc"},
+		{"SELECT id, (SELECT MAX(x) FROM t) FROM orders LIMIT 2", "select id, (select MAX(x) from t) from orders limit 2"},
+	} {
+		stmt, err := sql.Parse(c.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		orig := sql.String(stmt)
+		s, err := NewScatter(stmt.(*sql.Select))
+		if err != nil {
+			t.Fatalf("%s: %s", c.query, err.Error())
+		}
+		if s.SQL != c.sql {
+			t.Fatalf("%s: sql %q, want %q", c.query, s.SQL, c.sql)
+		}
+		if q := sql.String(stmt); q != orig {
+			t.Fatalf("%s: statement changed to %q", c.query, q)
+		}
+	}
+}
+
+func TestScatterErrors(t *testing.T) {
+	for _, query := range []string{
+		"SELECT COUNT(DISTINCT user_id) FROM orders",
+		"SELECT GROUP_CONCAT(id) FROM orders",
+		"SELECT SUM(x) + 1 FROM orders",
+		"SELECT status, COUNT(*) c FROM orders GROUP BY status HAVING c > 1",
+		"SELECT SQL_CALC_FOUND_ROWS * FROM orders LIMIT 1",
+		"SELECT * FROM orders LIMIT ?",
+		"SELECT * FROM orders ORDER BY COUNT(*)",
+		"SELECT o.*, COUNT(*), o.* FROM orders o",
+	} {
+		if _, err := newTestScatter(t, query); err == nil {
+			t.Fatalf("%s: no error", query)
+		}
+	}
+}
+
+func TestMergeOrder(t *testing.T) {
+	s, err := newTestScatter(t, "SELECT * FROM orders ORDER BY name DESC, id LIMIT 1, 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []*mysql.Field{
+		field("id", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("name", mysql.MYSQL_TYPE_VAR_STRING, 0),
+		field("name", mysql.MYSQL_TYPE_VAR_STRING, 0),
+		field("id", mysql.MYSQL_TYPE_LONGLONG, 0),
+	}
+	r, err := s.Merge([]*mysql.Resultset{
+		resultset(t, fields, []interface{}{1, "b", "b", 1}, []interface{}{4, "a", "a", 4}),
+		resultset(t, fields),
+		resultset(t, fields, []interface{}{10, "c", "c", 10}, []interface{}{3, "b", "b", 3}, []interface{}{2, nil, nil, 2}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"1", "b"}, {"3", "b"}, {"4", "a"}}
+	if got := rows(t, r); !reflect.DeepEqual(got, want) || len(r.Fields) != 2 {
+		t.Fatalf("rows %v, want %v", got, want)
+	}
+}
+
+func TestMergeGroups(t *testing.T) {
+	s, err := newTestScatter(t, "SELECT user_id, COUNT(*), AVG(amount) a, MAX(name) FROM orders GROUP BY user_id ORDER BY a DESC LIMIT 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []*mysql.Field{
+		field("user_id", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("COUNT(*)", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("a", mysql.MYSQL_TYPE_NEWDECIMAL, 2),
+		field("MAX(name)", mysql.MYSQL_TYPE_VAR_STRING, 0),
+		field("COUNT(amount)", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("user_id", mysql.MYSQL_TYPE_LONGLONG, 0),
+	}
+	r, err := s.Merge([]*mysql.Resultset{
+		resultset(t, fields,
+			[]interface{}{1, 2, "10.50", "x", 2, 1},
+			[]interface{}{2, 1, "5.00", "b", 1, 2},
+			[]interface{}{3, 1, nil, nil, 0, 3}),
+		resultset(t, fields,
+			[]interface{}{1, 2, "20.00", "z", 1, 1},
+			[]interface{}{2, 3, "100.00", "a", 2, 2}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"2", "4", "35.000000", "b"}, {"1", "4", "10.166667", "z"}}
+	if got := rows(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows %v, want %v", got, want)
+	}
+	if f := r.Fields[2]; f.Decimal != 6 || f.Data != nil {
+		t.Fatalf("avg field decimal %d", f.Decimal)
+	}
+}
+
+func TestMergeAggregates(t *testing.T) {
+	s, err := newTestScatter(t, "SELECT COUNT(*), SUM(x), MIN(name), MAX(d) FROM orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []*mysql.Field{
+		field("COUNT(*)", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("SUM(x)", mysql.MYSQL_TYPE_NEWDECIMAL, 0),
+		field("MIN(name)", mysql.MYSQL_TYPE_VAR_STRING, 0),
+		field("MAX(d)", mysql.MYSQL_TYPE_NEWDECIMAL, 1),
+	}
+	r, err := s.Merge([]*mysql.Resultset{
+		resultset(t, fields, []interface{}{3, "9", "m", "9.5"}),
+		resultset(t, fields, []interface{}{0, nil, nil, nil}),
+		resultset(t, fields, []interface{}{2, "12345678901234567890", "c", "10.0"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"5", "12345678901234567899", "c", "10.0"}}
+	if got := rows(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows %v, want %v", got, want)
+	}
+}
+
+func TestMergeDistinct(t *testing.T) {
+	s, err := newTestScatter(t, "SELECT DISTINCT status FROM orders ORDER BY status LIMIT 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := []*mysql.Field{
+		field("status", mysql.MYSQL_TYPE_LONGLONG, 0),
+		field("status", mysql.MYSQL_TYPE_LONGLONG, 0),
+	}
+	r, err := s.Merge([]*mysql.Resultset{
+		resultset(t, fields, []interface{}{1, 1}, []interface{}{3, 3}),
+		resultset(t, fields, []interface{}{1, 1}, []interface{}{2, 2}, []interface{}{10, 10}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"1"}, {"2"}, {"3"}}
+	if got := rows(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows %v, want %v", got, want)
+	}
+}
+
+func TestMergeCollation(t *testing.T) {
+	s, err := newTestScatter(t, "SELECT name FROM orders ORDER BY name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//utf8_general_ci
+	ci := field("name", mysql.MYSQL_TYPE_VAR_STRING, 0)
+	ci.Charset = 33
+	fields := []*mysql.Field{ci, ci}
+	if _, err := s.Merge([]*mysql.Resultset{resultset(t, fields, []interface{}{"b", "b"})}); err == nil {
+		t.Fatal("ORDER BY on a utf8_general_ci column merged")
+	}
+
+	//utf8_bin pads with spaces
+	bin := field("name", mysql.MYSQL_TYPE_VAR_STRING, 0)
+	bin.Charset, bin.Flag = 83, mysql.BINARY_FLAG
+	fields = []*mysql.Field{bin, bin}
+	r, err := s.Merge([]*mysql.Resultset{
+		resultset(t, fields, []interface{}{"b ", "b "}, []interface{}{"c", "c"}),
+		resultset(t, fields, []interface{}{"a", "a"}, []interface{}{"b", "b"}),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]string{{"a"}, {"b "}, {"b"}, {"c"}}
+	if got := rows(t, r); !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows %v, want %v", got, want)
+	}
+}
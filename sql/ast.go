@@ -1,9 +1,39 @@
 package sql
 
+import (
+	"strings"
+)
+
 type IStatement interface {
 	IStatement()
+	Node
 }
 
 func SetParseTree(yylex interface{}, stmt IStatement) {
 	yylex.(*SQLLexer).ParseTree = stmt
 }
+
+// symbolEnd is the offset past the last token of the rule an action
+// reduces, lookahead is the token the parser read beyond it or -1
+func symbolEnd(yylex interface{}, lookahead int) int {
+	lex := yylex.(*SQLLexer)
+	if lookahead >= 0 {
+		return int(lex.last_end_prev)
+	}
+	return int(lex.last_end)
+}
+
+// textFrom is the text of a rule from offset start to its end, the
+// clauses of a statement the tree does not model are kept as it
+func textFrom(yylex interface{}, lookahead int, start int) string {
+	return textBetween(yylex, start, symbolEnd(yylex, lookahead))
+}
+
+// textBetween is the text from offset start to end, without the blanks
+// around it
+func textBetween(yylex interface{}, start, end int) string {
+	if start >= end {
+		return ""
+	}
+	return strings.TrimSpace(string(yylex.(*SQLLexer).buf[start:end]))
+}
@@ -1,47 +1,94 @@
 package sql
 
+// AlterTable is `ALTER [IGNORE] TABLE Table [Commands]`, Commands is the
+// text of what is altered
 type AlterTable struct {
-	Table ISimpleTable
+	Ignore   bool
+	Table    ISimpleTable
+	Commands string
 }
 
 func (*AlterTable) IStatement()    {}
 func (*AlterTable) IDDLStatement() {}
 
+func (a *AlterTable) Format(buf *Buffer) {
+	buf.WriteString("alter ")
+	if a.Ignore {
+		buf.WriteString("ignore ")
+	}
+	buf.Printf("table %v", a.Table)
+	if a.Commands != "" {
+		buf.Printf(" %s", a.Commands)
+	}
+}
+
+// AlterDatabase is `ALTER DATABASE [Schema] Options`, Options is the text
+// of the character set and collation or of UPGRADE DATA DIRECTORY NAME
 type AlterDatabase struct {
-	Schema []byte
+	Schema  []byte
+	Options string
 }
 
 func (*AlterDatabase) IStatement()    {}
 func (*AlterDatabase) IDDLStatement() {}
 
+func (a *AlterDatabase) Format(buf *Buffer) {
+	buf.WriteString("alter database ")
+	if len(a.Schema) > 0 {
+		buf.formatName(a.Schema)
+		buf.WriteByte(' ')
+	}
+	buf.WriteString(a.Options)
+}
+
+// AlterProcedure is `ALTER PROCEDURE Procedure [Characteristics]`,
+// Characteristics is their text
 type AlterProcedure struct {
-	Procedure *Spname
+	Procedure       *Spname
+	Characteristics string
 }
 
 func (*AlterProcedure) IStatement()    {}
 func (*AlterProcedure) IDDLStatement() {}
 
+func (a *AlterProcedure) Format(buf *Buffer) {
+	buf.Printf("alter procedure %v", a.Procedure)
+	if a.Characteristics != "" {
+		buf.Printf(" %s", a.Characteristics)
+	}
+}
+
+// AlterFunction is `ALTER FUNCTION Function [Characteristics]`
 type AlterFunction struct {
-	Function *Spname
+	Function        *Spname
+	Characteristics string
 }
 
 func (*AlterFunction) IStatement()    {}
 func (*AlterFunction) IDDLStatement() {}
 
+func (a *AlterFunction) Format(buf *Buffer) {
+	buf.Printf("alter function %v", a.Function)
+	if a.Characteristics != "" {
+		buf.Printf(" %s", a.Characteristics)
+	}
+}
+
 /*************************
  * Alter View Statement
  *************************/
 func (*AlterView) IStatement()    {}
 func (*AlterView) IDDLStatement() {}
 
+// AlterView is `ALTER [Options] VIEW View [(Columns)] AS As
+// [CheckOption]`, Options is the text of the ALGORITHM, DEFINER and SQL
+// SECURITY clauses
 type AlterView struct {
-	View ISimpleTable
-	As   ISelect
-}
-
-type viewTail struct {
-	View ISimpleTable
-	As   ISelect
+	Options     string
+	View        ISimpleTable
+	Columns     [][]byte
+	As          ISelect
+	CheckOption string
 }
 
 func (av *AlterView) GetSchemas() []string {
@@ -54,6 +101,11 @@ func (av *AlterView) GetSchemas() []string {
 	return d
 }
 
+func (av *AlterView) Format(buf *Buffer) {
+	buf.WriteString("alter ")
+	formatView(buf, av.Options, av.View, av.Columns, av.As, av.CheckOption)
+}
+
 /*************************
  * Alter Event Statement
  *************************/
@@ -68,22 +120,51 @@ func (a *AlterEvent) GetSchemas() []string {
 	return GetSchemas(a.Event.GetSchemas(), a.Rename.GetSchemas())
 }
 
+// AlterEvent is `ALTER [Definer] EVENT Event [Schedule] [RENAME TO Rename]
+// [Definition]`, Schedule is the text of the schedule and completion,
+// Definition of the status, comment and body
 type AlterEvent struct {
-	Event  *Spname
-	Rename *Spname
+	Definer    string
+	Event      *Spname
+	Schedule   string
+	Rename     *Spname
+	Definition string
 }
 
-type AlterTablespace struct{}
+func (a *AlterEvent) Format(buf *Buffer) {
+	buf.WriteString("alter ")
+	if a.Definer != "" {
+		buf.Printf("%s ", a.Definer)
+	}
+	buf.Printf("event %v", a.Event)
+	if a.Schedule != "" {
+		buf.Printf(" %s", a.Schedule)
+	}
+	if a.Rename != nil {
+		buf.Printf(" rename to %v", a.Rename)
+	}
+	if a.Definition != "" {
+		buf.Printf(" %s", a.Definition)
+	}
+}
+
+type AlterTablespace struct {
+	Source
+}
 
 func (*AlterTablespace) IStatement()    {}
 func (*AlterTablespace) IDDLStatement() {}
 
-type AlterLogfile struct{}
+type AlterLogfile struct {
+	Source
+}
 
 func (*AlterLogfile) IStatement()    {}
 func (*AlterLogfile) IDDLStatement() {}
 
-type AlterServer struct{}
+type AlterServer struct {
+	Source
+}
 
 func (*AlterServer) IStatement()    {}
 func (*AlterServer) IDDLStatement() {}
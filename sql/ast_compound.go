@@ -1,13 +1,19 @@
 package sql
 
-type Signal struct{}
+type Signal struct {
+	Source
+}
 
 func (*Signal) IStatement() {}
 
-type Resignal struct{}
+type Resignal struct {
+	Source
+}
 
 func (*Resignal) IStatement() {}
 
-type Diagnostics struct{}
+type Diagnostics struct {
+	Source
+}
 
 func (*Diagnostics) IStatement() {}
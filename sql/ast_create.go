@@ -8,14 +8,94 @@ func (c *CreateTable) GetSchemas() []string {
 	return c.Table.GetSchemas()
 }
 
+// CreateTable is `CREATE [TEMPORARY] TABLE [IF NOT EXISTS] Table
+// Definition`, Definition is the text of the columns, options and select
+// that follow the name
 type CreateTable struct {
-	Table ISimpleTable
+	Temporary   bool
+	IfNotExists bool
+	Table       ISimpleTable
+	Definition  string
+}
+
+func (c *CreateTable) Format(buf *Buffer) {
+	buf.WriteString("create ")
+	if c.Temporary {
+		buf.WriteString("temporary ")
+	}
+	buf.WriteString("table ")
+	if c.IfNotExists {
+		buf.WriteString("if not exists ")
+	}
+	buf.Printf("%v %s", c.Table, c.Definition)
 }
 
 func (*CreateIndex) IStatement()    {}
 func (*CreateIndex) IDDLStatement() {}
+func (*CreateIndex) HasDDLSchemas() {}
+
+func (c *CreateIndex) GetSchemas() []string {
+	return c.Table.GetSchemas()
+}
 
-type CreateIndex struct{}
+// CreateIndex is `CREATE [Kind] INDEX Name [Using] ON Table (Columns)
+// [Options]`, Kind is "unique", "fulltext" or "spatial". Using and Options
+// are the text of the index type and of the options after the columns.
+type CreateIndex struct {
+	Kind    string
+	Name    []byte
+	Using   string
+	Table   ISimpleTable
+	Columns []*IndexColumn
+	Options string
+}
+
+// CreateIndex.Kind
+const (
+	INDEX_UNIQUE   = "unique"
+	INDEX_FULLTEXT = "fulltext"
+	INDEX_SPATIAL  = "spatial"
+)
+
+func (c *CreateIndex) Format(buf *Buffer) {
+	buf.WriteString("create ")
+	if c.Kind != "" {
+		buf.Printf("%s ", c.Kind)
+	}
+	buf.WriteString("index ")
+	buf.formatName(c.Name)
+	if c.Using != "" {
+		buf.Printf(" %s", c.Using)
+	}
+	buf.Printf(" on %v (", c.Table)
+	for i, col := range c.Columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", col)
+	}
+	buf.WriteByte(')')
+	if c.Options != "" {
+		buf.Printf(" %s", c.Options)
+	}
+}
+
+// IndexColumn is `Column [(Length)] [Order]` of an index
+type IndexColumn struct {
+	Column []byte
+	Length NumVal
+	Order  string
+}
+
+func (c *IndexColumn) Format(buf *Buffer) {
+	buf.formatName(c.Column)
+	if c.Length != nil {
+		buf.Printf("(%v)", c.Length)
+	}
+	if c.Order != "" {
+		buf.Printf(" %s", c.Order)
+	}
+}
 
 /****************************
  * Create Database Statement
@@ -23,35 +103,105 @@ type CreateIndex struct{}
 func (*CreateDatabase) IStatement()    {}
 func (*CreateDatabase) IDDLStatement() {}
 
-type CreateDatabase struct{}
+// CreateDatabase is `CREATE DATABASE [IF NOT EXISTS] Schema [Options]`,
+// Options is the text of the character set and collation
+type CreateDatabase struct {
+	IfNotExists bool
+	Schema      []byte
+	Options     string
+}
+
+func (c *CreateDatabase) Format(buf *Buffer) {
+	buf.WriteString("create database ")
+	if c.IfNotExists {
+		buf.WriteString("if not exists ")
+	}
+	buf.formatName(c.Schema)
+	if c.Options != "" {
+		buf.Printf(" %s", c.Options)
+	}
+}
 
 func (*CreateView) IStatement()    {}
 func (*CreateView) IDDLStatement() {}
 func (*CreateView) HasDDLSchemas() {}
 
+// CreateView is `CREATE [Options] VIEW View [(Columns)] AS As
+// [CheckOption]`, Options is the text of the OR REPLACE, ALGORITHM,
+// DEFINER and SQL SECURITY clauses
 type CreateView struct {
-	View ISimpleTable
-	As   ISelect
+	Options     string
+	View        ISimpleTable
+	Columns     [][]byte
+	As          ISelect
+	CheckOption string
 }
 
 func (c *CreateView) GetSchemas() []string {
 	return GetSchemas(c.View.GetSchemas(), c.As.GetSchemas())
 }
 
+func (c *CreateView) Format(buf *Buffer) {
+	buf.WriteString("create ")
+	formatView(buf, c.Options, c.View, c.Columns, c.As, c.CheckOption)
+}
+
+// formatView writes what follows CREATE or ALTER of a view
+func formatView(buf *Buffer, options string, view ISimpleTable, columns [][]byte, as ISelect, check string) {
+	if options != "" {
+		buf.Printf("%s ", options)
+	}
+	buf.Printf("view %v", view)
+	if len(columns) > 0 {
+		buf.WriteString(" (")
+		formatNameList(buf, columns)
+		buf.WriteByte(')')
+	}
+	buf.Printf(" as %v", as)
+	if check != "" {
+		buf.Printf(" %s", check)
+	}
+}
+
+// view_tail, optionsEnd is where the SQL SECURITY clause before VIEW
+// ends
+type viewTail struct {
+	View        ISimpleTable
+	Columns     [][]byte
+	As          ISelect
+	CheckOption string
+	optionsEnd  int
+}
+
 func (*CreateLog) IStatement()    {}
 func (*CreateLog) IDDLStatement() {}
 
-type CreateLog struct{}
+type CreateLog struct {
+	Source
+}
 
 func (*CreateTablespace) IStatement()    {}
 func (*CreateTablespace) IDDLStatement() {}
 
-type CreateTablespace struct{}
+type CreateTablespace struct {
+	Source
+}
 
 func (*CreateServer) IStatement()    {}
 func (*CreateServer) IDDLStatement() {}
 
-type CreateServer struct{}
+type CreateServer struct {
+	Source
+}
+
+// formatCreate writes `CREATE [Definer] What Name`
+func formatCreate(buf *Buffer, definer string, what string, name Node) {
+	buf.WriteString("create ")
+	if definer != "" {
+		buf.Printf("%s ", definer)
+	}
+	buf.Printf("%s %v", what, name)
+}
 
 /**********************
  * Create Event Statement
@@ -61,60 +211,116 @@ func (*CreateEvent) IStatement()    {}
 func (*CreateEvent) IDDLStatement() {}
 func (*CreateEvent) HasDDLSchemas() {}
 
+// CreateEvent is `CREATE [Definer] EVENT [IF NOT EXISTS] Event
+// Definition`, Definition is the text of the schedule, options and body
 type CreateEvent struct {
-	Event ISimpleTable
+	Definer     string
+	IfNotExists bool
+	Event       ISimpleTable
+	Definition  string
 }
 
 func (c *CreateEvent) GetSchemas() []string {
 	return c.Event.GetSchemas()
 }
 
+func (c *CreateEvent) Format(buf *Buffer) {
+	what := "event"
+	if c.IfNotExists {
+		what = "event if not exists"
+	}
+	formatCreate(buf, c.Definer, what, c.Event)
+	buf.Printf(" %s", c.Definition)
+}
+
 type eventTail struct {
-	Event ISimpleTable
+	IfNotExists bool
+	Event       ISimpleTable
+	Definition  string
 }
 
 func (*CreateProcedure) IStatement()    {}
 func (*CreateProcedure) IDDLStatement() {}
 func (*CreateProcedure) HasDDLSchemas() {}
 
+// CreateProcedure is `CREATE [Definer] PROCEDURE Procedure Definition`,
+// Definition is the text of the parameters, characteristics and body
 type CreateProcedure struct {
-	Procedure ISimpleTable
+	Definer    string
+	Procedure  ISimpleTable
+	Definition string
 }
 
 func (c *CreateProcedure) GetSchemas() []string {
 	return c.Procedure.GetSchemas()
 }
 
+func (c *CreateProcedure) Format(buf *Buffer) {
+	formatCreate(buf, c.Definer, "procedure", c.Procedure)
+	buf.Printf(" %s", c.Definition)
+}
+
 type spTail struct {
-	Procedure ISimpleTable
+	Procedure  ISimpleTable
+	Definition string
 }
 
 func (*CreateFunction) IStatement()    {}
 func (*CreateFunction) IDDLStatement() {}
 func (*CreateFunction) HasDDLSchemas() {}
 
+// CreateFunction is `CREATE [Definer] FUNCTION Function Definition`,
+// Definition is the text of the parameters, return type,
+// characteristics and body
 type CreateFunction struct {
-	Function ISimpleTable
+	Definer    string
+	Function   ISimpleTable
+	Definition string
 }
+
 type sfTail struct {
-	Function ISimpleTable
+	Function   ISimpleTable
+	Definition string
 }
 
 func (c *CreateFunction) GetSchemas() []string {
 	return c.Function.GetSchemas()
 }
 
+func (c *CreateFunction) Format(buf *Buffer) {
+	formatCreate(buf, c.Definer, "function", c.Function)
+	buf.Printf(" %s", c.Definition)
+}
+
 func (*CreateTrigger) IStatement()    {}
 func (*CreateTrigger) IDDLStatement() {}
 func (*CreateTrigger) HasDDLSchemas() {}
 
+// CreateTrigger is `CREATE [Definer] TRIGGER Trigger Time Event ON Table
+// FOR EACH ROW Body`, Time is "before" or "after", Event "insert",
+// "update" or "delete" and Body the text of the statement
 type CreateTrigger struct {
+	Definer string
 	Trigger ISimpleTable
+	Time    string
+	Event   string
+	Table   ISimpleTable
+	Body    string
 }
+
 type triggerTail struct {
 	Trigger ISimpleTable
+	Time    string
+	Event   string
+	Table   ISimpleTable
+	Body    string
 }
 
 func (c *CreateTrigger) GetSchemas() []string {
 	return c.Trigger.GetSchemas()
 }
+
+func (c *CreateTrigger) Format(buf *Buffer) {
+	formatCreate(buf, c.Definer, "trigger", c.Trigger)
+	buf.Printf(" %s %s on %v for each row %s", c.Time, c.Event, c.Table, c.Body)
+}
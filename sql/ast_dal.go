@@ -1,13 +1,41 @@
 package sql
 
+import (
+	"strings"
+)
+
 func (*Set) IStatement() {}
 
 type Set struct {
 	VarList Vars
 }
 
+// newSet is the statement of `SET vars`, setting a password is only kept
+// as text
+func newSet(vars Vars) IStatement {
+	for _, v := range vars {
+		if v.Type == Type_Sys && v.Name == "PASSWORD" {
+			return &SetPassword{}
+		}
+	}
+	return &Set{VarList: vars}
+}
+
+func (s *Set) Format(buf *Buffer) {
+	buf.WriteString("set ")
+	for i, v := range s.VarList {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		v.formatAssignment(buf)
+	}
+}
+
 type Vars []*Variable
 
+// Variable is a user variable `@Name` or a system variable `@@Name`, in a
+// SET statement or an expression. Name is "NAMES" and "CHARACTER SET" for
+// `SET NAMES` and `SET CHARACTER SET`.
 type Variable struct {
 	Type  VarType
 	Life  LifeType
@@ -15,6 +43,70 @@ type Variable struct {
 	Value IExpr
 }
 
+// Format writes the variable as an expression
+func (v *Variable) Format(buf *Buffer) {
+	if v.Type == Type_Usr {
+		buf.WriteByte('@')
+		buf.formatIdentOrText([]byte(v.Name))
+		if v.Value != nil {
+			buf.WriteString(" := ")
+			buf.formatOperand(v.Value, precAssign)
+		}
+		return
+	}
+	buf.WriteString("@@")
+	switch v.Life {
+	case Life_Global:
+		buf.WriteString("global.")
+	case Life_Local:
+		buf.WriteString("local.")
+	case Life_Session:
+		buf.WriteString("session.")
+	}
+	v.formatName(buf)
+}
+
+// formatAssignment writes the variable as it is set by SET
+func (v *Variable) formatAssignment(buf *Buffer) {
+	if v.Type == Type_Usr {
+		buf.WriteByte('@')
+		buf.formatIdentOrText([]byte(v.Name))
+		buf.Printf(" = %v", v.Value)
+		return
+	}
+	switch v.Name {
+	case "NAMES":
+		buf.Printf("names %v", v.Value)
+		return
+	case "CHARACTER SET":
+		buf.Printf("character set %v", v.Value)
+		return
+	}
+	switch v.Life {
+	case Life_Global:
+		buf.WriteString("global ")
+	case Life_Local:
+		buf.WriteString("local ")
+	case Life_Session:
+		buf.WriteString("session ")
+	}
+	v.formatName(buf)
+	buf.Printf(" = %v", v.Value)
+}
+
+func (v *Variable) formatName(buf *Buffer) {
+	for i, name := range strings.Split(v.Name, ".") {
+		if i > 0 {
+			buf.WriteByte('.')
+		}
+		if name == "DEFAULT" {
+			buf.WriteString("default")
+			continue
+		}
+		buf.formatName([]byte(name))
+	}
+}
+
 type VarType int
 type LifeType int
 
@@ -33,7 +125,9 @@ type IAccountMgrStmt interface {
 	IStatement
 }
 
-type Partition struct{}
+type Partition struct {
+	Source
+}
 
 func (*Partition) IStatement() {}
 
@@ -77,24 +171,68 @@ func (o *Optimize) GetSchemas() []string {
 	return o.Tables.GetSchemas()
 }
 
+// formatTableMt writes `Verb [NO_WRITE_TO_BINLOG] TABLE Tables [Options]`
+func formatTableMt(buf *Buffer, verb string, noWriteToBinlog bool, tables ISimpleTables, options string) {
+	buf.Printf("%s ", verb)
+	if noWriteToBinlog {
+		buf.WriteString("no_write_to_binlog ")
+	}
+	buf.Printf("table %v", tables)
+	if options != "" {
+		buf.Printf(" %s", options)
+	}
+}
+
+// Check is `CHECK TABLE Tables [Options]`, Options is the text of the
+// check types
 type Check struct {
-	Tables ISimpleTables
+	Tables  ISimpleTables
+	Options string
+}
+
+func (c *Check) Format(buf *Buffer) {
+	formatTableMt(buf, "check", false, c.Tables, c.Options)
 }
 
+// CheckSum is `CHECKSUM TABLE Tables [Option]`, Option is "quick" or
+// "extended"
 type CheckSum struct {
 	Tables ISimpleTables
+	Option string
 }
 
+func (c *CheckSum) Format(buf *Buffer) {
+	formatTableMt(buf, "checksum", false, c.Tables, c.Option)
+}
+
+// Repair is `REPAIR [NO_WRITE_TO_BINLOG] TABLE Tables [Options]`, Options
+// is the text of the repair types
 type Repair struct {
-	Tables ISimpleTables
+	NoWriteToBinlog bool
+	Tables          ISimpleTables
+	Options         string
+}
+
+func (r *Repair) Format(buf *Buffer) {
+	formatTableMt(buf, "repair", r.NoWriteToBinlog, r.Tables, r.Options)
 }
 
 type Analyze struct {
-	Tables ISimpleTables
+	NoWriteToBinlog bool
+	Tables          ISimpleTables
+}
+
+func (a *Analyze) Format(buf *Buffer) {
+	formatTableMt(buf, "analyze", a.NoWriteToBinlog, a.Tables, "")
 }
 
 type Optimize struct {
-	Tables ISimpleTables
+	NoWriteToBinlog bool
+	Tables          ISimpleTables
+}
+
+func (o *Optimize) Format(buf *Buffer) {
+	formatTableMt(buf, "optimize", o.NoWriteToBinlog, o.Tables, "")
 }
 
 /****************************
@@ -102,8 +240,20 @@ type Optimize struct {
  ***************************/
 func (*CacheIndex) IStatement() {}
 
+// CacheIndex is `CACHE INDEX TableIndexList IN KeyCache`, KeyCache is nil
+// for DEFAULT
 type CacheIndex struct {
 	TableIndexList TableIndexes
+	KeyCache       []byte
+}
+
+func (c *CacheIndex) Format(buf *Buffer) {
+	buf.Printf("cache index %v in ", c.TableIndexList)
+	if len(c.KeyCache) == 0 {
+		buf.WriteString("default")
+		return
+	}
+	buf.formatName(c.KeyCache)
 }
 
 func (c *CacheIndex) GetSchemas() []string {
@@ -115,10 +265,15 @@ func (c *CacheIndex) GetSchemas() []string {
 
 func (*LoadIndex) IStatement() {}
 
+// LoadIndex is `LOAD INDEX INTO CACHE TableIndexList`
 type LoadIndex struct {
 	TableIndexList TableIndexes
 }
 
+func (l *LoadIndex) Format(buf *Buffer) {
+	buf.Printf("load index into cache %v", l.TableIndexList)
+}
+
 func (l *LoadIndex) GetSchemas() []string {
 	if l.TableIndexList == nil || len(l.TableIndexList) == 0 {
 		return nil
@@ -128,6 +283,15 @@ func (l *LoadIndex) GetSchemas() []string {
 
 type TableIndexes []*TableIndex
 
+func (tis TableIndexes) Format(buf *Buffer) {
+	for i, ti := range tis {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", ti)
+	}
+}
+
 func (tis TableIndexes) GetSchemas() []string {
 	var rt []string
 	for _, v := range tis {
@@ -147,17 +311,42 @@ func (tis TableIndexes) GetSchemas() []string {
 	return rt
 }
 
+// TableIndex is `Table [Partitions] [INDEX (Keys)] [IGNORE LEAVES]` of
+// CACHE INDEX and LOAD INDEX, Partitions is the text of the PARTITION
+// clause
 type TableIndex struct {
-	Table ISimpleTable
+	Table        ISimpleTable
+	Partitions   string
+	Keys         [][]byte
+	IgnoreLeaves bool
 }
 
-type Binlog struct{}
+func (ti *TableIndex) Format(buf *Buffer) {
+	buf.Printf("%v", ti.Table)
+	if ti.Partitions != "" {
+		buf.Printf(" %s", ti.Partitions)
+	}
+	if len(ti.Keys) > 0 {
+		buf.WriteString(" index (")
+		formatNameList(buf, ti.Keys)
+		buf.WriteByte(')')
+	}
+	if ti.IgnoreLeaves {
+		buf.WriteString(" ignore leaves")
+	}
+}
+
+type Binlog struct {
+	Source
+}
 
 func (*Binlog) IStatement() {}
 
 func (*Flush) IStatement() {}
 
-type Flush struct{}
+type Flush struct {
+	Source
+}
 
 func (*FlushTables) IStatement() {}
 
@@ -168,15 +357,93 @@ func (f *FlushTables) GetSchemas() []string {
 	return f.Tables.GetSchemas()
 }
 
+// FlushTables is `FLUSH [NO_WRITE_TO_BINLOG] TABLES [Tables] [Lock]`,
+// Lock is "with read lock" or "for export"
 type FlushTables struct {
-	Tables ISimpleTables
+	NoWriteToBinlog bool
+	Tables          ISimpleTables
+	Lock            string
 }
 
-type Kill struct{}
+func (f *FlushTables) Format(buf *Buffer) {
+	buf.WriteString("flush ")
+	if f.NoWriteToBinlog {
+		buf.WriteString("no_write_to_binlog ")
+	}
+	buf.WriteString("tables")
+	if len(f.Tables) > 0 {
+		buf.Printf(" %v", f.Tables)
+	}
+	if f.Lock != "" {
+		buf.Printf(" %s", f.Lock)
+	}
+}
+
+type KillType int
+
+const (
+	KillType_Connection KillType = iota
+	KillType_Query
+)
+
+// Kill is `KILL [QUERY] Id`
+type Kill struct {
+	Type KillType
+	Id   IExpr
+}
 
 func (*Kill) IStatement() {}
 
-type Reset struct{}
+func (k *Kill) Format(buf *Buffer) {
+	buf.WriteString("kill ")
+	if k.Type == KillType_Query {
+		buf.WriteString("query ")
+	}
+	buf.Printf("%v", k.Id)
+}
+
+type AegisAction int
+
+const (
+	AegisAction_SetWeight AegisAction = iota
+	AegisAction_Offline
+	AegisAction_Online
+	AegisAction_Drain
+	AegisAction_AddReplica
+)
+
+// AegisAdmin changes a backend of the proxy at runtime, Addr is the
+// host:port of the backend. Weight is nil when it is not given.
+type AegisAdmin struct {
+	Action AegisAction
+	Addr   string
+	Weight NumVal
+}
+
+func (*AegisAdmin) IStatement() {}
+
+func (a *AegisAdmin) Format(buf *Buffer) {
+	addr := NewStrVal(a.Addr)
+	switch a.Action {
+	case AegisAction_SetWeight:
+		buf.Printf("aegis set backend %v", addr)
+	case AegisAction_Offline:
+		buf.Printf("aegis offline backend %v", addr)
+	case AegisAction_Online:
+		buf.Printf("aegis online backend %v", addr)
+	case AegisAction_Drain:
+		buf.Printf("aegis drain backend %v", addr)
+	case AegisAction_AddReplica:
+		buf.Printf("aegis add replica %v", addr)
+	}
+	if a.Weight != nil {
+		buf.Printf(" weight %v", a.Weight)
+	}
+}
+
+type Reset struct {
+	Source
+}
 
 func (*Reset) IStatement() {}
 
@@ -196,16 +463,36 @@ func (*CreateUDF) IsPluginAndUdf() {}
 func (*Uninstall) IStatement()     {}
 func (*Uninstall) IsPluginAndUdf() {}
 
-type Install struct{}
+type Install struct {
+	Source
+}
 
-type Uninstall struct{}
+type Uninstall struct {
+	Source
+}
 
+// CreateUDF is `CREATE [AGGREGATE] FUNCTION Function RETURNS Returns
+// SONAME Soname`, Returns is "string", "real", "decimal" or "int"
 type CreateUDF struct {
-	Function ISimpleTable
+	Aggregate bool
+	Function  ISimpleTable
+	Returns   string
+	Soname    StrVal
+}
+
+func (c *CreateUDF) Format(buf *Buffer) {
+	buf.WriteString("create ")
+	if c.Aggregate {
+		buf.WriteString("aggregate ")
+	}
+	buf.Printf("function %v returns %s soname %v", c.Function, c.Returns, c.Soname)
 }
 
 type udfTail struct {
-	Function ISimpleTable
+	Aggregate bool
+	Function  ISimpleTable
+	Returns   string
+	Soname    StrVal
 }
 
 /**********************************
@@ -214,37 +501,51 @@ type udfTail struct {
 func (*Grant) IStatement()       {}
 func (*Grant) IsAccountMgrStmt() {}
 
-type Grant struct{}
+type Grant struct {
+	Source
+}
 
 func (*SetPassword) IStatement()    {}
 func (*SetPassword) IsAccountStmt() {}
 
-type SetPassword struct{}
+type SetPassword struct {
+	Source
+}
 
 func (*RenameUser) IStatement()       {}
 func (*RenameUser) IsAccountMgrStmt() {}
 
-type RenameUser struct{}
+type RenameUser struct {
+	Source
+}
 
 func (*Revoke) IStatement()       {}
 func (*Revoke) IsAccountMgrStmt() {}
 
-type Revoke struct{}
+type Revoke struct {
+	Source
+}
 
 func (*CreateUser) IStatement()       {}
 func (*CreateUser) IDDLStatement()    {}
 func (*CreateUser) IsAccountMgrStmt() {}
 
-type CreateUser struct{}
+type CreateUser struct {
+	Source
+}
 
 func (*AlterUser) IStatement()       {}
 func (*AlterUser) IDDLStatement()    {}
 func (*AlterUser) IsAccountMgrStmt() {}
 
-type AlterUser struct{}
+type AlterUser struct {
+	Source
+}
 
 func (*DropUser) IStatement()       {}
 func (*DropUser) IDDLStatement()    {}
 func (*DropUser) IsAccountMgrStmt() {}
 
-type DropUser struct{}
+type DropUser struct {
+	Source
+}
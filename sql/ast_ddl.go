@@ -10,6 +10,7 @@ type IDDLSchemas interface {
 	HasDDLSchemas()
 }
 
+// RenameTable is `RENAME TABLE From TO To, ...`
 type RenameTable struct {
 	ToList []*TableToTable
 }
@@ -17,6 +18,16 @@ type RenameTable struct {
 func (*RenameTable) IStatement()    {}
 func (*RenameTable) IDDLStatement() {}
 
+func (r *RenameTable) Format(buf *Buffer) {
+	buf.WriteString("rename table ")
+	for i, t := range r.ToList {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", t)
+	}
+}
+
 func (*TruncateTable) IStatement()    {}
 func (*TruncateTable) IDDLStatement() {}
 func (*TruncateTable) HasDDLSchemas() {}
@@ -27,3 +38,7 @@ func (t *TruncateTable) GetSchemas() []string {
 type TruncateTable struct {
 	Table ISimpleTable
 }
+
+func (t *TruncateTable) Format(buf *Buffer) {
+	buf.Printf("truncate table %v", t.Table)
+}
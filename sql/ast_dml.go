@@ -1,5 +1,9 @@
 package sql
 
+import (
+	"strings"
+)
+
 /***********************************
  * Select Clause
  ***********************************/
@@ -22,10 +26,18 @@ func (*ParenSelect) IStatement() {}
 func (*Union) IStatement()       {}
 func (*SubQuery) IStatement()    {}
 
+// Union is `Left UNION [ALL | DISTINCT] Right`
 type Union struct {
 	Left, Right ISelect
+	Option      string
 }
 
+// Union.Option
+const (
+	UNION_ALL      = "all"
+	UNION_DISTINCT = "distinct"
+)
+
 func (u *Union) IsLocked() bool {
 	return u.Left.IsLocked() || u.Right.IsLocked()
 }
@@ -53,10 +65,18 @@ func (u *Union) GetSchemas() []string {
 }
 
 func (u *Union) GetTables() []string {
-	return nil
+	return tableNames(u)
 }
 
-// SubQuery ---------
+func (u *Union) Format(buf *Buffer) {
+	buf.Printf("%v union ", u.Left)
+	if u.Option != "" {
+		buf.Printf("%s ", u.Option)
+	}
+	buf.Printf("%v", u.Right)
+}
+
+// SubQuery is a query in parentheses used as a value or a table
 type SubQuery struct {
 	SelectStatement ISelect
 }
@@ -74,15 +94,43 @@ func (s *SubQuery) GetSchemas() []string {
 }
 
 func (s *SubQuery) GetTables() []string {
-	return nil
+	return tableNames(s)
+}
+
+func (s *SubQuery) Format(buf *Buffer) {
+	buf.Printf("(%v)", s.SelectStatement)
 }
 
 // Select -----------
 type Select struct {
-	From     ITables
-	LockType LockType
+	Options   []string
+	Fields    SelectFields
+	Into      string
+	From      ITables
+	Where     IExpr
+	GroupBy   GroupBy
+	Rollup    bool
+	Having    IExpr
+	OrderBy   OrderBy
+	Limit     *Limit
+	Procedure string
+	LockType  LockType
 }
 
+// Select.Options
+const (
+	SELECT_ALL                 = "all"
+	SELECT_DISTINCT            = "distinct"
+	SELECT_HIGH_PRIORITY       = "high_priority"
+	SELECT_STRAIGHT_JOIN       = "straight_join"
+	SELECT_SQL_SMALL_RESULT    = "sql_small_result"
+	SELECT_SQL_BIG_RESULT      = "sql_big_result"
+	SELECT_SQL_BUFFER_RESULT   = "sql_buffer_result"
+	SELECT_SQL_CACHE           = "sql_cache"
+	SELECT_SQL_NO_CACHE        = "sql_no_cache"
+	SELECT_SQL_CALC_FOUND_ROWS = "sql_calc_found_rows"
+)
+
 func (s *Select) IsLocked() bool {
 	return s.LockType != LockType_NoLock
 }
@@ -114,9 +162,84 @@ func (s *Select) GetTables() []string {
 	return ret
 }
 
-// ParenSelect ------
+func (s *Select) Format(buf *Buffer) {
+	buf.WriteString("select ")
+	for _, o := range s.Options {
+		buf.Printf("%s ", o)
+	}
+	buf.Printf("%v", s.Fields)
+	if s.Into != "" {
+		buf.Printf(" into %s", s.Into)
+	}
+	if len(s.From) > 0 {
+		buf.Printf(" from %v", s.From)
+	} else if s.Where != nil || len(s.GroupBy) > 0 || s.Having != nil {
+		buf.WriteString(" from dual")
+	}
+	if s.Where != nil {
+		buf.Printf(" where %v", s.Where)
+	}
+	if len(s.GroupBy) > 0 {
+		buf.Printf(" group by %v", s.GroupBy)
+		if s.Rollup {
+			buf.WriteString(" with rollup")
+		}
+	}
+	if s.Having != nil {
+		buf.Printf(" having %v", s.Having)
+	}
+	if len(s.OrderBy) > 0 {
+		buf.Printf(" order by %v", s.OrderBy)
+	}
+	if s.Limit != nil {
+		buf.Printf(" %v", s.Limit)
+	}
+	if s.Procedure != "" {
+		buf.Printf(" procedure %s", s.Procedure)
+	}
+	buf.Printf("%v", s.LockType)
+}
+
+// ISelectField is an item of a select list
+type ISelectField interface {
+	ISelectField()
+	Node
+}
+
+func (*StarExpr) ISelectField()     {}
+func (*AliasedField) ISelectField() {}
+
+type SelectFields []ISelectField
+
+func (fs SelectFields) Format(buf *Buffer) {
+	for i, f := range fs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", f)
+	}
+}
+
+// AliasedField is `expr [AS alias]` of a select list
+type AliasedField struct {
+	Expr IExpr
+	As   []byte
+}
+
+func (f *AliasedField) Format(buf *Buffer) {
+	buf.Printf("%v", f.Expr)
+	if len(f.As) > 0 {
+		buf.WriteString(" as ")
+		buf.formatName(f.As)
+	}
+}
+
+// ParenSelect is a select in parentheses, the ORDER BY and LIMIT after
+// them included
 type ParenSelect struct {
-	Select ISelect
+	Select  ISelect
+	OrderBy OrderBy
+	Limit   *Limit
 }
 
 func (p *ParenSelect) IsLocked() bool {
@@ -128,7 +251,17 @@ func (p *ParenSelect) GetSchemas() []string {
 }
 
 func (p *ParenSelect) GetTables() []string {
-	return nil
+	return tableNames(p)
+}
+
+func (p *ParenSelect) Format(buf *Buffer) {
+	buf.Printf("(%v)", p.Select)
+	if len(p.OrderBy) > 0 {
+		buf.Printf(" order by %v", p.OrderBy)
+	}
+	if p.Limit != nil {
+		buf.Printf(" %v", p.Limit)
+	}
 }
 
 type LockType int
@@ -139,6 +272,67 @@ const (
 	LockType_LockInShareMode
 )
 
+// Format writes the lock clause with a leading space, nothing for NoLock
+func (l LockType) Format(buf *Buffer) {
+	switch l {
+	case LockType_ForUpdate:
+		buf.WriteString(" for update")
+	case LockType_LockInShareMode:
+		buf.WriteString(" lock in share mode")
+	}
+}
+
+// orderLimit carries the ORDER BY and LIMIT after a select in parentheses
+// to the rule that builds it
+type orderLimit struct {
+	orderBy OrderBy
+	limit   *Limit
+}
+
+// groupClause carries a GROUP BY clause to the select it belongs to
+type groupClause struct {
+	groupBy GroupBy
+	rollup  bool
+}
+
+// withGroup puts the GROUP BY clause g on s
+func withGroup(s *Select, g *groupClause) *Select {
+	if g != nil {
+		s.GroupBy, s.Rollup = g.groupBy, g.rollup
+	}
+	return s
+}
+
+// withOrderLimit puts the ORDER BY and LIMIT after a select in
+// parentheses on it
+func withOrderLimit(s ISelect, ol *orderLimit) ISelect {
+	if ol == nil {
+		return s
+	}
+	switch v := s.(type) {
+	case *ParenSelect:
+		v.OrderBy, v.Limit = ol.orderBy, ol.limit
+	case *Select:
+		if v.OrderBy == nil && v.Limit == nil {
+			v.OrderBy, v.Limit = ol.orderBy, ol.limit
+		}
+	}
+	return s
+}
+
+// withUnion makes the union rest u of left, rest is either nil, a *Union
+// without its left side or the ORDER BY and LIMIT of left
+func withUnion(left ISelect, rest interface{}) ISelect {
+	switch v := rest.(type) {
+	case *Union:
+		v.Left = left
+		return v
+	case *orderLimit:
+		return withOrderLimit(left, v)
+	}
+	return left
+}
+
 /*********************************
  * Insert Clause
  * - http://dev.mysql.com/doc/refman/5.7/en/insert.html
@@ -160,7 +354,7 @@ func (i *Insert) GetSchemas() []string {
 	ret := i.Table.GetSchemas()
 	var s []string = nil
 	if i.HasISelect() {
-		s = i.InsertFields.(*Select).GetSchemas()
+		s = i.InsertFields.(ISelect).GetSchemas()
 	}
 
 	if ret == nil || len(ret) == 0 {
@@ -175,9 +369,124 @@ func (i *Insert) GetSchemas() []string {
 }
 
 type Insert struct {
-	Table ISimpleTable
+	Options []string
+	Table   ISimpleTable
+	// the column list of the `select` form, InsertValues keeps its own
+	Columns IValExprs
 	// can be `values(x,y,z)` list or `select` statement
 	InsertFields interface{}
+	// ON DUPLICATE KEY UPDATE
+	OnDup []*Assignment
+}
+
+// Insert.Options and Replace.Options
+const (
+	INSERT_LOW_PRIORITY  = "low_priority"
+	INSERT_DELAYED       = "delayed"
+	INSERT_HIGH_PRIORITY = "high_priority"
+	INSERT_IGNORE        = "ignore"
+)
+
+func (i *Insert) Format(buf *Buffer) {
+	buf.WriteString("insert ")
+	formatInsert(buf, i.Options, i.Table, i.Columns, i.InsertFields)
+	if len(i.OnDup) > 0 {
+		buf.WriteString(" on duplicate key update ")
+		formatAssignments(buf, i.OnDup)
+	}
+}
+
+// insertSelect carries the column list of the `select` form of an insert
+// or replace to the rule that builds it
+type insertSelect struct {
+	columns IValExprs
+	rows    ISelect
+}
+
+// splitInsertFields is the column list of the `select` form and the rows
+// of an insert_field_spec
+func splitInsertFields(fields interface{}) (IValExprs, interface{}) {
+	if v, ok := fields.(*insertSelect); ok {
+		return v.columns, v.rows
+	}
+	return nil, fields
+}
+
+// newOptions is the options given, the empty ones of the rules that
+// have none left out
+func newOptions(opts ...string) []string {
+	var ret []string
+	for _, o := range opts {
+		if o != "" {
+			ret = append(ret, o)
+		}
+	}
+	return ret
+}
+
+func formatInsert(buf *Buffer, options []string, table ITable, columns IValExprs, fields interface{}) {
+	for _, o := range options {
+		buf.Printf("%s ", o)
+	}
+	buf.Printf("into %v ", table)
+	switch v := fields.(type) {
+	case *InsertValues:
+		buf.Printf("%v", v)
+	case ISelect:
+		if len(columns) > 0 {
+			buf.Printf("(%v) ", columns)
+		}
+		buf.Printf("%v", v)
+	}
+}
+
+// InsertValues is the `values(x,y,z)` list of an insert or replace, the
+// `set x=1` form is turned into one. DEFAULT is kept as a StrVal without
+// quotes like in SET.
+type InsertValues struct {
+	Columns IValExprs
+	Rows    []IExprs
+}
+
+func (v *InsertValues) Format(buf *Buffer) {
+	if len(v.Columns) > 0 {
+		buf.Printf("(%v) ", v.Columns)
+	}
+	buf.WriteString("values ")
+	for i, row := range v.Rows {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", row)
+	}
+}
+
+func newInsertSet(set []*Assignment) *InsertValues {
+	v := &InsertValues{Rows: []IExprs{make(IExprs, 0, len(set))}}
+	for _, a := range set {
+		v.Columns = append(v.Columns, a.Column)
+		v.Rows[0] = append(v.Rows[0], a.Expr)
+	}
+	return v
+}
+
+// Assignment is a `col = expr` of an update or insert
+type Assignment struct {
+	Column IValExpr
+	Expr   IExpr
+}
+
+func (a *Assignment) Format(buf *Buffer) {
+	buf.Printf("%v = %v", a.Column, a.Expr)
+}
+
+func formatAssignments(buf *Buffer, list []*Assignment) {
+	for i, a := range list {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", a)
+	}
 }
 
 /*********************************
@@ -194,7 +503,41 @@ func (u *Update) GetSchemas() []string {
 }
 
 type Update struct {
-	Tables ITables
+	Options []string
+	Tables  ITables
+	Set     []*Assignment
+	Where   IExpr
+	OrderBy OrderBy
+	Limit   *Limit
+}
+
+// Update.Options and Delete.Options
+const (
+	DML_LOW_PRIORITY = "low_priority"
+	DML_IGNORE       = "ignore"
+	DML_QUICK        = "quick"
+)
+
+func (u *Update) Format(buf *Buffer) {
+	buf.WriteString("update ")
+	for _, o := range u.Options {
+		buf.Printf("%s ", o)
+	}
+	buf.Printf("%v set ", u.Tables)
+	formatAssignments(buf, u.Set)
+	formatWhereOrderLimit(buf, u.Where, u.OrderBy, u.Limit)
+}
+
+func formatWhereOrderLimit(buf *Buffer, where IExpr, orderBy OrderBy, limit *Limit) {
+	if where != nil {
+		buf.Printf(" where %v", where)
+	}
+	if len(orderBy) > 0 {
+		buf.Printf(" order by %v", orderBy)
+	}
+	if limit != nil {
+		buf.Printf(" %v", limit)
+	}
 }
 
 /*********************************
@@ -202,8 +545,17 @@ type Update struct {
  ********************************/
 func (*Delete) IStatement() {}
 
+// Delete has all its tables in Tables. The multiple table forms start them
+// with the Targets rows are deleted from, Using tells
+// `DELETE FROM targets USING tables` from `DELETE targets FROM tables`.
 type Delete struct {
-	Tables ITables
+	Options []string
+	Targets ITables
+	Using   bool
+	Tables  ITables
+	Where   IExpr
+	OrderBy OrderBy
+	Limit   *Limit
 }
 
 func (d *Delete) GetSchemas() []string {
@@ -213,6 +565,21 @@ func (d *Delete) GetSchemas() []string {
 	return d.Tables.GetSchemas()
 }
 
+func (d *Delete) Format(buf *Buffer) {
+	buf.WriteString("delete ")
+	for _, o := range d.Options {
+		buf.Printf("%s ", o)
+	}
+	if len(d.Targets) == 0 {
+		buf.Printf("from %v", d.Tables)
+	} else if d.Using {
+		buf.Printf("from %v using %v", d.Targets, d.Tables[len(d.Targets):])
+	} else {
+		buf.Printf("%v from %v", d.Targets, d.Tables[len(d.Targets):])
+	}
+	formatWhereOrderLimit(buf, d.Where, d.OrderBy, d.Limit)
+}
+
 /***********************************************
  * Replace Clause
  **********************************************/
@@ -232,7 +599,7 @@ func (r *Replace) GetSchemas() []string {
 	ret := r.Table.GetSchemas()
 	var s []string = nil
 	if r.HasISelect() {
-		s = r.ReplaceFields.(*Select).GetSchemas()
+		s = r.ReplaceFields.(ISelect).GetSchemas()
 	}
 
 	if ret == nil || len(ret) == 0 {
@@ -247,25 +614,68 @@ func (r *Replace) GetSchemas() []string {
 }
 
 type Replace struct {
-	Table ITable
+	Options []string
+	Table   ITable
+	// the column list of the `select` form, InsertValues keeps its own
+	Columns IValExprs
 	// can be `values(x,y,z)` list or `select` statement
 	ReplaceFields interface{}
 }
 
+func (r *Replace) Format(buf *Buffer) {
+	buf.WriteString("replace ")
+	formatInsert(buf, r.Options, r.Table, r.Columns, r.ReplaceFields)
+}
+
+// Call is `CALL Spname [(Args)]`, Args is nil without the parentheses
 type Call struct {
 	Spname *Spname
+	Args   IExprs
 }
 
 func (*Call) IStatement() {}
 
-type Do struct{}
+func (c *Call) Format(buf *Buffer) {
+	buf.Printf("call %v", c.Spname)
+	if c.Args != nil {
+		buf.Printf("%v", c.Args)
+	}
+}
+
+type Do struct {
+	Source
+}
 
 func (*Do) IStatement() {}
 
-type Load struct{}
+type Load struct {
+	Source
+}
 
 func (*Load) IStatement() {}
 
-type Handler struct{}
+type Handler struct {
+	Source
+}
 
 func (*Handler) IStatement() {}
+
+// Source is the text a statement was parsed from, the statements that are
+// not modeled clause by clause format as it
+type Source struct {
+	Text string
+}
+
+func (s *Source) setSource(text string) {
+	s.Text = text
+}
+
+func (s *Source) Format(buf *Buffer) {
+	buf.WriteString(s.Text)
+}
+
+// sourceText is the statement sql holds, without the blanks and ';'
+// around it
+func sourceText(sql string) string {
+	return strings.TrimRight(strings.TrimSpace(sql), "; \t\r\n")
+}
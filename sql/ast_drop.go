@@ -1,5 +1,13 @@
 package sql
 
+// formatDrop writes `DROP What [IF EXISTS] `
+func formatDrop(buf *Buffer, what string, ifExists bool) {
+	buf.Printf("drop %s ", what)
+	if ifExists {
+		buf.WriteString("if exists ")
+	}
+}
+
 func (*DropTables) IStatement()    {}
 func (*DropTables) IDDLStatement() {}
 func (*DropTables) HasDDLSchemas() {}
@@ -7,8 +15,25 @@ func (d *DropTables) GetSchemas() []string {
 	return d.Tables.GetSchemas()
 }
 
+// DropTables is `DROP [TEMPORARY] TABLE [IF EXISTS] Tables [Option]`,
+// Option is "restrict" or "cascade"
 type DropTables struct {
-	Tables ISimpleTables
+	Temporary bool
+	IfExists  bool
+	Tables    ISimpleTables
+	Option    string
+}
+
+func (d *DropTables) Format(buf *Buffer) {
+	what := "table"
+	if d.Temporary {
+		what = "temporary table"
+	}
+	formatDrop(buf, what, d.IfExists)
+	buf.Printf("%v", d.Tables)
+	if d.Option != "" {
+		buf.Printf(" %s", d.Option)
+	}
 }
 
 func (*DropIndex) IStatement()    {}
@@ -18,15 +43,37 @@ func (d *DropIndex) GetSchemas() []string {
 	return d.On.GetSchemas()
 }
 
+// DropIndex is `DROP INDEX Name ON On [Options]`, Options is the text of
+// the ALGORITHM and LOCK clauses
 type DropIndex struct {
-	On ISimpleTable
+	Name    []byte
+	On      ISimpleTable
+	Options string
+}
+
+func (d *DropIndex) Format(buf *Buffer) {
+	buf.WriteString("drop index ")
+	buf.formatName(d.Name)
+	buf.Printf(" on %v", d.On)
+	if d.Options != "" {
+		buf.Printf(" %s", d.Options)
+	}
 }
 
-type DropDatabase struct{}
+// DropDatabase is `DROP DATABASE [IF EXISTS] Schema`
+type DropDatabase struct {
+	IfExists bool
+	Schema   []byte
+}
 
 func (*DropDatabase) IStatement()    {}
 func (*DropDatabase) IDDLStatement() {}
 
+func (d *DropDatabase) Format(buf *Buffer) {
+	formatDrop(buf, "database", d.IfExists)
+	buf.formatName(d.Schema)
+}
+
 func (*DropFunction) IStatement()    {}
 func (*DropFunction) IDDLStatement() {}
 func (*DropFunction) HasDDLSchemas() {}
@@ -35,9 +82,15 @@ func (d *DropFunction) GetSchemas() []string {
 }
 
 type DropFunction struct {
+	IfExists bool
 	Function *Spname
 }
 
+func (d *DropFunction) Format(buf *Buffer) {
+	formatDrop(buf, "function", d.IfExists)
+	buf.Printf("%v", d.Function)
+}
+
 func (*DropProcedure) IStatement()    {}
 func (*DropProcedure) IDDLStatement() {}
 func (*DropProcedure) HasDDLSchemas() {}
@@ -46,14 +99,33 @@ func (d *DropProcedure) GetSchemas() []string {
 }
 
 type DropProcedure struct {
+	IfExists  bool
 	Procedure *Spname
 }
 
-type DropView struct{}
+func (d *DropProcedure) Format(buf *Buffer) {
+	formatDrop(buf, "procedure", d.IfExists)
+	buf.Printf("%v", d.Procedure)
+}
+
+// DropView is `DROP VIEW [IF EXISTS] Tables [Option]`
+type DropView struct {
+	IfExists bool
+	Tables   ISimpleTables
+	Option   string
+}
 
 func (*DropView) IStatement()    {}
 func (*DropView) IDDLStatement() {}
 
+func (d *DropView) Format(buf *Buffer) {
+	formatDrop(buf, "view", d.IfExists)
+	buf.Printf("%v", d.Tables)
+	if d.Option != "" {
+		buf.Printf(" %s", d.Option)
+	}
+}
+
 func (*DropTrigger) IStatement()    {}
 func (*DropTrigger) IDDLStatement() {}
 func (*DropTrigger) HasDDLSchemas() {}
@@ -62,23 +134,35 @@ func (d *DropTrigger) GetSchemas() []string {
 }
 
 type DropTrigger struct {
-	Trigger *Spname
+	IfExists bool
+	Trigger  *Spname
+}
+
+func (d *DropTrigger) Format(buf *Buffer) {
+	formatDrop(buf, "trigger", d.IfExists)
+	buf.Printf("%v", d.Trigger)
 }
 
 func (*DropTablespace) IStatement()    {}
 func (*DropTablespace) IDDLStatement() {}
 
-type DropTablespace struct{}
+type DropTablespace struct {
+	Source
+}
 
 func (*DropLogfile) IStatement()    {}
 func (*DropLogfile) IDDLStatement() {}
 
-type DropLogfile struct{}
+type DropLogfile struct {
+	Source
+}
 
 func (*DropServer) IStatement()    {}
 func (*DropServer) IDDLStatement() {}
 
-type DropServer struct{}
+type DropServer struct {
+	Source
+}
 
 func (*DropEvent) IStatement()    {}
 func (*DropEvent) IDDLStatement() {}
@@ -88,5 +172,11 @@ func (d *DropEvent) GetSchemas() []string {
 }
 
 type DropEvent struct {
-	Event *Spname
+	IfExists bool
+	Event    *Spname
+}
+
+func (d *DropEvent) Format(buf *Buffer) {
+	formatDrop(buf, "event", d.IfExists)
+	buf.Printf("%v", d.Event)
 }
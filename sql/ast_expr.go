@@ -8,6 +8,7 @@ import (
 // IExpr represents an expression.
 type IExpr interface {
 	IExpr()
+	Node
 }
 
 type IExprs []IExpr
@@ -28,22 +29,30 @@ func (*RangeCond) IExpr() {}
 func (*LikeCond) IExpr()  {}
 
 // simple_expr
-func (StrVal) IExpr()        {}
-func (NumVal) IExpr()        {}
-func (BoolVal) IExpr()       {}
-func (HexVal) IExpr()        {}
-func (BinVal) IExpr()        {}
-func (ValArg) IExpr()        {}
-func (*NullVal) IExpr()      {}
-func (*SchemaObject) IExpr() {}
-func (IValExprs) IExpr()     {}
-func (*SubQuery) IExpr()     {}
-func (*BinaryExpr) IExpr()   {}
-func (*UnaryExpr) IExpr()    {}
-func (*IntervalExpr) IExpr() {}
-func (*FuncExpr) IExpr()     {}
-func (*CaseExpr) IExpr()     {}
-func (*CollateExpr) IExpr()  {}
+func (StrVal) IExpr()            {}
+func (NumVal) IExpr()            {}
+func (BoolVal) IExpr()           {}
+func (HexVal) IExpr()            {}
+func (BinVal) IExpr()            {}
+func (ValArg) IExpr()            {}
+func (*NullVal) IExpr()          {}
+func (*SchemaObject) IExpr()     {}
+func (IValExprs) IExpr()         {}
+func (*SubQuery) IExpr()         {}
+func (*BinaryExpr) IExpr()       {}
+func (*UnaryExpr) IExpr()        {}
+func (*IntervalExpr) IExpr()     {}
+func (*FuncExpr) IExpr()         {}
+func (*CaseExpr) IExpr()         {}
+func (*CollateExpr) IExpr()      {}
+func (*StarExpr) IExpr()         {}
+func (*GroupConcatExpr) IExpr()  {}
+func (*ConvertExpr) IExpr()      {}
+func (*UsingExpr) IExpr()        {}
+func (*TrimExpr) IExpr()         {}
+func (*ExtractExpr) IExpr()      {}
+func (*WeightStringExpr) IExpr() {}
+func (TimeUnit) IExpr()          {}
 
 // BoolExpr represents a boolean expression.
 type IBoolExpr interface {
@@ -68,27 +77,55 @@ type AndExpr struct {
 	Left, Right IExpr
 }
 
+func (e *AndExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precAnd)
+	buf.WriteString(" and ")
+	buf.formatOperand(e.Right, precAnd+1)
+}
+
 // OrExpr represents an OR expression.
 type OrExpr struct {
 	Left, Right IExpr
 }
 
+func (e *OrExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precOr)
+	buf.WriteString(" or ")
+	buf.formatOperand(e.Right, precOr+1)
+}
+
 // XorExpr represents an OR expression.
 type XorExpr struct {
 	Left, Right IExpr
 }
 
+func (e *XorExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precXor)
+	buf.WriteString(" xor ")
+	buf.formatOperand(e.Right, precXor+1)
+}
+
 // NotExpr represents a NOT expression.
 type NotExpr struct {
 	Expr IExpr
 }
 
+func (e *NotExpr) Format(buf *Buffer) {
+	buf.WriteString("not ")
+	buf.formatOperand(e.Expr, precNot)
+}
+
 // IsCheck represents an IS TRUE | FALSE | UNKNOWN expression.
 type IsCheck struct {
 	Operator string
 	Expr     IBoolExpr
 }
 
+func (e *IsCheck) Format(buf *Buffer) {
+	buf.formatOperand(e.Expr, precCompare)
+	buf.Printf(" %s", e.Operator)
+}
+
 // IsCheck.Operator
 const (
 	OP_IS_TRUE        = "is true"
@@ -105,17 +142,34 @@ type NullCheck struct {
 	Expr     IBoolExpr
 }
 
+func (e *NullCheck) Format(buf *Buffer) {
+	buf.formatOperand(e.Expr, precCompare)
+	buf.Printf(" %s", e.Operator)
+}
+
 // NullCheck.Operator
 const (
 	OP_IS_NULL     = "is null"
 	OP_IS_NOT_NULL = "is not null"
 )
 
-// CompareExpr represents a two-value comparison expression.
+// CompareExpr represents a two-value comparison expression, Quantifier
+// is ALL or ANY when Right is a subquery they apply to.
 type CompareExpr struct {
-	Operator string
-	Left     IBoolExpr
-	Right    IValExpr
+	Operator   string
+	Left       IBoolExpr
+	Right      IValExpr
+	Quantifier string
+}
+
+func (e *CompareExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precCompare)
+	buf.Printf(" %s ", e.Operator)
+	if e.Quantifier != "" {
+		buf.Printf("%s %v", e.Quantifier, e.Right)
+		return
+	}
+	buf.formatOperand(e.Right, precPredicate)
 }
 
 // CompareExpr.Operator
@@ -129,10 +183,22 @@ const (
 	OP_NSE = "<=>"
 )
 
+// CompareExpr.Quantifier
+const (
+	OP_ALL = "all"
+	OP_ANY = "any"
+)
+
 type Predicate struct {
 	Expr IValExpr
 }
 
+// Format writes the wrapped expression, its parent puts the parentheses
+// as a Predicate binds like what it wraps
+func (e *Predicate) Format(buf *Buffer) {
+	buf.Printf("%v", e.Expr)
+}
+
 // IValExpr represents a value expression.
 type IValExpr interface {
 	IValExpr()
@@ -151,32 +217,40 @@ func (*LikeCond) IValExpr()  {}
 func (*BinaryExpr) IValExpr() {}
 
 // simple_expr
-func (StrVal) IValExpr()        {} // literal
-func (NumVal) IValExpr()        {}
-func (BoolVal) IValExpr()       {}
-func (HexVal) IValExpr()        {}
-func (BinVal) IValExpr()        {}
-func (*NullVal) IValExpr()      {}
-func (*SchemaObject) IValExpr() {} // identifier
-func (*FuncExpr) IValExpr()     {} // function
-func (*CollateExpr) IValExpr()  {} // function
-func (*Variable) IValExpr()     {} // variable
-func (*Variable) IExpr()        {}
-func (*OrOrExpr) IValExpr()     {} // || expr
-func (*OrOrExpr) IExpr()        {}
-func (*UnaryExpr) IValExpr()    {} // [+|-|~|!|BINARY] simple_expr
-func (IExprs) IValExpr()        {} // (expr [, expr] ...)
-func (IExprs) IExpr()           {}
-func (IValExprs) IValExpr()     {}
-func (*ExistsExpr) IValExpr()   {} // Exists (subquery)
-func (*ExistsExpr) IExpr()      {}
-func (ValArg) IValExpr()        {}
-func (*IdentExpr) IValExpr()    {}
-func (*IdentExpr) IExpr()       {}
-func (*MatchExpr) IValExpr()    {}
-func (*MatchExpr) IExpr()       {}
-func (*CaseExpr) IValExpr()     {}
-func (*IntervalExpr) IValExpr() {}
+func (StrVal) IValExpr()            {} // literal
+func (NumVal) IValExpr()            {}
+func (BoolVal) IValExpr()           {}
+func (HexVal) IValExpr()            {}
+func (BinVal) IValExpr()            {}
+func (*NullVal) IValExpr()          {}
+func (*SchemaObject) IValExpr()     {} // identifier
+func (*FuncExpr) IValExpr()         {} // function
+func (*CollateExpr) IValExpr()      {} // function
+func (*Variable) IValExpr()         {} // variable
+func (*Variable) IExpr()            {}
+func (*OrOrExpr) IValExpr()         {} // || expr
+func (*OrOrExpr) IExpr()            {}
+func (*UnaryExpr) IValExpr()        {} // [+|-|~|!|BINARY] simple_expr
+func (IExprs) IValExpr()            {} // (expr [, expr] ...)
+func (IExprs) IExpr()               {}
+func (IValExprs) IValExpr()         {}
+func (*ExistsExpr) IValExpr()       {} // Exists (subquery)
+func (*ExistsExpr) IExpr()          {}
+func (ValArg) IValExpr()            {}
+func (*IdentExpr) IValExpr()        {}
+func (*IdentExpr) IExpr()           {}
+func (*MatchExpr) IValExpr()        {}
+func (*MatchExpr) IExpr()           {}
+func (*CaseExpr) IValExpr()         {}
+func (*IntervalExpr) IValExpr()     {}
+func (*StarExpr) IValExpr()         {}
+func (*GroupConcatExpr) IValExpr()  {}
+func (*ConvertExpr) IValExpr()      {}
+func (*UsingExpr) IValExpr()        {}
+func (*TrimExpr) IValExpr()         {}
+func (*ExtractExpr) IValExpr()      {}
+func (*WeightStringExpr) IValExpr() {}
+func (TimeUnit) IValExpr()          {}
 
 // InCond
 type InCond struct {
@@ -185,6 +259,20 @@ type InCond struct {
 	Right    IExprs
 }
 
+func (e *InCond) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precBitOr)
+	buf.Printf(" %s ", e.Operator)
+	if len(e.Right) == 1 {
+		if s, ok := e.Right[0].(*SubQuery); ok {
+			buf.Printf("%v", s)
+			return
+		}
+	}
+	buf.WriteByte('(')
+	buf.formatExprs(e.Right)
+	buf.WriteByte(')')
+}
+
 const (
 	OP_IN     = "in"
 	OP_NOT_IN = "not in"
@@ -197,16 +285,41 @@ type RangeCond struct {
 	From, To IValExpr
 }
 
+func (e *RangeCond) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precBitOr)
+	buf.Printf(" %s ", e.Operator)
+	buf.formatOperand(e.From, precBitOr)
+	buf.WriteString(" and ")
+	buf.formatOperand(e.To, precPredicate)
+}
+
 // RangeCond.Operator
 const (
 	OP_BETWEEN     = "between"
 	OP_NOT_BETWEEN = "not between"
 )
 
+// LikeCond represents a LIKE, SOUNDS LIKE or REGEXP expression, Escape
+// only goes with LIKE.
 type LikeCond struct {
 	Operator string
 	Left     IValExpr
 	Right    IValExpr
+	Escape   IValExpr
+}
+
+func (e *LikeCond) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precBitOr)
+	buf.Printf(" %s ", e.Operator)
+	if e.Operator != OP_LIKE && e.Operator != OP_NOT_LIKE {
+		buf.formatOperand(e.Right, precBitOr)
+		return
+	}
+	buf.formatOperand(e.Right, precOrOr)
+	if e.Escape != nil {
+		buf.WriteString(" escape ")
+		buf.formatOperand(e.Escape, precOrOr)
+	}
 }
 
 const (
@@ -228,6 +341,12 @@ func (s StrVal) Trim() string {
 	return strings.Trim(string([]byte(s)), `"'`)
 }
 
+// Format writes the literal the way it was written, quotes and escapes
+// included
+func (s StrVal) Format(buf *Buffer) {
+	buf.Write(s)
+}
+
 // NumVal represents a number.
 type NumVal []byte
 
@@ -239,16 +358,46 @@ func (n NumVal) ParseInt() (int, error) {
 	}
 }
 
+func (n NumVal) Format(buf *Buffer) {
+	buf.Write(n)
+}
+
 type BoolVal bool
+
+func (b BoolVal) Format(buf *Buffer) {
+	if b {
+		buf.WriteString("true")
+	} else {
+		buf.WriteString("false")
+	}
+}
+
 type HexVal []byte
+
+func (h HexVal) Format(buf *Buffer) {
+	buf.Write(h)
+}
+
 type BinVal []byte
 
+func (b BinVal) Format(buf *Buffer) {
+	buf.Write(b)
+}
+
 // ValArg represents a named bind var argument.
 type ValArg []byte
 
+func (v ValArg) Format(buf *Buffer) {
+	buf.Write(v)
+}
+
 // NullVal represents a NULL value.
 type NullVal struct{}
 
+func (*NullVal) Format(buf *Buffer) {
+	buf.WriteString("null")
+}
+
 type TemporalVal struct {
 	Prefix []byte
 	Text   []byte
@@ -258,12 +407,36 @@ type TemporalVal struct {
 // It's not a valid expression because it's not parenthesized.
 type IValExprs []IValExpr
 
+func (es IValExprs) Format(buf *Buffer) {
+	for i, e := range es {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", e)
+	}
+}
+
+// Format writes the expressions in parentheses, a single one is an
+// expression in parentheses
+func (es IExprs) Format(buf *Buffer) {
+	buf.WriteByte('(')
+	buf.formatExprs(es)
+	buf.WriteByte(')')
+}
+
 // BinaryExpr represents a binary value expression.
 type BinaryExpr struct {
 	Operator    string
 	Left, Right IExpr
 }
 
+func (e *BinaryExpr) Format(buf *Buffer) {
+	prec := precedence(e)
+	buf.formatOperand(e.Left, prec)
+	buf.Printf(" %s ", e.Operator)
+	buf.formatOperand(e.Right, prec+1)
+}
+
 // BinaryExpr.Operator
 const (
 	OP_BITAND     = "&"
@@ -273,6 +446,7 @@ const (
 	OP_MINUS      = "-"
 	OP_MULT       = "*"
 	OP_DIV        = "/"
+	OP_INTDIV     = "div"
 	OP_MOD        = "%"
 	OP_SHIFTLEFT  = "<<"
 	OP_SHIFTRIGHT = ">>"
@@ -284,6 +458,15 @@ type UnaryExpr struct {
 	Expr     IExpr
 }
 
+func (e *UnaryExpr) Format(buf *Buffer) {
+	buf.WriteString(e.Operator)
+	if u, ok := e.Expr.(*UnaryExpr); e.Operator == OP_UBINARY || ok && u.Operator == e.Operator {
+		//keep apart BINARY x, and - -x which is not a comment
+		buf.WriteByte(' ')
+	}
+	buf.formatOperand(e.Expr, precUnary)
+}
+
 // UnaryExpr.Operator
 const (
 	OP_UPLUS   = "+"
@@ -300,42 +483,253 @@ type IntervalExpr struct {
 	Interval []byte
 }
 
+func (e *IntervalExpr) Format(buf *Buffer) {
+	buf.Printf("interval %v %s", e.Expr, e.Interval)
+}
+
 type OrOrExpr struct {
 	Left, Right IValExpr
 }
 
-// FuncExpr represents a function call.
+func (e *OrOrExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Left, precOrOr)
+	buf.WriteString(" || ")
+	buf.formatOperand(e.Right, precOrOr+1)
+}
+
+// FuncExpr represents a function call, Qualifier is the database of a
+// stored function.
 type FuncExpr struct {
-	Name     []byte
-	Distinct bool
-	Exprs    []IExpr
+	Qualifier []byte
+	Name      []byte
+	Distinct  bool
+	Exprs     []IExpr
+}
+
+func (e *FuncExpr) Format(buf *Buffer) {
+	if len(e.Qualifier) > 0 {
+		buf.formatNames(e.Qualifier, e.Name)
+	} else if plainName(e.Name) || isKeyword(e.Name) {
+		//the name of a built-in function is a keyword
+		buf.Write(e.Name)
+	} else {
+		buf.formatName(e.Name)
+	}
+	buf.WriteByte('(')
+	if e.Distinct {
+		buf.WriteString("distinct ")
+	}
+	buf.formatExprs(e.Exprs)
+	buf.WriteByte(')')
+}
+
+func isKeyword(name []byte) bool {
+	upper := strings.ToUpper(string(name))
+	if _, ok := Functions[upper]; ok {
+		return true
+	}
+	_, ok := Symbols[upper]
+	return ok
+}
+
+// StarExpr is the * of a select list or COUNT(*), Table and Schema
+// qualify it.
+type StarExpr struct {
+	Schema []byte
+	Table  []byte
+}
+
+func (e *StarExpr) Format(buf *Buffer) {
+	if len(e.Table) > 0 {
+		buf.formatNames(e.Schema, e.Table)
+		buf.WriteByte('.')
+	}
+	buf.WriteByte('*')
+}
+
+// GroupConcatExpr represents a GROUP_CONCAT() call.
+type GroupConcatExpr struct {
+	Distinct  bool
+	Exprs     IExprs
+	OrderBy   OrderBy
+	Separator StrVal
+}
+
+func (e *GroupConcatExpr) Format(buf *Buffer) {
+	buf.WriteString("group_concat(")
+	if e.Distinct {
+		buf.WriteString("distinct ")
+	}
+	buf.formatExprs(e.Exprs)
+	if len(e.OrderBy) > 0 {
+		buf.Printf(" order by %v", e.OrderBy)
+	}
+	if e.Separator != nil {
+		buf.Printf(" separator %v", e.Separator)
+	}
+	buf.WriteByte(')')
+}
+
+// ConvertExpr represents CAST(expr AS type) and CONVERT(expr, type), Type
+// is the text of the type.
+type ConvertExpr struct {
+	Name []byte
+	Expr IExpr
+	Type string
+}
+
+func (e *ConvertExpr) Format(buf *Buffer) {
+	if strings.EqualFold(string(e.Name), "cast") {
+		buf.Printf("%s(%v as %s)", e.Name, e.Expr, e.Type)
+	} else {
+		buf.Printf("%s(%v, %s)", e.Name, e.Expr, e.Type)
+	}
+}
+
+// UsingExpr represents CONVERT(expr USING charset) and
+// CHAR(expr, ... USING charset).
+type UsingExpr struct {
+	Name    []byte
+	Exprs   IExprs
+	Charset []byte
+}
+
+func (e *UsingExpr) Format(buf *Buffer) {
+	buf.Printf("%s(", e.Name)
+	buf.formatExprs(e.Exprs)
+	buf.WriteString(" using ")
+	buf.formatIdentOrText(e.Charset)
+	buf.WriteByte(')')
+}
+
+// TrimExpr represents TRIM([[BOTH | LEADING | TRAILING] [remstr] FROM] str).
+type TrimExpr struct {
+	Direction string
+	Remove    IExpr
+	Expr      IExpr
+}
+
+func (e *TrimExpr) Format(buf *Buffer) {
+	buf.WriteString("trim(")
+	if e.Direction != "" {
+		buf.Printf("%s ", e.Direction)
+	}
+	if e.Remove != nil {
+		buf.Printf("%v ", e.Remove)
+	}
+	if e.Direction != "" || e.Remove != nil {
+		buf.WriteString("from ")
+	}
+	buf.Printf("%v)", e.Expr)
+}
+
+// TrimExpr.Direction
+const (
+	TRIM_BOTH     = "both"
+	TRIM_LEADING  = "leading"
+	TRIM_TRAILING = "trailing"
+)
+
+// ExtractExpr represents EXTRACT(unit FROM expr).
+type ExtractExpr struct {
+	Unit []byte
+	Expr IExpr
+}
+
+func (e *ExtractExpr) Format(buf *Buffer) {
+	buf.Printf("extract(%s from %v)", e.Unit, e.Expr)
+}
+
+// WeightStringExpr represents WEIGHT_STRING(expr [AS type] [LEVEL levels]),
+// As and Levels are the text of the clauses.
+type WeightStringExpr struct {
+	Expr   IExpr
+	As     string
+	Levels string
+}
+
+func (e *WeightStringExpr) Format(buf *Buffer) {
+	buf.Printf("weight_string(%v", e.Expr)
+	if e.As != "" {
+		buf.Printf(" as %s", e.As)
+	}
+	if e.Levels != "" {
+		buf.Printf(" level %s", e.Levels)
+	}
+	buf.WriteByte(')')
+}
+
+// TimeUnit is a unit or a type keyword given to a date and time function,
+// like the DAY of TIMESTAMPADD(DAY, 1, d).
+type TimeUnit []byte
+
+func (u TimeUnit) Format(buf *Buffer) {
+	buf.Write(u)
 }
 
 // CaseExpr represents a CASE expression.
 type CaseExpr struct {
-	Expr  IValExpr
+	Expr  IExpr
 	Whens []*When
-	Else  IValExpr
+	Else  IExpr
+}
+
+func (e *CaseExpr) Format(buf *Buffer) {
+	buf.WriteString("case ")
+	if e.Expr != nil {
+		buf.Printf("%v ", e.Expr)
+	}
+	for _, w := range e.Whens {
+		buf.Printf("%v ", w)
+	}
+	if e.Else != nil {
+		buf.Printf("else %v ", e.Else)
+	}
+	buf.WriteString("end")
 }
 
 // When represents a WHEN sub-expression.
 type When struct {
-	Cond IBoolExpr
-	Val  IValExpr
+	Cond IExpr
+	Val  IExpr
+}
+
+func (w *When) Format(buf *Buffer) {
+	buf.Printf("when %v then %v", w.Cond, w.Val)
 }
 
 // GroupBy represents a GROUP BY clause.
-type GroupBy []IValExpr
+type GroupBy []*Order
+
+func (g GroupBy) Format(buf *Buffer) {
+	OrderBy(g).Format(buf)
+}
 
 // OrderBy represents an ORDER By clause.
 type OrderBy []*Order
 
+func (o OrderBy) Format(buf *Buffer) {
+	for i, order := range o {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", order)
+	}
+}
+
 // Order represents an ordering expression.
 type Order struct {
-	Expr      IValExpr
+	Expr      IExpr
 	Direction string
 }
 
+func (o *Order) Format(buf *Buffer) {
+	buf.Printf("%v", o.Expr)
+	if o.Direction != "" {
+		buf.Printf(" %s", o.Direction)
+	}
+}
+
 // Order.Direction
 const (
 	OP_ASC  = "asc"
@@ -347,6 +741,14 @@ type Limit struct {
 	Offset, Rowcount IValExpr
 }
 
+func (l *Limit) Format(buf *Buffer) {
+	buf.WriteString("limit ")
+	if l.Offset != nil {
+		buf.Printf("%v, ", l.Offset)
+	}
+	buf.Printf("%v", l.Rowcount)
+}
+
 // SchemaObject
 type SchemaObject struct {
 	Schema []byte
@@ -354,19 +756,44 @@ type SchemaObject struct {
 	Column []byte
 }
 
+func (s *SchemaObject) Format(buf *Buffer) {
+	buf.formatNames(s.Schema, s.Table, s.Column)
+}
+
 // ExistsExpr
 type ExistsExpr struct {
 	SubQuery *SubQuery
 }
 
-// IdentExpr
+func (e *ExistsExpr) Format(buf *Buffer) {
+	buf.Printf("exists %v", e.SubQuery)
+}
+
+// IdentExpr is an ODBC escape like {d '2006-01-02'}
 type IdentExpr struct {
 	Ident []byte
 	Expr  IExpr
 }
 
-// MatchExpr
+func (e *IdentExpr) Format(buf *Buffer) {
+	buf.WriteByte('{')
+	buf.formatName(e.Ident)
+	buf.Printf(" %v}", e.Expr)
+}
+
+// MatchExpr represents MATCH (columns) AGAINST (expr [modifier]).
 type MatchExpr struct {
+	Columns  IValExprs
+	Expr     IValExpr
+	Modifier string
+}
+
+func (e *MatchExpr) Format(buf *Buffer) {
+	buf.Printf("match (%v) against (%v", e.Columns, e.Expr)
+	if e.Modifier != "" {
+		buf.Printf(" %s", e.Modifier)
+	}
+	buf.WriteByte(')')
 }
 
 // CollateExpr
@@ -374,3 +801,79 @@ type CollateExpr struct {
 	Expr    IValExpr
 	Collate []byte
 }
+
+func (e *CollateExpr) Format(buf *Buffer) {
+	buf.formatOperand(e.Expr, precCollate)
+	buf.WriteString(" collate ")
+	buf.formatIdentOrText(e.Collate)
+}
+
+// the precedence of operators, from the loosest binding
+const (
+	precAssign = iota + 1
+	precOr
+	precXor
+	precAnd
+	precNot
+	precIs
+	precCompare
+	precPredicate
+	precBitOr
+	precBitAnd
+	precShift
+	precAdd
+	precMult
+	precBitXor
+	precOrOr
+	precUnary
+	precCollate
+	precAtom
+)
+
+// precedence is how tightly e binds to its operands, it decides where
+// Format needs parentheses
+func precedence(e IExpr) int {
+	switch v := e.(type) {
+	case *OrExpr:
+		return precOr
+	case *XorExpr:
+		return precXor
+	case *AndExpr:
+		return precAnd
+	case *NotExpr:
+		return precNot
+	case *IsCheck:
+		return precIs
+	case *NullCheck, *CompareExpr:
+		return precCompare
+	case *Predicate:
+		return precedence(v.Expr)
+	case *InCond, *RangeCond, *LikeCond:
+		return precPredicate
+	case *BinaryExpr:
+		switch v.Operator {
+		case OP_BITOR:
+			return precBitOr
+		case OP_BITAND:
+			return precBitAnd
+		case OP_SHIFTLEFT, OP_SHIFTRIGHT:
+			return precShift
+		case OP_PLUS, OP_MINUS:
+			return precAdd
+		case OP_BITXOR:
+			return precBitXor
+		}
+		return precMult
+	case *OrOrExpr:
+		return precOrOr
+	case *UnaryExpr:
+		return precUnary
+	case *CollateExpr:
+		return precCollate
+	case *Variable:
+		if v.Value != nil {
+			return precAssign
+		}
+	}
+	return precAtom
+}
@@ -1,13 +1,19 @@
 package sql
 
-type Deallocate struct{}
+type Deallocate struct {
+	Source
+}
 
 func (*Deallocate) IStatement() {}
 
-type Prepare struct{}
+type Prepare struct {
+	Source
+}
 
 func (*Prepare) IStatement() {}
 
-type Execute struct{}
+type Execute struct {
+	Source
+}
 
 func (*Execute) IStatement() {}
@@ -1,17 +1,25 @@
 package sql
 
-type Change struct{}
+type Change struct {
+	Source
+}
 
 func (*Change) IStatement() {}
 
-type Purge struct{}
+type Purge struct {
+	Source
+}
 
 func (*Purge) IStatement() {}
 
-type StartSlave struct{}
+type StartSlave struct {
+	Source
+}
 
 func (*StartSlave) IStatement() {}
 
-type StopSlave struct{}
+type StopSlave struct {
+	Source
+}
 
 func (*StopSlave) IStatement() {}
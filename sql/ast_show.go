@@ -63,6 +63,18 @@ func (*ShowTableStatus) IShow()      {}
 func (*ShowAegisStatus) IStatement() {}
 func (*ShowAegisStatus) IShow()      {}
 
+func (*ShowAegisBackendProcessList) IStatement() {}
+func (*ShowAegisBackendProcessList) IShow()      {}
+
+func (*ShowAegisPools) IStatement() {}
+func (*ShowAegisPools) IShow()      {}
+
+func (*ShowAegisBackends) IStatement() {}
+func (*ShowAegisBackends) IShow()      {}
+
+func (*ShowAegisSessions) IStatement() {}
+func (*ShowAegisSessions) IShow()      {}
+
 func (*ShowPlugins) IStatement() {}
 func (*ShowPlugins) IShow()      {}
 
@@ -77,6 +89,8 @@ func (*ShowProcessList) IShow()      {}
 
 func (*ShowProfiles) IStatement() {}
 func (*ShowProfiles) IShow()      {}
+func (*ShowProfile) IStatement()  {}
+func (*ShowProfile) IShow()       {}
 
 func (*ShowSlaveHosts) IStatement()   {}
 func (*ShowSlaveHosts) IShow()        {}
@@ -91,15 +105,52 @@ func (*ShowTriggers) IShow()      {}
 func (*ShowVariables) IStatement() {}
 func (*ShowVariables) IShow()      {}
 
-// currently we use only like for `show databases` syntax
+// LikeOrWhere is the `LIKE Like` or `WHERE Where` filter of a SHOW, Like
+// is the string as it was written
 type LikeOrWhere struct {
-	Like string
+	Like  string
+	Where IExpr
+}
+
+func (l *LikeOrWhere) Format(buf *Buffer) {
+	if l.Where != nil {
+		buf.Printf("where %v", l.Where)
+		return
+	}
+	buf.Printf("like %s", l.Like)
+}
+
+// formatShow writes `SHOW What [FROM From] [LikeOrWhere]`
+func formatShow(buf *Buffer, what string, from []byte, lw *LikeOrWhere) {
+	buf.Printf("show %s", what)
+	formatShowFilter(buf, from, lw)
+}
+
+func formatShowFilter(buf *Buffer, from []byte, lw *LikeOrWhere) {
+	if len(from) > 0 {
+		buf.WriteString(" from ")
+		buf.formatName(from)
+	}
+	if lw != nil {
+		buf.Printf(" %v", lw)
+	}
+}
+
+func full(what string, full bool) string {
+	if full {
+		return "full " + what
+	}
+	return what
 }
 
 type ShowDatabases struct {
 	LikeOrWhere *LikeOrWhere
 }
 
+func (s *ShowDatabases) Format(buf *Buffer) {
+	formatShow(buf, "databases", nil, s.LikeOrWhere)
+}
+
 func (s *ShowTables) GetSchemas() []string {
 	if s.From == nil || len(s.From) == 0 {
 		return nil
@@ -109,7 +160,13 @@ func (s *ShowTables) GetSchemas() []string {
 }
 
 type ShowTables struct {
-	From []byte
+	Full        bool
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowTables) Format(buf *Buffer) {
+	formatShow(buf, full("tables", s.Full), s.From, s.LikeOrWhere)
 }
 
 func (s *ShowTriggers) GetSchemas() []string {
@@ -121,7 +178,13 @@ func (s *ShowTriggers) GetSchemas() []string {
 }
 
 type ShowTriggers struct {
-	From []byte
+	Full        bool
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowTriggers) Format(buf *Buffer) {
+	formatShow(buf, full("triggers", s.Full), s.From, s.LikeOrWhere)
 }
 
 func (s *ShowEvents) GetSchemas() []string {
@@ -133,7 +196,12 @@ func (s *ShowEvents) GetSchemas() []string {
 }
 
 type ShowEvents struct {
-	From []byte
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowEvents) Format(buf *Buffer) {
+	formatShow(buf, "events", s.From, s.LikeOrWhere)
 }
 
 func (s *ShowTableStatus) GetSchemas() []string {
@@ -145,7 +213,12 @@ func (s *ShowTableStatus) GetSchemas() []string {
 }
 
 type ShowTableStatus struct {
-	From []byte
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowTableStatus) Format(buf *Buffer) {
+	formatShow(buf, "table status", s.From, s.LikeOrWhere)
 }
 
 func (s *ShowOpenTables) GetSchemas() []string {
@@ -157,7 +230,12 @@ func (s *ShowOpenTables) GetSchemas() []string {
 }
 
 type ShowOpenTables struct {
-	From []byte
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowOpenTables) Format(buf *Buffer) {
+	formatShow(buf, "open tables", s.From, s.LikeOrWhere)
 }
 
 func (s *ShowColumns) GetSchemas() []string {
@@ -168,9 +246,18 @@ func (s *ShowColumns) GetSchemas() []string {
 	return []string{string(s.From)}
 }
 
+// ShowColumns is `SHOW [FULL] COLUMNS FROM Table [FROM From]
+// [LikeOrWhere]`
 type ShowColumns struct {
-	Table ISimpleTable
-	From  []byte
+	Full        bool
+	Table       ISimpleTable
+	From        []byte
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowColumns) Format(buf *Buffer) {
+	buf.Printf("show %s from %v", full("columns", s.Full), s.Table)
+	formatShowFilter(buf, s.From, s.LikeOrWhere)
 }
 
 func (s *ShowIndex) GetSchemas() []string {
@@ -181,36 +268,74 @@ func (s *ShowIndex) GetSchemas() []string {
 	return []string{string(s.From)}
 }
 
+// ShowIndex is `SHOW INDEX FROM Table [FROM From] [WHERE Where]`
 type ShowIndex struct {
 	Table ISimpleTable
 	From  []byte
+	Where IExpr
+}
+
+func (s *ShowIndex) Format(buf *Buffer) {
+	buf.Printf("show index from %v", s.Table)
+	formatShowFilter(buf, s.From, nil)
+	if s.Where != nil {
+		buf.Printf(" where %v", s.Where)
+	}
 }
 
 func (s *ShowProcedure) GetSchemas() []string {
 	return s.Procedure.GetSchemas()
 }
 
+// ShowProcedure is `SHOW PROCEDURE CODE Procedure`, or `SHOW PROCEDURE
+// STATUS [LikeOrWhere]` when Procedure is nil
 type ShowProcedure struct {
-	Procedure *Spname
+	Procedure   *Spname
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowProcedure) Format(buf *Buffer) {
+	if s.Procedure != nil {
+		buf.Printf("show procedure code %v", s.Procedure)
+		return
+	}
+	formatShow(buf, "procedure status", nil, s.LikeOrWhere)
 }
 
 func (s *ShowFunction) GetSchemas() []string {
 	return s.Function.GetSchemas()
 }
 
+// ShowFunction is `SHOW FUNCTION CODE Function`, or `SHOW FUNCTION STATUS
+// [LikeOrWhere]` when Function is nil
 type ShowFunction struct {
-	Function *Spname
+	Function    *Spname
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowFunction) Format(buf *Buffer) {
+	if s.Function != nil {
+		buf.Printf("show function code %v", s.Function)
+		return
+	}
+	formatShow(buf, "function status", nil, s.LikeOrWhere)
 }
 
 func (s *ShowCreate) GetSchemas() []string {
 	return s.Table.GetSchemas()
 }
 
+// ShowCreate is `SHOW CREATE Prefix Table`, Prefix is "table", "view",
+// "procedure", "function", "trigger" or "event"
 type ShowCreate struct {
-	Prefix []byte
+	Prefix string
 	Table  ISimpleTable
 }
 
+func (s *ShowCreate) Format(buf *Buffer) {
+	buf.Printf("show create %s %v", s.Prefix, s.Table)
+}
+
 func (s *ShowCreateDatabase) GetSchemas() []string {
 	if s.Schema == nil || len(s.Schema) == 0 {
 		return nil
@@ -220,24 +345,255 @@ func (s *ShowCreateDatabase) GetSchemas() []string {
 }
 
 type ShowCreateDatabase struct {
-	Schema []byte
+	IfNotExists bool
+	Schema      []byte
+}
+
+func (s *ShowCreateDatabase) Format(buf *Buffer) {
+	buf.WriteString("show create database ")
+	if s.IfNotExists {
+		buf.WriteString("if not exists ")
+	}
+	buf.formatName(s.Schema)
+}
+
+// ShowGrants is `SHOW GRANTS [FOR User]`, User is the text of the account
+type ShowGrants struct {
+	User string
+}
+
+func (s *ShowGrants) Format(buf *Buffer) {
+	buf.WriteString("show grants")
+	if s.User != "" {
+		buf.Printf(" for %s", s.User)
+	}
+}
+
+type ShowCollation struct {
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowCollation) Format(buf *Buffer) {
+	formatShow(buf, "collation", nil, s.LikeOrWhere)
+}
+
+type ShowCharset struct {
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowCharset) Format(buf *Buffer) {
+	formatShow(buf, "charset", nil, s.LikeOrWhere)
+}
+
+// lifeShow is what of `SHOW [GLOBAL | SESSION] What`
+func lifeShow(what string, life LifeType) string {
+	switch life {
+	case Life_Global:
+		return "global " + what
+	case Life_Local:
+		return "local " + what
+	case Life_Session:
+		return "session " + what
+	}
+	return what
+}
+
+type ShowVariables struct {
+	Life        LifeType
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowVariables) Format(buf *Buffer) {
+	formatShow(buf, lifeShow("variables", s.Life), nil, s.LikeOrWhere)
+}
+
+type ShowProcessList struct {
+	Full bool
+}
+
+func (s *ShowProcessList) Format(buf *Buffer) {
+	formatShow(buf, full("processlist", s.Full), nil, nil)
+}
+
+type ShowStatus struct {
+	Life        LifeType
+	LikeOrWhere *LikeOrWhere
+}
+
+func (s *ShowStatus) Format(buf *Buffer) {
+	formatShow(buf, lifeShow("status", s.Life), nil, s.LikeOrWhere)
 }
 
-type ShowGrants struct{}
-type ShowCollation struct{}
-type ShowCharset struct{}
-type ShowVariables struct{}
-type ShowProcessList struct{}
-type ShowStatus struct{}
 type ShowProfiles struct{}
+
+func (*ShowProfiles) Format(buf *Buffer) {
+	buf.WriteString("show profiles")
+}
+
+// ShowProfile is `SHOW PROFILE [Types] [FOR QUERY Query] [Limit]`, a type
+// is "cpu", "block io" and the like
+type ShowProfile struct {
+	Types []string
+	Query NumVal
+	Limit *Limit
+}
+
+func (s *ShowProfile) Format(buf *Buffer) {
+	buf.WriteString("show profile")
+	for i, t := range s.Types {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Printf(" %s", t)
+	}
+	if s.Query != nil {
+		buf.Printf(" for query %v", s.Query)
+	}
+	if s.Limit != nil {
+		buf.Printf(" %v", s.Limit)
+	}
+}
+
 type ShowPrivileges struct{}
-type ShowWarnings struct{}
-type ShowErrors struct{}
-type ShowLogEvents struct{}
+
+func (*ShowPrivileges) Format(buf *Buffer) {
+	buf.WriteString("show privileges")
+}
+
+// ShowWarnings is `SHOW WARNINGS [Limit]` or `SHOW COUNT(*) WARNINGS`
+type ShowWarnings struct {
+	Count bool
+	Limit *Limit
+}
+
+func (s *ShowWarnings) Format(buf *Buffer) {
+	formatDiagnostics(buf, "warnings", s.Count, s.Limit)
+}
+
+// ShowErrors is `SHOW ERRORS [Limit]` or `SHOW COUNT(*) ERRORS`
+type ShowErrors struct {
+	Count bool
+	Limit *Limit
+}
+
+func (s *ShowErrors) Format(buf *Buffer) {
+	formatDiagnostics(buf, "errors", s.Count, s.Limit)
+}
+
+func formatDiagnostics(buf *Buffer, what string, count bool, limit *Limit) {
+	if count {
+		buf.Printf("show count(*) %s", what)
+		return
+	}
+	buf.Printf("show %s", what)
+	if limit != nil {
+		buf.Printf(" %v", limit)
+	}
+}
+
+// ShowLogEvents is `SHOW {BINLOG | RELAYLOG} EVENTS [IN In] [FROM From]
+// [Limit]`
+type ShowLogEvents struct {
+	Relay bool
+	In    StrVal
+	From  NumVal
+	Limit *Limit
+}
+
+func (s *ShowLogEvents) Format(buf *Buffer) {
+	if s.Relay {
+		buf.WriteString("show relaylog events")
+	} else {
+		buf.WriteString("show binlog events")
+	}
+	if s.In != nil {
+		buf.Printf(" in %v", s.In)
+	}
+	if s.From != nil {
+		buf.Printf(" from %v", s.From)
+	}
+	if s.Limit != nil {
+		buf.Printf(" %v", s.Limit)
+	}
+}
+
 type ShowSlaveHosts struct{}
+
+func (*ShowSlaveHosts) Format(buf *Buffer) {
+	buf.WriteString("show slave hosts")
+}
+
 type ShowSlaveStatus struct{}
+
+func (*ShowSlaveStatus) Format(buf *Buffer) {
+	buf.WriteString("show slave status")
+}
+
 type ShowAegisStatus struct{}
+
+func (*ShowAegisStatus) Format(buf *Buffer) {
+	buf.WriteString("show aegis status")
+}
+
+type ShowAegisBackendProcessList struct{}
+
+func (*ShowAegisBackendProcessList) Format(buf *Buffer) {
+	buf.WriteString("show aegis backend processlist")
+}
+
+type ShowAegisPools struct{}
+
+func (*ShowAegisPools) Format(buf *Buffer) {
+	buf.WriteString("show aegis pools")
+}
+
+type ShowAegisBackends struct{}
+
+func (*ShowAegisBackends) Format(buf *Buffer) {
+	buf.WriteString("show aegis backends")
+}
+
+type ShowAegisSessions struct{}
+
+func (*ShowAegisSessions) Format(buf *Buffer) {
+	buf.WriteString("show aegis sessions")
+}
+
 type ShowMasterStatus struct{}
+
+func (*ShowMasterStatus) Format(buf *Buffer) {
+	buf.WriteString("show master status")
+}
+
 type ShowLogs struct{}
+
+func (*ShowLogs) Format(buf *Buffer) {
+	buf.WriteString("show binary logs")
+}
+
 type ShowPlugins struct{}
-type ShowEngines struct{}
+
+func (*ShowPlugins) Format(buf *Buffer) {
+	buf.WriteString("show plugins")
+}
+
+// ShowEngines is `SHOW ENGINES`, or `SHOW ENGINE Engine Param` when Param
+// is "status", "mutex" or "logs". Engine is nil for ALL.
+type ShowEngines struct {
+	Engine []byte
+	Param  string
+}
+
+func (s *ShowEngines) Format(buf *Buffer) {
+	if s.Param == "" {
+		buf.WriteString("show engines")
+		return
+	}
+	buf.WriteString("show engine ")
+	if len(s.Engine) == 0 {
+		buf.WriteString("all")
+	} else {
+		buf.formatIdentOrText(s.Engine)
+	}
+	buf.Printf(" %s", s.Param)
+}
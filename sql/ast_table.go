@@ -15,10 +15,20 @@ type ITable interface {
 	IsTable()
 	GetSchemas() []string
 	GetTables() []string
+	Node
 }
 
 type ITables []ITable
 
+func (ts ITables) Format(buf *Buffer) {
+	for i, t := range ts {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", t)
+	}
+}
+
 func (ts ITables) GetSchemas() []string {
 	if ts == nil && len(ts) == 0 {
 		return nil
@@ -61,11 +71,47 @@ func (*JoinTable) IsTable()    {}
 func (*ParenTable) IsTable()   {}
 func (*AliasedTable) IsTable() {}
 
+// JoinTable is `Left Join Right [ON On | USING (Using)]`
 type JoinTable struct {
 	Left  ITable
-	Join  []byte
+	Join  string
 	Right ITable
-	// TODO On    BoolExpr
+	On    IExpr
+	Using [][]byte
+}
+
+// JoinTable.Join
+const (
+	JOIN               = "join"
+	JOIN_INNER         = "inner join"
+	JOIN_CROSS         = "cross join"
+	JOIN_STRAIGHT      = "straight_join"
+	JOIN_LEFT          = "left join"
+	JOIN_RIGHT         = "right join"
+	JOIN_NATURAL       = "natural join"
+	JOIN_NATURAL_LEFT  = "natural left join"
+	JOIN_NATURAL_RIGHT = "natural right join"
+)
+
+func (j *JoinTable) Format(buf *Buffer) {
+	buf.Printf("%v %s %v", j.Left, j.Join, j.Right)
+	if j.On != nil {
+		buf.Printf(" on %v", j.On)
+	}
+	if len(j.Using) > 0 {
+		buf.WriteString(" using (")
+		formatNameList(buf, j.Using)
+		buf.WriteByte(')')
+	}
+}
+
+func formatNameList(buf *Buffer, names [][]byte) {
+	for i, name := range names {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.formatName(name)
+	}
 }
 
 func (j *JoinTable) GetSchemas() []string {
@@ -115,35 +161,34 @@ func (j *JoinTable) GetTables() []string {
 	return append(l, r...)
 }
 
+// ParenTable is a list of tables in parentheses
 type ParenTable struct {
-	Table ITable
+	Tables ITables
 }
 
 func (p *ParenTable) GetSchemas() []string {
-	if p.Table == nil {
-		return nil
-	}
-	return p.Table.GetSchemas()
+	return p.Tables.GetSchemas()
 }
 
 func (p *ParenTable) GetTables() []string {
-	if p.Table == nil {
-		return nil
-	}
-	return p.Table.GetTables()
+	return p.Tables.GetTables()
+}
+
+func (p *ParenTable) Format(buf *Buffer) {
+	buf.Printf("(%v)", p.Tables)
 }
 
 type AliasedTable struct {
 	TableOrSubQuery interface{} // here may be the table_ident or subquery
 	As              []byte
-	// TODO IndexHints
+	IndexHints      []*IndexHint
 }
 
 func (a *AliasedTable) GetSchemas() []string {
 	if t, ok := a.TableOrSubQuery.(ITable); ok {
 		return t.GetSchemas()
-	} else if s, can := a.TableOrSubQuery.(*SubQuery); can {
-		return s.SelectStatement.GetSchemas()
+	} else if s, can := a.TableOrSubQuery.(ISelect); can {
+		return s.GetSchemas()
 	} else {
 		panic(fmt.Sprintf("alias table has no table_factor or subquery, element type[%T]", a.TableOrSubQuery))
 	}
@@ -154,11 +199,101 @@ func (a *AliasedTable) GetTables() []string {
 		return t.GetTables()
 	} else if s, can := a.TableOrSubQuery.(*SubQuery); can {
 		return s.SelectStatement.GetTables()
+	} else if s, can := a.TableOrSubQuery.(ISelect); can {
+		return s.GetTables()
 	} else {
 		panic(fmt.Sprintf("alias table has no table_factor or subquery, element type[%T]", a.TableOrSubQuery))
 	}
 }
 
+func (a *AliasedTable) Format(buf *Buffer) {
+	switch t := a.TableOrSubQuery.(type) {
+	case *SubQuery:
+		buf.Printf("%v", t)
+	case ISelect:
+		buf.Printf("(%v)", t)
+	case Node:
+		buf.Printf("%v", t)
+	}
+	if len(a.As) > 0 {
+		buf.WriteString(" as ")
+		buf.formatName(a.As)
+	}
+	for _, h := range a.IndexHints {
+		buf.Printf(" %v", h)
+	}
+}
+
+// IndexHint is `{USE | IGNORE | FORCE} INDEX [FOR For] (Indexes)`
+type IndexHint struct {
+	Type    string
+	For     string
+	Indexes [][]byte
+}
+
+// IndexHint.Type
+const (
+	INDEX_USE    = "use"
+	INDEX_IGNORE = "ignore"
+	INDEX_FORCE  = "force"
+)
+
+// IndexHint.For
+const (
+	INDEX_FOR_JOIN     = "join"
+	INDEX_FOR_ORDER_BY = "order by"
+	INDEX_FOR_GROUP_BY = "group by"
+)
+
+func (h *IndexHint) Format(buf *Buffer) {
+	buf.Printf("%s index ", h.Type)
+	if h.For != "" {
+		buf.Printf("for %s ", h.For)
+	}
+	buf.WriteByte('(')
+	formatNameList(buf, h.Indexes)
+	buf.WriteByte(')')
+}
+
+// newDerivedTable is the table of `(derived) [AS alias]`. derived is what
+// select_derived_union builds, a *Select whose From are the tables in the
+// parentheses unless it is a query.
+func newDerivedTable(derived ISelect, as []byte) ITable {
+	s, ok := derived.(*Select)
+	if !ok {
+		return &AliasedTable{TableOrSubQuery: &SubQuery{SelectStatement: derived}, As: as}
+	}
+	if len(s.From) == 1 {
+		if a, ok := s.From[0].(*AliasedTable); ok && len(a.As) == 0 {
+			switch v := a.TableOrSubQuery.(type) {
+			case *SubQuery:
+				return &AliasedTable{TableOrSubQuery: v, As: as}
+			case ISelect:
+				return &AliasedTable{TableOrSubQuery: &SubQuery{SelectStatement: v}, As: as}
+			}
+		}
+	}
+	return &ParenTable{Tables: s.From}
+}
+
+// derivedSelect is the query of derived, the left side of a union in
+// parentheses
+func derivedSelect(derived ISelect) ISelect {
+	s, ok := derived.(*Select)
+	if !ok || len(s.From) != 1 {
+		return derived
+	}
+	if a, ok := s.From[0].(*AliasedTable); ok && len(a.As) == 0 {
+		switch v := a.TableOrSubQuery.(type) {
+		case *SubQuery:
+			return &ParenSelect{Select: v.SelectStatement}
+		case ISelect:
+			return v
+		}
+	}
+	return derived
+}
+
 // SimpleTable contains only qualifier, name and a column field
 func (*SimpleTable) IsSimpleTable() {}
 func (*SimpleTable) IsTable()       {}
@@ -168,10 +303,25 @@ type ISimpleTable interface {
 	ITable
 }
 
+// SimpleTable is a table name, Column is the * of `t.*` and Partitions
+// the PARTITION (p, ...) after the name
 type SimpleTable struct {
-	Qualifier []byte
-	Name      []byte
-	Column    []byte
+	Qualifier  []byte
+	Name       []byte
+	Column     []byte
+	Partitions [][]byte
+}
+
+func (s *SimpleTable) Format(buf *Buffer) {
+	buf.formatNames(s.Qualifier, s.Name)
+	if len(s.Column) > 0 {
+		buf.WriteString(".*")
+	}
+	if len(s.Partitions) > 0 {
+		buf.WriteString(" partition (")
+		formatNameList(buf, s.Partitions)
+		buf.WriteByte(')')
+	}
 }
 
 func (s *SimpleTable) GetSchemas() []string {
@@ -190,6 +340,15 @@ func (s *SimpleTable) GetTables() []string {
 
 type ISimpleTables []ISimpleTable
 
+func (ts ISimpleTables) Format(buf *Buffer) {
+	for i, t := range ts {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", t)
+	}
+}
+
 func (ts ISimpleTables) GetSchemas() []string {
 	if ts == nil && len(ts) == 0 {
 		return nil
@@ -232,6 +391,10 @@ type Spname struct {
 	Name      []byte
 }
 
+func (s *Spname) Format(buf *Buffer) {
+	buf.formatNames(s.Qualifier, s.Name)
+}
+
 type SchemaInfo struct {
 	Name []byte
 }
@@ -255,3 +418,7 @@ type TableToTable struct {
 	From ISimpleTable
 	To   ISimpleTable
 }
+
+func (t *TableToTable) Format(buf *Buffer) {
+	buf.Printf("%v to %v", t.From, t.To)
+}
@@ -11,34 +11,128 @@ func (*SavePoint) IStatement()  {}
 func (*Release) IStatement()    {}
 func (*SetTrans) IStatement()   {}
 
-type StartTrans struct{}
+type StartTrans struct {
+	Source
+}
 
 func (l *Lock) GetSchemas() []string {
-	return l.Tables.GetSchemas()
+	var ret []string
+	for _, t := range l.Tables {
+		ret = append(ret, t.Table.GetSchemas()...)
+	}
+	return ret
 }
 
+// Lock is `LOCK TABLES Tables`
 type Lock struct {
-	Tables ISimpleTables
+	Tables []*TableLock
+}
+
+func (l *Lock) Format(buf *Buffer) {
+	buf.WriteString("lock tables ")
+	for i, t := range l.Tables {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", t)
+	}
 }
 
-type Unlock struct{}
+// TableLock is `Table [AS As] Type`, Type is one of the LOCK_* below
+type TableLock struct {
+	Table ISimpleTable
+	As    []byte
+	Type  string
+}
+
+// TableLock.Type
+const (
+	LOCK_READ               = "read"
+	LOCK_READ_LOCAL         = "read local"
+	LOCK_WRITE              = "write"
+	LOCK_LOW_PRIORITY_WRITE = "low_priority write"
+)
+
+func (t *TableLock) Format(buf *Buffer) {
+	buf.Printf("%v", t.Table)
+	if len(t.As) > 0 {
+		buf.WriteString(" as ")
+		buf.formatName(t.As)
+	}
+	buf.Printf(" %s", t.Type)
+}
+
+type Unlock struct {
+	Source
+}
 
 type Begin struct{}
 
-type Commit struct{}
+func (*Begin) Format(buf *Buffer) {
+	buf.WriteString("begin")
+}
+
+// Commit is `COMMIT [Chain] [Release]`, Chain is "and chain" or "and no
+// chain" and Release "release" or "no release"
+type Commit struct {
+	Chain   string
+	Release string
+}
 
+func (c *Commit) Format(buf *Buffer) {
+	buf.WriteString("commit")
+	formatCompletion(buf, c.Chain, c.Release)
+}
+
+// Rollback is `ROLLBACK [Chain] [Release]` or `ROLLBACK TO SAVEPOINT
+// Point`
 type Rollback struct {
-	Point []byte
+	Chain   string
+	Release string
+	Point   []byte
+}
+
+func (r *Rollback) Format(buf *Buffer) {
+	if len(r.Point) > 0 {
+		buf.WriteString("rollback to savepoint ")
+		buf.formatName(r.Point)
+		return
+	}
+	buf.WriteString("rollback")
+	formatCompletion(buf, r.Chain, r.Release)
+}
+
+func formatCompletion(buf *Buffer, chain, release string) {
+	if chain != "" {
+		buf.Printf(" %s", chain)
+	}
+	if release != "" {
+		buf.Printf(" %s", release)
+	}
 }
 
-type XA struct{}
+type XA struct {
+	Source
+}
 
 type SavePoint struct {
 	Point []byte
 }
 
+func (s *SavePoint) Format(buf *Buffer) {
+	buf.WriteString("savepoint ")
+	buf.formatName(s.Point)
+}
+
 type Release struct {
 	Point []byte
 }
 
-type SetTrans struct{}
+func (r *Release) Format(buf *Buffer) {
+	buf.WriteString("release savepoint ")
+	buf.formatName(r.Point)
+}
+
+type SetTrans struct {
+	Source
+}
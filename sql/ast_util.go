@@ -9,14 +9,26 @@ func (*DescribeTable) IStatement() {}
 func (*DescribeStmt) IStatement()  {}
 func (*Use) IStatement()           {}
 
-type Help struct{}
+type Help struct {
+	Source
+}
 
 func (d *DescribeTable) GetSchemas() []string {
 	return d.Table.GetSchemas()
 }
 
+// DescribeTable is `DESCRIBE Table [Column]`
 type DescribeTable struct {
-	Table ISimpleTable
+	Table  ISimpleTable
+	Column []byte
+}
+
+func (d *DescribeTable) Format(buf *Buffer) {
+	buf.Printf("describe %v", d.Table)
+	if len(d.Column) > 0 {
+		buf.WriteByte(' ')
+		buf.formatIdentOrText(d.Column)
+	}
 }
 
 func (d *DescribeStmt) GetSchemas() []string {
@@ -36,10 +48,26 @@ func (d *DescribeStmt) GetSchemas() []string {
 	}
 }
 
+// DescribeStmt is `EXPLAIN [Option] Stmt`, Option is "extended",
+// "partitions" or "format = name"
 type DescribeStmt struct {
-	Stmt IStatement
+	Option string
+	Stmt   IStatement
+}
+
+func (d *DescribeStmt) Format(buf *Buffer) {
+	buf.WriteString("explain ")
+	if d.Option != "" {
+		buf.Printf("%s ", d.Option)
+	}
+	buf.Printf("%v", d.Stmt)
 }
 
 type Use struct {
 	DB []byte
 }
+
+func (u *Use) Format(buf *Buffer) {
+	buf.WriteString("use ")
+	buf.formatName(u.DB)
+}
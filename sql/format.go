@@ -0,0 +1,213 @@
+package sql
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+)
+
+// Node is a part of a parse tree, Format writes it back as MySQL text
+// that parses to the same tree.
+type Node interface {
+	Format(buf *Buffer)
+}
+
+// String formats node as SQL
+func String(node Node) string {
+	buf := &Buffer{}
+	buf.Printf("%v", node)
+	return buf.String()
+}
+
+// Buffer collects the text of formatted nodes
+type Buffer struct {
+	bytes.Buffer
+}
+
+// Printf writes format like fmt does, but only knows %v for a Node and %s
+// for a string or []byte. A nil Node writes nothing.
+func (buf *Buffer) Printf(format string, values ...interface{}) {
+	n := 0
+	for i := 0; i < len(format); i++ {
+		c := format[i]
+		if c != '%' || i+1 == len(format) {
+			buf.WriteByte(c)
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'v':
+			if node, ok := values[n].(Node); ok && !isNil(node) {
+				node.Format(buf)
+			}
+			n++
+		case 's':
+			switch v := values[n].(type) {
+			case string:
+				buf.WriteString(v)
+			case []byte:
+				buf.Write(v)
+			}
+			n++
+		default:
+			buf.WriteByte(format[i])
+		}
+	}
+}
+
+// isNil is whether node is a nil pointer in an interface
+func isNil(node Node) bool {
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// formatName writes an identifier, back quoted when it is not a plain one
+// or is a keyword. The lexer keeps the quotes of a quoted identifier, such
+// a name is written as it is.
+func (buf *Buffer) formatName(name []byte) {
+	if len(name) > 1 && name[0] == '`' && name[len(name)-1] == '`' {
+		buf.Write(name)
+		return
+	}
+	if !plainName(name) {
+		buf.WriteByte('`')
+		buf.Write(bytes.Replace(name, []byte("`"), []byte("``"), -1))
+		buf.WriteByte('`')
+		return
+	}
+	buf.Write(name)
+}
+
+func plainName(name []byte) bool {
+	if len(name) == 0 || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '_' || c == '$') {
+			return false
+		}
+	}
+	_, keyword := Symbols[strings.ToUpper(string(name))]
+	return !keyword
+}
+
+// formatNames writes the names joined by '.', empty ones left out
+func (buf *Buffer) formatNames(names ...[]byte) {
+	first := true
+	for _, name := range names {
+		if len(name) == 0 {
+			continue
+		}
+		if !first {
+			buf.WriteByte('.')
+		}
+		buf.formatName(name)
+		first = false
+	}
+}
+
+// formatIdentOrText writes what an ident_or_text was parsed from, a
+// string is kept as it is and a name is quoted when needed
+func (buf *Buffer) formatIdentOrText(b []byte) {
+	if len(b) > 0 && (b[0] == '\'' || b[0] == '"') {
+		buf.Write(b)
+		return
+	}
+	buf.formatName(b)
+}
+
+// formatExprs writes the expressions separated by commas
+func (buf *Buffer) formatExprs(exprs []IExpr) {
+	for i, e := range exprs {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.Printf("%v", e)
+	}
+}
+
+// formatOperand writes e, in parentheses when it binds less tightly than
+// prec
+func (buf *Buffer) formatOperand(e IExpr, prec int) {
+	if e != nil && precedence(e) < prec {
+		buf.Printf("(%v)", e)
+		return
+	}
+	buf.Printf("%v", e)
+}
+
+// NewStrVal is the quoted string literal of s
+func NewStrVal(s string) StrVal {
+	buf := make([]byte, 0, len(s)+2)
+	buf = append(buf, '\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			buf = append(buf, '\\', '0')
+		case '\n':
+			buf = append(buf, '\\', 'n')
+		case '\r':
+			buf = append(buf, '\\', 'r')
+		case '\032':
+			buf = append(buf, '\\', 'Z')
+		case '\\', '\'':
+			buf = append(buf, '\\', c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return StrVal(append(buf, '\''))
+}
+
+// joinText is the parts joined by a space, in a new slice so that the
+// lexer buffer they point into is left alone
+func joinText(parts ...[]byte) []byte {
+	var ret []byte
+	for i, part := range parts {
+		if i > 0 {
+			ret = append(ret, ' ')
+		}
+		ret = append(ret, part...)
+	}
+	return ret
+}
+
+// unquoteText is the text of a quoted string or identifier as the lexer
+// keeps it, b is returned when it is not quoted
+func unquoteText(b []byte) []byte {
+	if len(b) < 2 {
+		return b
+	}
+	q := b[0]
+	if (q != '\'' && q != '"' && q != '`') || b[len(b)-1] != q {
+		return b
+	}
+	s := b[1 : len(b)-1]
+	ret := make([]byte, 0, len(s))
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == q && i+1 < len(s) && s[i+1] == q {
+			i++
+		} else if c == '\\' && q != '`' && i+1 < len(s) {
+			i++
+			switch c = s[i]; c {
+			case '0':
+				c = 0
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'Z':
+				c = '\032'
+			case '%', '_':
+				ret = append(ret, '\\')
+			}
+		}
+		ret = append(ret, c)
+	}
+	return ret
+}
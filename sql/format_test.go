@@ -0,0 +1,187 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	for _, c := range []struct {
+		sql, want string
+	}{
+		{"SELECT a, b AS c, count(*), count(DISTINCT x, y), t.*, db.t.* FROM t WHERE a = 1 AND b IN (SELECT c FROM u) OR NOT d",
+			"select a, b as c, count(*), count(distinct x, y), t.*, db.t.* from t where a = 1 and b in (select c from u) or not d"},
+		{"SELECT SQL_NO_CACHE DISTINCT a FROM t1 JOIN t2 ON t1.a = t2.b LEFT OUTER JOIN t3 USING (a, b) WHERE x BETWEEN 1 AND 2 " +
+			"GROUP BY a DESC WITH ROLLUP HAVING count(*) > 1 ORDER BY a, b DESC LIMIT 10, 20 FOR UPDATE",
+			"select sql_no_cache distinct a from t1 join t2 on t1.a = t2.b left join t3 using (a, b) where x between 1 and 2 " +
+				"group by a desc with rollup having count(*) > 1 order by a, b desc limit 10, 20 for update"},
+		{"select * from t1 straight_join t2 natural join t3 natural left join t4 cross join t5",
+			"select * from t1 straight_join t2 natural join t3 natural left join t4 cross join t5"},
+		{"select * from (t1, t2) join t3 on a = b", "select * from (t1, t2) join t3 on a = b"},
+		{"select * from t use index (i1) ignore index for order by (i2) force key for join (primary), u partition (p1, p2) as v",
+			"select * from t use index (i1) ignore index for order by (i2) force index for join (`primary`), u partition (p1, p2) as v"},
+		{"select sql_calc_found_rows a from t lock in share mode", "select sql_calc_found_rows a from t lock in share mode"},
+		{"select a from t into outfile '/tmp/x' fields terminated by ','", "select a into outfile '/tmp/x' fields terminated by ',' from t"},
+		{"select a into @x from t", "select a into @x from t"},
+
+		// subqueries and unions
+		{"select * from (select a from t) as x, (select 1) y where exists (select 1 from z) and a > all (select b from c)",
+			"select * from (select a from t) as x, (select 1) as y where exists (select 1 from z) and a > all (select b from c)"},
+		{"(select a from t) union all (select b from u) order by a limit 1", "(select a from t) union all (select b from u) order by a limit 1"},
+		{"select a from t union select b from u union distinct select c from v", "select a from t union select b from u union distinct select c from v"},
+		{"select * from ((select a from t) union (select b from u)) as x", "select * from ((select a from t) union (select b from u)) as x"},
+		{"select (select 1) + 1, 1 - (select 2)", "select (select 1) + 1, 1 - (select 2)"},
+
+		// expressions
+		{"select (a + b) * c, a - (b - c), -(-1), - -1, !a, ~b, a || b, a <=> b, a div b, a mod b",
+			"select (a + b) * c, a - (b - c), -(-1), - -1, !a, ~b, a || b, a <=> b, a div b, a % b"},
+		{"select a from t where not (a or b) and (a xor b) and a and (b or c)", "select a from t where not (a or b) and (a xor b) and a and (b or c)"},
+		{"select a like 'x%' escape '!', b not like c, h not between 1 and 2, i is null, j is not true",
+			"select a like 'x%' escape '!', b not like c, h not between 1 and 2, i is null, j is not true"},
+		{"select (a, b) = (1, 2), row(1, 2) = row(a, b)", "select (a, b) = (1, 2), row(1, 2) = row(a, b)"},
+		{"select @a := 1, (@a := 1) + 2, @@y, @@global.x", "select @a := 1, (@a := 1) + 2, @@y, @@global.x"},
+		{"select a collate utf8_bin, (a + b) collate latin1_bin", "select a collate utf8_bin, (a + b) collate latin1_bin"},
+		{"select 'a' 'b', n'y', x'0a', 0x0a, b'01', date '2020-01-01', null, true, 1.5e3, ?",
+			"select 'a' 'b', n'y', x'0a', 0x0a, b'01', date '2020-01-01', null, true, 1.5e3, ?"},
+		{"select `select`, `a b`, `a``b`, 'it''s' as `x y`", "select `select`, `a b`, `a``b`, 'it''s' as `x y`"},
+
+		// functions
+		{"select cast(a as char(10)), convert(b, unsigned int), convert(c using utf8), char(65, 66 using latin1)",
+			"select cast(a as char(10)), convert(b, unsigned int), convert(c using utf8), char(65, 66 using latin1)"},
+		{"select trim(both 'x' from a), extract(year from d), date_add(d, interval 1 day), d + interval 1 hour",
+			"select trim(both 'x' from a), extract(year from d), date_add(d, interval 1 day), d + interval 1 hour"},
+		{"select case a when 1 then 'x' else 'z' end, case when a then b end", "select case a when 1 then 'x' else 'z' end, case when a then b end"},
+		{"select group_concat(distinct a, b order by c desc separator ';') from t", "select group_concat(distinct a, b order by c desc separator ';') from t"},
+		{"select match a against ('y' in boolean mode)", "select match (a) against ('y' in boolean mode)"},
+		{"select position('a' in b), substring(a from 2 for 3), db.f(c), count(all a)", "select locate('a', b), substring(a, 2, 3), db.f(c), count(a)"},
+
+		// other statements
+		{"insert into t (a, b) values (1, 2), (3, default) on duplicate key update a = values(a)",
+			"insert into t (a, b) values (1, 2), (3, default) on duplicate key update a = values(a)"},
+		{"insert low_priority ignore into t set a = 1, b = 2", "insert low_priority ignore into t (a, b) values (1, 2)"},
+		{"insert into t (a) select b from u", "insert into t (a) select b from u"},
+		{"replace delayed into t values (1)", "replace delayed into t values (1)"},
+		{"update low_priority t set a = 1, b = b + 1 where c = 2 order by d limit 3", "update low_priority t set a = 1, b = b + 1 where c = 2 order by d limit 3"},
+		{"delete quick from t partition (p1) where a = 1 order by b limit 1", "delete quick from t partition (p1) where a = 1 order by b limit 1"},
+		{"delete t1, t2.* from t1 join t2 where t1.a = t2.a", "delete t1, t2.* from t1 join t2 where t1.a = t2.a"},
+		{"delete from t1, t2 using t1 join t2 on t1.a = t2.a", "delete from t1, t2 using t1 join t2 on t1.a = t2.a"},
+		{"set @a = 1, autocommit = 0, global max_connections = 10, @@session.y = 1, @@z = default",
+			"set @a = 1, autocommit = 0, global max_connections = 10, session y = 1, z = default"},
+		{"SET NAMES utf8 COLLATE utf8_bin", "set names utf8 collate utf8_bin"},
+		{"set character set utf8", "set character set utf8"},
+		{"USE db", "use db"},
+		{"describe t a", "describe t a"},
+		{"EXPLAIN extended select 1", "explain extended select 1"},
+		{"BEGIN", "begin"},
+		{"SAVEPOINT x", "savepoint x"},
+		{"RELEASE SAVEPOINT x", "release savepoint x"},
+
+		{"COMMIT WORK AND NO CHAIN RELEASE", "commit and no chain release"},
+		{"rollback work to savepoint x", "rollback to savepoint x"},
+		{"lock tables t read, db.u as v low_priority write", "lock tables t read, db.u as v low_priority write"},
+		{"call db.p(1, a + 2)", "call db.p(1, a + 2)"},
+		{"KILL CONNECTION 5", "kill 5"},
+		{"kill query 7", "kill query 7"},
+		{"aegis set backend '10.0.0.1:3306' weight 3", "aegis set backend '10.0.0.1:3306' weight 3"},
+		{"aegis add replica '10.0.0.2:3306'", "aegis add replica '10.0.0.2:3306'"},
+
+		// show
+		{"SHOW FULL TABLES FROM db LIKE 'a%'", "show full tables from db like 'a%'"},
+		{"show columns in t from db where Field = 'a'", "show columns from t from db where Field = 'a'"},
+		{"show keys from db.t where Key_name = 'PRIMARY'", "show index from db.t where Key_name = 'PRIMARY'"},
+		{"show global status like 'Com%'", "show global status like 'Com%'"},
+		{"show create table db.t", "show create table db.t"},
+		{"show engine innodb status", "show engine innodb status"},
+		{"show warnings limit 2, 5", "show warnings limit 2, 5"},
+		{"show count(*) errors", "show count(*) errors"},
+		{"show profile cpu, block io for query 3 limit 1", "show profile cpu, block io for query 3 limit 1"},
+		{"show binlog events in 'log.01' from 4 limit 2", "show binlog events in 'log.01' from 4 limit 2"},
+		{"show procedure code db.p", "show procedure code db.p"},
+		{"show grants for 'u'@'%'", "show grants for 'u'@'%'"},
+
+		// ddl, the clauses without a tree of their own keep their text
+		{"DROP TEMPORARY TABLE IF EXISTS t, db.u CASCADE", "drop temporary table if exists t, db.u cascade"},
+		{"drop index i on t algorithm = inplace", "drop index i on t algorithm = inplace"},
+		{"drop view if exists v", "drop view if exists v"},
+		{"drop procedure if exists db.p", "drop procedure if exists db.p"},
+		{"TRUNCATE t", "truncate table t"},
+		{"rename table a to b, db.c to db.d", "rename table a to b, db.c to db.d"},
+		{"CREATE TEMPORARY TABLE IF NOT EXISTS db.t (a int) ENGINE=InnoDB", "create temporary table if not exists db.t (a int) ENGINE=InnoDB"},
+		{"create unique index i using btree on t (a(10) desc, b) comment 'x'", "create unique index i using btree on t (a(10) desc, b) comment 'x'"},
+		{"ALTER TABLE db.t ADD COLUMN b int", "alter table db.t ADD COLUMN b int"},
+		{"CREATE OR REPLACE VIEW v (a, b) AS SELECT 1, 2 WITH CHECK OPTION", "create OR REPLACE view v (a, b) as select 1, 2 with check option"},
+		{"create definer = current_user trigger tr before insert on t for each row set @a = 1",
+			"create definer = current_user trigger tr before insert on t for each row set @a = 1"},
+		{"check table t, u quick", "check table t, u quick"},
+		{"optimize local table t", "optimize no_write_to_binlog table t"},
+		{"flush tables t with read lock", "flush tables t with read lock"},
+		{"cache index t index (i, j), u in kc", "cache index t index (i, j), u in kc"},
+		{"load index into cache t partition (p1) ignore leaves", "load index into cache t partition (p1) ignore leaves"},
+
+		// statements without a tree of their own keep their text
+		{"GRANT SELECT ON db.* TO 'u'@'%'", "GRANT SELECT ON db.* TO 'u'@'%'"},
+	} {
+		st, err := Parse(c.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", c.sql, err)
+		}
+		if s := String(st); s != c.want {
+			t.Fatalf("%s: formatted as %q, want %q", c.sql, s, c.want)
+		}
+		testFormat(c.sql, st, t)
+	}
+}
+
+func TestFormatStatements(t *testing.T) {
+	table := &SimpleTable{Name: []byte("t")}
+	for _, c := range []struct {
+		st   IStatement
+		want string
+	}{
+		{&Kill{Type: KillType_Query, Id: NumVal("5")}, "kill query 5"},
+		{&AegisAdmin{Action: AegisAction_Offline, Addr: "h:1"}, "aegis offline backend 'h:1'"},
+		{&DropTables{IfExists: true, Tables: ISimpleTables{table}}, "drop table if exists t"},
+		{&TruncateTable{Table: table}, "truncate table t"},
+		{&ShowTables{From: []byte("db"), LikeOrWhere: &LikeOrWhere{Like: "'a%'"}}, "show tables from db like 'a%'"},
+		{&ShowColumns{Full: true, Table: table}, "show full columns from t"},
+		{&CreateIndex{Name: []byte("i"), Table: table, Columns: []*IndexColumn{{Column: []byte("a")}}}, "create index i on t (a)"},
+	} {
+		if s := String(c.st); s != c.want {
+			t.Fatalf("%#v: formatted as %q, want %q", c.st, s, c.want)
+		}
+	}
+
+	//fields edited after parsing show in the text
+	st, err := Parse("CREATE TABLE db.t (a int)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.(*CreateTable).Table.(*SimpleTable).Qualifier = []byte("db2")
+	if s := String(st); s != "create table db2.t (a int)" {
+		t.Fatalf("edited create table formatted as %q", s)
+	}
+	st, err = Parse("show index from t from db")
+	if err != nil {
+		t.Fatal(err)
+	}
+	st.(*ShowIndex).From = []byte("db2")
+	if s := String(st); s != "show index from t from db2" {
+		t.Fatalf("edited show index formatted as %q", s)
+	}
+}
+
+func TestFormatPrecedence(t *testing.T) {
+	a, b, c := &SchemaObject{Column: []byte("a")}, &SchemaObject{Column: []byte("b")}, NumVal("1")
+
+	e := &BinaryExpr{Operator: OP_MULT, Left: &BinaryExpr{Operator: OP_PLUS, Left: a, Right: b}, Right: c}
+	if s := String(e); s != "(a + b) * 1" {
+		t.Fatalf("%q", s)
+	}
+	e = &BinaryExpr{Operator: OP_MINUS, Left: a, Right: &BinaryExpr{Operator: OP_MINUS, Left: b, Right: c}}
+	if s := String(e); s != "a - (b - 1)" {
+		t.Fatalf("%q", s)
+	}
+	o := &AndExpr{Left: &OrExpr{Left: a, Right: b}, Right: c}
+	if s := String(o); s != "(a or b) and 1" {
+		t.Fatalf("%q", s)
+	}
+}
@@ -32,6 +32,10 @@ type SQLLexer struct {
 	tok_start_prev uint
 	tok_end_prev   uint
 
+	// ends of the last two tokens returned
+	last_end      uint
+	last_end_prev uint
+
 	next_state uint // next should be state
 
 	in_comment uint
@@ -154,6 +158,9 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 		case MY_LEX_IDENT:
 
 			retstate, lval.bytes = lex.getIdentifier()
+			if retstate == WITH {
+				retstate, lval.bytes = lex.getWithOlap(lval.bytes)
+			}
 			goto TG_RET
 
 		case MY_LEX_IDENT_SEP: // Found ident before
@@ -192,6 +199,8 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 				}
 
 				if lex.ptr-lex.tok_start >= 4 && ident_map[c] == 0 {
+					lex.yyBack()
+					lval.bytes = lex.buf[lex.tok_start:lex.ptr]
 					retstate = HEX_NUM
 					goto TG_RET
 				}
@@ -204,6 +213,8 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 				}
 
 				if lex.ptr-lex.tok_start >= 4 && ident_map[c] == 0 {
+					lex.yyBack()
+					lval.bytes = lex.buf[lex.tok_start:lex.ptr]
 					retstate = BIN_NUM
 					goto TG_RET
 				}
@@ -224,8 +235,12 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 					break
 				}
 
-				if c == quote_char && lex.yyPeek() != quote_char {
-					break
+				if c == quote_char {
+					//a doubled quote is one inside the name
+					if lex.yyPeek() != quote_char {
+						break
+					}
+					lex.yySkip()
 				}
 			}
 
@@ -294,8 +309,7 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 				goto TG_RET
 			}
 
-			lex.yyNext()
-
+			lval.bytes = lex.buf[lex.tok_start:lex.ptr]
 			retstate = BIN_NUM
 			goto TG_RET
 		case MY_LEX_CMP_OP:
@@ -458,9 +472,11 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 			goto TG_RET
 
 		case MY_LEX_HOSTNAME:
-			for c = lex.yyNext(); cs.IsAlnum(c) || c == '.' || c == '_' || c == '$'; c = lex.yyNext() {
+			for c = lex.yyPeek(); cs.IsAlnum(c) || c == '.' || c == '_' || c == '$'; c = lex.yyPeek() {
+				lex.yySkip()
 			}
 
+			lval.bytes = lex.buf[lex.tok_start:lex.ptr]
 			retstate = LEX_HOSTNAME
 			goto TG_RET
 		case MY_LEX_SYSTEM_VAR:
@@ -477,10 +493,9 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 			goto TG_RET
 		case MY_LEX_IDENT_OR_KEYWORD:
 			result_state = 0
-			c = lex.yyNext()
-			for ident_map[c] != 0 {
+			for c = lex.yyPeek(); ident_map[c] != 0; c = lex.yyPeek() {
 				result_state |= int(c)
-				c = lex.yyNext()
+				lex.yySkip()
 			}
 
 			if result_state&0x80 != 0 {
@@ -493,16 +508,16 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 				lex.next_state = MY_LEX_IDENT_SEP
 			}
 
-			length = lex.ptr - lex.tok_start - 1
+			length = lex.ptr - lex.tok_start
 			if length == 0 {
 				retstate = ABORT_SYM
 				goto TG_RET
 			}
 
-			val := lex.buf[lex.tok_start : lex.ptr-1]
+			val := lex.buf[lex.tok_start:lex.ptr]
 			var ok bool
 			if retstate, ok = findKeywords(val, false); ok {
-				lex.yyBack()
+				lval.bytes = val
 				goto TG_RET
 			}
 
@@ -512,9 +527,9 @@ func (lex *SQLLexer) Lex(lval *MySQLSymType) (retstate int) {
 		}
 	}
 
-	retstate = 0
-
 TG_RET:
+	lval.end = int(lex.ptr)
+	lex.last_end_prev, lex.last_end = lex.last_end, lex.ptr
 
 	DEBUG(fmt.Sprintf("dbg return [%s]\n", TokenName(retstate)))
 	return
@@ -560,6 +575,31 @@ func (lex *SQLLexer) yyLookHead() (b byte) {
 	return
 }
 
+// getWithOlap is WITH_ROLLUP_SYM or WITH_CUBE_SYM when the WITH just read
+// is followed by ROLLUP or CUBE, the grammar needs them as one token to
+// tell GROUP BY ... WITH ROLLUP from WITH CHECK OPTION of a view
+func (lex *SQLLexer) getWithOlap(with []byte) (int, []byte) {
+	ptr := lex.ptr
+	for ptr < uint(len(lex.buf)) && lex.cs.StateMap[lex.buf[ptr]] == MY_LEX_SKIP {
+		ptr++
+	}
+	start := ptr
+	for ptr < uint(len(lex.buf)) && lex.cs.IdentMap[lex.buf[ptr]] != 0 {
+		ptr++
+	}
+	var ret int
+	switch strings.ToUpper(string(lex.buf[start:ptr])) {
+	case "ROLLUP":
+		ret = WITH_ROLLUP_SYM
+	case "CUBE":
+		ret = WITH_CUBE_SYM
+	default:
+		return WITH, with
+	}
+	lex.ptr = ptr
+	return ret, lex.buf[lex.tok_start:lex.ptr]
+}
+
 func (lex *SQLLexer) yySkip() {
 	lex.yyNext()
 	return
@@ -49,7 +49,7 @@ func (lex *SQLLexer) getIdentifier() (int, []byte) {
 	}
 
 	idc := lex.buf[lex.tok_start:lex.ptr]
-	DEBUG(fmt.Sprintf("idc:[" + string(idc) + "]\n"))
+	DEBUG(fmt.Sprintf("idc:[%s]\n", idc))
 
 	start := lex.ptr
 
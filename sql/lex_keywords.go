@@ -46,6 +46,8 @@ var Symbols map[string]int = map[string]int{
 	"AUTOEXTEND_SIZE":               AUTOEXTEND_SIZE_SYM,
 	"AVG":                           AVG_SYM,
 	"AVG_ROW_LENGTH":                AVG_ROW_LENGTH,
+	"BACKEND":                       BACKEND_SYM,
+	"BACKENDS":                      BACKENDS_SYM,
 	"BACKUP":                        BACKUP_SYM,
 	"BEFORE":                        BEFORE_SYM,
 	"BEGIN":                         BEGIN_SYM,
@@ -151,6 +153,7 @@ var Symbols map[string]int = map[string]int{
 	"DISTINCTROW":                   DISTINCT, /* Access likes this */
 	"DIV":                           DIV_SYM,
 	"DO":                            DO_SYM,
+	"DRAIN":                         DRAIN_SYM,
 	"DOUBLE":                        DOUBLE_SYM,
 	"DROP":                          DROP,
 	"DUAL":                          DUAL_SYM,
@@ -367,9 +370,11 @@ var Symbols map[string]int = map[string]int{
 	"NUMERIC":                       NUMERIC_SYM,
 	"NVARCHAR":                      NVARCHAR_SYM,
 	"OFFSET":                        OFFSET_SYM,
+	"OFFLINE":                       OFFLINE_SYM,
 	"OLD_PASSWORD":                  OLD_PASSWORD,
 	"ON":                            ON,
 	"ONE":                           ONE_SYM,
+	"ONLINE":                        ONLINE_SYM,
 	"ONLY":                          ONLY_SYM,
 	"OPEN":                          OPEN_SYM,
 	"OPTIMIZE":                      OPTIMIZE,
@@ -396,6 +401,7 @@ var Symbols map[string]int = map[string]int{
 	"PLUGIN_DIR":                    PLUGIN_DIR_SYM,
 	"POINT":                         POINT_SYM,
 	"POLYGON":                       POLYGON,
+	"POOLS":                         POOLS_SYM,
 	"PORT":                          PORT_SYM,
 	"PRECISION":                     PRECISION,
 	"PREPARE":                       PREPARE_SYM,
@@ -439,6 +445,7 @@ var Symbols map[string]int = map[string]int{
 	"REPAIR":                        REPAIR,
 	"REPEATABLE":                    REPEATABLE_SYM,
 	"REPLACE":                       REPLACE,
+	"REPLICA":                       REPLICA_SYM,
 	"REPLICATION":                   REPLICATION,
 	"REPEAT":                        REPEAT_SYM,
 	"REQUIRE":                       REQUIRE_SYM,
@@ -476,6 +483,7 @@ var Symbols map[string]int = map[string]int{
 	"SERIAL":                        SERIAL_SYM,
 	"SERIALIZABLE":                  SERIALIZABLE_SYM,
 	"SESSION":                       SESSION_SYM,
+	"SESSIONS":                      SESSIONS_SYM,
 	"SERVER":                        SERVER_SYM,
 	"SET":                           SET,
 	"SHARE":                         SHARE_SYM,
@@ -598,6 +606,7 @@ var Symbols map[string]int = map[string]int{
 	"WAIT":                          WAIT_SYM,
 	"WARNINGS":                      WARNINGS,
 	"WEEK":                          WEEK_SYM,
+	"WEIGHT":                        WEIGHT_SYM,
 	"WEIGHT_STRING":                 WEIGHT_STRING_SYM,
 	"WHEN":                          WHEN_SYM,
 	"WHERE":                         WHERE,
@@ -2,6 +2,9 @@ package sql
 
 import (
 	"errors"
+	"regexp"
+	"strconv"
+	"strings"
 )
 
 func Parse(sql string) (IStatement, error) {
@@ -10,5 +13,133 @@ func Parse(sql string) (IStatement, error) {
 		return nil, errors.New(lexer.LastError)
 	}
 
+	//statements not modeled clause by clause keep their text to format
+	if st, ok := lexer.ParseTree.(interface {
+		setSource(string)
+	}); ok {
+		st.setSource(sourceText(sql))
+	}
 	return lexer.ParseTree, nil
 }
+
+// Split cuts a multi-statement query at the top level ';' separators,
+// quoted text and comments are left as they are. Every part can be
+// handed to Parse on its own.
+func Split(sql string) []string {
+	lexer := NewSQLLexer(sql)
+	var lval MySQLSymType
+	var ret []string
+
+	var start uint = 0
+	for {
+		switch lexer.Lex(&lval) {
+		case ';':
+			if s := strings.TrimSpace(sql[start:lexer.tok_start]); len(s) != 0 {
+				ret = append(ret, s)
+			}
+			start = lexer.ptr
+		case EOF, END_OF_INPUT, ABORT_SYM:
+			if s := strings.TrimSpace(sql[start:]); len(s) != 0 {
+				ret = append(ret, s)
+			}
+			return ret
+		}
+	}
+}
+
+var maxExecutionTimeHint = regexp.MustCompile(`(?is)^[\s(]*select\s*/\*\+([^*]|\*[^/])*?\bmax_execution_time\s*\(\s*(\d+)\s*\)`)
+
+// MaxExecutionTime returns N of the optimizer hint
+// SELECT /*+ MAX_EXECUTION_TIME(N) */, the time limit of the statement
+// in milliseconds. Like mysql, the hint only counts right after SELECT.
+func MaxExecutionTime(sql string) (int, bool) {
+	m := maxExecutionTimeHint.FindStringSubmatch(sql)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[2])
+	if err != nil {
+		return 0, false
+	}
+	return n, true
+}
+
+// LastInsertIdSelect tells SELECT LAST_INSERT_ID() [[AS] alias] [FROM
+// DUAL], which a proxy answers itself since the connections to the
+// backends are shared. It returns the name of the column.
+func LastInsertIdSelect(stmt IStatement) (string, bool) {
+	s, ok := stmt.(*Select)
+	if !ok || len(s.Options) > 0 || len(s.Fields) != 1 || s.Into != "" || s.From != nil ||
+		s.Where != nil || s.GroupBy != nil || s.Having != nil || s.OrderBy != nil ||
+		s.Limit != nil || s.Procedure != "" || s.LockType != 0 {
+		return "", false
+	}
+	f, ok := s.Fields[0].(*AliasedField)
+	if !ok {
+		return "", false
+	}
+	expr := Node(f.Expr)
+	if p, ok := expr.(*Predicate); ok {
+		expr = p.Expr
+	}
+	call, ok := expr.(*FuncExpr)
+	if !ok || !isLastInsertId(call) {
+		return "", false
+	}
+	if len(f.As) > 0 {
+		return unquoteName(string(f.As)), true
+	}
+	return string(call.Name) + "()", true
+}
+
+// ReplaceLastInsertId writes id in place of the LAST_INSERT_ID() calls in
+// the tree of node and tells whether there was one. A field of a select
+// holding one keeps its name. A call with an argument is left to the
+// backend, the id it sets in a SELECT is not seen by the proxy.
+func ReplaceLastInsertId(node Node, id uint64) bool {
+	if s, ok := node.(*Select); ok {
+		for _, f := range s.Fields {
+			if af, ok := f.(*AliasedField); ok && len(af.As) == 0 && hasLastInsertId(af.Expr) {
+				name := strings.Replace(String(af.Expr), "`", "``", -1)
+				af.As = []byte("`" + name + "`")
+			}
+		}
+	}
+
+	found := false
+	Rewrite(node, func(c *Cursor) bool {
+		if call, ok := c.Node().(*FuncExpr); ok && isLastInsertId(call) {
+			c.Replace(NumVal(strconv.FormatUint(id, 10)))
+			found = true
+		}
+		return true
+	}, nil)
+	return found
+}
+
+func isLastInsertId(call *FuncExpr) bool {
+	return len(call.Qualifier) == 0 && len(call.Exprs) == 0 && strings.EqualFold(string(call.Name), "last_insert_id")
+}
+
+func hasLastInsertId(node Node) bool {
+	found := false
+	Walk(func(node Node) (bool, error) {
+		if call, ok := node.(*FuncExpr); ok && isLastInsertId(call) {
+			found = true
+		}
+		return !found, nil
+	}, node)
+	return found
+}
+
+// unquoteName strips the quotes of a quoted identifier or string
+func unquoteName(name string) string {
+	if len(name) < 2 {
+		return name
+	}
+	q := name[0]
+	if (q != '`' && q != '\'' && q != '"') || name[len(name)-1] != q {
+		return name
+	}
+	return strings.Replace(name[1:len(name)-1], string([]byte{q, q}), string(q), -1)
+}
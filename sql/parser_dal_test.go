@@ -60,6 +60,42 @@ func TestShow(t *testing.T) {
 
 	st = testParse(`SHOW FULL PROCESSLIST`, t, false)
 	matchType(t, st, &ShowProcessList{})
+	if !st.(*ShowProcessList).Full {
+		t.Fatal("show full processlist without full")
+	}
+
+	st = testParse(`SHOW PROCESSLIST`, t, false)
+	if st.(*ShowProcessList).Full {
+		t.Fatal("show processlist with full")
+	}
+
+	st = testParse(`SHOW WARNINGS LIMIT 10`, t, false)
+	if st.(*ShowWarnings).Count {
+		t.Fatal("show warnings with count")
+	}
+
+	st = testParse(`SHOW COUNT(*) WARNINGS`, t, false)
+	if !st.(*ShowWarnings).Count {
+		t.Fatal("show count(*) warnings without count")
+	}
+
+	st = testParse(`SHOW AEGIS BACKEND PROCESSLIST`, t, false)
+	matchType(t, st, &ShowAegisBackendProcessList{})
+
+	st = testParse(`SHOW AEGIS POOLS`, t, false)
+	matchType(t, st, &ShowAegisPools{})
+
+	st = testParse(`SHOW AEGIS BACKENDS`, t, false)
+	matchType(t, st, &ShowAegisBackends{})
+
+	st = testParse(`SHOW AEGIS SESSIONS`, t, false)
+	matchType(t, st, &ShowAegisSessions{})
+
+	st = testParse(`select sessions, pools from backends`, t, false)
+	matchType(t, st, &Select{})
+
+	st = testParse(`select backend from backend`, t, false)
+	matchType(t, st, &Select{})
 
 	st = testParse(`SHOW PLUGINS`, t, false)
 	matchType(t, st, &ShowPlugins{})
@@ -241,6 +277,63 @@ func TestFlush(t *testing.T) {
 func TestKill(t *testing.T) {
 	st := testParse(`kill connection 1234`, t, false)
 	matchType(t, st, &Kill{})
+	if st.(*Kill).Type != KillType_Connection {
+		t.Fatalf("kill type %d, want connection", st.(*Kill).Type)
+	}
+
+	st = testParse(`kill 1234`, t, false)
+	if st.(*Kill).Type != KillType_Connection {
+		t.Fatalf("kill type %d, want connection", st.(*Kill).Type)
+	}
+
+	st = testParse(`kill query 1234`, t, false)
+	if st.(*Kill).Type != KillType_Query {
+		t.Fatalf("kill type %d, want query", st.(*Kill).Type)
+	}
+	p, ok := st.(*Kill).Id.(*Predicate)
+	if !ok {
+		t.Fatalf("kill id %T", st.(*Kill).Id)
+	}
+	if v, ok := p.Expr.(NumVal); !ok || string(v) != "1234" {
+		t.Fatalf("kill id %T", p.Expr)
+	}
+}
+
+func TestAegisAdmin(t *testing.T) {
+	st := testParse(`AEGIS SET BACKEND '10.0.0.5:3306' WEIGHT 0`, t, false)
+	matchType(t, st, &AegisAdmin{})
+	a := st.(*AegisAdmin)
+	if a.Action != AegisAction_SetWeight || a.Addr != "10.0.0.5:3306" || string(a.Weight) != "0" {
+		t.Fatalf("aegis set backend %+v", a)
+	}
+
+	st = testParse(`aegis offline backend '10.0.0.5:3306'`, t, false)
+	if a := st.(*AegisAdmin); a.Action != AegisAction_Offline || a.Addr != "10.0.0.5:3306" {
+		t.Fatalf("aegis offline backend %+v", a)
+	}
+
+	st = testParse(`AEGIS ONLINE BACKEND '10.0.0.5:3306'`, t, false)
+	if a := st.(*AegisAdmin); a.Action != AegisAction_Online {
+		t.Fatalf("aegis online backend %+v", a)
+	}
+
+	st = testParse(`AEGIS DRAIN BACKEND '10.0.0.5:3306'`, t, false)
+	if a := st.(*AegisAdmin); a.Action != AegisAction_Drain {
+		t.Fatalf("aegis drain backend %+v", a)
+	}
+
+	st = testParse(`AEGIS ADD REPLICA '10.0.0.6:3306'`, t, false)
+	if a := st.(*AegisAdmin); a.Action != AegisAction_AddReplica || a.Weight != nil {
+		t.Fatalf("aegis add replica %+v", a)
+	}
+
+	st = testParse(`AEGIS ADD REPLICA '10.0.0.6:3306' WEIGHT 10`, t, false)
+	if a := st.(*AegisAdmin); string(a.Weight) != "10" {
+		t.Fatalf("aegis add replica weight %+v", a)
+	}
+
+	st = testParse(`select weight, replica, drain, online, offline from t`, t, false)
+	matchType(t, st, &Select{})
 }
 
 func TestReset(t *testing.T) {
@@ -111,6 +111,47 @@ func TestSelect(t *testing.T) {
 	testParse(`SELECT ?,?,? from t1;`, t, false)
 }
 
+func TestSelectWhereLimit(t *testing.T) {
+	st := testParse(`SELECT * FROM t1`, t, false)
+	if s := st.(*Select); s.Where != nil || s.Limit != nil {
+		t.Fatalf("where %v limit %v", s.Where, s.Limit)
+	}
+
+	st = testParse(`SELECT * FROM t1 WHERE a = 1 ORDER BY a LIMIT 10 FOR UPDATE`, t, false)
+	s := st.(*Select)
+	if s.Where == nil {
+		t.Fatal("no where")
+	}
+	if s.Limit == nil || s.Limit.Offset != nil || string(s.Limit.Rowcount.(NumVal)) != "10" {
+		t.Fatalf("limit %v", s.Limit)
+	}
+	if s.LockType != LockType_ForUpdate {
+		t.Fatalf("lock type is not For Update")
+	}
+
+	st = testParse(`SELECT * FROM t1 LIMIT 5, 10`, t, false)
+	s = st.(*Select)
+	if string(s.Limit.Offset.(NumVal)) != "5" || string(s.Limit.Rowcount.(NumVal)) != "10" {
+		t.Fatalf("limit %v", s.Limit)
+	}
+
+	st = testParse(`SELECT * FROM t1 LIMIT 10 OFFSET 5`, t, false)
+	s = st.(*Select)
+	if string(s.Limit.Offset.(NumVal)) != "5" || string(s.Limit.Rowcount.(NumVal)) != "10" {
+		t.Fatalf("limit %v", s.Limit)
+	}
+
+	st = testParse(`SELECT 1 LIMIT 1`, t, false)
+	if s := st.(*Select); s.From != nil || s.Limit == nil {
+		t.Fatalf("from %v limit %v", s.From, s.Limit)
+	}
+
+	st = testParse(`SELECT 1 FROM dual WHERE 1 = 1`, t, false)
+	if s := st.(*Select); s.From != nil || s.Where == nil {
+		t.Fatalf("from %v where %v", s.From, s.Where)
+	}
+}
+
 func TestInsert(t *testing.T) {
 	st := testParse(`INSERT INTO db1.tbl_temp2 (fld_id)
         SELECT tempdb.tbl_temp1.fld_order_id
@@ -118,6 +159,53 @@ func TestInsert(t *testing.T) {
 	matchSchemas(t, st, "db1", "tempdb")
 }
 
+func TestInsertValues(t *testing.T) {
+	st := testParse(`INSERT INTO t1 (a, t1.b) VALUES (1, 'x'), (2, DEFAULT) ON DUPLICATE KEY UPDATE b = 'y'`, t, false)
+	i := st.(*Insert)
+	v, ok := i.InsertFields.(*InsertValues)
+	if !ok {
+		t.Fatalf("insert fields %T", i.InsertFields)
+	}
+	if len(v.Columns) != 2 || string(v.Columns[1].(*SchemaObject).Table) != "t1" {
+		t.Fatalf("columns %v", v.Columns)
+	}
+	if len(v.Rows) != 2 || len(v.Rows[1]) != 2 {
+		t.Fatalf("rows %v", v.Rows)
+	}
+	if len(i.OnDup) != 1 || string(i.OnDup[0].Column.(*SchemaObject).Column) != "b" {
+		t.Fatalf("on duplicate %v", i.OnDup)
+	}
+
+	st = testParse(`INSERT INTO t1 SET a = 1, b = 2`, t, false)
+	v = st.(*Insert).InsertFields.(*InsertValues)
+	if len(v.Columns) != 2 || len(v.Rows) != 1 || len(v.Rows[0]) != 2 {
+		t.Fatalf("columns %v rows %v", v.Columns, v.Rows)
+	}
+
+	st = testParse(`REPLACE INTO t1 VALUES (1), ()`, t, false)
+	v = st.(*Replace).ReplaceFields.(*InsertValues)
+	if v.Columns != nil || len(v.Rows) != 2 || v.Rows[1] != nil {
+		t.Fatalf("columns %v rows %v", v.Columns, v.Rows)
+	}
+}
+
+func TestUpdateDeleteWhere(t *testing.T) {
+	st := testParse(`UPDATE t1 SET a = 1, b = b + 1 WHERE id = 3`, t, false)
+	u := st.(*Update)
+	if len(u.Set) != 2 || u.Where == nil {
+		t.Fatalf("set %v where %v", u.Set, u.Where)
+	}
+
+	st = testParse(`DELETE FROM t1 WHERE id = 3`, t, false)
+	if st.(*Delete).Where == nil {
+		t.Fatal("no where")
+	}
+	st = testParse(`DELETE FROM t1`, t, false)
+	if st.(*Delete).Where != nil {
+		t.Fatal("where without WHERE")
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	st := testParse(`UPDATE t1 SET col1 = col1 + 1, col2 = col1;`, t, false)
 	matchSchemas(t, st)
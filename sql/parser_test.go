@@ -1,7 +1,9 @@
 package sql
 
 import (
+	"bytes"
 	"fmt"
+	"reflect"
 	"testing"
 )
 
@@ -28,10 +30,64 @@ func testParse(sql string, t *testing.T, dbg bool) IStatement {
 		return nil
 	} else {
 		setDebug(false)
+		testFormat(sql, st, t)
 		return st
 	}
 }
 
+// testFormat checks that st formats to text parsing to the same tree
+func testFormat(sql string, st IStatement, t *testing.T) {
+	if st == nil {
+		return
+	}
+	text := String(st)
+	again, err := Parse(text)
+	if err != nil {
+		t.Fatalf("%s: formatted as %q: %v", sql, text, err)
+	}
+	if s := String(again); s != text {
+		t.Fatalf("%s: formatted as %q, then as %q", sql, text, s)
+	}
+	first, _ := Parse(sql)
+	normalize(reflect.ValueOf(first))
+	normalize(reflect.ValueOf(again))
+	if !reflect.DeepEqual(first, again) {
+		t.Fatalf("%s: formatted as %q, which parses to another tree", sql, text)
+	}
+}
+
+// normalize clears the source text in the tree of v, it is compared
+// through the formatted text, and unquotes the names, which are quoted
+// when they are keywords
+func normalize(v reflect.Value) {
+	if b, ok := v.Interface().([]byte); ok && v.CanSet() {
+		if len(b) > 1 && b[0] == '`' && b[len(b)-1] == '`' {
+			v.SetBytes(bytes.Replace(b[1:len(b)-1], []byte("``"), []byte("`"), -1))
+		}
+		return
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			normalize(v.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			normalize(v.Index(i))
+		}
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(Source{}) {
+			if v.CanSet() {
+				v.Set(reflect.ValueOf(Source{}))
+			}
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			normalize(v.Field(i))
+		}
+	}
+}
+
 func TestExplain(t *testing.T) {
 	testParse("EXPLAIN SELECT f1(5)", t, false)
 	testParse("EXPLAIN SELECT * FROM t1 AS a1, (SELECT BENCHMARK(1000000, MD5(NOW())));", t, false)
@@ -49,3 +105,112 @@ func TestTokenName(t *testing.T) {
 		t.Fatal("get token name error")
 	}
 }
+
+func TestSplit(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want []string
+	}{
+		{`select 1`, []string{`select 1`}},
+		{`select 1;`, []string{`select 1;`}},
+		{`update t set a = 1; select * from t`, []string{`update t set a = 1`, `select * from t`}},
+		{`select 'a;b'; select "c;d" /* ; */;`, []string{`select 'a;b'`, `select "c;d" /* ; */;`}},
+		{`select 1;; select 2`, []string{`select 1`, `select 2`}},
+		{` ; `, nil},
+	}
+
+	for _, c := range cases {
+		got := Split(c.sql)
+		if len(got) != len(c.want) {
+			t.Fatalf("split [%s] expect %q got %q", c.sql, c.want, got)
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Fatalf("split [%s] expect %q got %q", c.sql, c.want, got)
+			}
+			if _, err := Parse(got[i]); err != nil {
+				t.Fatalf("parse [%s] error %v", got[i], err)
+			}
+		}
+	}
+}
+
+func TestMaxExecutionTime(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want int
+		ok   bool
+	}{
+		{`select /*+ MAX_EXECUTION_TIME(1000) */ * from t`, 1000, true},
+		{`SELECT /*+ BKA(t1) max_execution_time( 50 ) NO_ICP(t1) */ a from t1`, 50, true},
+		{`(select/*+ MAX_EXECUTION_TIME(10) */ 1) union (select 2)`, 10, true},
+		{`select * from t /*+ MAX_EXECUTION_TIME(1000) */`, 0, false},
+		{`select /* MAX_EXECUTION_TIME(1000) */ 1`, 0, false},
+		{`select /*+ BKA(t1) */ 1 /*+ MAX_EXECUTION_TIME(1000) */`, 0, false},
+		{`update t set a = 1 /*+ MAX_EXECUTION_TIME(1000) */`, 0, false},
+	}
+
+	for _, c := range cases {
+		got, ok := MaxExecutionTime(c.sql)
+		if got != c.want || ok != c.ok {
+			t.Fatalf("hint of [%s] expect %d %v got %d %v", c.sql, c.want, c.ok, got, ok)
+		}
+		if _, err := Parse(c.sql); err != nil {
+			t.Fatalf("parse [%s] error %v", c.sql, err)
+		}
+	}
+}
+
+func TestLastInsertIdSelect(t *testing.T) {
+	cases := []struct {
+		sql  string
+		name string
+		ok   bool
+	}{
+		{`SELECT LAST_INSERT_ID()`, "LAST_INSERT_ID()", true},
+		{`select last_insert_id( );`, "last_insert_id()", true},
+		{`select last_insert_id() as id`, "id", true},
+		{"SELECT LAST_INSERT_ID() `the id`", "the id", true},
+		{`SELECT LAST_INSERT_ID() FROM dual`, "LAST_INSERT_ID()", true},
+		{`select /* the id */ last_insert_id()`, "last_insert_id()", true},
+		{`SELECT LAST_INSERT_ID(5)`, "", false},
+		{`SELECT LAST_INSERT_ID() + 1`, "", false},
+		{`SELECT LAST_INSERT_ID() FROM t`, "", false},
+		{`SELECT a, LAST_INSERT_ID() FROM t`, "", false},
+	}
+
+	for _, c := range cases {
+		st, err := Parse(c.sql)
+		if err != nil {
+			t.Fatalf("parse [%s] error %v", c.sql, err)
+		}
+		name, ok := LastInsertIdSelect(st)
+		if name != c.name || ok != c.ok {
+			t.Fatalf("[%s] expect %q %v got %q %v", c.sql, c.name, c.ok, name, ok)
+		}
+	}
+}
+
+func TestReplaceLastInsertId(t *testing.T) {
+	cases := []struct {
+		sql  string
+		want string
+		ok   bool
+	}{
+		{"select last_insert_id()+0", "select 7 + 0 as `last_insert_id() + 0`", true},
+		{"insert into t values (last_insert_id(), 1)", "insert into t values (7, 1)", true},
+		{"select a from t where id = LAST_INSERT_ID() and b = last_insert_id(3)", "select a from t where id = 7 and b = last_insert_id(3)", true},
+		{"select a from t", "select a from t", false},
+	}
+
+	for _, c := range cases {
+		st, err := Parse(c.sql)
+		if err != nil {
+			t.Fatalf("parse [%s] error %v", c.sql, err)
+		}
+		ok := ReplaceLastInsertId(st, 7)
+		if s := String(st); s != c.want || ok != c.ok {
+			t.Fatalf("[%s] expect %q %v got %q %v", c.sql, c.want, c.ok, s, ok)
+		}
+	}
+}
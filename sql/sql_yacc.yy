@@ -33,6 +33,8 @@ import (
     
     statement IStatement
     select_statement ISelect
+    select_stmt *Select
+    limit *Limit
     subquery *SubQuery
     table ISimpleTable
     table_list ISimpleTables
@@ -66,6 +68,30 @@ import (
     vars Vars
     var_type VarType
     life_type LifeType
+    kill_type KillType
+    aegis_action AegisAction
+
+    exprs_list []IExprs
+    assignment *Assignment
+    assignments []*Assignment
+
+    boolean bool
+    strs []string
+    idents [][]byte
+    select_field ISelectField
+    select_fields SelectFields
+    order_by OrderBy
+    order_limit *orderLimit
+    group *groupClause
+    index_hint *IndexHint
+    index_hints []*IndexHint
+    whens []*When
+    index_column *IndexColumn
+    index_columns []*IndexColumn
+    table_lock *TableLock
+    table_locks []*TableLock
+
+    end int
 }
 
 /*
@@ -111,6 +137,13 @@ import (
 %token<bytes>  AUTO_INC
 %token<bytes>  AVG_ROW_LENGTH
 %token<bytes>  AVG_SYM                       /* SQL-2003-N */
+%token<bytes>  BACKEND_SYM
+%token<bytes>  DRAIN_SYM                     /* AEGIS admin */
+%token<bytes>  OFFLINE_SYM                   /* AEGIS admin */
+%token<bytes>  ONLINE_SYM                    /* AEGIS admin */
+%token<bytes>  REPLICA_SYM                   /* AEGIS admin */
+%token<bytes>  WEIGHT_SYM                    /* AEGIS admin */
+%token<bytes>  BACKENDS_SYM
 %token<bytes>  BACKUP_SYM
 %token<bytes>  BEFORE_SYM                    /* SQL-2003-N */
 %token<bytes>  BEGIN_SYM                     /* SQL-2003-R */
@@ -478,6 +511,7 @@ import (
 %token<bytes>  PLUGINS_SYM
 %token<bytes>  POINT_SYM
 %token<bytes>  POLYGON
+%token<bytes>  POOLS_SYM
 %token<bytes>  PORT_SYM
 %token<bytes>  POSITION_SYM                  /* SQL-2003-N */
 %token<bytes>  PRECISION                     /* SQL-2003-R */
@@ -557,6 +591,7 @@ import (
 %token<bytes>  SERIALIZABLE_SYM              /* SQL-2003-N */
 %token<bytes>  SERIAL_SYM
 %token<bytes>  SESSION_SYM                   /* SQL-2003-N */
+%token<bytes>  SESSIONS_SYM
 %token<bytes>  SERVER_SYM
 %token<bytes>  SERVER_OPTIONS
 %token<bytes>  SET                           /* SQL-2003-R */
@@ -735,9 +770,27 @@ import (
 /* DML */
 %type <statement> insert update delete replace call do handler load single_multi
 
-%type <select_statement> select select_init select_init2 select_paren select_part2 select_derived2 opt_select_from query_specification select_init2_derived select_part2_derived select_paren_derived select_derived create_select 
-%type <select_statement> view_select view_select_aux create_view_select create_view_select_paren query_expression_body
-%type <select_statement> union_opt union_clause_opt select_derived_union union_list 
+%type <select_statement> select select_init select_init2 select_paren select_part2 select_derived2 query_specification select_init2_derived select_part2_derived select_paren_derived select_derived create_select 
+%type <select_statement> view_select_aux create_view_select create_view_select_paren query_expression_body
+%type <select_statement> select_derived_union
+%type <interf> union_opt union_clause_opt union_list
+%type <order_limit> order_or_limit union_order_or_limit opt_union_order_or_limit
+%type <select_fields> select_item_list
+%type <select_field> select_item table_wild
+%type <strs> select_options select_option_list opt_delete_options
+%type <str> select_option query_expression_option union_option into into_destination select_var_list_init select_var_list select_var_ident
+%type <str> procedure_analyse_clause opt_procedure_analyse_params
+%type <str> opt_load_data_charset opt_field_term field_term_list field_term opt_line_term line_term_list line_term
+%type <str> insert_lock_option replace_lock_option opt_low_priority opt_ignore opt_delete_option opt_extended_describe
+%type <bytes> select_alias opt_describe_column procedure_analyse_param TEXT_STRING_filesystem text_string
+%type <group> group_clause
+%type <boolean> olap_opt opt_distinct
+%type <order_by> opt_order_clause order_clause order_list group_list gorder_list opt_gorder_clause
+%type <str> order_dir normal_join index_hint_clause index_hint_type
+%type <idents> using_list opt_use_partition use_partition key_usage_list opt_key_usage_list
+%type <bytes> key_usage_element
+%type <index_hint> index_hint_definition
+%type <index_hints> index_hints_list opt_index_hints_list opt_key_definition
 
 %type <subquery> subselect
 
@@ -759,16 +812,27 @@ import (
 /* MySQL Utility Statement */
 %type <statement> describe help use explanable_command
 
-%type <bytes> ident IDENT_sys keyword keyword_sp ident_or_empty opt_wild opt_table_alias opt_db TEXT_STRING_sys ident_or_text interval interval_time_stamp TEXT_STRING_literal old_or_new_charset_name old_or_new_charset_name_or_default charset_name_or_default charset_name 
+%type <bytes> ident IDENT_sys keyword keyword_sp ident_or_empty opt_wild opt_table_alias opt_db TEXT_STRING_sys ident_or_text interval interval_time_stamp TEXT_STRING_literal old_or_new_charset_name old_or_new_charset_name_or_default charset_name_or_default charset_name opt_full 
 
 %type <interf> insert_field_spec insert_values view_or_trigger_or_sp_or_event definer_tail no_definer_tail start_option_value_list_following_option_type
 
-%type <table> table_name_with_opt_use_partition table_ident into_table insert_table table_ident_nodb table_wild_one table_ident_opt_wild table_name table_alias_ref table_lock
-%type <table_list> table_list table_lock_list opt_table_list
+%type <table> table_name_with_opt_use_partition table_ident into_table insert_table table_ident_nodb table_wild_one table_ident_opt_wild table_name table_alias_ref
+%type <table_list> table_list opt_table_list
+%type <table_lock> table_lock
+%type <table_locks> table_lock_list
+%type <index_column> key_part
+%type <index_columns> key_list
+%type <boolean> if_exists opt_temporary opt_if_not_exists opt_table_options opt_no_write_to_binlog opt_ignore_leaves
+%type <str> opt_restrict opt_unique trg_action_time trg_event udf_type show_engine_param opt_flush_lock opt_chain opt_release lock_option view_check_option profile_def opt_checksum_type
+%type <strs> opt_profile_defs profile_defs
+%type <bytes> known_storage_engines binlog_in binlog_from ulonglong_num opt_profile_args key_cache_name
+%type <idents> view_list_opt view_list cache_keys_spec cache_key_list_or_empty
+%type <life_type> opt_var_type
+%type <exprs> opt_sp_cparam_list opt_sp_cparams sp_cparams
 
 
 %type <table_ref> esc_table_ref table_ref table_factor join_table 
-%type <table_ref_list> select_into select_from join_table_list derived_table_list table_alias_ref_list table_wild_list opt_from_clause table_reference_list from_clause
+%type <table_ref_list> join_table_list derived_table_list table_alias_ref_list table_wild_list
 
 %type <table_to_table> table_to_table
 %type <table_to_table_list> table_to_table_list
@@ -796,12 +860,37 @@ import (
 %type <vars> option_value_list_continued option_value_list
 
 %type <life_type> option_type opt_var_ident_type
+%type <kill_type> kill_option
+%type <statement> aegis
+%type <numval> opt_backend_weight
+%type <aegis_action> aegis_backend_action
 %type <var_type> 
 
-%type <expr> expr set_expr_or_default
+%type <expr> expr set_expr_or_default where_clause
+%type <select_stmt> select_into select_from opt_select_from
+%type <expr> having_clause order_ident opt_expr opt_else in_sum_expr udf_expr
+%type <exprs> opt_expr_list udf_expr_list opt_udf_expr_list func_datetime_precision
+%type <valexpr> opt_escape function_call_keyword function_call_nonkeyword function_call_conflict function_call_generic geometry_function sum_expr now
+%type <valexprs> ident_list_arg ident_list
+%type <variable> variable_aux
+%type <whens> when_list
+%type <strval> opt_gconcat_separator
+%type <limit> delete_limit_clause
+%type <str> all_or_any cast_type opt_field_length field_length float_options precision type_datetime_precision opt_binary opt_bin_mod ascii unicode opt_component
+%type <str> ws_level_flag_desc ws_level_flag_reverse ws_level_flags ws_level_list_item ws_level_list ws_level_range ws_level_list_or_range opt_ws_levels
+%type <str> fulltext_options opt_natural_language_mode opt_query_expansion
+%type <bytes> date_time_type opt_collate collation_name collation_name_or_default ws_nweights ws_level_number ulong_num real_ulong_num dec_num_error dec_num
+%type <limit> opt_limit_clause_init opt_limit_clause limit_clause limit_options
 %type <exprs> expr_list
 %type <boolexpr> bool_pri
-%type <valexpr> predicate bit_expr simple_expr simple_ident literal param_marker variable text_literal temporal_literal NUM_literal simple_ident_q 
+%type <valexpr> simple_ident_nospvar insert_ident
+%type <valexprs> fields
+%type <exprs> no_braces opt_values values
+%type <exprs_list> values_list
+%type <expr> expr_or_default
+%type <assignment> ident_eq_value update_elem insert_update_elem
+%type <assignments> ident_eq_list update_list insert_update_list opt_insert_update
+%type <valexpr> limit_option predicate bit_expr simple_expr simple_ident literal param_marker variable text_literal temporal_literal NUM_literal simple_ident_q 
 
 %%
 
@@ -821,7 +910,8 @@ verb_clause:
 | begin { $$ = &Begin{} };
 
 statement:
-  alter {$$ = $1}
+  aegis {$$ = $1}
+| alter {$$ = $1}
 | analyze {$$ = $1} 
 | binlog_base64_event {$$ = $1}
 | call {$$ = $1} 
@@ -951,26 +1041,32 @@ master_file_def:
 | RELAY_LOG_POS_SYM EQ ulong_num;
 
 create:
-  CREATE opt_table_options TABLE_SYM opt_if_not_exists table_ident create2 { $$ = &CreateTable{Table: $5} }
-| CREATE opt_unique INDEX_SYM ident key_alg ON table_ident '(' key_list ')' normal_key_options opt_index_lock_algorithm { $$ = &CreateIndex{} }
-| CREATE fulltext INDEX_SYM ident init_key_options ON table_ident '(' key_list ')' fulltext_key_options opt_index_lock_algorithm { $$ = &CreateIndex{} }
-| CREATE spatial INDEX_SYM ident init_key_options ON table_ident '(' key_list ')' spatial_key_options opt_index_lock_algorithm { $$ = &CreateIndex{} }
-| CREATE DATABASE opt_if_not_exists ident opt_create_database_options { $$ = &CreateDatabase{} }
+  CREATE opt_table_options TABLE_SYM opt_if_not_exists table_ident create2
+  { $$ = &CreateTable{Temporary: $2, IfNotExists: $4, Table: $5, Definition: textFrom(MySQLlex, MySQLchar, $<end>5)} }
+| CREATE opt_unique INDEX_SYM ident key_alg ON table_ident '(' key_list ')' normal_key_options opt_index_lock_algorithm
+  { $$ = &CreateIndex{Kind: $2, Name: $4, Using: textBetween(MySQLlex, $<end>4, $<end>5), Table: $7, Columns: $9, Options: textFrom(MySQLlex, MySQLchar, $<end>10)} }
+| CREATE fulltext INDEX_SYM ident init_key_options ON table_ident '(' key_list ')' fulltext_key_options opt_index_lock_algorithm
+  { $$ = &CreateIndex{Kind: INDEX_FULLTEXT, Name: $4, Table: $7, Columns: $9, Options: textFrom(MySQLlex, MySQLchar, $<end>10)} }
+| CREATE spatial INDEX_SYM ident init_key_options ON table_ident '(' key_list ')' spatial_key_options opt_index_lock_algorithm
+  { $$ = &CreateIndex{Kind: INDEX_SPATIAL, Name: $4, Table: $7, Columns: $9, Options: textFrom(MySQLlex, MySQLchar, $<end>10)} }
+| CREATE DATABASE opt_if_not_exists ident opt_create_database_options
+  { $$ = &CreateDatabase{IfNotExists: $3, Schema: $4, Options: textFrom(MySQLlex, MySQLchar, $<end>4)} }
 | CREATE view_or_trigger_or_sp_or_event 
   { 
+    definer := textBetween(MySQLlex, $<end>1, $<end>2)
     switch st := $2.(type) {
         case *viewTail:
-        $$ = &CreateView{View: st.View}
+        $$ = &CreateView{Options: textBetween(MySQLlex, $<end>1, st.optionsEnd), View: st.View, Columns: st.Columns, As: st.As, CheckOption: st.CheckOption}
         case *triggerTail:
-        $$ = &CreateTrigger{Trigger: st.Trigger}
+        $$ = &CreateTrigger{Definer: definer, Trigger: st.Trigger, Time: st.Time, Event: st.Event, Table: st.Table, Body: st.Body}
         case *spTail:
-        $$ = &CreateProcedure{Procedure: st.Procedure}
+        $$ = &CreateProcedure{Definer: definer, Procedure: st.Procedure, Definition: st.Definition}
         case *sfTail:
-        $$ = &CreateFunction{Function: st.Function}
+        $$ = &CreateFunction{Definer: definer, Function: st.Function, Definition: st.Definition}
         case *udfTail:
-        $$ = &CreateUDF{Function: st.Function}
+        $$ = &CreateUDF{Aggregate: st.Aggregate, Function: st.Function, Returns: st.Returns, Soname: st.Soname}
         case *eventTail:
-        $$ = &CreateEvent{Event: st.Event}
+        $$ = &CreateEvent{Definer: definer, IfNotExists: st.IfNotExists, Event: st.Event, Definition: st.Definition}
         default:
         panic(__yyfmt__.Sprintf("unknow create statement:%T", st))
     }
@@ -999,7 +1095,7 @@ server_option:
 
 event_tail:
   remember_name EVENT_SYM opt_if_not_exists sp_name ON SCHEDULE_SYM ev_schedule_time opt_ev_on_completion opt_ev_status opt_ev_comment DO_SYM ev_sql_stmt
-  { $$ = &eventTail{Event: $4} }
+  { $$ = &eventTail{IfNotExists: $3, Event: $4, Definition: textFrom(MySQLlex, MySQLchar, $<end>4)} }
 ;
 
 ev_schedule_time:
@@ -1054,7 +1150,7 @@ clear_privileges:
  ;
 
 sp_name:
-  ident '.' ident { $$ = &Spname{Qualifier: $1, Name: $3} }
+  ident '.' ident { $$ = &Spname{Qualifier: $1, Name: $3}; $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
 | ident { $$ = &Spname{Name: $1} };
 
 sp_a_chistics:
@@ -1084,19 +1180,19 @@ sp_suid:
 | SQL_SYM SECURITY_SYM INVOKER_SYM;
 
 call:
-  CALL_SYM sp_name opt_sp_cparam_list { $$ = &Call{Spname:$2} };
+  CALL_SYM sp_name opt_sp_cparam_list { $$ = &Call{Spname: $2, Args: $3} };
 
 opt_sp_cparam_list:
- 
-| '(' opt_sp_cparams ')';
+  { $$ = nil }
+| '(' opt_sp_cparams ')' { $$ = $2 };
 
 opt_sp_cparams:
- 
-| sp_cparams;
+  { $$ = IExprs{} }
+| sp_cparams { $$ = $1 };
 
 sp_cparams:
-  sp_cparams ',' expr
-| expr;
+  sp_cparams ',' expr { $$ = append($1, $3) }
+| expr { $$ = IExprs{$1} };
 
 sp_fdparam_list:
  
@@ -1385,13 +1481,13 @@ sp_unlabeled_control:
 | REPEAT_SYM sp_proc_stmts1 UNTIL_SYM expr END REPEAT_SYM;
 
 trg_action_time:
-  BEFORE_SYM
-| AFTER_SYM;
+  BEFORE_SYM { $$ = "before" }
+| AFTER_SYM { $$ = "after" };
 
 trg_event:
-  INSERT
-| UPDATE_SYM
-| DELETE_SYM;
+  INSERT { $$ = "insert" }
+| UPDATE_SYM { $$ = "update" }
+| DELETE_SYM { $$ = "delete" };
 
 change_tablespace_access:
   tablespace_name ts_access_mode;
@@ -1724,13 +1820,7 @@ opt_part_option:
 
 create_select:
   SELECT_SYM select_options select_item_list opt_select_from
-  {
-    if $4 == nil {
-        $$ = &Select{From: nil, LockType: LockType_NoLock}
-    } else {
-        $$ = &Select{From: $4.(*Select).From, LockType: $4.(*Select).LockType}
-    }    
-  } 
+  { $4.Options, $4.Fields = $2, $3; $$ = $4 }
 ;
 
 opt_as:
@@ -1750,8 +1840,8 @@ create_database_option:
 | default_charset;
 
 opt_table_options:
- 
-| table_options;
+  { $$ = false }
+| table_options { $$ = true };
 
 table_options:
   table_option
@@ -1761,8 +1851,8 @@ table_option:
   TEMPORARY;
 
 opt_if_not_exists:
- 
-| IF not EXISTS;
+  { $$ = false }
+| IF not EXISTS { $$ = true };
 
 opt_create_table_options:
  
@@ -1819,7 +1909,7 @@ storage_engines:
   ident_or_text;
 
 known_storage_engines:
-  ident_or_text;
+  ident_or_text { $$ = $1 };
 
 row_types:
   DEFAULT
@@ -1835,15 +1925,15 @@ merge_insert_types:
 | LAST_SYM;
 
 opt_select_from:
-  opt_limit_clause { $$ = nil }
-| select_from select_lock_type { $$ = &Select{From: $1, LockType: $2} }
+  opt_limit_clause { $$ = &Select{Limit: $1} }
+| select_from select_lock_type { $1.LockType = $2; $$ = $1 }
 ;
 
 udf_type:
-  STRING_SYM
-| REAL
-| DECIMAL_SYM
-| INT_SYM;
+  STRING_SYM { $$ = "string" }
+| REAL { $$ = "real" }
+| DECIMAL_SYM { $$ = "decimal" }
+| INT_SYM { $$ = "int" };
 
 create_field_list:
   field_list;
@@ -1967,21 +2057,21 @@ real_type:
 | DOUBLE_SYM PRECISION;
 
 float_options:
- 
-| field_length
-| precision;
+  { $$ = "" }
+| field_length { $$ = $1 }
+| precision { $$ = $1 };
 
 precision:
-  '(' NUM ',' NUM ')';
+  '(' NUM ',' NUM ')' { $$ = "(" + string($2) + ", " + string($4) + ")" };
 
 type_datetime_precision:
- 
-| '(' NUM ')';
+  { $$ = "" }
+| '(' NUM ')' { $$ = "(" + string($2) + ")" };
 
 func_datetime_precision:
- 
-| '(' ')'
-| '(' NUM ')';
+  { $$ = nil }
+| '(' ')' { $$ = nil }
+| '(' NUM ')' { $$ = IExprs{NumVal($2)} };
 
 field_options:
  
@@ -1997,14 +2087,14 @@ field_option:
 | ZEROFILL;
 
 field_length:
-  '(' LONG_NUM ')'
-| '(' ULONGLONG_NUM ')'
-| '(' DECIMAL_NUM ')'
-| '(' NUM ')';
+  '(' LONG_NUM ')' { $$ = "(" + string($2) + ")" }
+| '(' ULONGLONG_NUM ')' { $$ = "(" + string($2) + ")" }
+| '(' DECIMAL_NUM ')' { $$ = "(" + string($2) + ")" }
+| '(' NUM ')' { $$ = "(" + string($2) + ")" };
 
 opt_field_length:
- 
-| field_length;
+  { $$ = "" }
+| field_length { $$ = $1 };
 
 opt_precision:
  
@@ -2041,7 +2131,7 @@ type_with_opt_collate:
   type opt_collate;
 
 now:
-  NOW_SYM func_datetime_precision;
+  NOW_SYM func_datetime_precision { $$ = &FuncExpr{Name: $1, Exprs: $2} };
 
 now_or_signed_literal:
   now
@@ -2060,8 +2150,8 @@ charset_name_or_default:
 | DEFAULT { $$ = $1 };
 
 opt_load_data_charset:
- 
-| charset charset_name_or_default;
+  { $$ = "" }
+| charset charset_name_or_default { $$ = " character set " + string($2) };
 
 old_or_new_charset_name:
   ident_or_text { $$ = $1 }
@@ -2072,79 +2162,79 @@ old_or_new_charset_name_or_default:
 | DEFAULT { $$ = $1 };
 
 collation_name:
-  ident_or_text;
+  ident_or_text { $$ = $1 };
 
 opt_collate:
- 
-| COLLATE_SYM collation_name_or_default;
+  { $$ = nil }
+| COLLATE_SYM collation_name_or_default { $$ = $2 };
 
 collation_name_or_default:
-  collation_name
-| DEFAULT;
+  collation_name { $$ = $1 }
+| DEFAULT { $$ = $1 };
 
 opt_default:
  
 | DEFAULT;
 
 ascii:
-  ASCII_SYM
-| BINARY ASCII_SYM
-| ASCII_SYM BINARY;
+  ASCII_SYM { $$ = "ascii" }
+| BINARY ASCII_SYM { $$ = "binary ascii" }
+| ASCII_SYM BINARY { $$ = "ascii binary" };
 
 unicode:
-  UNICODE_SYM
-| UNICODE_SYM BINARY
-| BINARY UNICODE_SYM;
+  UNICODE_SYM { $$ = "unicode" }
+| UNICODE_SYM BINARY { $$ = "unicode binary" }
+| BINARY UNICODE_SYM { $$ = "binary unicode" };
 
 opt_binary:
- 
-| ascii
-| unicode
-| BYTE_SYM
-| charset charset_name opt_bin_mod
-| BINARY
-| BINARY charset charset_name;
+  { $$ = "" }
+| ascii { $$ = " " + $1 }
+| unicode { $$ = " " + $1 }
+| BYTE_SYM { $$ = " byte" }
+| charset charset_name opt_bin_mod { $$ = " character set " + string($2) + $3 }
+| BINARY { $$ = " binary" }
+| BINARY charset charset_name { $$ = " binary character set " + string($3) };
 
 opt_bin_mod:
- 
-| BINARY;
+  { $$ = "" }
+| BINARY { $$ = " binary" };
 
 ws_nweights:
-  '(' real_ulong_num ')';
+  '(' real_ulong_num ')' { $$ = $2 };
 
 ws_level_flag_desc:
-  ASC
-| DESC;
+  ASC { $$ = OP_ASC }
+| DESC { $$ = OP_DESC };
 
 ws_level_flag_reverse:
-  REVERSE_SYM;
+  REVERSE_SYM { $$ = "reverse" };
 
 ws_level_flags:
- 
-| ws_level_flag_desc
-| ws_level_flag_desc ws_level_flag_reverse
-| ws_level_flag_reverse;
+  { $$ = "" }
+| ws_level_flag_desc { $$ = " " + $1 }
+| ws_level_flag_desc ws_level_flag_reverse { $$ = " " + $1 + " " + $2 }
+| ws_level_flag_reverse { $$ = " " + $1 };
 
 ws_level_number:
-  real_ulong_num;
+  real_ulong_num { $$ = $1 };
 
 ws_level_list_item:
-  ws_level_number ws_level_flags;
+  ws_level_number ws_level_flags { $$ = string($1) + $2 };
 
 ws_level_list:
-  ws_level_list_item
-| ws_level_list ',' ws_level_list_item;
+  ws_level_list_item { $$ = $1 }
+| ws_level_list ',' ws_level_list_item { $$ = $1 + ", " + $3 };
 
 ws_level_range:
-  ws_level_number '-' ws_level_number;
+  ws_level_number '-' ws_level_number { $$ = string($1) + "-" + string($3) };
 
 ws_level_list_or_range:
-  ws_level_list
-| ws_level_range;
+  ws_level_list { $$ = $1 }
+| ws_level_range { $$ = $1 };
 
 opt_ws_levels:
- 
-| LEVEL_SYM ws_level_list_or_range;
+  { $$ = "" }
+| LEVEL_SYM ws_level_list_or_range { $$ = $2 };
 
 opt_primary:
  
@@ -2202,8 +2292,8 @@ keys_or_index:
 | INDEXES;
 
 opt_unique:
- 
-| UNIQUE_SYM;
+  { $$ = "" }
+| UNIQUE_SYM { $$ = INDEX_UNIQUE };
 
 fulltext:
   FULLTEXT_SYM;
@@ -2215,8 +2305,8 @@ init_key_options:
  ;
 
 key_alg:
-  init_key_options
-| init_key_options key_using_alg;
+  init_key_options { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| init_key_options key_using_alg { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 normal_key_options:
  
@@ -2267,20 +2357,20 @@ btree_or_rtree:
 | HASH_SYM;
 
 key_list:
-  key_list ',' key_part order_dir
-| key_part order_dir;
+  key_list ',' key_part order_dir { $3.Order = $4; $$ = append($1, $3) }
+| key_part order_dir { $1.Order = $2; $$ = []*IndexColumn{$1} };
 
 key_part:
-  ident
-| ident '(' NUM ')';
+  ident { $$ = &IndexColumn{Column: $1} }
+| ident '(' NUM ')' { $$ = &IndexColumn{Column: $1, Length: NumVal($3)} };
 
 opt_ident:
  
 | field_ident;
 
 opt_component:
- 
-| '.' ident;
+  { $$ = "" }
+| '.' ident { $$ = "." + string($2) };
 
 string_list:
   text_string
@@ -2288,16 +2378,19 @@ string_list:
 
 alter:
   ALTER opt_ignore TABLE_SYM table_ident alter_commands 
-  { $$ = &AlterTable{Table: $4} }
+  { $$ = &AlterTable{Ignore: $2 != "", Table: $4, Commands: textFrom(MySQLlex, MySQLchar, $<end>4)} }
 | ALTER DATABASE ident_or_empty create_database_options 
-  { $$ = &AlterDatabase{Schema: $3} }
+  { $$ = &AlterDatabase{Schema: $3, Options: textFrom(MySQLlex, MySQLchar, $<end>3)} }
 | ALTER DATABASE ident UPGRADE_SYM DATA_SYM DIRECTORY_SYM NAME_SYM 
-  { $$ = &AlterDatabase{Schema: $3} }
-| ALTER PROCEDURE_SYM sp_name sp_a_chistics { $$ = &AlterProcedure{Procedure: $3} }
-| ALTER FUNCTION_SYM sp_name sp_a_chistics { $$ = &AlterFunction{Function: $3} }
-| ALTER view_algorithm definer_opt view_tail { $$ = &AlterView{View: $4.View, As: $4.As} }
-| ALTER definer_opt view_tail { $$ = &AlterView{View: $3.View, As: $3.As} }
-| ALTER definer_opt EVENT_SYM sp_name ev_alter_on_schedule_completion opt_ev_rename_to opt_ev_status opt_ev_comment opt_ev_sql_stmt { $$ = &AlterEvent{Event: $4, Rename: $6} }
+  { $$ = &AlterDatabase{Schema: $3, Options: textFrom(MySQLlex, MySQLchar, $<end>3)} }
+| ALTER PROCEDURE_SYM sp_name sp_a_chistics { $$ = &AlterProcedure{Procedure: $3, Characteristics: textFrom(MySQLlex, MySQLchar, $<end>3)} }
+| ALTER FUNCTION_SYM sp_name sp_a_chistics { $$ = &AlterFunction{Function: $3, Characteristics: textFrom(MySQLlex, MySQLchar, $<end>3)} }
+| ALTER view_algorithm definer_opt view_tail
+  { $$ = &AlterView{Options: textBetween(MySQLlex, $<end>1, $4.optionsEnd), View: $4.View, Columns: $4.Columns, As: $4.As, CheckOption: $4.CheckOption} }
+| ALTER definer_opt view_tail
+  { $$ = &AlterView{Options: textBetween(MySQLlex, $<end>1, $3.optionsEnd), View: $3.View, Columns: $3.Columns, As: $3.As, CheckOption: $3.CheckOption} }
+| ALTER definer_opt EVENT_SYM sp_name ev_alter_on_schedule_completion opt_ev_rename_to opt_ev_status opt_ev_comment opt_ev_sql_stmt
+  { $$ = &AlterEvent{Definer: textBetween(MySQLlex, $<end>1, $<end>2), Event: $4, Schedule: textBetween(MySQLlex, $<end>4, $<end>5), Rename: $6, Definition: textFrom(MySQLlex, MySQLchar, $<end>6)} }
 | ALTER TABLESPACE alter_tablespace_info { $$ = &AlterTablespace{} }
 | ALTER LOGFILE_SYM GROUP_SYM alter_logfile_group_info { $$ = &AlterLogfile{} }
 | ALTER TABLESPACE change_tablespace_info { $$ = &AlterTablespace{} }
@@ -2311,21 +2404,21 @@ alter_user_list:
 | alter_user_list ',' user PASSWORD EXPIRE_SYM;
 
 ev_alter_on_schedule_completion:
- 
-| ON SCHEDULE_SYM ev_schedule_time
-| ev_on_completion
-| ON SCHEDULE_SYM ev_schedule_time ev_on_completion;
+  { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| ON SCHEDULE_SYM ev_schedule_time { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| ev_on_completion { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| ON SCHEDULE_SYM ev_schedule_time ev_on_completion { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 opt_ev_rename_to:
-  { $$ = nil }
-| RENAME TO_SYM sp_name { $$ = $3 };
+  { $$ = nil; $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| RENAME TO_SYM sp_name { $$ = $3; $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 opt_ev_sql_stmt:
  
 | DO_SYM ev_sql_stmt;
 
 ident_or_empty:
-  { $$ = nil } 
+  { $$ = nil; $<end>$ = symbolEnd(MySQLlex, MySQLchar) } 
 | ident { $$ = $1 };
 
 alter_commands:
@@ -2426,13 +2519,13 @@ opt_column:
 | COLUMN_SYM;
 
 opt_ignore:
- 
-| IGNORE_SYM;
+  { $$ = "" }
+| IGNORE_SYM { $$ = DML_IGNORE };
 
 opt_restrict:
- 
-| RESTRICT
-| CASCADE;
+  { $$ = "" }
+| RESTRICT { $$ = "restrict" }
+| CASCADE { $$ = "cascade" };
 
 opt_place:
  
@@ -2509,15 +2602,16 @@ slave_until_opts:
 | SQL_AFTER_MTS_GAPS;
 
 checksum:
-  CHECKSUM_SYM table_or_tables table_list opt_checksum_type { $$ = &CheckSum{Tables: $3} } ;
+  CHECKSUM_SYM table_or_tables table_list opt_checksum_type { $$ = &CheckSum{Tables: $3, Option: $4} } ;
 
 opt_checksum_type:
- 
-| QUICK
-| EXTENDED_SYM;
+  { $$ = "" }
+| QUICK { $$ = "quick" }
+| EXTENDED_SYM { $$ = "extended" };
 
 repair:
-  REPAIR opt_no_write_to_binlog table_or_tables table_list opt_mi_repair_type { $$ = &Repair{Tables: $4} };
+  REPAIR opt_no_write_to_binlog table_or_tables table_list opt_mi_repair_type
+  { $$ = &Repair{NoWriteToBinlog: $2, Tables: $4, Options: textFrom(MySQLlex, MySQLchar, $<end>4)} };
 
 opt_mi_repair_type:
  
@@ -2533,13 +2627,13 @@ mi_repair_type:
 | USE_FRM;
 
 analyze:
-  ANALYZE_SYM opt_no_write_to_binlog table_or_tables table_list { $$ = &Analyze{Tables: $4} };
+  ANALYZE_SYM opt_no_write_to_binlog table_or_tables table_list { $$ = &Analyze{NoWriteToBinlog: $2, Tables: $4} };
 
 binlog_base64_event:
   BINLOG_SYM TEXT_STRING_sys { $$ = &Binlog{} };
 
 check:
-  CHECK_SYM table_or_tables table_list opt_mi_check_type { $$ = &Check{Tables: $3} } ;
+  CHECK_SYM table_or_tables table_list opt_mi_check_type { $$ = &Check{Tables: $3, Options: textFrom(MySQLlex, MySQLchar, $<end>3)} } ;
 
 opt_mi_check_type:
  
@@ -2558,12 +2652,12 @@ mi_check_type:
 | FOR_SYM UPGRADE_SYM;
 
 optimize:
-  OPTIMIZE opt_no_write_to_binlog table_or_tables table_list { $$ = &Optimize{Tables: $4} }; 
+  OPTIMIZE opt_no_write_to_binlog table_or_tables table_list { $$ = &Optimize{NoWriteToBinlog: $2, Tables: $4} }; 
 
 opt_no_write_to_binlog:
- 
-| NO_WRITE_TO_BINLOG
-| LOCAL_SYM;
+  { $$ = false }
+| NO_WRITE_TO_BINLOG { $$ = true }
+| LOCAL_SYM { $$ = true };
 
 rename:
   RENAME table_or_tables table_to_table_list { $$ = &RenameTable{ToList: $3} }
@@ -2583,7 +2677,7 @@ table_to_table:
 
 keycache:
   CACHE_SYM INDEX_SYM keycache_list_or_parts IN_SYM key_cache_name 
-  { $$ = &CacheIndex{TableIndexList: $3} };
+  { $$ = &CacheIndex{TableIndexList: $3, KeyCache: $5} };
 
 keycache_list_or_parts:
   keycache_list { $$ = $1 }
@@ -2595,14 +2689,15 @@ keycache_list:
 | keycache_list ',' assign_to_keycache { $$ = append($1, $3) };
 
 assign_to_keycache:
-  table_ident cache_keys_spec { $$ = &TableIndex{Table: $1} };
+  table_ident cache_keys_spec { $$ = &TableIndex{Table: $1, Keys: $2} };
 
 assign_to_keycache_parts:
-  table_ident adm_partition cache_keys_spec { $$ = &TableIndex{Table: $1} };
+  table_ident adm_partition cache_keys_spec
+  { $$ = &TableIndex{Table: $1, Partitions: textBetween(MySQLlex, $<end>1, $<end>2), Keys: $3} };
 
 key_cache_name:
-  ident
-| DEFAULT;
+  ident { $$ = $1 }
+| DEFAULT { $$ = nil };
 
 preload:
   LOAD INDEX_SYM INTO CACHE_SYM preload_list_or_parts { $$ = &LoadIndex{TableIndexList: $5} }
@@ -2617,24 +2712,25 @@ preload_list:
 | preload_list ',' preload_keys { $$ = append($1, $3) };
 
 preload_keys:
-  table_ident cache_keys_spec opt_ignore_leaves { $$ = &TableIndex{Table: $1} };
+  table_ident cache_keys_spec opt_ignore_leaves { $$ = &TableIndex{Table: $1, Keys: $2, IgnoreLeaves: $3} };
 
 preload_keys_parts:
-  table_ident adm_partition cache_keys_spec opt_ignore_leaves {$$ = &TableIndex{Table: $1} };
+  table_ident adm_partition cache_keys_spec opt_ignore_leaves
+  { $$ = &TableIndex{Table: $1, Partitions: textBetween(MySQLlex, $<end>1, $<end>2), Keys: $3, IgnoreLeaves: $4} };
 
 adm_partition:
-  PARTITION_SYM have_partitioning '(' all_or_alt_part_name_list ')';
+  PARTITION_SYM have_partitioning '(' all_or_alt_part_name_list ')' { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 cache_keys_spec:
-  cache_key_list_or_empty;
+  cache_key_list_or_empty { $$ = $1 };
 
 cache_key_list_or_empty:
- 
-| key_or_index '(' opt_key_usage_list ')';
+  { $$ = nil }
+| key_or_index '(' opt_key_usage_list ')' { $$ = $3 };
 
 opt_ignore_leaves:
- 
-| IGNORE_SYM LEAVES;
+  { $$ = false }
+| IGNORE_SYM LEAVES { $$ = true };
 
 select:
   select_init { $$ = $1 };
@@ -2642,68 +2738,56 @@ select:
 select_init:
   SELECT_SYM select_init2 { $$ = $2 }
 | '(' select_paren ')' union_opt 
-  { 
-    if $4 == nil {
-        $$ = &SubQuery{SelectStatement: $2} // subquery?
-    } else {
-        $$ = &Union{Left: $2, Right: $4}
-    }
-  }
+  { $$ = withUnion(&ParenSelect{Select: $2}, $4) }
 ;
 
 select_paren:
   SELECT_SYM select_part2
   { $$ = $2 }
 | '(' select_paren ')'
-  { $$ = &SubQuery{SelectStatement : $2} } 
+  { $$ = &ParenSelect{Select: $2} } 
 ;
 
 select_paren_derived:
   SELECT_SYM select_part2_derived { $$ = $2 }
-| '(' select_paren_derived ')' { $$ = $2 }
+| '(' select_paren_derived ')' { $$ = &ParenSelect{Select: $2} }
 ;
 
 select_init2:
   select_part2 union_clause_opt
-  {
-    if $2 == nil {
-        $$ = $1
-    } else { // we got a right-recuse union clause
-        $$ = &Union{Left: $1, Right: $2}
-    }
-  }
+  { $$ = withUnion($1, $2) }
 ;
 
 select_part2:
   select_options select_item_list select_into select_lock_type
-  { $$ = &Select {From: $3, LockType: $4} }
+  { $3.Options, $3.Fields, $3.LockType = $1, $2, $4; $$ = $3 }
 ;
 
 select_into:
-  opt_order_clause opt_limit_clause { $$ = nil }
-| into { $$ = nil }
+  opt_order_clause opt_limit_clause { $$ = &Select{OrderBy: $1, Limit: $2} }
+| into { $$ = &Select{Into: $1} }
 | select_from { $$ = $1 }
-| into select_from { $$ = $2 }
-| select_from into { $$ = $1 }
+| into select_from { $2.Into = $1; $$ = $2 }
+| select_from into { $1.Into = $2; $$ = $1 }
 ;
 
 select_from:
   FROM join_table_list where_clause group_clause having_clause opt_order_clause opt_limit_clause procedure_analyse_clause
-  { $$ = $2 }
-| FROM DUAL_SYM where_clause opt_limit_clause { $$ = nil };
+  { $$ = withGroup(&Select{From: $2, Where: $3, Having: $5, OrderBy: $6, Limit: $7, Procedure: $8}, $4) }
+| FROM DUAL_SYM where_clause opt_limit_clause { $$ = &Select{Where: $3, Limit: $4} };
 
 select_options:
- 
-| select_option_list;
+  { $$ = nil }
+| select_option_list { $$ = $1 };
 
 select_option_list:
-  select_option_list select_option
-| select_option;
+  select_option_list select_option { $$ = append($1, $2) }
+| select_option { $$ = []string{$1} };
 
 select_option:
-  query_expression_option
-| SQL_NO_CACHE_SYM
-| SQL_CACHE_SYM;
+  query_expression_option { $$ = $1 }
+| SQL_NO_CACHE_SYM { $$ = SELECT_SQL_NO_CACHE }
+| SQL_CACHE_SYM { $$ = SELECT_SQL_CACHE };
 
 select_lock_type:
   { $$ = LockType_NoLock }
@@ -2711,13 +2795,14 @@ select_lock_type:
 | LOCK_SYM IN_SYM SHARE_SYM MODE_SYM { $$ = LockType_LockInShareMode };
 
 select_item_list:
-  select_item_list ',' select_item
-| select_item
-| '*';
+  select_item_list ',' select_item { $$ = append($1, $3) }
+| select_item { $$ = SelectFields{$1} }
+| '*' { $$ = SelectFields{&StarExpr{}} };
 
 select_item:
-  remember_name table_wild remember_end
-| remember_name expr remember_end select_alias;
+  remember_name table_wild remember_end { $$ = $2 }
+| remember_name expr remember_end select_alias
+  { $$ = &AliasedField{Expr: $2, As: $4} };
 
 remember_name:
  ;
@@ -2726,11 +2811,11 @@ remember_end:
  ;
 
 select_alias:
- 
-| AS ident
-| AS TEXT_STRING_sys
-| ident
-| TEXT_STRING_sys;
+  { $$ = nil }
+| AS ident { $$ = $2 }
+| AS TEXT_STRING_sys { $$ = unquoteText($2) }
+| ident { $$ = $1 }
+| TEXT_STRING_sys { $$ = unquoteText($1) };
 
 optional_braces:
  
@@ -2767,11 +2852,11 @@ bool_pri:
 | bool_pri IS not NULL_SYM %prec IS
   { $$ = &NullCheck{Operator: OP_IS_NOT_NULL, Expr: $1} }
 | bool_pri EQUAL_SYM predicate %prec EQUAL_SYM
-  { $$ = &CompareExpr{Left: $1, Operator: OP_EQ, Right: $3} }
+  { $$ = &CompareExpr{Left: $1, Operator: OP_NSE, Right: $3} }
 | bool_pri comp_op predicate %prec EQ
   { $$ = &CompareExpr{Left: $1, Operator: $2, Right: $3} }
 | bool_pri comp_op all_or_any '(' subselect ')' %prec EQ
-  { $$ = &CompareExpr{Left: $1, Operator: $2, Right: $5} }
+  { $$ = &CompareExpr{Left: $1, Operator: $2, Right: $5, Quantifier: $3} }
 | predicate
   { $$ = &Predicate{Expr: $1} };
 
@@ -2795,9 +2880,9 @@ predicate:
 | bit_expr SOUNDS_SYM LIKE bit_expr
   { $$ = &LikeCond{Left: $1, Operator: OP_SOUNDS_LIKE, Right: $4} }
 | bit_expr LIKE simple_expr opt_escape
-  { $$ = &LikeCond{Left: $1, Operator: OP_LIKE, Right: $3} }
+  { $$ = &LikeCond{Left: $1, Operator: OP_LIKE, Right: $3, Escape: $4} }
 | bit_expr not LIKE simple_expr opt_escape
-  { $$ = &LikeCond{Left: $1, Operator: OP_NOT_LIKE, Right: $4} }
+  { $$ = &LikeCond{Left: $1, Operator: OP_NOT_LIKE, Right: $4, Escape: $5} }
 | bit_expr REGEXP bit_expr
   { $$ = &LikeCond{Left: $1, Operator: OP_REGEXP, Right: $3} }
 | bit_expr not REGEXP bit_expr
@@ -2828,7 +2913,7 @@ bit_expr:
 | bit_expr '%' bit_expr %prec '%'
   { $$ = &BinaryExpr{Left: $1, Operator: OP_MOD, Right: $3} }
 | bit_expr DIV_SYM bit_expr %prec DIV_SYM
-  { $$ = &BinaryExpr{Left: $1, Operator: OP_DIV, Right: $3} }
+  { $$ = &BinaryExpr{Left: $1, Operator: OP_INTDIV, Right: $3} }
 | bit_expr MOD_SYM bit_expr %prec MOD_SYM
   { $$ = &BinaryExpr{Left: $1, Operator: OP_MOD, Right: $3} }
 | bit_expr '^' bit_expr
@@ -2862,247 +2947,255 @@ comp_op:
 | NE { $$ = OP_NE };
 
 all_or_any:
-  ALL
-| ANY_SYM;
+  ALL { $$ = OP_ALL }
+| ANY_SYM { $$ = OP_ANY };
 
 simple_expr:
   simple_ident { $$ = $1 }
-| function_call_keyword { $$ = &FuncExpr{} }
-| function_call_nonkeyword { $$ = &FuncExpr{} }
-| function_call_generic { $$ = &FuncExpr{} }
-| function_call_conflict { $$ = &FuncExpr{} }
+| function_call_keyword { $$ = $1 }
+| function_call_nonkeyword { $$ = $1 }
+| function_call_generic { $$ = $1 }
+| function_call_conflict { $$ = $1 }
 | simple_expr COLLATE_SYM ident_or_text %prec NEG
   { $$ = &CollateExpr{Expr: $1, Collate: $3} }
 | literal { $$ = $1 }
 | param_marker { $$ = $1 }
 | variable { $$ = $1 }
-| sum_expr { $$ = &FuncExpr{} }
+| sum_expr { $$ = $1 }
 | simple_expr OR_OR_SYM simple_expr { $$ = &OrOrExpr{Left: $1, Right: $3} }
 | '+' simple_expr %prec NEG { $$ = &UnaryExpr{Expr: $2, Operator: OP_UPLUS} }
 | '-' simple_expr %prec NEG { $$ = &UnaryExpr{Expr: $2, Operator: OP_UMINUS} }
 | '~' simple_expr %prec NEG { $$ = &UnaryExpr{Expr: $2, Operator: OP_TILDA} }
 | not2 simple_expr %prec NEG { $$ = &UnaryExpr{Expr: $2, Operator: OP_NOT2} }
-| '(' subselect ')' { $$ = &SubQuery{SelectStatement: $2} }
-| '(' expr ')' { $$ = &IExprs{$2} }
+| '(' subselect ')' { $$ = $2 }
+| '(' expr ')' { $$ = IExprs{$2} }
 | '(' expr ',' expr_list ')' { $$ = append(IExprs{$2}, $4...) }
-| ROW_SYM '(' expr ',' expr_list ')' { $$ = &FuncExpr{} }
+| ROW_SYM '(' expr ',' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: append(IExprs{$3}, $5...)} }
 | EXISTS '(' subselect ')' { $$ = &ExistsExpr{SubQuery: $3} }
 | '{' ident expr '}' { $$ = &IdentExpr{Ident: $2, Expr: $3} }
-| MATCH ident_list_arg AGAINST '(' bit_expr fulltext_options ')' { $$ = &MatchExpr{} }
+| MATCH ident_list_arg AGAINST '(' bit_expr fulltext_options ')' { $$ = &MatchExpr{Columns: $2, Expr: $5, Modifier: $6} }
 | BINARY simple_expr %prec NEG { $$ = &UnaryExpr{Expr: $2, Operator: OP_UBINARY} }
-| CAST_SYM '(' expr AS cast_type ')' { $$ = &FuncExpr{} }
-| CASE_SYM opt_expr when_list opt_else END { $$ = &FuncExpr{} }
-| CONVERT_SYM '(' expr ',' cast_type ')' { $$ = &FuncExpr{} }
-| CONVERT_SYM '(' expr USING charset_name ')' { $$ = &FuncExpr{} }
-| DEFAULT '(' simple_ident ')' { $$ = &FuncExpr{} }
-| VALUES '(' simple_ident_nospvar ')' { $$ = &FuncExpr{} }
-| INTERVAL_SYM expr interval '+' expr %prec INTERVAL_SYM { $$ = &IntervalExpr{Expr: &BinaryExpr{Left: $2, Right: $5, Operator: OP_PLUS}, Interval: $3} };
+| CAST_SYM '(' expr AS cast_type ')' { $$ = &ConvertExpr{Name: $1, Expr: $3, Type: $5} }
+| CASE_SYM opt_expr when_list opt_else END { $$ = &CaseExpr{Expr: $2, Whens: $3, Else: $4} }
+| CONVERT_SYM '(' expr ',' cast_type ')' { $$ = &ConvertExpr{Name: $1, Expr: $3, Type: $5} }
+| CONVERT_SYM '(' expr USING charset_name ')' { $$ = &UsingExpr{Name: $1, Exprs: IExprs{$3}, Charset: $5} }
+| DEFAULT '(' simple_ident ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| VALUES '(' simple_ident_nospvar ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| INTERVAL_SYM expr interval '+' expr %prec INTERVAL_SYM { $$ = &BinaryExpr{Left: &IntervalExpr{Expr: $2, Interval: $3}, Operator: OP_PLUS, Right: $5} };
 
 function_call_keyword:
-  CHAR_SYM '(' expr_list ')'
-| CHAR_SYM '(' expr_list USING charset_name ')'
-| CURRENT_USER optional_braces
-| DATE_SYM '(' expr ')'
-| DAY_SYM '(' expr ')'
-| HOUR_SYM '(' expr ')'
-| INSERT '(' expr ',' expr ',' expr ',' expr ')'
-| INTERVAL_SYM '(' expr ',' expr ')' %prec INTERVAL_SYM
-| INTERVAL_SYM '(' expr ',' expr ',' expr_list ')' %prec INTERVAL_SYM
-| LEFT '(' expr ',' expr ')'
-| MINUTE_SYM '(' expr ')'
-| MONTH_SYM '(' expr ')'
-| RIGHT '(' expr ',' expr ')'
-| SECOND_SYM '(' expr ')'
-| TIME_SYM '(' expr ')'
-| TIMESTAMP '(' expr ')'
-| TIMESTAMP '(' expr ',' expr ')'
-| TRIM '(' expr ')'
-| TRIM '(' LEADING expr FROM expr ')'
-| TRIM '(' TRAILING expr FROM expr ')'
-| TRIM '(' BOTH expr FROM expr ')'
-| TRIM '(' LEADING FROM expr ')'
-| TRIM '(' TRAILING FROM expr ')'
-| TRIM '(' BOTH FROM expr ')'
-| TRIM '(' expr FROM expr ')'
-| USER '(' ')'
-| YEAR_SYM '(' expr ')';
+  CHAR_SYM '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| CHAR_SYM '(' expr_list USING charset_name ')' { $$ = &UsingExpr{Name: $1, Exprs: $3, Charset: $5} }
+| CURRENT_USER optional_braces { $$ = &FuncExpr{Name: $1} }
+| DATE_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| DAY_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| HOUR_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| INSERT '(' expr ',' expr ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7, $9}} }
+| INTERVAL_SYM '(' expr ',' expr ')' %prec INTERVAL_SYM { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| INTERVAL_SYM '(' expr ',' expr ',' expr_list ')' %prec INTERVAL_SYM { $$ = &FuncExpr{Name: $1, Exprs: append(IExprs{$3, $5}, $7...)} }
+| LEFT '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| MINUTE_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| MONTH_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| RIGHT '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| SECOND_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| TIME_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| TIMESTAMP '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| TIMESTAMP '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| TRIM '(' expr ')' { $$ = &TrimExpr{Expr: $3} }
+| TRIM '(' LEADING expr FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_LEADING, Remove: $4, Expr: $6} }
+| TRIM '(' TRAILING expr FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_TRAILING, Remove: $4, Expr: $6} }
+| TRIM '(' BOTH expr FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_BOTH, Remove: $4, Expr: $6} }
+| TRIM '(' LEADING FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_LEADING, Expr: $5} }
+| TRIM '(' TRAILING FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_TRAILING, Expr: $5} }
+| TRIM '(' BOTH FROM expr ')' { $$ = &TrimExpr{Direction: TRIM_BOTH, Expr: $5} }
+| TRIM '(' expr FROM expr ')' { $$ = &TrimExpr{Remove: $3, Expr: $5} }
+| USER '(' ')' { $$ = &FuncExpr{Name: $1} }
+| YEAR_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} };
 
 function_call_nonkeyword:
-  ADDDATE_SYM '(' expr ',' expr ')'
-| ADDDATE_SYM '(' expr ',' INTERVAL_SYM expr interval ')'
-| CURDATE optional_braces
-| CURTIME func_datetime_precision
-| DATE_ADD_INTERVAL '(' expr ',' INTERVAL_SYM expr interval ')' %prec INTERVAL_SYM
-| DATE_SUB_INTERVAL '(' expr ',' INTERVAL_SYM expr interval ')' %prec INTERVAL_SYM
-| EXTRACT_SYM '(' interval FROM expr ')'
-| GET_FORMAT '(' date_time_type ',' expr ')'
-| now
-| POSITION_SYM '(' bit_expr IN_SYM expr ')'
-| SUBDATE_SYM '(' expr ',' expr ')'
-| SUBDATE_SYM '(' expr ',' INTERVAL_SYM expr interval ')'
-| SUBSTRING '(' expr ',' expr ',' expr ')'
-| SUBSTRING '(' expr ',' expr ')'
-| SUBSTRING '(' expr FROM expr FOR_SYM expr ')'
-| SUBSTRING '(' expr FROM expr ')'
-| SYSDATE func_datetime_precision
-| TIMESTAMP_ADD '(' interval_time_stamp ',' expr ',' expr ')'
-| TIMESTAMP_DIFF '(' interval_time_stamp ',' expr ',' expr ')'
-| UTC_DATE_SYM optional_braces
-| UTC_TIME_SYM func_datetime_precision
-| UTC_TIMESTAMP_SYM func_datetime_precision;
+  ADDDATE_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| ADDDATE_SYM '(' expr ',' INTERVAL_SYM expr interval ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, &IntervalExpr{Expr: $6, Interval: $7}}} }
+| CURDATE optional_braces { $$ = &FuncExpr{Name: $1} }
+| CURTIME func_datetime_precision { $$ = &FuncExpr{Name: $1, Exprs: $2} }
+| DATE_ADD_INTERVAL '(' expr ',' INTERVAL_SYM expr interval ')' %prec INTERVAL_SYM { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, &IntervalExpr{Expr: $6, Interval: $7}}} }
+| DATE_SUB_INTERVAL '(' expr ',' INTERVAL_SYM expr interval ')' %prec INTERVAL_SYM { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, &IntervalExpr{Expr: $6, Interval: $7}}} }
+| EXTRACT_SYM '(' interval FROM expr ')' { $$ = &ExtractExpr{Unit: $3, Expr: $5} }
+| GET_FORMAT '(' date_time_type ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{TimeUnit($3), $5}} }
+| now { $$ = $1 }
+| POSITION_SYM '(' bit_expr IN_SYM expr ')' { $$ = &FuncExpr{Name: []byte("locate"), Exprs: IExprs{&Predicate{Expr: $3}, $5}} }
+| SUBDATE_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| SUBDATE_SYM '(' expr ',' INTERVAL_SYM expr interval ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, &IntervalExpr{Expr: $6, Interval: $7}}} }
+| SUBSTRING '(' expr ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7}} }
+| SUBSTRING '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| SUBSTRING '(' expr FROM expr FOR_SYM expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7}} }
+| SUBSTRING '(' expr FROM expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| SYSDATE func_datetime_precision { $$ = &FuncExpr{Name: $1, Exprs: $2} }
+| TIMESTAMP_ADD '(' interval_time_stamp ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{TimeUnit($3), $5, $7}} }
+| TIMESTAMP_DIFF '(' interval_time_stamp ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{TimeUnit($3), $5, $7}} }
+| UTC_DATE_SYM optional_braces { $$ = &FuncExpr{Name: $1} }
+| UTC_TIME_SYM func_datetime_precision { $$ = &FuncExpr{Name: $1, Exprs: $2} }
+| UTC_TIMESTAMP_SYM func_datetime_precision { $$ = &FuncExpr{Name: $1, Exprs: $2} };
 
 function_call_conflict:
-  ASCII_SYM '(' expr ')'
-| CHARSET '(' expr ')'
-| COALESCE '(' expr_list ')'
-| COLLATION_SYM '(' expr ')'
-| DATABASE '(' ')'
-| IF '(' expr ',' expr ',' expr ')'
-| FORMAT_SYM '(' expr ',' expr ')'
-| FORMAT_SYM '(' expr ',' expr ',' expr ')'
-| MICROSECOND_SYM '(' expr ')'
-| MOD_SYM '(' expr ',' expr ')'
-| OLD_PASSWORD '(' expr ')'
-| PASSWORD '(' expr ')'
-| QUARTER_SYM '(' expr ')'
-| REPEAT_SYM '(' expr ',' expr ')'
-| REPLACE '(' expr ',' expr ',' expr ')'
-| REVERSE_SYM '(' expr ')'
-| ROW_COUNT_SYM '(' ')'
-| TRUNCATE_SYM '(' expr ',' expr ')'
-| WEEK_SYM '(' expr ')'
-| WEEK_SYM '(' expr ',' expr ')'
-| WEIGHT_STRING_SYM '(' expr opt_ws_levels ')'
-| WEIGHT_STRING_SYM '(' expr AS CHAR_SYM ws_nweights opt_ws_levels ')'
-| WEIGHT_STRING_SYM '(' expr AS BINARY ws_nweights ')'
-| WEIGHT_STRING_SYM '(' expr ',' ulong_num ',' ulong_num ',' ulong_num ')'
-| geometry_function;
+  ASCII_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| CHARSET '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| COALESCE '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| COLLATION_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| DATABASE '(' ')' { $$ = &FuncExpr{Name: $1} }
+| IF '(' expr ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7}} }
+| FORMAT_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| FORMAT_SYM '(' expr ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7}} }
+| MICROSECOND_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| MOD_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| OLD_PASSWORD '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| PASSWORD '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| QUARTER_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| REPEAT_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| REPLACE '(' expr ',' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5, $7}} }
+| REVERSE_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| ROW_COUNT_SYM '(' ')' { $$ = &FuncExpr{Name: $1} }
+| TRUNCATE_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| WEEK_SYM '(' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| WEEK_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| WEIGHT_STRING_SYM '(' expr opt_ws_levels ')' { $$ = &WeightStringExpr{Expr: $3, Levels: $4} }
+| WEIGHT_STRING_SYM '(' expr AS CHAR_SYM ws_nweights opt_ws_levels ')' { $$ = &WeightStringExpr{Expr: $3, As: "char(" + string($6) + ")", Levels: $7} }
+| WEIGHT_STRING_SYM '(' expr AS BINARY ws_nweights ')' { $$ = &WeightStringExpr{Expr: $3, As: "binary(" + string($6) + ")"} }
+| WEIGHT_STRING_SYM '(' expr ',' ulong_num ',' ulong_num ',' ulong_num ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, NumVal($5), NumVal($7), NumVal($9)}} }
+| geometry_function { $$ = $1 };
 
 geometry_function:
-  CONTAINS_SYM '(' expr ',' expr ')'
-| GEOMETRYCOLLECTION '(' expr_list ')'
-| LINESTRING '(' expr_list ')'
-| MULTILINESTRING '(' expr_list ')'
-| MULTIPOINT '(' expr_list ')'
-| MULTIPOLYGON '(' expr_list ')'
-| POINT_SYM '(' expr ',' expr ')'
-| POLYGON '(' expr_list ')';
+  CONTAINS_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| GEOMETRYCOLLECTION '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| LINESTRING '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| MULTILINESTRING '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| MULTIPOINT '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| MULTIPOLYGON '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| POINT_SYM '(' expr ',' expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3, $5}} }
+| POLYGON '(' expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} };
 
 function_call_generic:
-  IDENT_sys '(' opt_udf_expr_list ')'
-| ident '.' ident '(' opt_expr_list ')';
+  IDENT_sys '(' opt_udf_expr_list ')' { $$ = &FuncExpr{Name: $1, Exprs: $3} }
+| ident '.' ident '(' opt_expr_list ')' { $$ = &FuncExpr{Qualifier: $1, Name: $3, Exprs: $5} };
 
 fulltext_options:
   opt_natural_language_mode opt_query_expansion
-| IN_SYM BOOLEAN_SYM MODE_SYM;
+  {
+    if $1 != "" && $2 != "" {
+        $$ = $1 + " " + $2
+    } else {
+        $$ = $1 + $2
+    }
+  }
+| IN_SYM BOOLEAN_SYM MODE_SYM { $$ = "in boolean mode" };
 
 opt_natural_language_mode:
- 
-| IN_SYM NATURAL LANGUAGE_SYM MODE_SYM;
+  { $$ = "" }
+| IN_SYM NATURAL LANGUAGE_SYM MODE_SYM { $$ = "in natural language mode" };
 
 opt_query_expansion:
- 
-| WITH QUERY_SYM EXPANSION_SYM;
+  { $$ = "" }
+| WITH QUERY_SYM EXPANSION_SYM { $$ = "with query expansion" };
 
 opt_udf_expr_list:
- 
-| udf_expr_list;
+  { $$ = nil }
+| udf_expr_list { $$ = $1 };
 
 udf_expr_list:
-  udf_expr
-| udf_expr_list ',' udf_expr;
+  udf_expr { $$ = IExprs{$1} }
+| udf_expr_list ',' udf_expr { $$ = append($1, $3) };
 
 udf_expr:
-  remember_name expr remember_end select_alias;
+  remember_name expr remember_end select_alias { $$ = $2 };
 
 sum_expr:
-  AVG_SYM '(' in_sum_expr ')' {}
-| AVG_SYM '(' DISTINCT in_sum_expr ')'
-| BIT_AND '(' in_sum_expr ')'
-| BIT_OR '(' in_sum_expr ')'
-| BIT_XOR '(' in_sum_expr ')'
-| COUNT_SYM '(' opt_all '*' ')'
-| COUNT_SYM '(' in_sum_expr ')'
-| COUNT_SYM '(' DISTINCT expr_list ')'
-| MIN_SYM '(' in_sum_expr ')'
-| MIN_SYM '(' DISTINCT in_sum_expr ')'
-| MAX_SYM '(' in_sum_expr ')'
-| MAX_SYM '(' DISTINCT in_sum_expr ')'
-| STD_SYM '(' in_sum_expr ')'
-| VARIANCE_SYM '(' in_sum_expr ')'
-| STDDEV_SAMP_SYM '(' in_sum_expr ')'
-| VAR_SAMP_SYM '(' in_sum_expr ')'
-| SUM_SYM '(' in_sum_expr ')'
-| SUM_SYM '(' DISTINCT in_sum_expr ')'
-| GROUP_CONCAT_SYM '(' opt_distinct expr_list opt_gorder_clause opt_gconcat_separator ')';
+  AVG_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| AVG_SYM '(' DISTINCT in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Distinct: true, Exprs: IExprs{$4}} }
+| BIT_AND '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| BIT_OR '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| BIT_XOR '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| COUNT_SYM '(' opt_all '*' ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{&StarExpr{}}} }
+| COUNT_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| COUNT_SYM '(' DISTINCT expr_list ')' { $$ = &FuncExpr{Name: $1, Distinct: true, Exprs: $4} }
+| MIN_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| MIN_SYM '(' DISTINCT in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Distinct: true, Exprs: IExprs{$4}} }
+| MAX_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| MAX_SYM '(' DISTINCT in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Distinct: true, Exprs: IExprs{$4}} }
+| STD_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| VARIANCE_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| STDDEV_SAMP_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| VAR_SAMP_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| SUM_SYM '(' in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Exprs: IExprs{$3}} }
+| SUM_SYM '(' DISTINCT in_sum_expr ')' { $$ = &FuncExpr{Name: $1, Distinct: true, Exprs: IExprs{$4}} }
+| GROUP_CONCAT_SYM '(' opt_distinct expr_list opt_gorder_clause opt_gconcat_separator ')' { $$ = &GroupConcatExpr{Distinct: $3, Exprs: $4, OrderBy: $5, Separator: $6} };
 
 variable:
-  '@' variable_aux { $$ = &Variable{} };
+  '@' variable_aux { $$ = $2 };
 
 variable_aux:
-  ident_or_text SET_VAR expr
-| ident_or_text
-| '@' opt_var_ident_type ident_or_text opt_component;
+  ident_or_text SET_VAR expr { $$ = &Variable{Type: Type_Usr, Name: string($1), Value: $3} }
+| ident_or_text { $$ = &Variable{Type: Type_Usr, Name: string($1)} }
+| '@' opt_var_ident_type ident_or_text opt_component
+  { $$ = &Variable{Type: Type_Sys, Life: $2, Name: string($3) + $4} };
 
 opt_distinct:
- 
-| DISTINCT;
+  { $$ = false }
+| DISTINCT { $$ = true };
 
 opt_gconcat_separator:
- 
-| SEPARATOR_SYM text_string;
+  { $$ = nil }
+| SEPARATOR_SYM text_string { $$ = StrVal($2) };
 
 opt_gorder_clause:
- 
-| ORDER_SYM BY gorder_list;
+  { $$ = nil }
+| ORDER_SYM BY gorder_list { $$ = $3 };
 
 gorder_list:
-  gorder_list ',' order_ident order_dir
-| order_ident order_dir;
+  gorder_list ',' order_ident order_dir { $$ = append($1, &Order{Expr: $3, Direction: $4}) }
+| order_ident order_dir { $$ = OrderBy{&Order{Expr: $1, Direction: $2}} };
 
 in_sum_expr:
-  opt_all expr;
+  opt_all expr { $$ = $2 };
 
 cast_type:
-  BINARY opt_field_length
-| CHAR_SYM opt_field_length opt_binary
-| NCHAR_SYM opt_field_length
-| SIGNED_SYM
-| SIGNED_SYM INT_SYM
-| UNSIGNED
-| UNSIGNED INT_SYM
-| DATE_SYM
-| TIME_SYM type_datetime_precision
-| DATETIME type_datetime_precision
-| DECIMAL_SYM float_options;
+  BINARY opt_field_length { $$ = "binary" + $2 }
+| CHAR_SYM opt_field_length opt_binary { $$ = "char" + $2 + $3 }
+| NCHAR_SYM opt_field_length { $$ = "nchar" + $2 }
+| SIGNED_SYM { $$ = "signed" }
+| SIGNED_SYM INT_SYM { $$ = "signed int" }
+| UNSIGNED { $$ = "unsigned" }
+| UNSIGNED INT_SYM { $$ = "unsigned int" }
+| DATE_SYM { $$ = "date" }
+| TIME_SYM type_datetime_precision { $$ = "time" + $2 }
+| DATETIME type_datetime_precision { $$ = "datetime" + $2 }
+| DECIMAL_SYM float_options { $$ = "decimal" + $2 };
 
 opt_expr_list:
- 
-| expr_list;
+  { $$ = nil }
+| expr_list { $$ = $1 };
 
 expr_list:
   expr { $$ = IExprs{$1} }
 | expr_list ',' expr { $$ = append($1, $3) };
 
 ident_list_arg:
-  ident_list
-| '(' ident_list ')';
+  ident_list { $$ = $1 }
+| '(' ident_list ')' { $$ = $2 };
 
 ident_list:
-  simple_ident
-| ident_list ',' simple_ident;
+  simple_ident { $$ = IValExprs{$1} }
+| ident_list ',' simple_ident { $$ = append($1, $3) };
 
 opt_expr:
- 
-| expr;
+  { $$ = nil }
+| expr { $$ = $1 };
 
 opt_else:
- 
-| ELSE expr;
+  { $$ = nil }
+| ELSE expr { $$ = $2 };
 
 when_list:
-  WHEN_SYM expr THEN_SYM expr
-| when_list WHEN_SYM expr THEN_SYM expr;
+  WHEN_SYM expr THEN_SYM expr { $$ = []*When{&When{Cond: $2, Val: $4}} }
+| when_list WHEN_SYM expr THEN_SYM expr { $$ = append($1, &When{Cond: $3, Val: $5}) };
 
 table_ref:
   table_factor { $$ = $1 }
@@ -3121,99 +3214,79 @@ derived_table_list:
 
 join_table:
   table_ref normal_join table_ref %prec TABLE_REF_PRIORITY 
-  { $$ = &JoinTable{Left: $1, Right: $3} }
+  { $$ = &JoinTable{Left: $1, Join: $2, Right: $3} }
 | table_ref STRAIGHT_JOIN table_factor
-  { $$ = &JoinTable{Left: $1, Right: $3} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_STRAIGHT, Right: $3} }
 | table_ref normal_join table_ref ON expr
-  { $$ = &JoinTable{Left: $1, Right: $3} }
+  { $$ = &JoinTable{Left: $1, Join: $2, Right: $3, On: $5} }
 | table_ref STRAIGHT_JOIN table_factor ON expr
-  { $$ = &JoinTable{Left: $1, Right: $3} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_STRAIGHT, Right: $3, On: $5} }
 | table_ref normal_join table_ref USING '(' using_list ')'
-  { $$ = &JoinTable{Left: $1, Right: $3} }
+  { $$ = &JoinTable{Left: $1, Join: $2, Right: $3, Using: $6} }
 | table_ref NATURAL JOIN_SYM table_factor
-  { $$ = &JoinTable{Left: $1, Right: $4} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_NATURAL, Right: $4} }
 | table_ref LEFT opt_outer JOIN_SYM table_ref ON expr
-  { $$ = &JoinTable{Left: $1, Right: $5} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_LEFT, Right: $5, On: $7} }
 | table_ref LEFT opt_outer JOIN_SYM table_factor USING '(' using_list ')'
-  { $$ = &JoinTable{Left: $1, Right: $5} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_LEFT, Right: $5, Using: $8} }
 | table_ref NATURAL LEFT opt_outer JOIN_SYM table_factor
-  { $$ = &JoinTable{Left: $1, Right: $6} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_NATURAL_LEFT, Right: $6} }
 | table_ref RIGHT opt_outer JOIN_SYM table_ref ON expr
-  { $$ = &JoinTable{Left: $1, Right: $5} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_RIGHT, Right: $5, On: $7} }
 | table_ref RIGHT opt_outer JOIN_SYM table_factor USING '(' using_list ')'
-  { $$ = &JoinTable{Left: $1, Right: $5} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_RIGHT, Right: $5, Using: $8} }
 | table_ref NATURAL RIGHT opt_outer JOIN_SYM table_factor
-  { $$ = &JoinTable{Left: $1, Right: $6} }
+  { $$ = &JoinTable{Left: $1, Join: JOIN_NATURAL_RIGHT, Right: $6} }
 ;
 
 normal_join:
-  JOIN_SYM
-| INNER_SYM JOIN_SYM
-| CROSS JOIN_SYM;
+  JOIN_SYM { $$ = JOIN }
+| INNER_SYM JOIN_SYM { $$ = JOIN_INNER }
+| CROSS JOIN_SYM { $$ = JOIN_CROSS };
 
 opt_use_partition:
- 
-| use_partition;
+  { $$ = nil }
+| use_partition { $$ = $1 };
 
 use_partition:
-  PARTITION_SYM '(' using_list ')' have_partitioning;
+  PARTITION_SYM '(' using_list ')' have_partitioning { $$ = $3 };
 
 table_factor:
   table_ident opt_use_partition opt_table_alias opt_key_definition
-  { $$ = &AliasedTable{TableOrSubQuery: $1, As: $3} }
+  {
+    if t, ok := $1.(*SimpleTable); ok {
+        t.Partitions = $2
+    }
+    $$ = &AliasedTable{TableOrSubQuery: $1, As: $3, IndexHints: $4}
+  }
 | select_derived_init get_select_lex select_derived2
   { $$ = &AliasedTable{TableOrSubQuery: $3} }
 | '(' get_select_lex select_derived_union ')' opt_table_alias
-  { $$ = &AliasedTable{TableOrSubQuery: $3, As: $5} }
+  { $$ = newDerivedTable($3, $5) }
 ;
 
 select_derived_union:
   select_derived opt_union_order_or_limit 
-  { $$ = &SubQuery{SelectStatement:$1} }
-| select_derived_union UNION_SYM union_option query_specification opt_union_order_or_limit { $$ = &SubQuery{SelectStatement: &Union{Left:$1, Right: $4}} }
+  {
+    if $2 == nil {
+        $$ = $1
+    } else {
+        $$ = withOrderLimit(derivedSelect($1), $2)
+    }
+  }
+| select_derived_union UNION_SYM union_option query_specification opt_union_order_or_limit
+  { $$ = &Union{Left: derivedSelect($1), Option: $3, Right: withOrderLimit($4, $5)} }
 ;
 
 select_init2_derived:
   select_part2_derived { $$ = $1 };
 
 select_part2_derived:
-          opt_from_clause               /* #1 */
-          opt_where_clause              /* #2 */
-          opt_group_clause              /* #3 */
-          opt_having_clause             /* #4 */
-          opt_order_clause              /* #5 */
-          opt_limit_clause              /* #6 */
-          opt_procedure_analyse_clause  /* #7 */
-          select_lock_type          /* #8 */
-  {
-        $$ = &Select{From: $1, LockType: $8}
-  }
+  select_options select_item_list opt_select_from
+  { $3.Options, $3.Fields = $1, $2; $$ = $3 }
 ;
 
 
-from_clause:
-          FROM table_reference_list { $$= $2; }
-        ;
-
-opt_from_clause:
-          /* empty */ { $$= nil; }
-        | from_clause
-        ;
-
-
-table_reference_list:
-          join_table_list
-          {
-          { $$ = $1 }
-          }
-        | DUAL_SYM { $$ = nil  }
-          /* oracle compatibility: oracle always requires FROM clause,
-             and DUAL is system table without fields.
-             Is "SELECT 1 FROM DUAL" any better than "SELECT 1" ?
-          Hmmm :) */
-        ;
-
-
 
 
 select_derived:
@@ -3221,7 +3294,7 @@ select_derived:
 
 select_derived2:
   select_options select_item_list opt_select_from
-  { $$ = &SubQuery{SelectStatement: $3} }
+  { $3.Options, $3.Fields = $1, $2; $$ = $3 }
 ;
 
 get_select_lex:
@@ -3235,45 +3308,47 @@ opt_outer:
 | OUTER;
 
 index_hint_clause:
- 
-| FOR_SYM JOIN_SYM
-| FOR_SYM ORDER_SYM BY
-| FOR_SYM GROUP_SYM BY;
+  { $$ = "" }
+| FOR_SYM JOIN_SYM { $$ = INDEX_FOR_JOIN }
+| FOR_SYM ORDER_SYM BY { $$ = INDEX_FOR_ORDER_BY }
+| FOR_SYM GROUP_SYM BY { $$ = INDEX_FOR_GROUP_BY };
 
 index_hint_type:
-  FORCE_SYM
-| IGNORE_SYM;
+  FORCE_SYM { $$ = INDEX_FORCE }
+| IGNORE_SYM { $$ = INDEX_IGNORE };
 
 index_hint_definition:
   index_hint_type key_or_index index_hint_clause '(' key_usage_list ')'
-| USE_SYM key_or_index index_hint_clause '(' opt_key_usage_list ')';
+  { $$ = &IndexHint{Type: $1, For: $3, Indexes: $5} }
+| USE_SYM key_or_index index_hint_clause '(' opt_key_usage_list ')'
+  { $$ = &IndexHint{Type: INDEX_USE, For: $3, Indexes: $5} };
 
 index_hints_list:
-  index_hint_definition
-| index_hints_list index_hint_definition;
+  index_hint_definition { $$ = []*IndexHint{$1} }
+| index_hints_list index_hint_definition { $$ = append($1, $2) };
 
 opt_index_hints_list:
- 
-| index_hints_list;
+  { $$ = nil }
+| index_hints_list { $$ = $1 };
 
 opt_key_definition:
-  opt_index_hints_list;
+  opt_index_hints_list { $$ = $1 };
 
 opt_key_usage_list:
- 
-| key_usage_list;
+  { $$ = nil }
+| key_usage_list { $$ = $1 };
 
 key_usage_element:
-  ident
-| PRIMARY_SYM;
+  ident { $$ = $1 }
+| PRIMARY_SYM { $$ = $1 };
 
 key_usage_list:
-  key_usage_element
-| key_usage_list ',' key_usage_element;
+  key_usage_element { $$ = [][]byte{$1} }
+| key_usage_list ',' key_usage_element { $$ = append($1, $3) };
 
 using_list:
-  ident
-| using_list ',' ident;
+  ident { $$ = [][]byte{$1} }
+| using_list ',' ident { $$ = append($1, $3) };
 
 interval:
   interval_time_stamp { $$ = $1 }
@@ -3303,10 +3378,10 @@ interval_time_stamp:
 ;
 
 date_time_type:
-  DATE_SYM
-| TIME_SYM
-| TIMESTAMP
-| DATETIME;
+  DATE_SYM { $$ = $1 }
+| TIME_SYM { $$ = $1 }
+| TIMESTAMP { $$ = $1 }
+| DATETIME { $$ = $1 };
 
 table_alias:
  
@@ -3324,43 +3399,33 @@ opt_all:
 | ALL;
 
 where_clause:
- 
-| WHERE expr;
-
-opt_where_clause:
- 
-| WHERE expr;
+  { $$ = nil }
+| WHERE expr { $$ = $2 };
 
 
 
 having_clause:
- 
-| HAVING expr;
+  { $$ = nil }
+| HAVING expr { $$ = $2 };
 
 
-opt_having_clause:
- 
-| HAVING expr;
-
 opt_escape:
-  ESCAPE_SYM simple_expr
-|;
+  ESCAPE_SYM simple_expr { $$ = $2 }
+| { $$ = nil };
 
 group_clause:
- 
-| GROUP_SYM BY group_list olap_opt;
+  { $$ = nil }
+| GROUP_SYM BY group_list olap_opt { $$ = &groupClause{groupBy: GroupBy($3), rollup: $4} };
 
-opt_group_clause:
-| GROUP_SYM BY group_list olap_opt;
 
 group_list:
-  group_list ',' order_ident order_dir
-| order_ident order_dir;
+  group_list ',' order_ident order_dir { $$ = append($1, &Order{Expr: $3, Direction: $4}) }
+| order_ident order_dir { $$ = OrderBy{&Order{Expr: $1, Direction: $2}} };
 
 olap_opt:
- 
-| WITH_CUBE_SYM
-| WITH_ROLLUP_SYM;
+  { $$ = false }
+| WITH_CUBE_SYM { $$ = true }
+| WITH_ROLLUP_SYM { $$ = true };
 
 alter_order_clause:
   ORDER_SYM BY alter_order_list;
@@ -3373,47 +3438,47 @@ alter_order_item:
   simple_ident_nospvar order_dir;
 
 opt_order_clause:
- 
-| order_clause;
+  { $$ = nil }
+| order_clause { $$ = $1 };
 
 order_clause:
-  ORDER_SYM BY order_list;
+  ORDER_SYM BY order_list { $$ = $3 };
 
 order_list:
-  order_list ',' order_ident order_dir
-| order_ident order_dir;
+  order_list ',' order_ident order_dir { $$ = append($1, &Order{Expr: $3, Direction: $4}) }
+| order_ident order_dir { $$ = OrderBy{&Order{Expr: $1, Direction: $2}} };
 
 order_dir:
- 
-| ASC
-| DESC;
+  { $$ = "" }
+| ASC { $$ = OP_ASC }
+| DESC { $$ = OP_DESC };
 
 opt_limit_clause_init:
- 
-| limit_clause;
+  { $$ = nil }
+| limit_clause { $$ = $1 };
 
 opt_limit_clause:
- 
-| limit_clause;
+  { $$ = nil }
+| limit_clause { $$ = $1 };
 
 limit_clause:
-  LIMIT limit_options;
+  LIMIT limit_options { $$ = $2 };
 
 limit_options:
-  limit_option
-| limit_option ',' limit_option
-| limit_option OFFSET_SYM limit_option;
+  limit_option { $$ = &Limit{Rowcount: $1} }
+| limit_option ',' limit_option { $$ = &Limit{Offset: $1, Rowcount: $3} }
+| limit_option OFFSET_SYM limit_option { $$ = &Limit{Offset: $3, Rowcount: $1} };
 
 limit_option:
-  ident
-| param_marker
-| ULONGLONG_NUM
-| LONG_NUM
-| NUM;
+  ident { $$ = &SchemaObject{Column: $1} }
+| param_marker { $$ = $1 }
+| ULONGLONG_NUM { $$ = NumVal($1) }
+| LONG_NUM { $$ = NumVal($1) }
+| NUM { $$ = NumVal($1) };
 
 delete_limit_clause:
- 
-| LIMIT limit_option;
+  { $$ = nil }
+| LIMIT limit_option { $$ = &Limit{Rowcount: $2} };
 
 ulong_num:
   NUM
@@ -3451,55 +3516,54 @@ dec_num:
 | FLOAT_NUM;
 
 procedure_analyse_clause:
- 
-| PROCEDURE_SYM ANALYSE_SYM '(' opt_procedure_analyse_params ')';
-opt_procedure_analyse_clause:
- 
-| PROCEDURE_SYM ANALYSE_SYM '(' opt_procedure_analyse_params ')';
+  { $$ = "" }
+| PROCEDURE_SYM ANALYSE_SYM '(' opt_procedure_analyse_params ')' { $$ = "analyse(" + $4 + ")" };
 
 opt_procedure_analyse_params:
- 
-| procedure_analyse_param
-| procedure_analyse_param ',' procedure_analyse_param;
+  { $$ = "" }
+| procedure_analyse_param { $$ = string($1) }
+| procedure_analyse_param ',' procedure_analyse_param { $$ = string($1) + ", " + string($3) };
 
 procedure_analyse_param:
-  NUM;
+  NUM { $$ = $1 };
 
 select_var_list_init:
-  select_var_list;
+  select_var_list { $$ = $1 };
 
 select_var_list:
-  select_var_list ',' select_var_ident
-| select_var_ident;
+  select_var_list ',' select_var_ident { $$ = $1 + ", " + $3 }
+| select_var_ident { $$ = $1 };
 
 select_var_ident:
-  '@' ident_or_text
-| ident_or_text;
+  '@' ident_or_text { $$ = "@" + string($2) }
+| ident_or_text { $$ = string($1) };
 
 into:
-  INTO into_destination;
+  INTO into_destination { $$ = $2 };
 
 into_destination:
   OUTFILE TEXT_STRING_filesystem opt_load_data_charset opt_field_term opt_line_term
-| DUMPFILE TEXT_STRING_filesystem
-| select_var_list_init;
+  { $$ = "outfile " + string($2) + $3 + $4 + $5 }
+| DUMPFILE TEXT_STRING_filesystem { $$ = "dumpfile " + string($2) }
+| select_var_list_init { $$ = $1 };
 
 do:
   DO_SYM expr_list { $$ = &Do{} };
 
 drop:
-  DROP opt_temporary table_or_tables if_exists table_list opt_restrict { $$ = &DropTables{Tables: $5} }
-| DROP INDEX_SYM ident ON table_ident opt_index_lock_algorithm { $$ = &DropIndex{On: $5} }
-| DROP DATABASE if_exists ident { $$ = &DropDatabase{} }
+  DROP opt_temporary table_or_tables if_exists table_list opt_restrict
+  { $$ = &DropTables{Temporary: $2, IfExists: $4, Tables: $5, Option: $6} }
+| DROP INDEX_SYM ident ON table_ident opt_index_lock_algorithm { $$ = &DropIndex{Name: $3, On: $5, Options: textFrom(MySQLlex, MySQLchar, $<end>5)} }
+| DROP DATABASE if_exists ident { $$ = &DropDatabase{IfExists: $3, Schema: $4} }
 | DROP FUNCTION_SYM if_exists ident '.' ident 
-  { $$ = &DropFunction{Function: &Spname{Qualifier: $4 , Name: $6}} }
+  { $$ = &DropFunction{IfExists: $3, Function: &Spname{Qualifier: $4 , Name: $6}} }
 | DROP FUNCTION_SYM if_exists ident
-  { $$ = &DropFunction{Function: &Spname{Name: $4}} }
-| DROP PROCEDURE_SYM if_exists sp_name { $$ = &DropProcedure{Procedure: $4} }
+  { $$ = &DropFunction{IfExists: $3, Function: &Spname{Name: $4}} }
+| DROP PROCEDURE_SYM if_exists sp_name { $$ = &DropProcedure{IfExists: $3, Procedure: $4} }
 | DROP USER clear_privileges user_list { $$ = &DropUser{} }
-| DROP VIEW_SYM if_exists table_list opt_restrict { $$ = &DropView{} }
-| DROP EVENT_SYM if_exists sp_name { $$ = &DropEvent{Event: $4} }
-| DROP TRIGGER_SYM if_exists sp_name { $$ = &DropTrigger{Trigger: $4} }
+| DROP VIEW_SYM if_exists table_list opt_restrict { $$ = &DropView{IfExists: $3, Tables: $4, Option: $5} }
+| DROP EVENT_SYM if_exists sp_name { $$ = &DropEvent{IfExists: $3, Event: $4} }
+| DROP TRIGGER_SYM if_exists sp_name { $$ = &DropTrigger{IfExists: $3, Trigger: $4} }
 | DROP TABLESPACE tablespace_name drop_ts_options_list { $$ = &DropTablespace{} }
 | DROP LOGFILE_SYM GROUP_SYM logfile_group_name drop_ts_options_list { $$ = &DropLogfile{} }
 | DROP SERVER_SYM if_exists ident_or_text { $$ = &DropServer{} }
@@ -3507,13 +3571,19 @@ drop:
 
 table_list:
   table_name { $$ = ISimpleTables{$1} }
-| table_list ',' table_name { $$ = append($1, $3) };
+| table_list ',' table_name { $$ = append($1, $3); $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 table_name:
   table_ident { $$ = $1 };
 
 table_name_with_opt_use_partition:
-  table_ident opt_use_partition { $$ = $1 };
+  table_ident opt_use_partition
+  {
+    if t, ok := $1.(*SimpleTable); ok {
+        t.Partitions = $2
+    }
+    $$ = $1
+  };
 
 table_alias_ref_list:
   table_alias_ref 
@@ -3526,12 +3596,12 @@ table_alias_ref:
   table_ident_opt_wild { $$ = $1 };
 
 if_exists:
- 
-| IF EXISTS;
+  { $$ = false }
+| IF EXISTS { $$ = true };
 
 opt_temporary:
- 
-| TEMPORARY;
+  { $$ = false }
+| TEMPORARY { $$ = true };
 
 drop_ts_options_list:
  
@@ -3549,26 +3619,28 @@ drop_ts_option:
 insert:
   INSERT insert_lock_option opt_ignore into_table insert_field_spec opt_insert_update
   {
-    $$ = &Insert{Table: $4, InsertFields: $5}
+    columns, fields := splitInsertFields($5)
+    $$ = &Insert{Options: newOptions($2, $3), Table: $4, Columns: columns, InsertFields: fields, OnDup: $6}
   }
 ;
 
 replace:
   REPLACE replace_lock_option into_table insert_field_spec 
   { 
-    $$ = &Replace{Table: $3, ReplaceFields: $4}
+    columns, fields := splitInsertFields($4)
+    $$ = &Replace{Options: newOptions($2), Table: $3, Columns: columns, ReplaceFields: fields}
   }
 ;
 
 insert_lock_option:
- 
-| LOW_PRIORITY
-| DELAYED_SYM
-| HIGH_PRIORITY;
+  { $$ = "" }
+| LOW_PRIORITY { $$ = INSERT_LOW_PRIORITY }
+| DELAYED_SYM { $$ = INSERT_DELAYED }
+| HIGH_PRIORITY { $$ = INSERT_HIGH_PRIORITY };
 
 replace_lock_option:
-  opt_low_priority
-| DELAYED_SYM;
+  opt_low_priority { $$ = $1 }
+| DELAYED_SYM { $$ = INSERT_DELAYED };
 
 into_table:
   INTO insert_table { $$ = $2 }
@@ -3580,44 +3652,40 @@ insert_table:
 insert_field_spec:
   insert_values { $$ = $1 }
 | '(' ')' insert_values { $$ = $3 }
-| '(' fields ')' insert_values { $$ = $4 }
-| SET ident_eq_list { $$ = struct{}{} };
+| '(' fields ')' insert_values
+  {
+    if v, ok := $4.(*InsertValues); ok {
+        v.Columns = $2
+        $$ = v
+    } else {
+        $$ = &insertSelect{columns: $2, rows: $4.(ISelect)}
+    }
+  }
+| SET ident_eq_list { $$ = newInsertSet($2) };
 
 fields:
-  fields ',' insert_ident
-| insert_ident;
+  fields ',' insert_ident { $$ = append($1, $3) }
+| insert_ident { $$ = IValExprs{$1} };
 
 insert_values:
-  VALUES values_list { $$ = struct{}{} }
-| VALUE_SYM values_list { $$ = struct{}{} }
+  VALUES values_list { $$ = &InsertValues{Rows: $2} }
+| VALUE_SYM values_list { $$ = &InsertValues{Rows: $2} }
 | create_select union_clause_opt
-  {
-    if $2 == nil {
-        $$ = $1
-    } else {
-        $$ = &Union{Left: $1, Right: $2}
-    }
-  }
+  { $$ = withUnion($1, $2) }
 | '(' create_select ')' union_opt
-  {
-    if $4 == nil {
-        $$ = $2
-    } else {
-        $$ = &Union{Left: $2, Right: $4}
-    }
-  }
+  { $$ = withUnion(&ParenSelect{Select: $2}, $4) }
 ;
 
 values_list:
-  values_list ',' no_braces
-| no_braces;
+  values_list ',' no_braces { $$ = append($1, $3) }
+| no_braces { $$ = []IExprs{$1} };
 
 ident_eq_list:
-  ident_eq_list ',' ident_eq_value
-| ident_eq_value;
+  ident_eq_list ',' ident_eq_value { $$ = append($1, $3) }
+| ident_eq_value { $$ = []*Assignment{$1} };
 
 ident_eq_value:
-  simple_ident_nospvar equal expr_or_default;
+  simple_ident_nospvar equal expr_or_default { $$ = &Assignment{Column: $1, Expr: $3} };
 
 equal:
   EQ
@@ -3628,60 +3696,70 @@ opt_equal:
 | equal;
 
 no_braces:
-  '(' opt_values ')';
+  '(' opt_values ')' { $$ = $2 };
 
 opt_values:
- 
-| values;
+  { $$ = nil }
+| values { $$ = $1 };
 
 values:
-  values ',' expr_or_default
-| expr_or_default;
+  values ',' expr_or_default { $$ = append($1, $3) }
+| expr_or_default { $$ = IExprs{$1} };
 
 expr_or_default:
-  expr
-| DEFAULT;
+  expr { $$ = $1 }
+| DEFAULT { $$ = StrVal($1) };
 
 opt_insert_update:
- 
-| ON DUPLICATE_SYM KEY_SYM UPDATE_SYM insert_update_list;
+  { $$ = nil }
+| ON DUPLICATE_SYM KEY_SYM UPDATE_SYM insert_update_list { $$ = $5 };
 
 update:
   UPDATE_SYM opt_low_priority opt_ignore join_table_list SET update_list where_clause opt_order_clause delete_limit_clause 
   { 
-    $$ = &Update{Tables: $4}
+    $$ = &Update{Options: newOptions($2, $3), Tables: $4, Set: $6, Where: $7, OrderBy: $8, Limit: $9}
   }
 ;
 
 update_list:
-  update_list ',' update_elem
-| update_elem;
+  update_list ',' update_elem { $$ = append($1, $3) }
+| update_elem { $$ = []*Assignment{$1} };
 
 update_elem:
-  simple_ident_nospvar equal expr_or_default;
+  simple_ident_nospvar equal expr_or_default { $$ = &Assignment{Column: $1, Expr: $3} };
 
 insert_update_list:
-  insert_update_list ',' insert_update_elem
-| insert_update_elem;
+  insert_update_list ',' insert_update_elem { $$ = append($1, $3) }
+| insert_update_elem { $$ = []*Assignment{$1} };
 
 insert_update_elem:
-  simple_ident_nospvar equal expr_or_default;
+  simple_ident_nospvar equal expr_or_default { $$ = &Assignment{Column: $1, Expr: $3} };
 
 opt_low_priority:
- 
-| LOW_PRIORITY;
+  { $$ = "" }
+| LOW_PRIORITY { $$ = DML_LOW_PRIORITY };
 
 delete:
-  DELETE_SYM opt_delete_options single_multi { $$ = $3 }
+  DELETE_SYM opt_delete_options single_multi
+  {
+    d := $3.(*Delete)
+    d.Options = $2
+    $$ = d
+  }
 ;
 
 single_multi:
   FROM table_ident opt_use_partition where_clause opt_order_clause delete_limit_clause 
-  { $$ = &Delete{Tables: ITables{$2}} }
+  {
+    if t, ok := $2.(*SimpleTable); ok {
+        t.Partitions = $3
+    }
+    $$ = &Delete{Tables: ITables{$2}, Where: $4, OrderBy: $5, Limit: $6}
+  }
 | table_wild_list FROM join_table_list where_clause 
-  { $$ = &Delete{Tables: append($1, $3...)} }
+  { $$ = &Delete{Targets: $1, Tables: append(append(ITables{}, $1...), $3...), Where: $4} }
 | FROM table_alias_ref_list USING join_table_list where_clause
-  { $$ = &Delete{Tables: append($2, $4...)} }
+  { $$ = &Delete{Targets: $2, Using: true, Tables: append(append(ITables{}, $2...), $4...), Where: $5} }
 ;
 
 table_wild_list:
@@ -3699,13 +3777,13 @@ opt_wild:
 | '.' '*' { $$ = []byte{'*'} };
 
 opt_delete_options:
- 
-| opt_delete_option opt_delete_options;
+  { $$ = nil }
+| opt_delete_option opt_delete_options { $$ = append([]string{$1}, $2...) };
 
 opt_delete_option:
-  QUICK
-| LOW_PRIORITY
-| IGNORE_SYM;
+  QUICK { $$ = DML_QUICK }
+| LOW_PRIORITY { $$ = DML_LOW_PRIORITY }
+| IGNORE_SYM { $$ = DML_IGNORE };
 
 truncate:
   TRUNCATE_SYM opt_table_sym table_name { $$ = &TruncateTable{Table: $3} }
@@ -3716,82 +3794,86 @@ opt_table_sym:
 | TABLE_SYM;
 
 opt_profile_defs:
- 
-| profile_defs;
+  { $$ = nil }
+| profile_defs { $$ = $1 };
 
 profile_defs:
-  profile_def
-| profile_defs ',' profile_def;
+  profile_def { $$ = []string{$1} }
+| profile_defs ',' profile_def { $$ = append($1, $3) };
 
 profile_def:
-  CPU_SYM
-| MEMORY_SYM
-| BLOCK_SYM IO_SYM
-| CONTEXT_SYM SWITCHES_SYM
-| PAGE_SYM FAULTS_SYM
-| IPC_SYM
-| SWAPS_SYM
-| SOURCE_SYM
-| ALL;
+  CPU_SYM { $$ = "cpu" }
+| MEMORY_SYM { $$ = "memory" }
+| BLOCK_SYM IO_SYM { $$ = "block io" }
+| CONTEXT_SYM SWITCHES_SYM { $$ = "context switches" }
+| PAGE_SYM FAULTS_SYM { $$ = "page faults" }
+| IPC_SYM { $$ = "ipc" }
+| SWAPS_SYM { $$ = "swaps" }
+| SOURCE_SYM { $$ = "source" }
+| ALL { $$ = "all" };
 
 opt_profile_args:
- 
-| FOR_SYM QUERY_SYM NUM;
+  { $$ = nil }
+| FOR_SYM QUERY_SYM NUM { $$ = $3 };
 
 show:
   SHOW show_param { $$ = $2 };
 
 show_param:
   DATABASES wild_and_where { $$ = &ShowDatabases{LikeOrWhere: $2} }
-| opt_full TABLES opt_db wild_and_where { $$ = &ShowTables{From: $3} }
-| opt_full TRIGGERS_SYM opt_db wild_and_where { $$ = &ShowTriggers{From: $3} }
-| EVENTS_SYM opt_db wild_and_where { $$ = &ShowEvents{From: $2} }
-| TABLE_SYM STATUS_SYM opt_db wild_and_where { $$ = &ShowTableStatus{From: $3} }
-| OPEN_SYM TABLES opt_db wild_and_where { $$ = &ShowOpenTables{From: $3} }
+| opt_full TABLES opt_db wild_and_where { $$ = &ShowTables{Full: $1 != nil, From: $3, LikeOrWhere: $4} }
+| opt_full TRIGGERS_SYM opt_db wild_and_where { $$ = &ShowTriggers{Full: $1 != nil, From: $3, LikeOrWhere: $4} }
+| EVENTS_SYM opt_db wild_and_where { $$ = &ShowEvents{From: $2, LikeOrWhere: $3} }
+| TABLE_SYM STATUS_SYM opt_db wild_and_where { $$ = &ShowTableStatus{From: $3, LikeOrWhere: $4} }
+| OPEN_SYM TABLES opt_db wild_and_where { $$ = &ShowOpenTables{From: $3, LikeOrWhere: $4} }
 | PLUGINS_SYM { $$ = &ShowPlugins{} }
-| ENGINE_SYM known_storage_engines show_engine_param { $$ = &ShowEngines{} }
-| ENGINE_SYM ALL show_engine_param { $$ = &ShowEngines{} }
-| opt_full COLUMNS from_or_in table_ident opt_db wild_and_where { $$ = &ShowColumns{Table: $4, From: $5} }
+| ENGINE_SYM known_storage_engines show_engine_param { $$ = &ShowEngines{Engine: $2, Param: $3} }
+| ENGINE_SYM ALL show_engine_param { $$ = &ShowEngines{Param: $3} }
+| opt_full COLUMNS from_or_in table_ident opt_db wild_and_where { $$ = &ShowColumns{Full: $1 != nil, Table: $4, From: $5, LikeOrWhere: $6} }
 | master_or_binary LOGS_SYM { $$ = &ShowLogs{} }
 | SLAVE HOSTS_SYM { $$ = &ShowSlaveHosts{} } 
-| BINLOG_SYM EVENTS_SYM binlog_in binlog_from opt_limit_clause_init { $$ = &ShowLogEvents{} } 
-| RELAYLOG_SYM EVENTS_SYM binlog_in binlog_from opt_limit_clause_init { $$ = &ShowLogEvents{} } 
-| keys_or_index from_or_in table_ident opt_db where_clause { $$ = &ShowIndex{Table: $3, From: $4} }
+| BINLOG_SYM EVENTS_SYM binlog_in binlog_from opt_limit_clause_init { $$ = &ShowLogEvents{In: $3, From: $4, Limit: $5} } 
+| RELAYLOG_SYM EVENTS_SYM binlog_in binlog_from opt_limit_clause_init { $$ = &ShowLogEvents{Relay: true, In: $3, From: $4, Limit: $5} } 
+| keys_or_index from_or_in table_ident opt_db where_clause { $$ = &ShowIndex{Table: $3, From: $4, Where: $5} }
 | opt_storage ENGINES_SYM { $$ = &ShowEngines{} }
 | PRIVILEGES { $$ = &ShowPrivileges{} }
-| COUNT_SYM '(' '*' ')' WARNINGS { $$ = &ShowWarnings{} }
-| COUNT_SYM '(' '*' ')' ERRORS { $$ = &ShowErrors{} }
-| WARNINGS opt_limit_clause_init { $$ = &ShowWarnings{} }
-| ERRORS opt_limit_clause_init { $$ = &ShowErrors{} }
+| COUNT_SYM '(' '*' ')' WARNINGS { $$ = &ShowWarnings{Count: true} }
+| COUNT_SYM '(' '*' ')' ERRORS { $$ = &ShowErrors{Count: true} }
+| WARNINGS opt_limit_clause_init { $$ = &ShowWarnings{Limit: $2} }
+| ERRORS opt_limit_clause_init { $$ = &ShowErrors{Limit: $2} }
 | PROFILES_SYM { $$ = &ShowProfiles{} }
-| PROFILE_SYM opt_profile_defs opt_profile_args opt_limit_clause_init { $$ = &ShowProfiles{} }
-| opt_var_type STATUS_SYM wild_and_where { $$ = &ShowStatus{} }
-| opt_full PROCESSLIST_SYM { $$ = &ShowProcessList{} }
-| opt_var_type VARIABLES wild_and_where { $$ = &ShowVariables{} }
-| charset wild_and_where { $$ = &ShowCharset{} }
-| COLLATION_SYM wild_and_where { $$ = &ShowCollation{} }
+| PROFILE_SYM opt_profile_defs opt_profile_args opt_limit_clause_init { $$ = &ShowProfile{Types: $2, Query: $3, Limit: $4} }
+| opt_var_type STATUS_SYM wild_and_where { $$ = &ShowStatus{Life: $1, LikeOrWhere: $3} }
+| opt_full PROCESSLIST_SYM { $$ = &ShowProcessList{Full: $1 != nil} }
+| opt_var_type VARIABLES wild_and_where { $$ = &ShowVariables{Life: $1, LikeOrWhere: $3} }
+| charset wild_and_where { $$ = &ShowCharset{LikeOrWhere: $2} }
+| COLLATION_SYM wild_and_where { $$ = &ShowCollation{LikeOrWhere: $2} }
 | GRANTS { $$ = &ShowGrants{} }
-| GRANTS FOR_SYM user { $$ = &ShowGrants{} }
-| CREATE DATABASE opt_if_not_exists ident { $$ = &ShowCreateDatabase{Schema: $4} }
-| CREATE TABLE_SYM table_ident { $$ = &ShowCreate{Prefix: $2, Table: $3} }
-| CREATE VIEW_SYM table_ident { $$ = &ShowCreate{Prefix: $2, Table: $3} }
+| GRANTS FOR_SYM user { $$ = &ShowGrants{User: textFrom(MySQLlex, MySQLchar, $<end>2)} }
+| CREATE DATABASE opt_if_not_exists ident { $$ = &ShowCreateDatabase{IfNotExists: $3, Schema: $4} }
+| CREATE TABLE_SYM table_ident { $$ = &ShowCreate{Prefix: "table", Table: $3} }
+| CREATE VIEW_SYM table_ident { $$ = &ShowCreate{Prefix: "view", Table: $3} }
 | MASTER_SYM STATUS_SYM { $$ = &ShowMasterStatus{} } 
 | SLAVE STATUS_SYM { $$ = &ShowSlaveStatus{} }
 | AEGIS STATUS_SYM { $$ = &ShowAegisStatus{} }
-| CREATE PROCEDURE_SYM sp_name { $$ = &ShowCreate{Prefix: $2, Table: $3} }
-| CREATE FUNCTION_SYM sp_name { $$ = &ShowCreate{Prefix: $2, Table: $3} }
-| CREATE TRIGGER_SYM sp_name { $$ = &ShowCreate{Prefix: $2, Table: $3} }
-| PROCEDURE_SYM STATUS_SYM wild_and_where { $$ = &ShowProcedure{} }
-| FUNCTION_SYM STATUS_SYM wild_and_where { $$ = &ShowFunction{} }
+| AEGIS BACKEND_SYM PROCESSLIST_SYM { $$ = &ShowAegisBackendProcessList{} }
+| AEGIS POOLS_SYM { $$ = &ShowAegisPools{} }
+| AEGIS BACKENDS_SYM { $$ = &ShowAegisBackends{} }
+| AEGIS SESSIONS_SYM { $$ = &ShowAegisSessions{} }
+| CREATE PROCEDURE_SYM sp_name { $$ = &ShowCreate{Prefix: "procedure", Table: $3} }
+| CREATE FUNCTION_SYM sp_name { $$ = &ShowCreate{Prefix: "function", Table: $3} }
+| CREATE TRIGGER_SYM sp_name { $$ = &ShowCreate{Prefix: "trigger", Table: $3} }
+| PROCEDURE_SYM STATUS_SYM wild_and_where { $$ = &ShowProcedure{LikeOrWhere: $3} }
+| FUNCTION_SYM STATUS_SYM wild_and_where { $$ = &ShowFunction{LikeOrWhere: $3} }
 | PROCEDURE_SYM CODE_SYM sp_name { $$ = &ShowProcedure{Procedure: $3} }
 | FUNCTION_SYM CODE_SYM sp_name { $$ = &ShowFunction{Function: $3} }
-| CREATE EVENT_SYM sp_name { $$ = &ShowCreate{Prefix: $2, Table: $3} }
+| CREATE EVENT_SYM sp_name { $$ = &ShowCreate{Prefix: "event", Table: $3} }
 ;
 
 show_engine_param:
-  STATUS_SYM
-| MUTEX_SYM
-| LOGS_SYM;
+  STATUS_SYM { $$ = "status" }
+| MUTEX_SYM { $$ = "mutex" }
+| LOGS_SYM { $$ = "logs" };
 
 master_or_binary:
   MASTER_SYM
@@ -3806,31 +3888,31 @@ opt_db:
 | from_or_in ident { $$ = $2 };
 
 opt_full:
- 
-| FULL;
+  { $$ = nil }
+| FULL { $$ = $1 };
 
 from_or_in:
   FROM
 | IN_SYM;
 
 binlog_in:
- 
-| IN_SYM TEXT_STRING_sys;
+  { $$ = nil }
+| IN_SYM TEXT_STRING_sys { $$ = $2 };
 
 binlog_from:
- 
-| FROM ulonglong_num;
+  { $$ = nil }
+| FROM ulonglong_num { $$ = $2 };
 
 wild_and_where:
   { $$ = nil } 
 | LIKE TEXT_STRING_sys { $$ = &LikeOrWhere{Like: string($2)} }
-| WHERE expr { $$ = nil };
+| WHERE expr { $$ = &LikeOrWhere{Where: $2} };
 
 describe:
   describe_command table_ident opt_describe_column 
-  { $$ = &DescribeTable{Table: $2} }
+  { $$ = &DescribeTable{Table: $2, Column: $3} }
 | describe_command opt_extended_describe explanable_command 
-  { $$ = &DescribeStmt{Stmt: $3} }
+  { $$ = &DescribeStmt{Option: $2, Stmt: $3} }
 ;
 
 explanable_command:
@@ -3845,27 +3927,33 @@ describe_command:
 | DESCRIBE;
 
 opt_extended_describe:
- 
-| EXTENDED_SYM
-| PARTITIONS_SYM
-| FORMAT_SYM EQ ident_or_text;
+  { $$ = "" }
+| EXTENDED_SYM { $$ = "extended" }
+| PARTITIONS_SYM { $$ = "partitions" }
+| FORMAT_SYM EQ ident_or_text { $$ = "format = " + string($3) };
 
 opt_describe_column:
- 
-| text_string
-| ident;
+  { $$ = nil }
+| text_string { $$ = $1 }
+| ident { $$ = $1 };
 
 flush:
-  FLUSH_SYM opt_no_write_to_binlog flush_options { $$ = $3 };
+  FLUSH_SYM opt_no_write_to_binlog flush_options
+  {
+    if f, ok := $3.(*FlushTables); ok {
+        f.NoWriteToBinlog = $2
+    }
+    $$ = $3
+  };
 
 flush_options:
-  table_or_tables opt_table_list opt_flush_lock { $$ = &FlushTables{Tables: $2} }
+  table_or_tables opt_table_list opt_flush_lock { $$ = &FlushTables{Tables: $2, Lock: $3} }
 | flush_options_list { $$ = &Flush{} };
 
 opt_flush_lock:
- 
-| WITH READ_SYM LOCK_SYM
-| FOR_SYM EXPORT_SYM;
+  { $$ = "" }
+| WITH READ_SYM LOCK_SYM { $$ = "with read lock" }
+| FOR_SYM EXPORT_SYM { $$ = "for export" };
 
 flush_options_list:
   flush_options_list ',' flush_option
@@ -3917,12 +4005,26 @@ purge_option:
 | BEFORE_SYM expr;
 
 kill:
-  KILL_SYM kill_option expr { $$ = &Kill{} };
+  KILL_SYM kill_option expr { $$ = &Kill{Type: $2, Id: $3} };
 
 kill_option:
- 
-| CONNECTION_SYM
-| QUERY_SYM;
+  { $$ = KillType_Connection }
+| CONNECTION_SYM { $$ = KillType_Connection }
+| QUERY_SYM { $$ = KillType_Query };
+
+aegis:
+  AEGIS SET BACKEND_SYM TEXT_STRING_sys WEIGHT_SYM NUM { $$ = &AegisAdmin{Action: AegisAction_SetWeight, Addr: StrVal($4).Trim(), Weight: NumVal($6)} }
+| AEGIS aegis_backend_action BACKEND_SYM TEXT_STRING_sys { $$ = &AegisAdmin{Action: $2, Addr: StrVal($4).Trim()} }
+| AEGIS ADD REPLICA_SYM TEXT_STRING_sys opt_backend_weight { $$ = &AegisAdmin{Action: AegisAction_AddReplica, Addr: StrVal($4).Trim(), Weight: $5} };
+
+aegis_backend_action:
+  OFFLINE_SYM { $$ = AegisAction_Offline }
+| ONLINE_SYM { $$ = AegisAction_Online }
+| DRAIN_SYM { $$ = AegisAction_Drain };
+
+opt_backend_weight:
+  { $$ = nil }
+| WEIGHT_SYM NUM { $$ = NumVal($2) };
 
 use:
   USE_SYM ident { $$ = &Use{DB: $2} };
@@ -3953,30 +4055,30 @@ opt_duplicate:
 | IGNORE_SYM;
 
 opt_field_term:
- 
-| COLUMNS field_term_list;
+  { $$ = "" }
+| COLUMNS field_term_list { $$ = " fields" + $2 };
 
 field_term_list:
-  field_term_list field_term
-| field_term;
+  field_term_list field_term { $$ = $1 + $2 }
+| field_term { $$ = $1 };
 
 field_term:
-  TERMINATED BY text_string
-| OPTIONALLY ENCLOSED BY text_string
-| ENCLOSED BY text_string
-| ESCAPED BY text_string;
+  TERMINATED BY text_string { $$ = " terminated by " + string($3) }
+| OPTIONALLY ENCLOSED BY text_string { $$ = " optionally enclosed by " + string($4) }
+| ENCLOSED BY text_string { $$ = " enclosed by " + string($3) }
+| ESCAPED BY text_string { $$ = " escaped by " + string($3) };
 
 opt_line_term:
- 
-| LINES line_term_list;
+  { $$ = "" }
+| LINES line_term_list { $$ = " lines" + $2 };
 
 line_term_list:
-  line_term_list line_term
-| line_term;
+  line_term_list line_term { $$ = $1 + $2 }
+| line_term { $$ = $1 };
 
 line_term:
-  TERMINATED BY text_string
-| STARTING BY text_string;
+  TERMINATED BY text_string { $$ = " terminated by " + string($3) }
+| STARTING BY text_string { $$ = " starting by " + string($3) };
 
 opt_xml_rows_identified_by:
  
@@ -4017,13 +4119,13 @@ load_data_set_elem:
 text_literal:
   TEXT_STRING { $$ = StrVal($1) }
 | NCHAR_STRING { $$ = StrVal($1) }
-| UNDERSCORE_CHARSET TEXT_STRING { $$ = StrVal(append(append($1, ' '), $2...)) }
-| text_literal TEXT_STRING_literal { $$ = StrVal(append($1.(StrVal), $2...)) };
+| UNDERSCORE_CHARSET TEXT_STRING { $$ = StrVal(joinText($1, $2)) }
+| text_literal TEXT_STRING_literal { $$ = StrVal(joinText($1.(StrVal), $2)) };
 
 text_string:
-  TEXT_STRING_literal
-| HEX_NUM
-| BIN_NUM;
+  TEXT_STRING_literal { $$ = $1 }
+| HEX_NUM { $$ = $1 }
+| BIN_NUM { $$ = $1 };
 
 param_marker:
   PARAM_MARKER { $$ = StrVal("?") };
@@ -4042,8 +4144,8 @@ literal:
 | TRUE_SYM { $$ = BoolVal(true) }
 | HEX_NUM { $$ = HexVal($1) }
 | BIN_NUM { $$ = BinVal($1) }
-| UNDERSCORE_CHARSET HEX_NUM { $$ = HexVal(append(append($1, ' '), $2...)) }
-| UNDERSCORE_CHARSET BIN_NUM { $$ = BinVal(append(append($1, ' '), $2...)) }
+| UNDERSCORE_CHARSET HEX_NUM { $$ = HexVal(joinText($1, $2)) }
+| UNDERSCORE_CHARSET BIN_NUM { $$ = BinVal(joinText($1, $2)) }
 ;
 
 NUM_literal:
@@ -4054,29 +4156,29 @@ NUM_literal:
 | FLOAT_NUM { $$ = NumVal($1) };
 
 temporal_literal:
-  DATE_SYM TEXT_STRING { $$ = StrVal(append($1, $2...)) }
-| TIME_SYM TEXT_STRING { $$ = StrVal(append($1, $2...)) }
-| TIMESTAMP TEXT_STRING { $$ = StrVal(append($1, $2...)) }
+  DATE_SYM TEXT_STRING { $$ = StrVal(joinText($1, $2)) }
+| TIME_SYM TEXT_STRING { $$ = StrVal(joinText($1, $2)) }
+| TIMESTAMP TEXT_STRING { $$ = StrVal(joinText($1, $2)) }
 ;
 
 insert_ident:
-  simple_ident_nospvar
-| table_wild;
+  simple_ident_nospvar { $$ = $1 }
+| table_wild { $$ = nil };
 
 table_wild:
-  ident '.' '*'
-| ident '.' ident '.' '*';
+  ident '.' '*' { $$ = &StarExpr{Table: $1} }
+| ident '.' ident '.' '*' { $$ = &StarExpr{Schema: $1, Table: $3} };
 
 order_ident:
-  expr;
+  expr { $$ = $1 };
 
 simple_ident:
   ident { $$ = &SchemaObject{Column: $1} }
 | simple_ident_q { $$ = $1 };
 
 simple_ident_nospvar:
-  ident
-| simple_ident_q;
+  ident { $$ = &SchemaObject{Column: $1} }
+| simple_ident_q { $$ = $1 };
 
 simple_ident_q:
   ident '.' ident { $$ = &SchemaObject{Table: $1, Column: $3} }
@@ -4091,8 +4193,8 @@ field_ident:
 
 table_ident:
   ident { $$ = &SimpleTable{Name: $1} }
-| ident '.' ident { $$ = &SimpleTable{Qualifier: $1, Name: $3} }
-| '.' ident { $$ = &SimpleTable{Name: $2} } ;
+| ident '.' ident { $$ = &SimpleTable{Qualifier: $1, Name: $3}; $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| '.' ident { $$ = &SimpleTable{Name: $2}; $<end>$ = symbolEnd(MySQLlex, MySQLchar) } ;
 
 table_ident_opt_wild:
   ident opt_wild { $$ = &SimpleTable{Name: $1, Column: $2} }
@@ -4116,7 +4218,7 @@ TEXT_STRING_literal:
   TEXT_STRING;
 
 TEXT_STRING_filesystem:
-  TEXT_STRING;
+  TEXT_STRING { $$ = $1 };
 
 ident:
   IDENT_sys { $$ = $1 }
@@ -4203,6 +4305,9 @@ keyword_sp:
 | AUTOEXTEND_SIZE_SYM { $$ = $1 }
 | AVG_ROW_LENGTH { $$ = $1 }
 | AVG_SYM { $$ = $1 }
+| BACKEND_SYM { $$ = $1 }
+| BACKENDS_SYM { $$ = $1 }
+| DRAIN_SYM { $$ = $1 }
 | BINLOG_SYM { $$ = $1 }
 | BIT_SYM { $$ = $1 }
 | BLOCK_SYM { $$ = $1 }
@@ -4365,8 +4470,10 @@ keyword_sp:
 | NUMBER_SYM { $$ = $1 }
 | NVARCHAR_SYM { $$ = $1 }
 | OFFSET_SYM { $$ = $1 }
+| OFFLINE_SYM { $$ = $1 }
 | OLD_PASSWORD { $$ = $1 }
 | ONE_SYM { $$ = $1 }
+| ONLINE_SYM { $$ = $1 }
 | ONLY_SYM { $$ = $1 }
 | PACK_KEYS_SYM { $$ = $1 }
 | PAGE_SYM { $$ = $1 }
@@ -4380,6 +4487,7 @@ keyword_sp:
 | PLUGINS_SYM { $$ = $1 }
 | POINT_SYM { $$ = $1 }
 | POLYGON { $$ = $1 }
+| POOLS_SYM { $$ = $1 }
 | PRESERVE_SYM { $$ = $1 }
 | PREV_SYM { $$ = $1 }
 | PRIVILEGES { $$ = $1 }
@@ -4406,6 +4514,7 @@ keyword_sp:
 | REORGANIZE_SYM { $$ = $1 }
 | REPEATABLE_SYM { $$ = $1 }
 | REPLICATION { $$ = $1 }
+| REPLICA_SYM { $$ = $1 }
 | RESOURCES { $$ = $1 }
 | RESUME_SYM { $$ = $1 }
 | RETURNED_SQLSTATE_SYM { $$ = $1 }
@@ -4424,6 +4533,7 @@ keyword_sp:
 | SERIAL_SYM { $$ = $1 }
 | SERIALIZABLE_SYM { $$ = $1 }
 | SESSION_SYM { $$ = $1 }
+| SESSIONS_SYM { $$ = $1 }
 | SIMPLE_SYM { $$ = $1 }
 | SHARE_SYM { $$ = $1 }
 | SHUTDOWN { $$ = $1 }
@@ -4485,6 +4595,7 @@ keyword_sp:
 | VALUE_SYM { $$ = $1 }
 | WARNINGS { $$ = $1 } 
 | WAIT_SYM { $$ = $1 }
+| WEIGHT_SYM { $$ = $1 }
 | WEEK_SYM { $$ = $1 }
 | WORK_SYM { $$ = $1 }
 | WEIGHT_STRING_SYM { $$ = $1 }
@@ -4499,11 +4610,7 @@ set:
 start_option_value_list:
   option_value_no_option_type option_value_list_continued 
   {
-    if $2 == nil {
-        $$ = &Set{VarList: Vars{$1}}
-    } else {
-        $$ = &Set{VarList: append($2, $1)}
-    }
+    $$ = newSet(append(Vars{$1}, $2...))
   }
 | TRANSACTION_SYM transaction_characteristics { $$ = &SetTrans{} }
 | option_type start_option_value_list_following_option_type 
@@ -4513,10 +4620,12 @@ start_option_value_list:
     } else {
         tmp := $2.(Vars)
         for _, v := range tmp {
-            v.Life = $1
+            if v.Life == Life_Unknown {
+                v.Life = $1
+            }
         }
 
-        $$ = &Set{VarList: tmp}
+        $$ = newSet(tmp)
     }
   };
 
@@ -4554,10 +4663,10 @@ option_type:
 | SESSION_SYM { $$ = Life_Session };
 
 opt_var_type:
- 
-| GLOBAL_SYM
-| LOCAL_SYM
-| SESSION_SYM;
+  { $$ = Life_Unknown }
+| GLOBAL_SYM { $$ = Life_Global }
+| LOCAL_SYM { $$ = Life_Local }
+| SESSION_SYM { $$ = Life_Session };
 
 opt_var_ident_type:
   { $$ = Life_Unknown} 
@@ -4567,11 +4676,11 @@ opt_var_ident_type:
 
 option_value_following_option_type:
   internal_variable_name equal set_expr_or_default 
-  { $$ = &Variable{Type: Type_Usr, Name: $1, Value: $3} };
+  { $$ = &Variable{Type: Type_Sys, Name: $1, Value: $3} };
 
 option_value_no_option_type:
   internal_variable_name equal set_expr_or_default 
-  { $$ = &Variable{Type: Type_Usr, Name: $1, Value: $3} }
+  { $$ = &Variable{Type: Type_Sys, Name: $1, Value: $3} }
 | '@' ident_or_text equal expr 
   { $$ = &Variable{Type: Type_Usr, Name: string($2), Value: $4} }
 | '@' '@' opt_var_ident_type internal_variable_name equal set_expr_or_default
@@ -4581,7 +4690,13 @@ option_value_no_option_type:
 | NAMES_SYM equal expr
   { $$ = &Variable{Type: Type_Sys, Name: "NAMES", Value: $3} }
 | NAMES_SYM charset_name_or_default opt_collate
-  { $$ = &Variable{Type: Type_Sys, Name: "NAMES", Value: StrVal($2)} }
+  {
+    if $3 == nil {
+        $$ = &Variable{Type: Type_Sys, Name: "NAMES", Value: StrVal($2)}
+    } else {
+        $$ = &Variable{Type: Type_Sys, Name: "NAMES", Value: &CollateExpr{Expr: StrVal($2), Collate: $3}}
+    }
+  }
 | PASSWORD equal text_or_password
   { $$ = &Variable{Type: Type_Sys, Name: "PASSWORD"} }
 | PASSWORD FOR_SYM user equal text_or_password
@@ -4636,17 +4751,17 @@ table_or_tables:
 | TABLES;
 
 table_lock_list:
-  table_lock { $$ = ISimpleTables{$1} }
+  table_lock { $$ = []*TableLock{$1} }
 | table_lock_list ',' table_lock { $$ = append($1, $3) };
 
 table_lock:
-  table_ident opt_table_alias lock_option { $$ = $1 };
+  table_ident opt_table_alias lock_option { $$ = &TableLock{Table: $1, As: $2, Type: $3} };
 
 lock_option:
-  READ_SYM
-| WRITE_SYM
-| LOW_PRIORITY WRITE_SYM
-| READ_SYM LOCAL_SYM;
+  READ_SYM { $$ = LOCK_READ }
+| WRITE_SYM { $$ = LOCK_WRITE }
+| LOW_PRIORITY WRITE_SYM { $$ = LOCK_LOW_PRIORITY_WRITE }
+| READ_SYM LOCAL_SYM { $$ = LOCK_READ_LOCAL };
 
 unlock:
   UNLOCK_SYM table_or_tables { $$ = &Unlock{} };
@@ -4826,24 +4941,24 @@ opt_work:
 | WORK_SYM;
 
 opt_chain:
- 
-| AND_SYM NO_SYM CHAIN_SYM
-| AND_SYM CHAIN_SYM;
+  { $$ = "" }
+| AND_SYM NO_SYM CHAIN_SYM { $$ = "and no chain" }
+| AND_SYM CHAIN_SYM { $$ = "and chain" };
 
 opt_release:
- 
-| RELEASE_SYM
-| NO_SYM RELEASE_SYM;
+  { $$ = "" }
+| RELEASE_SYM { $$ = "release" }
+| NO_SYM RELEASE_SYM { $$ = "no release" };
 
 opt_savepoint:
  
 | SAVEPOINT_SYM;
 
 commit:
-  COMMIT_SYM opt_work opt_chain opt_release { $$ = &Commit{} };
+  COMMIT_SYM opt_work opt_chain opt_release { $$ = &Commit{Chain: $3, Release: $4} };
 
 rollback:
-  ROLLBACK_SYM opt_work opt_chain opt_release { $$ = &Rollback{} }
+  ROLLBACK_SYM opt_work opt_chain opt_release { $$ = &Rollback{Chain: $3, Release: $4} }
 | ROLLBACK_SYM opt_work TO_SYM opt_savepoint ident { $$ = &Rollback{Point: $5} }
 ;
 
@@ -4859,38 +4974,39 @@ union_clause_opt:
 | union_list { $$ = $1 };
 
 union_list:
-  UNION_SYM union_option select_init { $$ = $3 }
+  UNION_SYM union_option select_init { $$ = &Union{Option: $2, Right: $3} }
 ;
 
 union_opt:
   { $$ = nil } 
 | union_list { $$ = $1 }
-| union_order_or_limit { $$ = nil };
+| union_order_or_limit { $$ = $1 };
 
 opt_union_order_or_limit:
- 
-| union_order_or_limit;
+  { $$ = nil }
+| union_order_or_limit { $$ = $1 };
 
 union_order_or_limit:
-  order_or_limit;
+  order_or_limit { $$ = $1 };
 
 order_or_limit:
-  order_clause opt_limit_clause_init
-| limit_clause;
+  order_clause opt_limit_clause_init { $$ = &orderLimit{orderBy: $1, limit: $2} }
+| limit_clause { $$ = &orderLimit{limit: $1} };
 
 union_option:
- 
-| DISTINCT
-| ALL;
+  { $$ = "" }
+| DISTINCT { $$ = UNION_DISTINCT }
+| ALL { $$ = UNION_ALL };
 
 query_specification:
-  SELECT_SYM select_init2_derived { $$ = &SubQuery{SelectStatement: $2} }
-| '(' select_paren_derived ')' { $$ = &SubQuery{SelectStatement: $2} }
+  SELECT_SYM select_init2_derived { $$ = $2 }
+| '(' select_paren_derived ')' { $$ = &ParenSelect{Select: $2} }
 ;
 
 query_expression_body:
-  query_specification opt_union_order_or_limit { $$ = $1 }
-| query_expression_body UNION_SYM union_option query_specification opt_union_order_or_limit { $$ = &Union{Left: $1, Right: $4} };
+  query_specification opt_union_order_or_limit { $$ = withOrderLimit($1, $2) }
+| query_expression_body UNION_SYM union_option query_specification opt_union_order_or_limit
+  { $$ = &Union{Left: $1, Option: $3, Right: withOrderLimit($4, $5)} };
 
 subselect:
   subselect_start query_expression_body subselect_end { $$ = &SubQuery{SelectStatement: $2} };
@@ -4907,19 +5023,19 @@ query_expression_option_list:
 | query_expression_option;
 
 query_expression_option:
-  STRAIGHT_JOIN
-| HIGH_PRIORITY
-| DISTINCT
-| SQL_SMALL_RESULT
-| SQL_BIG_RESULT
-| SQL_BUFFER_RESULT
-| SQL_CALC_FOUND_ROWS
-| ALL;
+  STRAIGHT_JOIN { $$ = SELECT_STRAIGHT_JOIN }
+| HIGH_PRIORITY { $$ = SELECT_HIGH_PRIORITY }
+| DISTINCT { $$ = SELECT_DISTINCT }
+| SQL_SMALL_RESULT { $$ = SELECT_SQL_SMALL_RESULT }
+| SQL_BIG_RESULT { $$ = SELECT_SQL_BIG_RESULT }
+| SQL_BUFFER_RESULT { $$ = SELECT_SQL_BUFFER_RESULT }
+| SQL_CALC_FOUND_ROWS { $$ = SELECT_SQL_CALC_FOUND_ROWS }
+| ALL { $$ = SELECT_ALL };
 
 view_or_trigger_or_sp_or_event:
   definer definer_tail { $$ = $2 }
 | no_definer no_definer_tail { $$ = $2 }
-| view_replace_or_algorithm definer_opt view_tail { $$ = $3 };
+| view_replace_or_algorithm definer_opt view_tail { $$ = $3; $<end>$ = $<end>2 };
 
 definer_tail:
   view_tail { $$ = $1 }
@@ -4943,10 +5059,10 @@ definer_opt:
 | definer;
 
 no_definer:
- ;
+  { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 definer:
-  DEFINER_SYM EQ user;
+  DEFINER_SYM EQ user { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 view_replace_or_algorithm:
   view_replace
@@ -4962,43 +5078,28 @@ view_algorithm:
 | ALGORITHM_SYM EQ TEMPTABLE_SYM;
 
 view_suid:
- 
-| SQL_SYM SECURITY_SYM DEFINER_SYM
-| SQL_SYM SECURITY_SYM INVOKER_SYM;
+  { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| SQL_SYM SECURITY_SYM DEFINER_SYM { $<end>$ = symbolEnd(MySQLlex, MySQLchar) }
+| SQL_SYM SECURITY_SYM INVOKER_SYM { $<end>$ = symbolEnd(MySQLlex, MySQLchar) };
 
 view_tail:
-  view_suid VIEW_SYM table_ident view_list_opt AS view_select
-  { $$ = &viewTail{View: $3, As: $6} }
+  view_suid VIEW_SYM table_ident view_list_opt AS view_select_aux view_check_option
+  { $$ = &viewTail{View: $3, Columns: $4, As: $6, CheckOption: $7, optionsEnd: $<end>1} }
 ;
 
 view_list_opt:
- 
-| '(' view_list ')';
+  { $$ = nil }
+| '(' view_list ')' { $$ = $2 };
 
 view_list:
-  ident
-| view_list ',' ident;
-
-view_select:
-  view_select_aux view_check_option { $$ = $1 };
+  ident { $$ = [][]byte{$1} }
+| view_list ',' ident { $$ = append($1, $3) };
 
 view_select_aux:
   create_view_select union_clause_opt
-  {
-    if $2 == nil {
-        $$ = $1
-    } else {
-        $$ = &Union{Left: $1, Right: $2}
-    }
-  }
+  { $$ = withUnion($1, $2) }
 | '(' create_view_select_paren ')' union_opt
-  {
-    if $4 == nil {
-        $$ = &ParenSelect{Select: $2}
-    } else {
-        $$ = &Union{Left: &ParenSelect{Select: $2}, Right: $4}
-    }
-  }
+  { $$ = withUnion(&ParenSelect{Select: $2}, $4) }
 ;
 
 create_view_select_paren:
@@ -5010,31 +5111,31 @@ create_view_select:
   SELECT_SYM select_part2 { $$ = $2 };
 
 view_check_option:
- 
-| WITH CHECK_SYM OPTION
-| WITH CASCADED CHECK_SYM OPTION
-| WITH LOCAL_SYM CHECK_SYM OPTION;
+  { $$ = "" }
+| WITH CHECK_SYM OPTION { $$ = "with check option" }
+| WITH CASCADED CHECK_SYM OPTION { $$ = "with cascaded check option" }
+| WITH LOCAL_SYM CHECK_SYM OPTION { $$ = "with local check option" };
 
 trigger_tail:
   TRIGGER_SYM remember_name sp_name trg_action_time trg_event ON remember_name table_ident FOR_SYM remember_name EACH_SYM ROW_SYM sp_proc_stmt
-  { $$ = &triggerTail{Trigger: $3} }
+  { $$ = &triggerTail{Trigger: $3, Time: $4, Event: $5, Table: $8, Body: textFrom(MySQLlex, MySQLchar, $<end>12)} }
 ;
 
 udf_tail:
   AGGREGATE_SYM remember_name FUNCTION_SYM ident RETURNS_SYM udf_type SONAME_SYM TEXT_STRING_sys 
-  { $$ = &udfTail{} }
+  { $$ = &udfTail{Aggregate: true, Function: &Spname{Name: $4}, Returns: $6, Soname: StrVal($8)} }
 | remember_name FUNCTION_SYM ident RETURNS_SYM udf_type SONAME_SYM TEXT_STRING_sys
-  { $$ = &udfTail{} }
+  { $$ = &udfTail{Function: &Spname{Name: $3}, Returns: $5, Soname: StrVal($7)} }
 ;
 
 sf_tail:
   remember_name FUNCTION_SYM sp_name '(' sp_fdparam_list ')' RETURNS_SYM type_with_opt_collate sp_c_chistics sp_proc_stmt
-  { $$ = &sfTail{Function: $3} }
+  { $$ = &sfTail{Function: $3, Definition: textFrom(MySQLlex, MySQLchar, $<end>3)} }
 ;
 
 sp_tail:
   PROCEDURE_SYM remember_name sp_name '(' sp_pdparam_list ')' sp_c_chistics sp_proc_stmt
-  { $$ = &spTail{Procedure: $3} }
+  { $$ = &spTail{Procedure: $3, Definition: textFrom(MySQLlex, MySQLchar, $<end>3)} }
 ;
 
 xa:
@@ -82,5 +82,5 @@ func GetLexStatus(which uint) string {
 		return v
 	}
 
-	return fmt.Sprint("Unknow Status[%d]", which)
+	return fmt.Sprintf("Unknow Status[%d]", which)
 }
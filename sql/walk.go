@@ -0,0 +1,497 @@
+package sql
+
+import (
+	"reflect"
+)
+
+// Visit is called by Walk for each node. It returns false to leave out
+// the children of node, an error stops the walk and is returned by Walk.
+type Visit func(node Node) (kontinue bool, err error)
+
+// Walk calls visit for each node of the trees of nodes in pre-order,
+// statements, expressions, table references, subqueries and unions.
+func Walk(visit Visit, nodes ...Node) error {
+	var err error
+	r := &rewriter{}
+	r.pre = func(c *Cursor) bool {
+		kontinue, e := visit(c.node)
+		if e != nil {
+			err, r.stop = e, true
+			return false
+		}
+		return kontinue
+	}
+	for _, node := range nodes {
+		r.apply(nil, node, func(Node) {})
+	}
+	return err
+}
+
+// ApplyFunc is called by Rewrite for each node. The pre one returns false
+// to leave out the children of the node, the post one false to stop the
+// rewrite.
+type ApplyFunc func(c *Cursor) bool
+
+// Cursor is the node Rewrite is at, with its parent and a way to put
+// another node in its place
+type Cursor struct {
+	parent   Node
+	node     Node
+	replacer func(Node)
+}
+
+// Node is the current node
+func (c *Cursor) Node() Node {
+	return c.node
+}
+
+// Parent is the node holding the current one, nil for the root
+func (c *Cursor) Parent() Node {
+	return c.parent
+}
+
+// Replace puts node in place of the current one in its parent. It panics
+// when node can not go where the current one is, like a table in place of
+// an expression. The children walked after a pre call are those of node.
+func (c *Cursor) Replace(node Node) {
+	c.replacer(node)
+	c.node = node
+}
+
+// Rewrite walks the tree of node depth first, calling pre before the
+// children of each node and post after them, either may be nil. It is
+// node, or what replaced it, that is returned.
+func Rewrite(node Node, pre, post ApplyFunc) Node {
+	r := &rewriter{pre: pre, post: post}
+	r.apply(nil, node, func(n Node) { node = n })
+	return node
+}
+
+type rewriter struct {
+	pre, post ApplyFunc
+	stop      bool
+}
+
+func (r *rewriter) apply(parent, node Node, replacer func(Node)) {
+	if r.stop || absent(node) {
+		return
+	}
+	c := &Cursor{parent: parent, node: node, replacer: replacer}
+	if r.pre != nil && !r.pre(c) {
+		return
+	}
+	r.applyChildren(c.node)
+	if !r.stop && r.post != nil && !r.post(c) {
+		r.stop = true
+	}
+}
+
+// absent is whether node is left out, a nil pointer or a nil list like the
+// ORDER BY of a select that has none
+func absent(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return v.IsNil()
+	}
+	return false
+}
+
+// applyChildren applies r to the children of node in the order they are
+// written in SQL
+func (r *rewriter) applyChildren(node Node) {
+	switch n := node.(type) {
+	//statements
+	case *Select:
+		r.apply(n, n.Fields, func(x Node) { n.Fields = x.(SelectFields) })
+		r.apply(n, n.From, func(x Node) { n.From = x.(ITables) })
+		r.apply(n, n.Where, func(x Node) { n.Where = x.(IExpr) })
+		r.apply(n, n.GroupBy, func(x Node) { n.GroupBy = x.(GroupBy) })
+		r.apply(n, n.Having, func(x Node) { n.Having = x.(IExpr) })
+		r.apply(n, n.OrderBy, func(x Node) { n.OrderBy = x.(OrderBy) })
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *Union:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(ISelect) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(ISelect) })
+	case *ParenSelect:
+		r.apply(n, n.Select, func(x Node) { n.Select = x.(ISelect) })
+		r.apply(n, n.OrderBy, func(x Node) { n.OrderBy = x.(OrderBy) })
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *SubQuery:
+		r.apply(n, n.SelectStatement, func(x Node) { n.SelectStatement = x.(ISelect) })
+	case *Insert:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+		r.apply(n, n.Columns, func(x Node) { n.Columns = x.(IValExprs) })
+		if fields, ok := n.InsertFields.(Node); ok {
+			r.apply(n, fields, func(x Node) { n.InsertFields = x })
+		}
+		r.applyAssignments(n, n.OnDup)
+	case *Replace:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ITable) })
+		r.apply(n, n.Columns, func(x Node) { n.Columns = x.(IValExprs) })
+		if fields, ok := n.ReplaceFields.(Node); ok {
+			r.apply(n, fields, func(x Node) { n.ReplaceFields = x })
+		}
+	case *Update:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ITables) })
+		r.applyAssignments(n, n.Set)
+		r.apply(n, n.Where, func(x Node) { n.Where = x.(IExpr) })
+		r.apply(n, n.OrderBy, func(x Node) { n.OrderBy = x.(OrderBy) })
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *Delete:
+		//Tables starts with the Targets, they are walked once and
+		//replaced in both
+		for i := range n.Tables {
+			i := i
+			r.apply(n, n.Tables[i], func(x Node) {
+				n.Tables[i] = x.(ITable)
+				if i < len(n.Targets) {
+					n.Targets[i] = n.Tables[i]
+				}
+			})
+		}
+		r.apply(n, n.Where, func(x Node) { n.Where = x.(IExpr) })
+		r.apply(n, n.OrderBy, func(x Node) { n.OrderBy = x.(OrderBy) })
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *Set:
+		for i := range n.VarList {
+			i := i
+			r.apply(n, n.VarList[i], func(x Node) { n.VarList[i] = x.(*Variable) })
+		}
+	case *DescribeTable:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *DescribeStmt:
+		r.apply(n, n.Stmt, func(x Node) { n.Stmt = x.(IStatement) })
+	case *Call:
+		r.apply(n, n.Spname, func(x Node) { n.Spname = x.(*Spname) })
+		r.apply(n, n.Args, func(x Node) { n.Args = x.(IExprs) })
+	case *Lock:
+		for i := range n.Tables {
+			i := i
+			r.apply(n, n.Tables[i], func(x Node) { n.Tables[i] = x.(*TableLock) })
+		}
+	case *CreateTable:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *CreateIndex:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *CreateView:
+		r.apply(n, n.View, func(x Node) { n.View = x.(ISimpleTable) })
+		r.apply(n, n.As, func(x Node) { n.As = x.(ISelect) })
+	case *CreateEvent:
+		r.apply(n, n.Event, func(x Node) { n.Event = x.(ISimpleTable) })
+	case *CreateProcedure:
+		r.apply(n, n.Procedure, func(x Node) { n.Procedure = x.(ISimpleTable) })
+	case *CreateFunction:
+		r.apply(n, n.Function, func(x Node) { n.Function = x.(ISimpleTable) })
+	case *CreateUDF:
+		r.apply(n, n.Function, func(x Node) { n.Function = x.(ISimpleTable) })
+	case *CreateTrigger:
+		r.apply(n, n.Trigger, func(x Node) { n.Trigger = x.(ISimpleTable) })
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *AlterTable:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *AlterView:
+		r.apply(n, n.View, func(x Node) { n.View = x.(ISimpleTable) })
+		r.apply(n, n.As, func(x Node) { n.As = x.(ISelect) })
+	case *AlterEvent:
+		r.apply(n, n.Event, func(x Node) { n.Event = x.(*Spname) })
+		r.apply(n, n.Rename, func(x Node) { n.Rename = x.(*Spname) })
+	case *AlterProcedure:
+		r.apply(n, n.Procedure, func(x Node) { n.Procedure = x.(*Spname) })
+	case *AlterFunction:
+		r.apply(n, n.Function, func(x Node) { n.Function = x.(*Spname) })
+	case *DropTables:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *DropView:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *DropIndex:
+		r.apply(n, n.On, func(x Node) { n.On = x.(ISimpleTable) })
+	case *DropEvent:
+		r.apply(n, n.Event, func(x Node) { n.Event = x.(*Spname) })
+	case *DropProcedure:
+		r.apply(n, n.Procedure, func(x Node) { n.Procedure = x.(*Spname) })
+	case *DropFunction:
+		r.apply(n, n.Function, func(x Node) { n.Function = x.(*Spname) })
+	case *DropTrigger:
+		r.apply(n, n.Trigger, func(x Node) { n.Trigger = x.(*Spname) })
+	case *RenameTable:
+		for i := range n.ToList {
+			i := i
+			r.apply(n, n.ToList[i], func(x Node) { n.ToList[i] = x.(*TableToTable) })
+		}
+	case *TruncateTable:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *Check:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *CheckSum:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *Repair:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *Analyze:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *Optimize:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *CacheIndex:
+		r.apply(n, n.TableIndexList, func(x Node) { n.TableIndexList = x.(TableIndexes) })
+	case *LoadIndex:
+		r.apply(n, n.TableIndexList, func(x Node) { n.TableIndexList = x.(TableIndexes) })
+	case *FlushTables:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ISimpleTables) })
+	case *Kill:
+		r.apply(n, n.Id, func(x Node) { n.Id = x.(IExpr) })
+	case *ShowDatabases:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowTables:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowTriggers:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowEvents:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowTableStatus:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowOpenTables:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowColumns:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowIndex:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+		r.apply(n, n.Where, func(x Node) { n.Where = x.(IExpr) })
+	case *ShowProcedure:
+		r.apply(n, n.Procedure, func(x Node) { n.Procedure = x.(*Spname) })
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowFunction:
+		r.apply(n, n.Function, func(x Node) { n.Function = x.(*Spname) })
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowCreate:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case *ShowCollation:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowCharset:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowVariables:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowStatus:
+		r.apply(n, n.LikeOrWhere, func(x Node) { n.LikeOrWhere = x.(*LikeOrWhere) })
+	case *ShowProfile:
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *ShowWarnings:
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *ShowErrors:
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+	case *ShowLogEvents:
+		r.apply(n, n.Limit, func(x Node) { n.Limit = x.(*Limit) })
+
+	//clauses
+	case SelectFields:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(ISelectField) })
+		}
+	case *AliasedField:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case GroupBy:
+		r.applyOrders(n, n)
+	case OrderBy:
+		r.applyOrders(n, n)
+	case *Order:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *Limit:
+		r.apply(n, n.Offset, func(x Node) { n.Offset = x.(IValExpr) })
+		r.apply(n, n.Rowcount, func(x Node) { n.Rowcount = x.(IValExpr) })
+	case *InsertValues:
+		r.apply(n, n.Columns, func(x Node) { n.Columns = x.(IValExprs) })
+		for i := range n.Rows {
+			i := i
+			r.apply(n, n.Rows[i], func(x Node) { n.Rows[i] = x.(IExprs) })
+		}
+	case *Assignment:
+		r.apply(n, n.Column, func(x Node) { n.Column = x.(IValExpr) })
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *Variable:
+		r.apply(n, n.Value, func(x Node) { n.Value = x.(IExpr) })
+	case *LikeOrWhere:
+		r.apply(n, n.Where, func(x Node) { n.Where = x.(IExpr) })
+	case *TableLock:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+	case TableIndexes:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(*TableIndex) })
+		}
+	case *TableIndex:
+		r.apply(n, n.Table, func(x Node) { n.Table = x.(ISimpleTable) })
+
+	//table references
+	case ITables:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(ITable) })
+		}
+	case *JoinTable:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(ITable) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(ITable) })
+		r.apply(n, n.On, func(x Node) { n.On = x.(IExpr) })
+	case *ParenTable:
+		r.apply(n, n.Tables, func(x Node) { n.Tables = x.(ITables) })
+	case ISimpleTables:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(ISimpleTable) })
+		}
+	case *TableToTable:
+		r.apply(n, n.From, func(x Node) { n.From = x.(ISimpleTable) })
+		r.apply(n, n.To, func(x Node) { n.To = x.(ISimpleTable) })
+	case *AliasedTable:
+		if t, ok := n.TableOrSubQuery.(Node); ok {
+			r.apply(n, t, func(x Node) { n.TableOrSubQuery = x })
+		}
+		for i := range n.IndexHints {
+			i := i
+			r.apply(n, n.IndexHints[i], func(x Node) { n.IndexHints[i] = x.(*IndexHint) })
+		}
+
+	//expressions
+	case *AndExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IExpr) })
+	case *OrExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IExpr) })
+	case *XorExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IExpr) })
+	case *NotExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *IsCheck:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IBoolExpr) })
+	case *NullCheck:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IBoolExpr) })
+	case *CompareExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IBoolExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IValExpr) })
+	case *Predicate:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IValExpr) })
+	case *InCond:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IValExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IExprs) })
+	case *RangeCond:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IValExpr) })
+		r.apply(n, n.From, func(x Node) { n.From = x.(IValExpr) })
+		r.apply(n, n.To, func(x Node) { n.To = x.(IValExpr) })
+	case *LikeCond:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IValExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IValExpr) })
+		r.apply(n, n.Escape, func(x Node) { n.Escape = x.(IValExpr) })
+	case IExprs:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(IExpr) })
+		}
+	case IValExprs:
+		for i := range n {
+			i := i
+			r.apply(n, n[i], func(x Node) { n[i] = x.(IValExpr) })
+		}
+	case *BinaryExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IExpr) })
+	case *UnaryExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *IntervalExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *OrOrExpr:
+		r.apply(n, n.Left, func(x Node) { n.Left = x.(IValExpr) })
+		r.apply(n, n.Right, func(x Node) { n.Right = x.(IValExpr) })
+	case *FuncExpr:
+		for i := range n.Exprs {
+			i := i
+			r.apply(n, n.Exprs[i], func(x Node) { n.Exprs[i] = x.(IExpr) })
+		}
+	case *GroupConcatExpr:
+		r.apply(n, n.Exprs, func(x Node) { n.Exprs = x.(IExprs) })
+		r.apply(n, n.OrderBy, func(x Node) { n.OrderBy = x.(OrderBy) })
+	case *ConvertExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *UsingExpr:
+		r.apply(n, n.Exprs, func(x Node) { n.Exprs = x.(IExprs) })
+	case *TrimExpr:
+		r.apply(n, n.Remove, func(x Node) { n.Remove = x.(IExpr) })
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *ExtractExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *WeightStringExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *CaseExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+		for i := range n.Whens {
+			i := i
+			r.apply(n, n.Whens[i], func(x Node) { n.Whens[i] = x.(*When) })
+		}
+		r.apply(n, n.Else, func(x Node) { n.Else = x.(IExpr) })
+	case *When:
+		r.apply(n, n.Cond, func(x Node) { n.Cond = x.(IExpr) })
+		r.apply(n, n.Val, func(x Node) { n.Val = x.(IExpr) })
+	case *ExistsExpr:
+		r.apply(n, n.SubQuery, func(x Node) { n.SubQuery = x.(*SubQuery) })
+	case *IdentExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IExpr) })
+	case *MatchExpr:
+		r.apply(n, n.Columns, func(x Node) { n.Columns = x.(IValExprs) })
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IValExpr) })
+	case *CollateExpr:
+		r.apply(n, n.Expr, func(x Node) { n.Expr = x.(IValExpr) })
+	}
+}
+
+func (r *rewriter) applyOrders(parent Node, list []*Order) {
+	for i := range list {
+		i := i
+		r.apply(parent, list[i], func(x Node) { list[i] = x.(*Order) })
+	}
+}
+
+func (r *rewriter) applyAssignments(parent Node, list []*Assignment) {
+	for i := range list {
+		i := i
+		r.apply(parent, list[i], func(x Node) { list[i] = x.(*Assignment) })
+	}
+}
+
+// FromTables is the tables in the FROM clauses of node, those of derived
+// tables and of the selects of a union included. Subqueries elsewhere, like
+// in WHERE, are left out as GetSchemas leaves them out.
+func FromTables(node Node) []*SimpleTable {
+	var ret []*SimpleTable
+	var visit Visit
+	visit = func(node Node) (bool, error) {
+		switch v := node.(type) {
+		case *Select:
+			return false, Walk(visit, v.From)
+		case *ParenSelect:
+			return false, Walk(visit, v.Select)
+		case *JoinTable:
+			return false, Walk(visit, v.Left, v.Right)
+		case *SimpleTable:
+			ret = append(ret, v)
+		}
+		return true, nil
+	}
+	Walk(visit, node)
+	return ret
+}
+
+// tableNames is the names of the tables in the FROM clauses of node, nil
+// when there are none
+func tableNames(node Node) []string {
+	var ret []string
+	for _, t := range FromTables(node) {
+		if len(t.Name) > 0 {
+			ret = append(ret, string(t.Name))
+		}
+	}
+	return ret
+}
@@ -0,0 +1,201 @@
+package sql
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// columns is the column names under node in the order Walk visits them
+func columns(t *testing.T, node Node, visit func(node Node) bool) []string {
+	var ret []string
+	err := Walk(func(node Node) (bool, error) {
+		if c, ok := node.(*SchemaObject); ok {
+			ret = append(ret, String(c))
+		}
+		return visit == nil || visit(node), nil
+	}, node)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ret
+}
+
+func TestWalk(t *testing.T) {
+	st, err := Parse("select a, f(b), (select c from x) from t join u on t.d = u.e where g in (select h from v) and exists (select i from w) " +
+		"group by j having k > 1 order by l limit 1 union all (select m from y) order by n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "c", "t.d", "u.e", "g", "h", "i", "j", "k", "l", "m", "n"}
+	if got := columns(t, st, nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("%v, want %v", got, want)
+	}
+
+	//the subqueries are left out, a select in parentheses is not one
+	got := columns(t, st, func(node Node) bool {
+		_, ok := node.(*SubQuery)
+		return !ok
+	})
+	want = []string{"a", "b", "t.d", "u.e", "g", "j", "k", "l", "m", "n"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%v, want %v", got, want)
+	}
+
+	st, err = Parse("insert into t (a) select b from u on duplicate key update c = d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"a", "b", "c", "d"}
+	if got := columns(t, st, nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("%v, want %v", got, want)
+	}
+
+	//an error stops the walk
+	stop := errors.New("stop")
+	n := 0
+	err = Walk(func(node Node) (bool, error) {
+		if c, ok := node.(*SchemaObject); ok {
+			if n++; string(c.Column) == "b" {
+				return false, stop
+			}
+		}
+		return true, nil
+	}, st, st)
+	if err != stop || n != 2 {
+		t.Fatalf("%v after %d columns", err, n)
+	}
+}
+
+func TestWalkTables(t *testing.T) {
+	for _, c := range []struct {
+		sql    string
+		tables []string
+	}{
+		{"drop table if exists a, db.b", []string{"a", "db.b"}},
+		{"drop index i on a", []string{"a"}},
+		{"truncate table db.a", []string{"db.a"}},
+		{"rename table a to b, c to db.d", []string{"a", "b", "c", "db.d"}},
+		{"create index i on a (x)", []string{"a"}},
+		{"create view v as select x from a", []string{"v", "a"}},
+		{"lock tables a read, b write", []string{"a", "b"}},
+		{"cache index a, b in c", []string{"a", "b"}},
+		{"show columns from a", []string{"a"}},
+		{"show index from a where x in (select y from b)", []string{"a", "b"}},
+		{"show tables where x in (select y from a)", []string{"a"}},
+	} {
+		st, err := Parse(c.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", c.sql, err)
+		}
+		var got []string
+		Walk(func(node Node) (bool, error) {
+			if v, ok := node.(*SimpleTable); ok {
+				got = append(got, String(v))
+			}
+			return true, nil
+		}, st)
+		if !reflect.DeepEqual(got, c.tables) {
+			t.Fatalf("%s: %v, want %v", c.sql, got, c.tables)
+		}
+	}
+
+	//the tables are qualified in place
+	st, err := Parse("drop table a, b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Rewrite(st, func(c *Cursor) bool {
+		if v, ok := c.Node().(*SimpleTable); ok {
+			c.Replace(&SimpleTable{Qualifier: []byte("db"), Name: v.Name})
+		}
+		return true
+	}, nil)
+	if s := String(st); s != "drop table db.a, db.b" {
+		t.Fatal(s)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	st, err := Parse("select a from t where b = 'x' and c in (1, 2) and d like 'y%' limit 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	//literals are masked
+	Rewrite(st, func(c *Cursor) bool {
+		switch c.Node().(type) {
+		case StrVal, NumVal:
+			c.Replace(ValArg("?"))
+		}
+		return true
+	}, nil)
+	if s := String(st); s != "select a from t where b = ? and c in (?, ?) and d like ? limit ?" {
+		t.Fatal(s)
+	}
+
+	//the root is replaced, post sees the children first
+	var order []string
+	e := Rewrite(&BinaryExpr{Operator: OP_PLUS, Left: NumVal("1"), Right: NumVal("2")}, nil, func(c *Cursor) bool {
+		order = append(order, String(c.Node()))
+		if _, ok := c.Node().(*BinaryExpr); ok && c.Parent() == nil {
+			c.Replace(&UnaryExpr{Operator: OP_UMINUS, Expr: c.Node().(IExpr)})
+		}
+		return true
+	})
+	if s := String(e); s != "-(1 + 2)" || strings.Join(order, "|") != "1|2|1 + 2" {
+		t.Fatalf("%s %v", s, order)
+	}
+
+	//post returning false stops the rewrite
+	n := 0
+	Rewrite(st, nil, func(c *Cursor) bool {
+		n++
+		return false
+	})
+	if n != 1 {
+		t.Fatalf("%d", n)
+	}
+
+	//a target of a delete is replaced with its table
+	st, err = Parse("delete t1, t2 from t1 join t2 where t1.a = t2.a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	Rewrite(st, func(c *Cursor) bool {
+		if v, ok := c.Node().(*SimpleTable); ok && string(v.Name) == "t1" {
+			c.Replace(&SimpleTable{Qualifier: []byte("db"), Name: v.Name})
+		}
+		return true
+	}, nil)
+	if s := String(st); s != "delete db.t1, t2 from db.t1 join t2 where t1.a = t2.a" {
+		t.Fatal(s)
+	}
+	if s := st.(*Delete).Tables.GetSchemas(); !reflect.DeepEqual(s, []string{"db", "db"}) {
+		t.Fatal(s)
+	}
+}
+
+func TestGetTables(t *testing.T) {
+	for _, c := range []struct {
+		sql    string
+		tables []string
+	}{
+		{"select a from t union select b from db.u", []string{"t", "u"}},
+		{"(select a from t join u) order by (select c from v)", []string{"t", "u"}},
+		{"select * from t where a in (select b from u)", []string{"t"}},
+		{"select * from (select a from t union select b from u) x", []string{"t", "u"}},
+	} {
+		st, err := Parse(c.sql)
+		if err != nil {
+			t.Fatalf("%s: %v", c.sql, err)
+		}
+		s := st.(ISelect)
+		if got := s.GetTables(); !reflect.DeepEqual(got, c.tables) {
+			t.Fatalf("%s: %v, want %v", c.sql, got, c.tables)
+		}
+		if got := (&SubQuery{SelectStatement: s}).GetTables(); !reflect.DeepEqual(got, c.tables) {
+			t.Fatalf("%s: subquery %v, want %v", c.sql, got, c.tables)
+		}
+	}
+}